@@ -1,24 +1,33 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sporthub/activities-api/internal/clients"
 	"github.com/sporthub/activities-api/internal/config"
+	"github.com/sporthub/activities-api/internal/consumers"
 	"github.com/sporthub/activities-api/internal/controllers"
 	"github.com/sporthub/activities-api/internal/db"
+	"github.com/sporthub/activities-api/internal/middleware"
 	"github.com/sporthub/activities-api/internal/repository"
 	"github.com/sporthub/activities-api/internal/services"
 	"github.com/sporthub/activities-api/internal/utils"
+	"github.com/sporthub/shared/jwks"
+	"github.com/sporthub/shared/rabbit"
 )
 
 func main() {
 	cfg := config.Load()
 
-	// Wait for RabbitMQ to be ready
+	// Wait for RabbitMQ to be ready. Si no está, el cliente sigue
+	// reintentando en background y los eventos fallan hasta que conecte.
 	if err := utils.WaitForService("rabbitmq", 5672, 60); err != nil {
-		log.Fatalf("Failed to wait for RabbitMQ: %v", err)
+		log.Printf("WARN: RabbitMQ not ready (%v), will keep retrying", err)
 	}
 
 	// DB
@@ -26,11 +35,24 @@ func main() {
 	defer mc.Disconnect(cfg.Ctx)
 
 	// RabbitMQ
-	rmq := clients.MustRabbit(cfg.RabbitURL, cfg.Exchange, cfg.ExchangeType)
+	rmq := rabbit.New(cfg.RabbitURL, cfg.Exchange, cfg.ExchangeType, cfg.ConfirmTimeout)
 	defer rmq.Close()
 
 	// Users client
-	users := clients.NewUsersClient(cfg.UsersAPIBase)
+	users := clients.NewUsersClient(cfg.UsersAPIBase, cfg.UsersClientID, cfg.UsersClientSecret)
+
+	// Tokens revocados en users-api (logout / reuso de refresh token)
+	revoked := clients.NewRevocationList(users, cfg.RevocationPollInterval)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go revoked.Run(ctx)
+	keys := jwks.New(cfg.JWKSURL, 10*time.Minute)
+	auth := middleware.JWTAuth(keys, cfg.JWTIssuer, cfg.JWTAudience, revoked)
+
+	// Los documentos anteriores a las organizaciones pasan a DEFAULT_ORG_ID
+	if err := repository.BackfillTenant(cfg.Ctx, mdb, cfg.DefaultOrgID); err != nil {
+		log.Printf("WARN: tenant backfill: %v", err)
+	}
 
 	// Repos
 	actRepo := repository.NewActivitiesMongo(mdb)
@@ -42,16 +64,36 @@ func main() {
 	sesSvc := services.NewSessionsService(sesRepo, actRepo, rmq, cfg)
 	enrSvc := services.NewEnrollmentsService(enrRepo, sesRepo, actRepo, rmq, cfg)
 
+	// Bajas de cuentas en users-api: cancela/anonimiza inscripciones y
+	// reasigna actividades
+	userEvents := consumers.NewUserEventsConsumer(cfg.RabbitURL, cfg.UsersEventsExchange, cfg.UsersEventsQueue,
+		services.NewUserEventsService(enrRepo, sesRepo, actRepo, rmq, cfg))
+	go userEvents.Run(ctx)
+
 	// Router
 	r := gin.Default()
 	r.Use(controllers.CORSMiddleware())
+	// Organización (club) de cada request: los repos filtran por ella
+	r.Use(middleware.Tenant(cfg.DefaultOrgID))
+
+	r.GET("/health", func(c *gin.Context) {
+		rabbit := "disconnected"
+		if rmq.Connected() {
+			rabbit = "connected"
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "rabbit": rabbit})
+	})
 
 	// IMPORTANTE: Registrar rutas más específicas PRIMERO
 	// RegisterSessionRoutes registra /activities/:activityId/sessions
 	// que debe registrarse ANTES de /activities/:id
-	controllers.RegisterSessionRoutes(r, sesSvc, actSvc, rmq.GetChannel(), cfg.JWTSecret)
-	controllers.RegisterActivityRoutes(r, actSvc, sesSvc, cfg)
-	controllers.RegisterEnrollmentRoutes(r, enrSvc, cfg.JWTSecret)
+	controllers.RegisterSessionRoutes(r, sesSvc, actSvc, auth)
+	controllers.RegisterActivityRoutes(r, actSvc, sesSvc, auth)
+	controllers.RegisterEnrollmentRoutes(r, enrSvc, auth, cfg.RequireVerifiedEmail)
+
+	// Rutas internas para users-api (exportación de datos personales)
+	serviceAuth := middleware.ServiceAuth(keys, cfg.JWTIssuer, cfg.ServiceAudience, strings.Split(cfg.InternalClients, ",")...)
+	controllers.RegisterInternalRoutes(r, enrSvc, serviceAuth)
 
 	port := cfg.Port
 	if port == "" {
@@ -0,0 +1,8 @@
+package clients
+
+// Publisher publica eventos de dominio. En producción es el publisher de
+// shared/rabbit (confirms, mandatory y reconexión); en los tests, un fake.
+type Publisher interface {
+	Close()
+	Publish(routing string, payload any) error
+}
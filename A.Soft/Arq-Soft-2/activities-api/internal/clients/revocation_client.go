@@ -0,0 +1,104 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RevocationList mantiene en memoria los access tokens revocados en
+// users-api (logout, reuso de refresh token). Se actualiza consultando
+// periódicamente GET /auth/revoked?since=..., que pide token de servicio (el
+// mismo del UsersClient)
+type RevocationList struct {
+	users    *UsersClient
+	interval time.Duration
+
+	mu      sync.RWMutex
+	revoked map[string]time.Time // jti -> expiración del token
+	since   int64
+}
+
+type revokedResponse struct {
+	Revoked []struct {
+		JTI string `json:"jti"`
+		Exp int64  `json:"exp"`
+	} `json:"revoked"`
+	Now int64 `json:"now"`
+}
+
+func NewRevocationList(users *UsersClient, interval time.Duration) *RevocationList {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &RevocationList{
+		users:    users,
+		interval: interval,
+		revoked:  map[string]time.Time{},
+	}
+}
+
+// IsRevoked indica si el jti está en la lista
+func (l *RevocationList) IsRevoked(jti string) bool {
+	if jti == "" {
+		return false
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.revoked[jti]
+	return ok
+}
+
+// Run sincroniza la lista cada interval hasta que se cancele ctx
+func (l *RevocationList) Run(ctx context.Context) {
+	t := time.NewTicker(l.interval)
+	defer t.Stop()
+	for {
+		if err := l.sync(); err != nil {
+			log.Printf("[auth] WARN: revocation list sync failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (l *RevocationList) sync() error {
+	l.mu.RLock()
+	since := l.since
+	l.mu.RUnlock()
+
+	res, err := l.users.get(fmt.Sprintf("%s/auth/revoked?since=%d", l.users.base, since))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("users-api returned %d", res.StatusCode)
+	}
+	var out revokedResponse
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, r := range out.Revoked {
+		l.revoked[r.JTI] = time.Unix(r.Exp, 0)
+	}
+	// los tokens ya expirados los rechaza igual la validación de exp
+	for jti, exp := range l.revoked {
+		if now.After(exp) {
+			delete(l.revoked, jti)
+		}
+	}
+	l.since = out.Now
+	return nil
+}
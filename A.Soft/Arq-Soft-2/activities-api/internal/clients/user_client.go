@@ -4,30 +4,46 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
+// UsersClient consulta users-api autenticándose con un token de servicio
+// (client credentials), que se pide a /auth/token y se cachea hasta que vence
 type UsersClient struct {
 	base string
 	http *http.Client
+
+	clientID     string
+	clientSecret string
+	mu           sync.Mutex
+	token        string
+	tokenExp     time.Time
 }
 
+// UserDTO es la vista resumida de GET /users/:id (sin datos de contacto)
 type UserDTO struct {
-	ID    uint64 `json:"id"`
-	Role  string `json:"rol"`
-	Email string `json:"email"`
+	ID            uint64 `json:"id"`
+	Username      string `json:"username"`
+	Role          string `json:"role"`
+	Email         string `json:"email"`
+	DisplayName   string `json:"displayName"`
+	EmailVerified bool   `json:"emailVerified"`
 }
 
-func NewUsersClient(base string) *UsersClient {
+func NewUsersClient(base, clientID, clientSecret string) *UsersClient {
 	return &UsersClient{
-		base: base,
-		http: &http.Client{Timeout: 5 * time.Second},
+		base:         base,
+		http:         &http.Client{Timeout: 5 * time.Second},
+		clientID:     clientID,
+		clientSecret: clientSecret,
 	}
 }
 
 func (c *UsersClient) GetUser(id string) (*UserDTO, error) {
-	req, _ := http.NewRequest("GET", fmt.Sprintf("%s/users/%s", c.base, id), nil)
-	res, err := c.http.Do(req)
+	res, err := c.get(fmt.Sprintf("%s/users/%s", c.base, id))
 	if err != nil {
 		return nil, err
 	}
@@ -41,3 +57,91 @@ func (c *UsersClient) GetUser(id string) (*UserDTO, error) {
 	}
 	return &u, nil
 }
+
+// MembershipDTO es la membresía de un usuario en una organización (GET
+// /orgs/:id/members/:userId)
+type MembershipDTO struct {
+	OrgID  uint64 `json:"orgId"`
+	UserID uint64 `json:"userId"`
+	Role   string `json:"role"`
+}
+
+// GetMembership devuelve el rol del usuario en la organización; nil, nil si
+// no es miembro
+func (c *UsersClient) GetMembership(orgID, userID string) (*MembershipDTO, error) {
+	res, err := c.get(fmt.Sprintf("%s/orgs/%s/members/%s", c.base, orgID, userID))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("users-api returned %d", res.StatusCode)
+	}
+	var m MembershipDTO
+	if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// get hace un GET con el token de servicio; ante un 401 (token rotado o
+// vencido antes de lo esperado) pide uno nuevo y reintenta una vez
+func (c *UsersClient) get(u string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		token, err := c.serviceToken()
+		if err != nil {
+			return nil, err
+		}
+		req, _ := http.NewRequest("GET", u, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := c.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return res, nil
+		}
+		res.Body.Close()
+		c.mu.Lock()
+		c.token = ""
+		c.mu.Unlock()
+	}
+}
+
+func (c *UsersClient) serviceToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// margen para no mandar un token que vence en vuelo
+	if c.token != "" && time.Now().Add(30*time.Second).Before(c.tokenExp) {
+		return c.token, nil
+	}
+	if c.clientSecret == "" {
+		return "", fmt.Errorf("users-api client credentials not configured (USERS_API_CLIENT_SECRET)")
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, _ := http.NewRequest("POST", c.base+"/auth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.clientID, c.clientSecret)
+	res, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("users-api token endpoint returned %d", res.StatusCode)
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	c.token = body.AccessToken
+	c.tokenExp = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	return c.token, nil
+}
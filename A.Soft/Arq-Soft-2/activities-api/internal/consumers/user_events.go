@@ -0,0 +1,215 @@
+// Package consumers procesa los eventos que activities-api recibe de otros
+// servicios por RabbitMQ
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/sporthub/activities-api/internal/services"
+)
+
+const (
+	userEventsTag = "activities-api-user-events"
+	// retriesHeader cuenta los reintentos de una entrega
+	retriesHeader = "x-retries"
+)
+
+// retryDelays son las esperas de los reintentos: cada una es una cola
+// <cola>.retry.N con ese TTL que, al vencer, devuelve el mensaje a la cola
+// principal (dead-letter al exchange por defecto). Agotados los reintentos el
+// mensaje queda en <cola>.dlq para revisarlo a mano.
+var retryDelays = []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute, 10 * time.Minute, 30 * time.Minute}
+
+// UserEventsConsumer escucha user.* de users-api en una cola propia y
+// duradera, así los eventos emitidos mientras activities-api está caído se
+// procesan al volver
+type UserEventsConsumer struct {
+	url      string
+	exchange string
+	queue    string
+	svc      *services.UserEventsService
+}
+
+func NewUserEventsConsumer(url, exchange, queue string, svc *services.UserEventsService) *UserEventsConsumer {
+	return &UserEventsConsumer{url: url, exchange: exchange, queue: queue, svc: svc}
+}
+
+// Run consume y se reconecta con backoff exponencial hasta que se cancele ctx
+func (c *UserEventsConsumer) Run(ctx context.Context) {
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		err := c.consume(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("[users-events] consumer stopped (%v), retrying in %v (attempt %d)...", err, delay, attempt)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, 30*time.Second)
+	}
+}
+
+func (c *UserEventsConsumer) consume(ctx context.Context) error {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return fmt.Errorf("rabbit dial: %w", err)
+	}
+	defer conn.Close()
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("rabbit channel: %w", err)
+	}
+	defer ch.Close()
+
+	if err := ch.ExchangeDeclare(c.exchange, "topic", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("exchange declare: %w", err)
+	}
+	if _, err := ch.QueueDeclare(c.queue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("queue declare: %w", err)
+	}
+	if err := ch.QueueBind(c.queue, "user.*", c.exchange, false, nil); err != nil {
+		return fmt.Errorf("queue bind: %w", err)
+	}
+	for i, d := range retryDelays {
+		_, err := ch.QueueDeclare(c.retryQueue(i+1), true, false, false, false, amqp.Table{
+			"x-message-ttl":             d.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": c.queue,
+		})
+		if err != nil {
+			return fmt.Errorf("retry queue declare: %w", err)
+		}
+	}
+	if _, err := ch.QueueDeclare(c.queue+".dlq", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("dlq declare: %w", err)
+	}
+	// confirm: una entrega se ackea solo cuando su copia ya está en la cola
+	// de reintento o en la dlq
+	if err := ch.Confirm(false); err != nil {
+		return fmt.Errorf("confirm mode: %w", err)
+	}
+	if err := ch.Qos(10, 0, false); err != nil {
+		return fmt.Errorf("qos: %w", err)
+	}
+	msgs, err := ch.Consume(c.queue, userEventsTag, false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("consume: %w", err)
+	}
+	log.Printf("[users-events] listening on queue %s (exchange=%s)", c.queue, c.exchange)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case m, ok := <-msgs:
+			if !ok {
+				return fmt.Errorf("channel closed")
+			}
+			c.process(ctx, ch, m)
+		}
+	}
+}
+
+// process maneja una entrega y hace el ack. Si falla se pasa a la cola de
+// reintento que corresponde (backoff) y, agotados los reintentos, a la dlq.
+func (c *UserEventsConsumer) process(ctx context.Context, ch *amqp.Channel, m amqp.Delivery) {
+	var ev services.UserEvent
+	if err := json.Unmarshal(m.Body, &ev); err != nil {
+		log.Printf("[users-events] ERROR: invalid event JSON: %v (%s)", err, m.Body)
+		c.deadLetter(ctx, ch, m, err)
+		return
+	}
+	err := c.svc.Handle(ctx, ev)
+	if err == nil {
+		_ = m.Ack(false)
+		return
+	}
+	retries := retryCount(m)
+	if retries >= len(retryDelays) {
+		log.Printf("[users-events] ERROR: %s for user %s failed after %d retries: %v", ev.Op, ev.UserID, retries, err)
+		c.deadLetter(ctx, ch, m, err)
+		return
+	}
+	log.Printf("[users-events] WARN: %s for user %s failed (%v), retry %d in %v", ev.Op, ev.UserID, err, retries+1, retryDelays[retries])
+	if err := c.republish(ctx, ch, c.retryQueue(retries+1), m, retries+1, err); err != nil {
+		log.Printf("[users-events] WARN: could not schedule retry (%v), requeueing", err)
+		_ = m.Nack(false, true)
+		return
+	}
+	_ = m.Ack(false)
+}
+
+func (c *UserEventsConsumer) deadLetter(ctx context.Context, ch *amqp.Channel, m amqp.Delivery, cause error) {
+	if err := c.republish(ctx, ch, c.queue+".dlq", m, retryCount(m), cause); err != nil {
+		log.Printf("[users-events] WARN: could not dead-letter (%v), requeueing", err)
+		_ = m.Nack(false, true)
+		return
+	}
+	_ = m.Ack(false)
+}
+
+func (c *UserEventsConsumer) retryQueue(n int) string {
+	return fmt.Sprintf("%s.retry.%d", c.queue, n)
+}
+
+// republish copia la entrega a queue (por el exchange por defecto) con el
+// contador de reintentos y el último error, y espera la confirmación
+func (c *UserEventsConsumer) republish(ctx context.Context, ch *amqp.Channel, queue string, m amqp.Delivery, retries int, cause error) error {
+	headers := amqp.Table{}
+	for k, v := range m.Headers {
+		headers[k] = v
+	}
+	headers[retriesHeader] = int32(retries)
+	headers["x-last-error"] = cause.Error()
+	if _, ok := headers["x-routing-key"]; !ok {
+		headers["x-routing-key"] = m.RoutingKey
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	dc, err := ch.PublishWithDeferredConfirmWithContext(ctx, "", queue, true, false, amqp.Publishing{
+		ContentType:  m.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    m.MessageId,
+		Timestamp:    m.Timestamp,
+		Headers:      headers,
+		Body:         m.Body,
+	})
+	if err != nil {
+		return err
+	}
+	acked, err := dc.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return errors.New("broker nacked the message")
+	}
+	return nil
+}
+
+// retryCount lee x-retries de la entrega (0 si no tiene)
+func retryCount(m amqp.Delivery) int {
+	switch v := m.Headers[retriesHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
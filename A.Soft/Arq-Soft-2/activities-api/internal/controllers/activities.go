@@ -9,14 +9,13 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sporthub/activities-api/internal/config"
 	"github.com/sporthub/activities-api/internal/domain"
-	"github.com/sporthub/activities-api/internal/middleware"
 	"github.com/sporthub/activities-api/internal/services"
+	"github.com/sporthub/shared/authz"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
-func RegisterActivityRoutes(r *gin.Engine, svc *services.ActivitiesService, sesSvc *services.SessionsService, cfg *config.Config) {
+func RegisterActivityRoutes(r *gin.Engine, svc *services.ActivitiesService, sesSvc *services.SessionsService, auth gin.HandlerFunc) {
 	// Public listing with pagination
 	pub := r.Group("/activities")
 	pub.GET("", func(c *gin.Context) {
@@ -78,15 +77,17 @@ func RegisterActivityRoutes(r *gin.Engine, svc *services.ActivitiesService, sesS
 			Price:      activity.PrecioBase,
 			Tags:       []string{},
 			UpdatedAt:  activity.UpdatedAt.Format(time.RFC3339),
+			OrgID:      activity.OrgID,
 		}
 
 		c.JSON(http.StatusOK, doc)
 	})
 
-	// Protected admin routes
+	// Protected routes: cada una exige su permiso; sin activity:manage-any
+	// solo sobre actividades propias (dueño o instructor)
 	g := r.Group("/activities")
-	g.Use(middleware.JWTAuth(cfg.JWTSecret))
-	g.POST("", middleware.RequireAdmin(), func(c *gin.Context) {
+	g.Use(auth)
+	g.POST("", authz.RequirePermission(authz.PermActivityCreate), func(c *gin.Context) {
 		var req CreateActivityRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -119,21 +120,25 @@ func RegisterActivityRoutes(r *gin.Engine, svc *services.ActivitiesService, sesS
 			Ubicacion:   req.Ubicacion,
 			Instructor:  req.Instructor,
 			PrecioBase:  req.PrecioBase,
+			InstructorUserID: req.InstructorUserID,
 		}
 		id, err := svc.Create(c, activity)
-		if err != nil {
+		if err != nil && !savedWithoutEvent(c, err) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusCreated, gin.H{"id": id})
 	})
 
-	g.PUT("/:id", middleware.RequireAdmin(), func(c *gin.Context) {
+	g.PUT("/:id", authz.RequirePermission(authz.PermActivityUpdate), func(c *gin.Context) {
 		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid activity id format"})
 			return
 		}
+		if !authorizeActivity(c, svc, id) {
+			return
+		}
 		
 		// Leer el body manualmente para verificar si instructor viene en el JSON
 		bodyBytes, err := io.ReadAll(c.Request.Body)
@@ -186,21 +191,27 @@ func RegisterActivityRoutes(r *gin.Engine, svc *services.ActivitiesService, sesS
 		if _, exists := jsonData["instructor"]; exists {
 			update["instructor"] = req.Instructor
 		}
+		if _, exists := jsonData["instructorUserId"]; exists {
+			update["instructorUserId"] = req.InstructorUserID
+		}
 		
-		if err := svc.Update(c, id, update); err != nil {
+		if err := svc.Update(c, id, update); err != nil && !savedWithoutEvent(c, err) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"ok": true})
 	})
 
-	g.DELETE("/:id", middleware.RequireAdmin(), func(c *gin.Context) {
+	g.DELETE("/:id", authz.RequirePermission(authz.PermActivityDelete), func(c *gin.Context) {
 		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid activity id format"})
 			return
 		}
-		if err := svc.Delete(c, id); err != nil {
+		if !authorizeActivity(c, svc, id) {
+			return
+		}
+		if err := svc.Delete(c, id); err != nil && !savedWithoutEvent(c, err) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
@@ -208,7 +219,7 @@ func RegisterActivityRoutes(r *gin.Engine, svc *services.ActivitiesService, sesS
 	})
 
 	// Endpoint para reindexar todas las actividades en Solr
-	g.POST("/reindex", middleware.RequireAdmin(), func(c *gin.Context) {
+	g.POST("/reindex", authz.RequirePermission(authz.PermSearchReindex), func(c *gin.Context) {
 		count, err := svc.ReindexAll(c)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sporthub/activities-api/internal/services"
+	"github.com/sporthub/shared/authz"
+)
+
+// requesterID es el userId del JWT como string (como se guarda en Mongo)
+func requesterID(c *gin.Context) string {
+	if id, ok := c.Get("userId"); ok {
+		return fmt.Sprintf("%v", id)
+	}
+	return ""
+}
+
+// authorizeActivity corta el request (403/404) si el usuario no puede
+// gestionar la actividad: hace falta activity:manage-any o ser su dueño o
+// instructor
+func authorizeActivity(c *gin.Context, svc *services.ActivitiesService, activityID uint64) bool {
+	_, err := svc.Authorize(c, activityID, requesterID(c), authz.HasPermission(c, authz.PermActivityManageAny))
+	if err != nil {
+		writeAuthzError(c, err)
+		return false
+	}
+	return true
+}
+
+func writeAuthzError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the owner or instructor of the activity can do this"})
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -7,15 +7,17 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sporthub/activities-api/internal/domain"
 	"github.com/sporthub/activities-api/internal/middleware"
 	"github.com/sporthub/activities-api/internal/services"
+	"github.com/sporthub/shared/authz"
 )
 
 type enrollReq struct {
 	SessionID string `json:"sessionId" binding:"required"`
 }
 
-func RegisterEnrollmentRoutes(r *gin.Engine, svc *services.EnrollmentsService, jwtSecret string) {
+func RegisterEnrollmentRoutes(r *gin.Engine, svc *services.EnrollmentsService, auth gin.HandlerFunc, requireVerified bool) {
 	// RUTA PÚBLICA: fuera del grupo protegido
 	r.GET("/enrollments/by-user/:userId", func(c *gin.Context) {
 		out, err := svc.ListByUser(c, c.Param("userId"))
@@ -28,11 +30,10 @@ func RegisterEnrollmentRoutes(r *gin.Engine, svc *services.EnrollmentsService, j
 
 	// GRUPO PROTEGIDO: todas las rutas aquí requieren autenticación
 	g := r.Group("/enrollments")
-	g.Use(middleware.JWTAuth(jwtSecret))
-	g.Use(middleware.RequireUser())
+	g.Use(auth)
 
-	// Protect enroll endpoint: user role required
-	g.POST("", func(c *gin.Context) {
+	// Protect enroll endpoint: enrollment:create (y email verificado si REQUIRE_VERIFIED_EMAIL)
+	g.POST("", authz.RequirePermission(authz.PermEnrollmentCreate), middleware.RequireVerifiedEmail(requireVerified), func(c *gin.Context) {
 		var req enrollReq
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -66,7 +67,7 @@ func RegisterEnrollmentRoutes(r *gin.Engine, svc *services.EnrollmentsService, j
 		}
 		
 		id, err := svc.Enroll(c, sessionID, userIdStr)
-		if err != nil {
+		if err != nil && !savedWithoutEvent(c, err) {
 			if errors.Is(err, services.ErrAlreadyEnrolled) {
 				c.JSON(http.StatusConflict, gin.H{"error": "ya inscripto en esta sesión"})
 				return
@@ -81,7 +82,26 @@ func RegisterEnrollmentRoutes(r *gin.Engine, svc *services.EnrollmentsService, j
 		c.JSON(http.StatusCreated, gin.H{"id": id})
 	})
 
-	// Cancel enrollment (owner or admin)
+	// Roster de una sesión: enrollment:view-roster y ser dueño o instructor de
+	// la actividad (o activity:manage-any)
+	g.GET("/by-session/:sessionId", authz.RequirePermission(authz.PermEnrollmentViewRoster), func(c *gin.Context) {
+		sessionID, err := strconv.ParseUint(c.Param("sessionId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id format"})
+			return
+		}
+		out, err := svc.Roster(c, sessionID, requesterID(c), authz.HasPermission(c, authz.PermActivityManageAny))
+		if err != nil {
+			writeAuthzError(c, err)
+			return
+		}
+		if out == nil {
+			out = []domain.Enrollment{}
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	// Cancel enrollment (owner or enrollment:manage-any)
 	g.PATCH("/:id/cancel", func(c *gin.Context) {
 		idStr := c.Param("id")
 		enrollmentID, err := strconv.ParseUint(idStr, 10, 64)
@@ -99,17 +119,11 @@ func RegisterEnrollmentRoutes(r *gin.Engine, svc *services.EnrollmentsService, j
 				requester = vv
 			}
 		}
-		role := ""
-		if r, ok := c.Get("role"); ok {
-			if rs, ok := r.(string); ok {
-				role = rs
-			}
-		}
 		if requester == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authentication"})
 			return
 		}
-		if err := svc.CancelEnrollment(c, enrollmentID, requester, role); err != nil {
+		if err := svc.CancelEnrollment(c, enrollmentID, requester, authz.HasPermission(c, authz.PermEnrollmentManageAny)); err != nil && !savedWithoutEvent(c, err) {
 			if errors.Is(err, services.ErrForbidden) {
 				c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 				return
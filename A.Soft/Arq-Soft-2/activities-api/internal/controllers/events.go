@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sporthub/activities-api/internal/services"
+)
+
+// savedWithoutEvent indica si err es solo ErrEventNotPublished: el cambio se
+// guardó pero su evento no llegó al broker. El request se responde como
+// éxito con un header Warning, así el cliente sabe que la búsqueda puede no
+// reflejarlo hasta un reindex (POST /admin/reindex).
+func savedWithoutEvent(c *gin.Context, err error) bool {
+	if !errors.Is(err, services.ErrEventNotPublished) {
+		return false
+	}
+	c.Header("Warning", `199 activities-api "change saved but its event was not published"`)
+	return true
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sporthub/activities-api/internal/domain"
+	"github.com/sporthub/activities-api/internal/middleware"
+	"github.com/sporthub/activities-api/internal/services"
+)
+
+// RegisterInternalRoutes registra las rutas para otros servicios (token de
+// servicio, ver middleware.ServiceAuth); no son para el frontend. Ven los
+// datos de todas las organizaciones.
+func RegisterInternalRoutes(r *gin.Engine, enrollments *services.EnrollmentsService, serviceAuth gin.HandlerFunc) {
+	g := r.Group("/internal", serviceAuth, middleware.AllTenants())
+
+	// Todas las inscripciones del usuario, canceladas incluidas (exportación
+	// de datos personales de users-api)
+	g.GET("/users/:userId/enrollments", func(c *gin.Context) {
+		out, err := enrollments.ListAllByUser(c, c.Param("userId"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if out == nil {
+			out = []domain.Enrollment{}
+		}
+		c.JSON(http.StatusOK, out)
+	})
+}
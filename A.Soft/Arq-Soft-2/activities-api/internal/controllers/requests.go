@@ -8,6 +8,9 @@ type CreateActivityRequest struct {
 	Ubicacion  string  `json:"ubicacion" binding:"required"`
 	Instructor string  `json:"instructor"`
 	PrecioBase float64 `json:"precioBase" binding:"required,gt=0"`
+
+	// Usuario instructor de la actividad (puede gestionarla igual que el dueño)
+	InstructorUserID string `json:"instructorUserId"`
 }
 
 type CreateSessionRequest struct {
@@ -1,30 +1,28 @@
 package controllers
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/rabbitmq/amqp091-go"
 
 	"github.com/sporthub/activities-api/internal/domain"
-	"github.com/sporthub/activities-api/internal/middleware"
 	"github.com/sporthub/activities-api/internal/services"
+	"github.com/sporthub/shared/authz"
 )
 
 // SessionController maneja las rutas relacionadas con las sesiones de actividades.
+// Los eventos activity.session.* los publica SessionsService.
 type SessionController struct {
 	service    *services.SessionsService
 	activity   *services.ActivitiesService
-	rabbitChan *amqp091.Channel
 }
 
 // NewSessionController crea un nuevo controlador de sesiones.
-func NewSessionController(s *services.SessionsService, a *services.ActivitiesService, ch *amqp091.Channel) *SessionController {
-	return &SessionController{service: s, activity: a, rabbitChan: ch}
+func NewSessionController(s *services.SessionsService, a *services.ActivitiesService) *SessionController {
+	return &SessionController{service: s, activity: a}
 }
 
 //
@@ -41,6 +39,9 @@ func (c *SessionController) CreateSession(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid activity id format"})
 		return
 	}
+	if !authorizeActivity(ctx, c.activity, activityID) {
+		return
+	}
 	var s domain.Session
 	if err := ctx.ShouldBindJSON(&s); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -50,14 +51,12 @@ func (c *SessionController) CreateSession(ctx *gin.Context) {
 	s.CreatedAt = time.Now()
 
 	id, err := c.service.CreateSession(ctx, &s)
-	if err != nil {
+	if err != nil && !savedWithoutEvent(ctx, err) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	s.ID = id
 
-	// Ya no publicamos eventos de sesiones para search-api (solo indexamos actividades)
-	// c.publishEvent("create", s.ActivityID, s.ID)
 	ctx.JSON(http.StatusCreated, s)
 }
 
@@ -101,17 +100,18 @@ func (c *SessionController) UpdateSession(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id format"})
 		return
 	}
+	if !c.authorizeSession(ctx, id) {
+		return
+	}
 	var update domain.Session
 	if err := ctx.ShouldBindJSON(&update); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := c.service.UpdateSession(ctx, id, &update); err != nil {
+	if err := c.service.UpdateSession(ctx, id, &update); err != nil && !savedWithoutEvent(ctx, err) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	// Ya no publicamos eventos de sesiones para search-api (solo indexamos actividades)
-	// c.publishEvent("update", update.ActivityID, id)
 	ctx.JSON(http.StatusOK, update)
 }
 
@@ -123,14 +123,27 @@ func (c *SessionController) DeleteSession(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id format"})
 		return
 	}
+	if !c.authorizeSession(ctx, id) {
+		return
+	}
 
-	if err := c.service.DeleteSession(ctx, id); err != nil {
+	if err := c.service.DeleteSession(ctx, id); err != nil && !savedWithoutEvent(ctx, err) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{"deleted": id})
 }
 
+// authorizeSession: solo quien puede gestionar la actividad de la sesión
+func (c *SessionController) authorizeSession(ctx *gin.Context, sessionID uint64) bool {
+	s, err := c.service.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "sesión no encontrada"})
+		return false
+	}
+	return authorizeActivity(ctx, c.activity, s.ActivityID)
+}
+
 //
 // ======================
 // Endpoint para search-api
@@ -173,6 +186,7 @@ func (c *SessionController) GetSearchDoc(ctx *gin.Context) {
 		Price:      activity.PrecioBase,                          // Usamos precio base
 		Tags:       []string{},                                   // Tags removido de Activity, se mantiene vacío para compatibilidad con search-api
 		UpdatedAt:  time.Now().Format(time.RFC3339),
+		OrgID:      activity.OrgID,
 	}
 
 	ctx.JSON(http.StatusOK, doc)
@@ -181,9 +195,9 @@ func (c *SessionController) GetSearchDoc(ctx *gin.Context) {
 // ======================
 // Función para registrar rutas de sesiones
 // ======================
-func RegisterSessionRoutes(r *gin.Engine, svc *services.SessionsService, actSvc *services.ActivitiesService, rabbitChan *amqp091.Channel, jwtSecret string) {
+func RegisterSessionRoutes(r *gin.Engine, svc *services.SessionsService, actSvc *services.ActivitiesService, auth gin.HandlerFunc) {
 	// Crear el controlador con todos los parámetros necesarios
-	controller := NewSessionController(svc, actSvc, rabbitChan)
+	controller := NewSessionController(svc, actSvc)
 
 	// Rutas públicas
 	r.GET("/sessions/:id", controller.GetSessionByID)
@@ -191,8 +205,8 @@ func RegisterSessionRoutes(r *gin.Engine, svc *services.SessionsService, actSvc
 
 	// Rutas protegidas
 	protected := r.Group("/sessions")
-	protected.Use(middleware.JWTAuth(jwtSecret))
-	protected.Use(middleware.RequireAdmin())
+	protected.Use(auth)
+	protected.Use(authz.RequirePermission(authz.PermSessionManage))
 
 	protected.POST("/", controller.CreateSession)
 	protected.PUT("/:id", controller.UpdateSession)
@@ -206,29 +220,7 @@ func RegisterSessionRoutes(r *gin.Engine, svc *services.SessionsService, actSvc
 	activities.GET("/:id/sessions", controller.GetSessionsByActivity)
 
 	protectedActivities := activities.Group("")
-	protectedActivities.Use(middleware.JWTAuth(jwtSecret))
-	protectedActivities.Use(middleware.RequireAdmin())
+	protectedActivities.Use(auth)
+	protectedActivities.Use(authz.RequirePermission(authz.PermSessionManage))
 	protectedActivities.POST("/:id/sessions", controller.CreateSession)
 }
-
-// ======================
-// Función auxiliar para publicar eventos
-// ======================
-func (c *SessionController) publishEvent(op string, activityID, sessionID uint64) {
-	event := map[string]interface{}{
-		"op":         op,
-		"activityId": fmt.Sprintf("%d", activityID),
-		"sessionId":  fmt.Sprintf("%d", sessionID),
-		"timestamp":  time.Now().Format(time.RFC3339),
-	}
-	body, _ := json.Marshal(event)
-	_ = c.rabbitChan.Publish(
-		"activities.events", // exchange
-		"search_sync",       // routing key
-		false, false,
-		amqp091.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		})
-}
-
@@ -4,6 +4,7 @@ import "time"
 
 type Activity struct {
 	ID          uint64    `bson:"_id,omitempty" json:"id"`
+	OrgID       string    `bson:"orgId"          json:"orgId"`
 	OwnerUserID string    `bson:"ownerUserId"    json:"ownerUserId"`
 	Categoria   string    `bson:"categoria"      json:"categoria"`
 	Nombre      string    `bson:"nombre"         json:"nombre"`
@@ -12,4 +13,18 @@ type Activity struct {
 	PrecioBase  float64   `bson:"precioBase"     json:"precioBase"`
 	Rating      float64   `bson:"rating"         json:"rating"`
 	UpdatedAt   time.Time `bson:"updatedAt"      json:"updatedAt"`
+
+	// OwnerDeleted marca las actividades cuyo dueño dio de baja su cuenta y
+	// no se reasignaron (ORPHAN_ACTIVITY_OWNER_ID vacío)
+	OwnerDeleted bool `bson:"ownerDeleted,omitempty" json:"ownerDeleted,omitempty"`
+
+	// InstructorUserID es el usuario (rol instructor) que dicta la actividad;
+	// Instructor queda como nombre para mostrar
+	InstructorUserID string `bson:"instructorUserId,omitempty" json:"instructorUserId,omitempty"`
+}
+
+// ManagedBy indica si el usuario es dueño o instructor de la actividad (lo
+// que necesita un instructor para gestionarla)
+func (a *Activity) ManagedBy(userID string) bool {
+	return userID != "" && (a.OwnerUserID == userID || a.InstructorUserID == userID)
 }
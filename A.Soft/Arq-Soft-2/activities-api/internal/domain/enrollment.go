@@ -4,10 +4,18 @@ import "time"
 
 type Enrollment struct {
 	ID          uint64    `bson:"_id,omitempty" json:"id"`
+	OrgID       string    `bson:"orgId"         json:"orgId"`
 	ActivityID  uint64    `bson:"activityId"    json:"activityId"`
 	SessionID   uint64    `bson:"sessionId"     json:"sessionId"`
 	UserID      string    `bson:"userId"        json:"userId"`
 	PrecioFinal float64   `bson:"precioFinal"   json:"precioFinal"`
 	Estado      string    `bson:"estado"        json:"estado"` // pendiente|confirmada|cancelada
 	CreatedAt   time.Time `bson:"createdAt"     json:"createdAt"`
+
+	// AnonymizedAt se setea al borrar la cuenta del usuario: UserID pasa a
+	// DeletedUserID y la inscripción queda solo para estadísticas
+	AnonymizedAt *time.Time `bson:"anonymizedAt,omitempty" json:"anonymizedAt,omitempty"`
 }
+
+// DeletedUserID reemplaza al userId en las inscripciones anonimizadas
+const DeletedUserID = "deleted"
@@ -15,4 +15,5 @@ type SearchDoc struct {
 	Price      float64  `json:"price"`
 	Tags       []string `json:"tags"`
 	UpdatedAt  string   `json:"updated_dt"`
+	OrgID      string   `json:"org_id"` // organización (tenant) de la actividad
 }
@@ -4,6 +4,7 @@ import "time"
 
 type Session struct {
 	ID         uint64    `bson:"_id,omitempty" json:"id"`
+	OrgID      string    `bson:"orgId"         json:"orgId"`
 	ActivityID uint64    `bson:"activityId"    json:"activityId"`
 	Fecha      string    `bson:"fecha"         json:"fecha"`  // YYYY-MM-DD
 	Inicio     string    `bson:"inicio"        json:"inicio"` // HH:mm
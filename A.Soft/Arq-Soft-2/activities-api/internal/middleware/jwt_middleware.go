@@ -7,9 +7,18 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/sporthub/shared/authz"
+	"github.com/sporthub/shared/jwks"
 )
 
-func JWTAuth(secret string) gin.HandlerFunc {
+// RevocationChecker indica si un access token (por jti) fue revocado
+type RevocationChecker interface {
+	IsRevoked(jti string) bool
+}
+
+// JWTAuth valida tokens RS256 de users-api con las claves del JWKS y exige
+// el iss y aud configurados
+func JWTAuth(keys *jwks.KeySet, issuer, audience string, revoked RevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := c.GetHeader("Authorization")
 		if !strings.HasPrefix(h, "Bearer ") {
@@ -18,12 +27,11 @@ func JWTAuth(secret string) gin.HandlerFunc {
 			return
 		}
 		tokenStr := strings.TrimPrefix(h, "Bearer ")
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(secret), nil
-		})
+		token, err := jwt.Parse(tokenStr, keys.Keyfunc,
+			jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+			jwt.WithIssuer(issuer),
+			jwt.WithAudience(audience),
+			jwt.WithExpirationRequired())
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			c.Abort()
@@ -46,13 +54,26 @@ func JWTAuth(secret string) gin.HandlerFunc {
 				return
 			}
 
+			if jti, _ := claims["jti"].(string); revoked != nil && revoked.IsRevoked(jti) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+				c.Abort()
+				return
+			}
+
 			// subject (sub) should be numeric user id
 			if subF, ok := claims["sub"].(float64); ok {
 				c.Set("userId", uint64(subF))
 			}
-			if r, ok := claims["rol"].(string); ok {
-				c.Set("role", r)
+			rol, _ := claims["rol"].(string)
+			if rol != "" {
+				c.Set("role", rol)
 			}
+			// permisos en la organización del request (claim orgs); un admin
+			// global conserva los de su rol (claim perms) en todas
+			authz.SetPermissions(c, authz.PermissionsIn(claims, TenantOf(c)))
+			// tokens emitidos antes de la verificación de email no traen el claim
+			verified, _ := claims["email_verified"].(bool)
+			c.Set("emailVerified", verified)
 		} else {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
 			c.Abort()
@@ -63,42 +84,15 @@ func JWTAuth(secret string) gin.HandlerFunc {
 	}
 }
 
-func RequireAdmin() gin.HandlerFunc {
+// RequireVerifiedEmail rechaza tokens sin email_verified cuando enabled
+// (REQUIRE_VERIFIED_EMAIL); si no, deja pasar todo
+func RequireVerifiedEmail(enabled bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authentication"})
+		if enabled && !c.GetBool("emailVerified") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "email not verified"})
 			c.Abort()
 			return
 		}
-
-		if role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "admin role required"})
-			c.Abort()
-			return
-		}
-
-		c.Next()
-	}
-}
-
-// RequireUser ensures the requester has a non-admin user role (or admin can also be accepted depending on rules)
-func RequireUser() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authentication"})
-			c.Abort()
-			return
-		}
-
-		rs, _ := role.(string)
-		if rs != "user" && rs != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "user role required"})
-			c.Abort()
-			return
-		}
-
 		c.Next()
 	}
 }
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sporthub/shared/jwks"
+)
+
+// ServiceAuth valida tokens de servicio firmados por users-api para esta API
+// (aud propio, rol "service"). Si clients no está vacío, el sub (client_id)
+// tiene que ser uno de ellos. Los tokens de usuario no pasan.
+func ServiceAuth(keys *jwks.KeySet, issuer, audience string, clients ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := c.GetHeader("Authorization")
+		if !strings.HasPrefix(h, "Bearer ") {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
+			return
+		}
+		token, err := jwt.Parse(strings.TrimPrefix(h, "Bearer "), keys.Keyfunc,
+			jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+			jwt.WithIssuer(issuer),
+			jwt.WithAudience(audience),
+			jwt.WithExpirationRequired())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+		claims, _ := token.Claims.(jwt.MapClaims)
+		clientID, _ := claims["sub"].(string)
+		if rol, _ := claims["rol"].(string); rol != "service" || clientID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+			return
+		}
+		if len(clients) > 0 && !slices.Contains(clients, clientID) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "client not allowed"})
+			return
+		}
+		c.Set("service", clientID)
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sporthub/activities-api/internal/repository"
+)
+
+// TenantHeader es el header con la organización (club) del request
+const TenantHeader = "X-Org-ID"
+
+var orgIDRe = regexp.MustCompile(`^[0-9]{1,20}$`)
+
+// Tenant resuelve la organización del request (header X-Org-ID o query
+// ?org=, si no defaultOrg) y la deja en el contexto: los repos filtran todas
+// las consultas por ella y JWTAuth toma los permisos de esa organización.
+// Va antes de JWTAuth.
+func Tenant(defaultOrg string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		org := c.GetHeader(TenantHeader)
+		if org == "" {
+			org = c.Query("org")
+		}
+		if org == "" {
+			org = defaultOrg
+		}
+		if !orgIDRe.MatchString(org) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid organization id"})
+			return
+		}
+		c.Set(repository.TenantContextKey, org)
+		c.Next()
+	}
+}
+
+// AllTenants saca el filtro por organización (rutas internas entre servicios)
+func AllTenants() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(repository.TenantContextKey, "")
+		c.Next()
+	}
+}
+
+// TenantOf devuelve la organización del request ("" = todas)
+func TenantOf(c *gin.Context) string {
+	return c.GetString(repository.TenantContextKey)
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sporthub/activities-api/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// activitiesMemory es un ActivitiesRepository en memoria, para tests y
+// desarrollo sin Mongo. Filtra por organización igual que el de Mongo.
+type activitiesMemory struct {
+	mu     sync.Mutex
+	nextID uint64
+	byID   map[uint64]*domain.Activity
+}
+
+func NewActivitiesMemory() ActivitiesRepository {
+	return &activitiesMemory{byID: map[uint64]*domain.Activity{}}
+}
+
+func (r *activitiesMemory) Create(ctx context.Context, a *domain.Activity) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	a.ID = r.nextID
+	a.OrgID = tenantOf(ctx, a.OrgID)
+	a.UpdatedAt = time.Now()
+	cp := *a
+	r.byID[a.ID] = &cp
+	return a.ID, nil
+}
+
+func (r *activitiesMemory) GetByID(ctx context.Context, id uint64) (*domain.Activity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.byID[id]
+	if !ok || !inTenant(ctx, a.OrgID) {
+		return nil, mongo.ErrNoDocuments
+	}
+	cp := *a
+	return &cp, nil
+}
+
+func (r *activitiesMemory) Update(ctx context.Context, id uint64, update bson.M) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.byID[id]
+	if !ok || !inTenant(ctx, a.OrgID) {
+		return nil
+	}
+	update["updatedAt"] = time.Now()
+	return applySet(a, update)
+}
+
+func (r *activitiesMemory) Delete(ctx context.Context, id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if a, ok := r.byID[id]; ok && inTenant(ctx, a.OrgID) {
+		delete(r.byID, id)
+	}
+	return nil
+}
+
+func (r *activitiesMemory) List(ctx context.Context, skip int, limit int) ([]*domain.Activity, int64, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	all := r.filter(ctx, func(*domain.Activity) bool { return true })
+	sort.Slice(all, func(i, j int) bool { return all[i].UpdatedAt.After(all[j].UpdatedAt) })
+	total := int64(len(all))
+	if skip > len(all) {
+		skip = len(all)
+	}
+	all = all[skip:]
+	if len(all) > limit {
+		all = all[:limit]
+	}
+	return all, total, nil
+}
+
+func (r *activitiesMemory) ListByOwner(ctx context.Context, ownerUserId string) ([]*domain.Activity, error) {
+	return r.filter(ctx, func(a *domain.Activity) bool { return a.OwnerUserID == ownerUserId }), nil
+}
+
+func (r *activitiesMemory) filter(ctx context.Context, keep func(*domain.Activity) bool) []*domain.Activity {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*domain.Activity
+	for _, a := range r.byID {
+		if inTenant(ctx, a.OrgID) && keep(a) {
+			cp := *a
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
@@ -17,6 +17,7 @@ type ActivitiesRepository interface {
 	Update(ctx context.Context, id uint64, update bson.M) error
 	Delete(ctx context.Context, id uint64) error
 	List(ctx context.Context, skip int, limit int) ([]*domain.Activity, int64, error)
+	ListByOwner(ctx context.Context, ownerUserId string) ([]*domain.Activity, error)
 }
 
 type activitiesMongo struct {
@@ -39,6 +40,7 @@ func (r *activitiesMongo) Create(ctx context.Context, a *domain.Activity) (uint6
 	}
 	
 	a.ID = id
+	a.OrgID = tenantOf(ctx, a.OrgID)
 	a.UpdatedAt = time.Now()
 	
 	_, err = r.col.InsertOne(ctx, a)
@@ -50,7 +52,7 @@ func (r *activitiesMongo) Create(ctx context.Context, a *domain.Activity) (uint6
 
 func (r *activitiesMongo) GetByID(ctx context.Context, id uint64) (*domain.Activity, error) {
 	var out domain.Activity
-	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&out); err != nil {
+	if err := r.col.FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&out); err != nil {
 		return nil, err
 	}
 	return &out, nil
@@ -58,12 +60,12 @@ func (r *activitiesMongo) GetByID(ctx context.Context, id uint64) (*domain.Activ
 
 func (r *activitiesMongo) Update(ctx context.Context, id uint64, update bson.M) error {
 	update["updatedAt"] = time.Now()
-	_, err := r.col.UpdateByID(ctx, id, bson.M{"$set": update})
+	_, err := r.col.UpdateOne(ctx, scoped(ctx, bson.M{"_id": id}), bson.M{"$set": update})
 	return err
 }
 
 func (r *activitiesMongo) Delete(ctx context.Context, id uint64) error {
-	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	_, err := r.col.DeleteOne(ctx, scoped(ctx, bson.M{"_id": id}))
 	return err
 }
 
@@ -74,12 +76,12 @@ func (r *activitiesMongo) List(ctx context.Context, skip int, limit int) ([]*dom
 	}
 	opts := options.Find().SetSkip(int64(skip)).SetLimit(int64(limit)).SetSort(bson.M{"updatedAt": -1})
 
-	total, err := r.col.CountDocuments(ctx, bson.M{})
+	total, err := r.col.CountDocuments(ctx, scoped(ctx, bson.M{}))
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count documents: %w", err)
 	}
 
-	cur, err := r.col.Find(ctx, bson.M{}, opts)
+	cur, err := r.col.Find(ctx, scoped(ctx, bson.M{}), opts)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to find documents: %w", err)
 	}
@@ -91,3 +93,16 @@ func (r *activitiesMongo) List(ctx context.Context, skip int, limit int) ([]*dom
 	}
 	return out, total, nil
 }
+
+func (r *activitiesMongo) ListByOwner(ctx context.Context, ownerUserId string) ([]*domain.Activity, error) {
+	cur, err := r.col.Find(ctx, scoped(ctx, bson.M{"ownerUserId": ownerUserId}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []*domain.Activity
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
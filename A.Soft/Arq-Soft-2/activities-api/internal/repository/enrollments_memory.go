@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sporthub/activities-api/internal/domain"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// enrollmentsMemory es un EnrollmentsRepository en memoria, para tests y
+// desarrollo sin Mongo
+type enrollmentsMemory struct {
+	mu     sync.Mutex
+	nextID uint64
+	byID   map[uint64]*domain.Enrollment
+}
+
+func NewEnrollmentsMemory() EnrollmentsRepository {
+	return &enrollmentsMemory{byID: map[uint64]*domain.Enrollment{}}
+}
+
+func (r *enrollmentsMemory) Create(ctx context.Context, e *domain.Enrollment) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	e.ID = r.nextID
+	e.OrgID = tenantOf(ctx, e.OrgID)
+	e.CreatedAt = time.Now()
+	cp := *e
+	r.byID[e.ID] = &cp
+	return e.ID, nil
+}
+
+func (r *enrollmentsMemory) ListByUser(ctx context.Context, userId string) ([]domain.Enrollment, error) {
+	return r.filter(ctx, func(e *domain.Enrollment) bool { return e.UserID == userId && e.Estado == "confirmada" }), nil
+}
+
+func (r *enrollmentsMemory) Exists(ctx context.Context, userId string, sessionId uint64) (bool, error) {
+	list := r.filter(ctx, func(e *domain.Enrollment) bool {
+		return e.UserID == userId && e.SessionID == sessionId && e.Estado == "confirmada"
+	})
+	return len(list) > 0, nil
+}
+
+func (r *enrollmentsMemory) GetByID(ctx context.Context, id uint64) (*domain.Enrollment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.byID[id]
+	if !ok || !inTenant(ctx, e.OrgID) {
+		return nil, mongo.ErrNoDocuments
+	}
+	cp := *e
+	return &cp, nil
+}
+
+func (r *enrollmentsMemory) UpdateStatus(ctx context.Context, id uint64, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.byID[id]; ok && inTenant(ctx, e.OrgID) {
+		e.Estado = status
+	}
+	return nil
+}
+
+func (r *enrollmentsMemory) ListAllByUser(ctx context.Context, userId string) ([]domain.Enrollment, error) {
+	return r.filter(ctx, func(e *domain.Enrollment) bool { return e.UserID == userId }), nil
+}
+
+func (r *enrollmentsMemory) Anonymize(ctx context.Context, id uint64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.byID[id]; ok && inTenant(ctx, e.OrgID) {
+		e.UserID, e.AnonymizedAt = domain.DeletedUserID, &at
+	}
+	return nil
+}
+
+func (r *enrollmentsMemory) ListBySession(ctx context.Context, sessionId uint64) ([]domain.Enrollment, error) {
+	return r.filter(ctx, func(e *domain.Enrollment) bool { return e.SessionID == sessionId }), nil
+}
+
+func (r *enrollmentsMemory) filter(ctx context.Context, keep func(*domain.Enrollment) bool) []domain.Enrollment {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.Enrollment
+	for _, e := range r.byID {
+		if inTenant(ctx, e.OrgID) && keep(e) {
+			out = append(out, *e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
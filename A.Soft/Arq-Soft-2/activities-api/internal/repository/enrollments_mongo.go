@@ -15,6 +15,12 @@ type EnrollmentsRepository interface {
 	Exists(ctx context.Context, userId string, sessionId uint64) (bool, error)
 	GetByID(ctx context.Context, id uint64) (*domain.Enrollment, error)
 	UpdateStatus(ctx context.Context, id uint64, status string) error
+	// ListAllByUser incluye las canceladas (para la baja de la cuenta)
+	ListAllByUser(ctx context.Context, userId string) ([]domain.Enrollment, error)
+	// Anonymize reemplaza el userId por domain.DeletedUserID
+	Anonymize(ctx context.Context, id uint64, at time.Time) error
+	// ListBySession son todas las inscripciones de la sesión (roster)
+	ListBySession(ctx context.Context, sessionId uint64) ([]domain.Enrollment, error)
 }
 
 type enrollmentsMongo struct {
@@ -37,6 +43,7 @@ func (r *enrollmentsMongo) Create(ctx context.Context, e *domain.Enrollment) (ui
 	}
 	
 	e.ID = id
+	e.OrgID = tenantOf(ctx, e.OrgID)
 	e.CreatedAt = time.Now()
 	
 	_, err = r.col.InsertOne(ctx, e)
@@ -48,7 +55,7 @@ func (r *enrollmentsMongo) Create(ctx context.Context, e *domain.Enrollment) (ui
 
 func (r *enrollmentsMongo) ListByUser(ctx context.Context, userId string) ([]domain.Enrollment, error) {
 	// Solo devolver inscripciones confirmadas (excluir canceladas)
-	cur, err := r.col.Find(ctx, bson.M{"userId": userId, "estado": "confirmada"})
+	cur, err := r.col.Find(ctx, scoped(ctx, bson.M{"userId": userId, "estado": "confirmada"}))
 	if err != nil {
 		return nil, err
 	}
@@ -62,11 +69,11 @@ func (r *enrollmentsMongo) ListByUser(ctx context.Context, userId string) ([]dom
 
 func (r *enrollmentsMongo) Exists(ctx context.Context, userId string, sessionId uint64) (bool, error) {
 	// Solo contar inscripciones confirmadas, excluir canceladas
-	cnt, err := r.col.CountDocuments(ctx, bson.M{
+	cnt, err := r.col.CountDocuments(ctx, scoped(ctx, bson.M{
 		"userId":    userId,
 		"sessionId": sessionId,
 		"estado":    "confirmada",
-	})
+	}))
 	if err != nil {
 		return false, err
 	}
@@ -75,13 +82,44 @@ func (r *enrollmentsMongo) Exists(ctx context.Context, userId string, sessionId
 
 func (r *enrollmentsMongo) GetByID(ctx context.Context, id uint64) (*domain.Enrollment, error) {
 	var out domain.Enrollment
-	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&out); err != nil {
+	if err := r.col.FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&out); err != nil {
 		return nil, err
 	}
 	return &out, nil
 }
 
 func (r *enrollmentsMongo) UpdateStatus(ctx context.Context, id uint64, status string) error {
-	_, err := r.col.UpdateByID(ctx, id, bson.M{"$set": bson.M{"estado": status}})
+	_, err := r.col.UpdateOne(ctx, scoped(ctx, bson.M{"_id": id}), bson.M{"$set": bson.M{"estado": status}})
 	return err
 }
+
+func (r *enrollmentsMongo) ListAllByUser(ctx context.Context, userId string) ([]domain.Enrollment, error) {
+	cur, err := r.col.Find(ctx, scoped(ctx, bson.M{"userId": userId}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []domain.Enrollment
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *enrollmentsMongo) Anonymize(ctx context.Context, id uint64, at time.Time) error {
+	_, err := r.col.UpdateOne(ctx, scoped(ctx, bson.M{"_id": id}), bson.M{"$set": bson.M{"userId": domain.DeletedUserID, "anonymizedAt": at}})
+	return err
+}
+
+func (r *enrollmentsMongo) ListBySession(ctx context.Context, sessionId uint64) ([]domain.Enrollment, error) {
+	cur, err := r.col.Find(ctx, scoped(ctx, bson.M{"sessionId": sessionId}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []domain.Enrollment
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// inTenant indica si un documento de orgID es visible con ctx (mismo
+// criterio que scoped en Mongo: sin organización en ctx se ve todo)
+func inTenant(ctx context.Context, orgID string) bool {
+	org := TenantFrom(ctx)
+	return org == "" || org == orgID
+}
+
+// applySet aplica a doc un $set de Mongo (claves bson) pasándolo por bson,
+// así los repos en memoria aceptan los mismos updates que los de Mongo
+func applySet(doc any, update bson.M) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	var m bson.M
+	if err := bson.Unmarshal(raw, &m); err != nil {
+		return err
+	}
+	for k, v := range update {
+		m[k] = v
+	}
+	raw, err = bson.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(raw, doc)
+}
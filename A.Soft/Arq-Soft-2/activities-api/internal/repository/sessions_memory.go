@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sporthub/activities-api/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// sessionsMemory es un SessionsRepository en memoria; CountEnrollments
+// cuenta las inscripciones confirmadas de enrollments
+type sessionsMemory struct {
+	mu          sync.Mutex
+	nextID      uint64
+	byID        map[uint64]*domain.Session
+	enrollments EnrollmentsRepository
+}
+
+func NewSessionsMemory(enrollments EnrollmentsRepository) SessionsRepository {
+	return &sessionsMemory{byID: map[uint64]*domain.Session{}, enrollments: enrollments}
+}
+
+func (r *sessionsMemory) Create(ctx context.Context, s *domain.Session) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	s.ID = r.nextID
+	s.OrgID = tenantOf(ctx, s.OrgID)
+	now := time.Now()
+	s.CreatedAt, s.UpdatedAt = now, now
+	cp := *s
+	r.byID[s.ID] = &cp
+	return s.ID, nil
+}
+
+func (r *sessionsMemory) ListByActivity(ctx context.Context, activityId uint64) ([]domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.Session
+	for _, s := range r.byID {
+		if s.ActivityID == activityId && inTenant(ctx, s.OrgID) {
+			out = append(out, *s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (r *sessionsMemory) GetByID(ctx context.Context, id uint64) (*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.byID[id]
+	if !ok || !inTenant(ctx, s.OrgID) {
+		return nil, mongo.ErrNoDocuments
+	}
+	cp := *s
+	return &cp, nil
+}
+
+func (r *sessionsMemory) Update(ctx context.Context, id uint64, update bson.M) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.byID[id]
+	if !ok || !inTenant(ctx, s.OrgID) {
+		return nil
+	}
+	update["updatedAt"] = time.Now()
+	return applySet(s, update)
+}
+
+func (r *sessionsMemory) Delete(ctx context.Context, id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.byID[id]; ok && inTenant(ctx, s.OrgID) {
+		delete(r.byID, id)
+	}
+	return nil
+}
+
+func (r *sessionsMemory) CountEnrollments(ctx context.Context, sessionId uint64) (int, error) {
+	list, err := r.enrollments.ListBySession(ctx, sessionId)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, e := range list {
+		if e.Estado == "confirmada" {
+			n++
+		}
+	}
+	return n, nil
+}
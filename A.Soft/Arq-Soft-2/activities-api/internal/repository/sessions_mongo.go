@@ -40,6 +40,7 @@ func (r *sessionsMongo) Create(ctx context.Context, s *domain.Session) (uint64,
 	}
 	
 	s.ID = id
+	s.OrgID = tenantOf(ctx, s.OrgID)
 	now := time.Now()
 	s.CreatedAt, s.UpdatedAt = now, now
 	
@@ -51,7 +52,7 @@ func (r *sessionsMongo) Create(ctx context.Context, s *domain.Session) (uint64,
 }
 
 func (r *sessionsMongo) ListByActivity(ctx context.Context, activityId uint64) ([]domain.Session, error) {
-	cur, err := r.scol.Find(ctx, bson.M{"activityId": activityId})
+	cur, err := r.scol.Find(ctx, scoped(ctx, bson.M{"activityId": activityId}))
 	if err != nil {
 		return nil, err
 	}
@@ -65,7 +66,7 @@ func (r *sessionsMongo) ListByActivity(ctx context.Context, activityId uint64) (
 
 func (r *sessionsMongo) GetByID(ctx context.Context, id uint64) (*domain.Session, error) {
 	var s domain.Session
-	if err := r.scol.FindOne(ctx, bson.M{"_id": id}).Decode(&s); err != nil {
+	if err := r.scol.FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&s); err != nil {
 		return nil, err
 	}
 	return &s, nil
@@ -73,17 +74,17 @@ func (r *sessionsMongo) GetByID(ctx context.Context, id uint64) (*domain.Session
 
 func (r *sessionsMongo) Update(ctx context.Context, id uint64, update bson.M) error {
 	update["updatedAt"] = time.Now()
-	_, err := r.scol.UpdateByID(ctx, id, bson.M{"$set": update})
+	_, err := r.scol.UpdateOne(ctx, scoped(ctx, bson.M{"_id": id}), bson.M{"$set": update})
 	return err
 }
 
 func (r *sessionsMongo) Delete(ctx context.Context, id uint64) error {
-	_, err := r.scol.DeleteOne(ctx, bson.M{"_id": id})
+	_, err := r.scol.DeleteOne(ctx, scoped(ctx, bson.M{"_id": id}))
 	return err
 }
 
 func (r *sessionsMongo) CountEnrollments(ctx context.Context, sessionId uint64) (int, error) {
-	n, err := r.ecol.CountDocuments(ctx, bson.M{"sessionId": sessionId, "estado": "confirmada"})
+	n, err := r.ecol.CountDocuments(ctx, scoped(ctx, bson.M{"sessionId": sessionId, "estado": "confirmada"}))
 	return int(n), err
 }
 
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type tenantKey struct{}
+
+// TenantContextKey es la clave con la que middleware.Tenant guarda en el
+// gin.Context la organización del request (gin.Context.Value la resuelve)
+const TenantContextKey = "orgId"
+
+// WithTenant limita las consultas hechas con ctx a la organización orgID.
+// Con "" no se filtra (consumers, rutas internas, reindexado global).
+func WithTenant(ctx context.Context, orgID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, orgID)
+}
+
+// TenantFrom devuelve la organización de ctx ("" = todas)
+func TenantFrom(ctx context.Context) string {
+	if org, ok := ctx.Value(tenantKey{}).(string); ok {
+		return org
+	}
+	org, _ := ctx.Value(TenantContextKey).(string)
+	return org
+}
+
+// scoped agrega el filtro por organización de ctx a filter
+func scoped(ctx context.Context, filter bson.M) bson.M {
+	if org := TenantFrom(ctx); org != "" {
+		filter["orgId"] = org
+	}
+	return filter
+}
+
+// tenantOf es la organización con la que se guarda un documento nuevo: la
+// que ya trae o, si no, la del request
+func tenantOf(ctx context.Context, orgID string) string {
+	if orgID != "" {
+		return orgID
+	}
+	return TenantFrom(ctx)
+}
+
+// BackfillTenant asigna defaultOrg a los documentos anteriores a las
+// organizaciones (sin orgId) y crea los índices por orgId
+func BackfillTenant(ctx context.Context, db *mongo.Database, defaultOrg string) error {
+	for _, name := range []string{"activities", "sessions", "enrollments"} {
+		col := db.Collection(name)
+		res, err := col.UpdateMany(ctx, bson.M{"orgId": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"orgId": defaultOrg}})
+		if err != nil {
+			return err
+		}
+		if res.ModifiedCount > 0 {
+			log.Printf("[tenant] %d %s assigned to organization %s", res.ModifiedCount, name, defaultOrg)
+		}
+		if _, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "orgId", Value: 1}}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
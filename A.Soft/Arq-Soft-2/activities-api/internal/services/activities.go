@@ -2,24 +2,34 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/sporthub/activities-api/internal/clients"
 	"github.com/sporthub/activities-api/internal/config"
 	"github.com/sporthub/activities-api/internal/domain"
 	"github.com/sporthub/activities-api/internal/repository"
+	"github.com/sporthub/shared/authz"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalidInstructor: instructorUserId no es instructor (o admin) en la
+// organización de la actividad
+var ErrInvalidInstructor = errors.New("instructorUserId must be an instructor or admin of the organization")
+
 type ActivitiesService struct {
 	repo  repository.ActivitiesRepository
 	users *clients.UsersClient
-	bus   *clients.Rabbit
+	bus   clients.Publisher
 	cfg   *config.Config
 }
 
-func NewActivitiesService(r repository.ActivitiesRepository, u *clients.UsersClient, bus *clients.Rabbit, cfg *config.Config) *ActivitiesService {
+func NewActivitiesService(r repository.ActivitiesRepository, u *clients.UsersClient, bus clients.Publisher, cfg *config.Config) *ActivitiesService {
 	return &ActivitiesService{repo: r, users: u, bus: bus, cfg: cfg}
 }
 
@@ -27,18 +37,24 @@ func (s *ActivitiesService) Create(ctx context.Context, a *domain.Activity) (uin
 	if _, err := s.users.GetUser(a.OwnerUserID); err != nil {
 		return 0, err
 	}
+	if a.OrgID == "" {
+		a.OrgID = repository.TenantFrom(ctx)
+	}
+	if err := s.checkInstructor(a.OrgID, a.InstructorUserID); err != nil {
+		return 0, err
+	}
 	id, err := s.repo.Create(ctx, a)
 	if err != nil {
 		return 0, err
 	}
 	// Publicar evento para search-api con routing key que coincida con los bindings de RabbitMQ
-	_ = s.bus.Publish("activity.created", map[string]any{
+	return id, publishEvent(s.bus, "activity.created", map[string]any{
 		"op":         "create",
 		"activityId": fmt.Sprintf("%d", id),
+		"orgId":      a.OrgID,
 		"sessionId":  "", // Vacío porque es un evento de actividad
 		"timestamp":  time.Now().Format(time.RFC3339),
 	})
-	return id, nil
 }
 
 func (s *ActivitiesService) GetByID(ctx context.Context, id uint64) (*domain.Activity, error) {
@@ -46,16 +62,70 @@ func (s *ActivitiesService) GetByID(ctx context.Context, id uint64) (*domain.Act
 }
 
 func (s *ActivitiesService) Update(ctx context.Context, id uint64, update bson.M) error {
+	if instructor, ok := update["instructorUserId"].(string); ok {
+		if err := s.checkInstructor(repository.TenantFrom(ctx), instructor); err != nil {
+			return err
+		}
+	}
 	if err := s.repo.Update(ctx, id, update); err != nil {
 		return err
 	}
 	// Publicar evento para search-api con routing key que coincida con los bindings de RabbitMQ
-	_ = s.bus.Publish("activity.updated", map[string]any{
+	return publishEvent(s.bus, "activity.updated", map[string]any{
 		"op":         "update",
 		"activityId": fmt.Sprintf("%d", id),
+		"orgId":      repository.TenantFrom(ctx),
 		"sessionId":  "", // Vacío porque es un evento de actividad
 		"timestamp":  time.Now().Format(time.RFC3339),
 	})
+}
+
+// Authorize devuelve la actividad si el usuario puede gestionarla: con
+// manageAny (activity:manage-any) cualquiera, si no solo las que tiene como
+// dueño o instructor
+func (s *ActivitiesService) Authorize(ctx context.Context, id uint64, userID string, manageAny bool) (*domain.Activity, error) {
+	a, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if !manageAny && !a.ManagedBy(userID) {
+		return nil, ErrForbidden
+	}
+	return a, nil
+}
+
+// checkInstructor valida instructorUserId contra users-api ("" = sin
+// instructor): tiene que poder gestionar actividades en la organización
+// orgID, con los mismos permisos que le daría su token ahí (admin global,
+// su rol global en la organización por defecto o su rol en el club)
+func (s *ActivitiesService) checkInstructor(orgID, userID string) error {
+	if userID == "" {
+		return nil
+	}
+	u, err := s.users.GetUser(userID)
+	if err != nil {
+		return err
+	}
+	if u.Role == authz.RoleAdmin {
+		return nil
+	}
+	var perms []string
+	if orgID == s.cfg.DefaultOrgID {
+		perms = authz.PermissionsFor(u.Role)
+	}
+	m, err := s.users.GetMembership(orgID, userID)
+	if err != nil {
+		return err
+	}
+	if m != nil {
+		perms = append(perms, authz.OrgPermissionsFor(m.Role)...)
+	}
+	if !slices.Contains(perms, authz.PermActivityUpdate) {
+		return ErrInvalidInstructor
+	}
 	return nil
 }
 
@@ -64,13 +134,13 @@ func (s *ActivitiesService) Delete(ctx context.Context, id uint64) error {
 		return err
 	}
 	// Publicar evento para search-api con routing key que coincida con los bindings de RabbitMQ
-	_ = s.bus.Publish("activity.deleted", map[string]any{
+	return publishEvent(s.bus, "activity.deleted", map[string]any{
 		"op":         "delete",
 		"activityId": fmt.Sprintf("%d", id),
+		"orgId":      repository.TenantFrom(ctx),
 		"sessionId":  "", // Vacío porque es un evento de actividad
 		"timestamp":  time.Now().Format(time.RFC3339),
 	})
-	return nil
 }
 
 func (s *ActivitiesService) List(ctx context.Context, skip int, limit int) ([]*domain.Activity, int64, error) {
@@ -80,21 +150,26 @@ func (s *ActivitiesService) List(ctx context.Context, skip int, limit int) ([]*d
 // ReindexAll publica eventos de actualización para todas las actividades existentes
 // Esto hace que el consumer de search-api las indexe en Solr
 func (s *ActivitiesService) ReindexAll(ctx context.Context) (int, error) {
-	// Obtener todas las actividades (sin límite)
-	activities, _, err := s.repo.List(ctx, 0, 10000) // Usar un límite alto para obtener todas
+	// Obtener todas las actividades (sin límite) de todas las organizaciones
+	activities, _, err := s.repo.List(repository.WithTenant(ctx, ""), 0, 10000) // Usar un límite alto para obtener todas
 	if err != nil {
 		return 0, err
 	}
 	
 	count := 0
 	for _, activity := range activities {
-		// Publicar evento de actualización para cada actividad
-		_ = s.bus.Publish("activity.updated", map[string]any{
+		// Publicar evento de actualización para cada actividad; si el broker
+		// no lo confirma se corta para que el admin sepa hasta dónde llegó
+		err := s.bus.Publish("activity.updated", map[string]any{
 			"op":         "update",
 			"activityId": fmt.Sprintf("%d", activity.ID),
+			"orgId":      activity.OrgID,
 			"sessionId":  "", // Vacío porque es un evento de actividad
 			"timestamp":  time.Now().Format(time.RFC3339),
 		})
+		if err != nil {
+			return count, fmt.Errorf("reindex activity %d: %w", activity.ID, err)
+		}
 		count++
 	}
 	return count, nil
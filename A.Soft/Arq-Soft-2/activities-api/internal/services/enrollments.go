@@ -10,6 +10,7 @@ import (
 	"github.com/sporthub/activities-api/internal/config"
 	"github.com/sporthub/activities-api/internal/domain"
 	"github.com/sporthub/activities-api/internal/repository"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var ErrNoCupo = errors.New("no hay cupo disponible")
@@ -116,6 +117,7 @@ func (svc *EnrollmentsService) Enroll(ctx context.Context, sessionId uint64, use
 
 	// Crear inscripción
 	enr := &domain.Enrollment{
+		OrgID:       act.OrgID,
 		ActivityID:  act.ID,
 		SessionID:   sessionId,
 		UserID:      userId,
@@ -129,28 +131,57 @@ func (svc *EnrollmentsService) Enroll(ctx context.Context, sessionId uint64, use
 	}
 
 	// Publicar evento
-	_ = svc.bus.Publish("enrollment.created", map[string]any{
-		"op": "enroll", "id": id, "sessionId": sessionId, "activityId": act.ID, "userId": userId, "total": precio, "ts": time.Now(),
+	return id, publishEvent(svc.bus, "enrollment.created", map[string]any{
+		"op": "enroll", "id": id, "orgId": act.OrgID, "sessionId": sessionId, "activityId": act.ID, "userId": userId, "total": precio, "ts": time.Now(),
 	})
-	return id, nil
 }
 
 func (svc *EnrollmentsService) ListByUser(ctx context.Context, userId string) ([]domain.Enrollment, error) {
 	return svc.erepo.ListByUser(ctx, userId)
 }
 
-func (svc *EnrollmentsService) CancelEnrollment(ctx context.Context, enrollmentId uint64, requesterUserId string, requesterRole string) error {
+// ListAllByUser incluye las canceladas (exportación de datos del usuario)
+func (svc *EnrollmentsService) ListAllByUser(ctx context.Context, userId string) ([]domain.Enrollment, error) {
+	return svc.erepo.ListAllByUser(ctx, userId)
+}
+
+// Roster devuelve las inscripciones de una sesión. Con manageAny cualquier
+// sesión; si no, solo las de actividades de las que el usuario es dueño o
+// instructor.
+func (svc *EnrollmentsService) Roster(ctx context.Context, sessionId uint64, requesterUserId string, manageAny bool) ([]domain.Enrollment, error) {
+	sess, err := svc.srepo.GetByID(ctx, sessionId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	act, err := svc.arepo.GetByID(ctx, sess.ActivityID)
+	if err != nil {
+		// sesión huérfana (la actividad se borró)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if !manageAny && !act.ManagedBy(requesterUserId) {
+		return nil, ErrForbidden
+	}
+	return svc.erepo.ListBySession(ctx, sessionId)
+}
+
+// CancelEnrollment: el dueño de la inscripción, o cualquiera con
+// enrollment:manage-any (manageAny)
+func (svc *EnrollmentsService) CancelEnrollment(ctx context.Context, enrollmentId uint64, requesterUserId string, manageAny bool) error {
 	enr, err := svc.erepo.GetByID(ctx, enrollmentId)
 	if err != nil {
 		return err
 	}
-	// only owner or admin can cancel
-	if requesterRole != "admin" && enr.UserID != requesterUserId {
+	if !manageAny && enr.UserID != requesterUserId {
 		return ErrForbidden
 	}
 	if err := svc.erepo.UpdateStatus(ctx, enrollmentId, "cancelada"); err != nil {
 		return err
 	}
-	_ = svc.bus.Publish("enrollment.cancelled", map[string]any{"op": "cancel", "id": enrollmentId, "sessionId": enr.SessionID, "activityId": enr.ActivityID, "userId": enr.UserID, "ts": time.Now()})
-	return nil
+	return publishEvent(svc.bus, "enrollment.cancelled", map[string]any{"op": "cancel", "id": enrollmentId, "orgId": enr.OrgID, "sessionId": enr.SessionID, "activityId": enr.ActivityID, "userId": enr.UserID, "ts": time.Now()})
 }
@@ -0,0 +1,24 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/sporthub/activities-api/internal/clients"
+)
+
+// ErrEventNotPublished: el cambio se guardó en Mongo pero su evento no llegó
+// al broker (search-api no se entera hasta el próximo reindex)
+var ErrEventNotPublished = errors.New("change saved but its event was not published")
+
+// publishEvent publica un evento de dominio. Si falla lo loguea con la causa
+// (sin conexión, nack, sin ruta o timeout de confirm) y devuelve un error que
+// envuelve ErrEventNotPublished para que el caller decida qué hacer.
+func publishEvent(bus clients.Publisher, routing string, payload any) error {
+	if err := bus.Publish(routing, payload); err != nil {
+		log.Printf("[rabbit] ERROR: %v", err)
+		return fmt.Errorf("%w: %w", ErrEventNotPublished, err)
+	}
+	return nil
+}
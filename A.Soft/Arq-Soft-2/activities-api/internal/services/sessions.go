@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/sporthub/activities-api/internal/clients"
@@ -15,30 +16,32 @@ import (
 type SessionsService struct {
 	srepo repository.SessionsRepository
 	arepo repository.ActivitiesRepository
-	bus   *clients.Rabbit
+	bus   clients.Publisher
 	cfg   *config.Config
 }
 
-func NewSessionsService(s repository.SessionsRepository, a repository.ActivitiesRepository, bus *clients.Rabbit, cfg *config.Config) *SessionsService {
+func NewSessionsService(s repository.SessionsRepository, a repository.ActivitiesRepository, bus clients.Publisher, cfg *config.Config) *SessionsService {
 	return &SessionsService{srepo: s, arepo: a, bus: bus, cfg: cfg}
 }
 
 func (s *SessionsService) Create(ctx context.Context, sess *domain.Session) (uint64, error) {
-	// valida que exista la actividad
-	if _, err := s.arepo.GetByID(ctx, sess.ActivityID); err != nil {
+	// valida que exista la actividad (en la organización del request)
+	act, err := s.arepo.GetByID(ctx, sess.ActivityID)
+	if err != nil {
 		return 0, err
 	}
+	sess.OrgID = act.OrgID
 	id, err := s.srepo.Create(ctx, sess)
 	if err != nil {
 		return 0, err
 	}
-	_ = s.bus.Publish("activity.session.created", map[string]any{
+	return id, publishEvent(s.bus, "activity.session.created", map[string]any{
 		"op":         "create",
 		"sessionId":  fmt.Sprintf("%d", id),
 		"activityId": fmt.Sprintf("%d", sess.ActivityID),
+		"orgId":      sess.OrgID,
 		"timestamp":  time.Now().Format(time.RFC3339),
 	})
-	return id, nil
 }
 
 func (s *SessionsService) ListByActivity(ctx context.Context, activityId uint64) ([]domain.Session, error) {
@@ -51,15 +54,17 @@ func (s *SessionsService) Update(ctx context.Context, id uint64, update bson.M)
 	}
 	// Obtener la sesión para tener el activityId
 	session, err := s.srepo.GetByID(ctx, id)
-	if err == nil {
-		_ = s.bus.Publish("activity.session.updated", map[string]any{
-			"op":         "update",
-			"sessionId":  fmt.Sprintf("%d", id),
-			"activityId": fmt.Sprintf("%d", session.ActivityID),
-			"timestamp":  time.Now().Format(time.RFC3339),
-		})
+	if err != nil {
+		log.Printf("[rabbit] ERROR: session %d updated but could not be read to publish its event: %v", id, err)
+		return fmt.Errorf("%w: %w", ErrEventNotPublished, err)
 	}
-	return nil
+	return publishEvent(s.bus, "activity.session.updated", map[string]any{
+		"op":         "update",
+		"sessionId":  fmt.Sprintf("%d", id),
+		"activityId": fmt.Sprintf("%d", session.ActivityID),
+		"orgId":      session.OrgID,
+		"timestamp":  time.Now().Format(time.RFC3339),
+	})
 }
 
 func (s *SessionsService) Delete(ctx context.Context, id uint64) error {
@@ -73,13 +78,13 @@ func (s *SessionsService) Delete(ctx context.Context, id uint64) error {
 		return err
 	}
 	
-	_ = s.bus.Publish("activity.session.deleted", map[string]any{
+	return publishEvent(s.bus, "activity.session.deleted", map[string]any{
 		"op":         "delete",
 		"sessionId":  fmt.Sprintf("%d", id),
 		"activityId": fmt.Sprintf("%d", session.ActivityID),
+		"orgId":      session.OrgID,
 		"timestamp":  time.Now().Format(time.RFC3339),
 	})
-	return nil
 }
 
 func (s *SessionsService) GetByID(ctx context.Context, id uint64) (*domain.Session, error) {
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sporthub/activities-api/internal/clients"
+	"github.com/sporthub/activities-api/internal/config"
+	"github.com/sporthub/activities-api/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UserEvent es el payload de los eventos user.* que publica users-api
+type UserEvent struct {
+	Op        string `json:"op"` // created | updated | deleted
+	UserID    string `json:"userId"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	Timestamp string `json:"timestamp"`
+}
+
+// UserEventsService aplica en activities-api los cambios de cuentas de
+// users-api. Todo es idempotente: el mismo evento puede llegar más de una vez.
+type UserEventsService struct {
+	erepo repository.EnrollmentsRepository
+	srepo repository.SessionsRepository
+	arepo repository.ActivitiesRepository
+	bus   clients.Publisher
+	cfg   *config.Config
+}
+
+func NewUserEventsService(e repository.EnrollmentsRepository, s repository.SessionsRepository, a repository.ActivitiesRepository, bus clients.Publisher, cfg *config.Config) *UserEventsService {
+	return &UserEventsService{erepo: e, srepo: s, arepo: a, bus: bus, cfg: cfg}
+}
+
+// Handle procesa un evento; created/updated no requieren nada por ahora
+func (svc *UserEventsService) Handle(ctx context.Context, ev UserEvent) error {
+	if ev.UserID == "" {
+		log.Printf("[users-events] WARN: %s event without userId, skipping", ev.Op)
+		return nil
+	}
+	if ev.Op == "deleted" {
+		return svc.userDeleted(ctx, ev.UserID)
+	}
+	return nil
+}
+
+// userDeleted cancela las inscripciones activas (pendientes o confirmadas) a
+// sesiones que no empezaron, así se libera el cupo, anonimiza todas y
+// reasigna o marca las actividades del usuario
+func (svc *UserEventsService) userDeleted(ctx context.Context, userId string) error {
+	enrollments, err := svc.erepo.ListAllByUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var cancelled, anonymized int
+	for _, enr := range enrollments {
+		if enr.Estado != "cancelada" {
+			future, err := svc.sessionIsFuture(ctx, enr.SessionID, now)
+			if err != nil {
+				return err
+			}
+			if future {
+				if err := svc.erepo.UpdateStatus(ctx, enr.ID, "cancelada"); err != nil {
+					return err
+				}
+				// un reintento no lo republicaría (la inscripción ya quedó
+				// cancelada): publishEvent ya logueó el error
+				_ = publishEvent(svc.bus, "enrollment.cancelled", map[string]any{"op": "cancel", "id": enr.ID, "orgId": enr.OrgID, "sessionId": enr.SessionID, "activityId": enr.ActivityID, "userId": userId, "ts": now})
+				cancelled++
+			}
+		}
+		if err := svc.erepo.Anonymize(ctx, enr.ID, now); err != nil {
+			return err
+		}
+		anonymized++
+	}
+
+	owned, err := svc.arepo.ListByOwner(ctx, userId)
+	if err != nil {
+		return err
+	}
+	owner, flagged := "", true
+	if svc.cfg.OrphanActivityOwnerID != "" {
+		owner, flagged = svc.cfg.OrphanActivityOwnerID, false
+	}
+	for _, a := range owned {
+		if err := svc.arepo.Update(ctx, a.ID, bson.M{"ownerUserId": owner, "ownerDeleted": flagged}); err != nil {
+			return err
+		}
+		// idem: la actividad ya cambió de dueño, un reintento no la encuentra
+		_ = publishEvent(svc.bus, "activity.updated", map[string]any{
+			"op":         "update",
+			"activityId": fmt.Sprintf("%d", a.ID),
+			"orgId":      a.OrgID,
+			"sessionId":  "",
+			"timestamp":  now.Format(time.RFC3339),
+		})
+	}
+	log.Printf("[users-events] user %s deleted: %d enrollments cancelled, %d anonymized, %d activities orphaned",
+		userId, cancelled, anonymized, len(owned))
+	return nil
+}
+
+// sessionIsFuture indica si la sesión todavía no empezó. Si la sesión ya no
+// existe no hay cupo que liberar (se trata como pasada); si su fecha no se
+// puede leer se trata como futura, así el cupo no queda tomado por una cuenta
+// borrada.
+func (svc *UserEventsService) sessionIsFuture(ctx context.Context, sessionId uint64, now time.Time) (bool, error) {
+	sess, err := svc.srepo.GetByID(ctx, sessionId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return false, nil
+		}
+		return false, err
+	}
+	start, err := time.ParseInLocation("2006-01-02 15:04", sess.Fecha+" "+sess.Inicio, time.Local)
+	if err != nil {
+		log.Printf("[users-events] WARN: session %d has an invalid date %q %q, treating it as future", sessionId, sess.Fecha, sess.Inicio)
+		return true, nil
+	}
+	return start.After(now), nil
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sporthub/activities-api/internal/domain"
+	"github.com/sporthub/activities-api/internal/repository"
+	"github.com/sporthub/activities-api/internal/services"
+)
+
+func TestManagedByOwnerOrInstructorOnly(t *testing.T) {
+	a := &domain.Activity{OwnerUserID: "7", InstructorUserID: "9"}
+	for _, tt := range []struct {
+		user string
+		want bool
+	}{{"7", true}, {"9", true}, {"8", false}, {"", false}} {
+		if got := a.ManagedBy(tt.user); got != tt.want {
+			t.Errorf("ManagedBy(%q) = %v, want %v", tt.user, got, tt.want)
+		}
+	}
+	// sin instructor, "" no es el instructor
+	if (&domain.Activity{OwnerUserID: "7"}).ManagedBy("") {
+		t.Error("an empty user id must never manage an activity")
+	}
+}
+
+func TestAuthorizeActivityOwnership(t *testing.T) {
+	ctx := context.Background()
+	activities := repository.NewActivitiesMemory()
+	id, _ := activities.Create(ctx, &domain.Activity{OrgID: "1", OwnerUserID: "7", InstructorUserID: "9", Nombre: "Yoga"})
+	svc := services.NewActivitiesService(activities, nil, nil, testConfig())
+
+	for _, user := range []string{"7", "9"} {
+		if _, err := svc.Authorize(ctx, id, user, false); err != nil {
+			t.Errorf("user %s should manage the activity: %v", user, err)
+		}
+	}
+	if _, err := svc.Authorize(ctx, id, "8", false); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("expected ErrForbidden for another instructor, got %v", err)
+	}
+	if _, err := svc.Authorize(ctx, id, "8", true); err != nil {
+		t.Errorf("activity:manage-any should manage any activity: %v", err)
+	}
+	if _, err := svc.Authorize(ctx, id+100, "7", true); !errors.Is(err, services.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRosterOnlyForTheActivitysStaff(t *testing.T) {
+	ctx := context.Background()
+	enrollments := repository.NewEnrollmentsMemory()
+	sessions := repository.NewSessionsMemory(enrollments)
+	activities := repository.NewActivitiesMemory()
+	svc := services.NewEnrollmentsService(enrollments, sessions, activities, &fakePublisher{}, testConfig())
+
+	act, _ := activities.Create(ctx, &domain.Activity{OrgID: "1", OwnerUserID: "7", InstructorUserID: "9", Nombre: "Yoga"})
+	sess, _ := sessions.Create(ctx, &domain.Session{OrgID: "1", ActivityID: act, Fecha: "2030-01-01", Inicio: "10:00", Capacidad: 5})
+	_, _ = enrollments.Create(ctx, &domain.Enrollment{OrgID: "1", SessionID: sess, ActivityID: act, UserID: "3", Estado: "confirmada"})
+
+	if out, err := svc.Roster(ctx, sess, "9", false); err != nil || len(out) != 1 {
+		t.Fatalf("the instructor should see the roster, got %v, %v", out, err)
+	}
+	if _, err := svc.Roster(ctx, sess, "8", false); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("expected ErrForbidden for another instructor, got %v", err)
+	}
+	if _, err := svc.Roster(ctx, sess+100, "7", true); !errors.Is(err, services.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing session, got %v", err)
+	}
+
+	// sesión de una actividad que ya no existe: 404, no 500
+	orphan, _ := sessions.Create(ctx, &domain.Session{OrgID: "1", ActivityID: act + 100, Fecha: "2030-01-01", Inicio: "10:00"})
+	if _, err := svc.Roster(ctx, orphan, "7", true); !errors.Is(err, services.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing activity, got %v", err)
+	}
+}
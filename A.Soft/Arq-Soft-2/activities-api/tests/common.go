@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"sync"
+
+	"github.com/sporthub/activities-api/internal/config"
+)
+
+// fakePublisher guarda las routing keys publicadas (o falla con err)
+type fakePublisher struct {
+	mu   sync.Mutex
+	keys []string
+	err  error
+}
+
+func (f *fakePublisher) Publish(routing string, payload any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.keys = append(f.keys, routing)
+	return nil
+}
+
+func (f *fakePublisher) Close() {}
+
+func (f *fakePublisher) published(routing string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, k := range f.keys {
+		if k == routing {
+			n++
+		}
+	}
+	return n
+}
+
+func testConfig() *config.Config {
+	return &config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub", DefaultOrgID: "1"}
+}
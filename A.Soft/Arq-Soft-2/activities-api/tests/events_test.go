@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sporthub/activities-api/internal/domain"
+	"github.com/sporthub/activities-api/internal/repository"
+	"github.com/sporthub/activities-api/internal/services"
+	"github.com/sporthub/shared/rabbit"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPublishFailuresReachTheCaller(t *testing.T) {
+	ctx := repository.WithTenant(context.Background(), "1")
+	pub := &fakePublisher{err: rabbit.ErrUnroutable}
+	activities := repository.NewActivitiesMemory()
+	users := fakeUsersAPI(t, map[string]string{"1": "instructor"}, nil)
+	acts := services.NewActivitiesService(activities, users, pub, testConfig())
+	sessions := services.NewSessionsService(repository.NewSessionsMemory(repository.NewEnrollmentsMemory()), activities, pub, testConfig())
+
+	// el cambio queda guardado y el caller se entera de que el evento no salió
+	id, err := acts.Create(ctx, &domain.Activity{OwnerUserID: "1", Nombre: "Padel", Categoria: "padel", PrecioBase: 1000})
+	if !errors.Is(err, services.ErrEventNotPublished) || !errors.Is(err, rabbit.ErrUnroutable) {
+		t.Fatalf("Create() error = %v, want ErrEventNotPublished wrapping the cause", err)
+	}
+	if _, err := acts.GetByID(ctx, id); err != nil {
+		t.Fatalf("the activity must be saved even if its event was not published: %v", err)
+	}
+	if err := acts.Update(ctx, id, bson.M{"nombre": "Padel mixto"}); !errors.Is(err, services.ErrEventNotPublished) {
+		t.Fatalf("Update() error = %v, want ErrEventNotPublished", err)
+	}
+
+	sid, err := sessions.Create(ctx, &domain.Session{ActivityID: id, Capacidad: 10})
+	if !errors.Is(err, services.ErrEventNotPublished) || sid == 0 {
+		t.Fatalf("session Create() = %d, %v, want the id and ErrEventNotPublished", sid, err)
+	}
+	if err := sessions.Delete(ctx, sid); !errors.Is(err, services.ErrEventNotPublished) {
+		t.Fatalf("session Delete() error = %v, want ErrEventNotPublished", err)
+	}
+
+	// con el broker disponible no hay error
+	pub.err = nil
+	if err := acts.Delete(ctx, id); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if pub.published("activity.deleted") != 1 {
+		t.Fatal("expected activity.deleted to be published")
+	}
+}
@@ -0,0 +1,292 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/sporthub/activities-api/internal/clients"
+	"github.com/sporthub/activities-api/internal/domain"
+	"github.com/sporthub/activities-api/internal/middleware"
+	"github.com/sporthub/activities-api/internal/repository"
+	"github.com/sporthub/activities-api/internal/services"
+	"github.com/sporthub/shared/authz"
+	"github.com/sporthub/shared/jwks"
+)
+
+// fakeUsersAPI responde como users-api: rol global de cada usuario y sus
+// membresías ("org/user" -> rol en el club)
+func fakeUsersAPI(t *testing.T, roles map[string]string, memberships map[string]string) *clients.UsersClient {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/token", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"access_token": "service-token", "expires_in": 3600})
+	})
+	r.GET("/users/:id", func(c *gin.Context) {
+		role, ok := roles[c.Param("id")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+		c.JSON(http.StatusOK, gin.H{"id": id, "role": role})
+	})
+	r.GET("/orgs/:id/members/:userId", func(c *gin.Context) {
+		role, ok := memberships[c.Param("id")+"/"+c.Param("userId")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user is not a member of the organization"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"orgId": 0, "userId": 0, "role": role})
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return clients.NewUsersClient(srv.URL, "activities-api", "secret")
+}
+
+func TestInstructorMustBelongToTheActivitysOrg(t *testing.T) {
+	users := fakeUsersAPI(t,
+		map[string]string{"1": "user", "2": "user", "3": "instructor", "4": "admin", "5": "user"},
+		map[string]string{"7/2": "instructor", "7/5": "member", "8/3": "member"})
+	svc := services.NewActivitiesService(repository.NewActivitiesMemory(), users, &fakePublisher{}, testConfig())
+
+	tests := []struct {
+		name       string
+		org        string
+		instructor string
+		ok         bool
+	}{
+		{"club instructor in their club", "7", "2", true},
+		{"club instructor in another club", "8", "2", false},
+		{"club member", "7", "5", false},
+		{"global instructor in the default org", "1", "3", true},
+		{"global instructor in a club where they are a member", "8", "3", false},
+		{"global admin anywhere", "8", "4", true},
+		{"no instructor", "7", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := repository.WithTenant(context.Background(), tt.org)
+			_, err := svc.Create(ctx, &domain.Activity{OwnerUserID: "1", InstructorUserID: tt.instructor, Nombre: "Yoga"})
+			if tt.ok && err != nil {
+				t.Errorf("expected the instructor to be accepted, got %v", err)
+			}
+			if !tt.ok && !errors.Is(err, services.ErrInvalidInstructor) {
+				t.Errorf("expected ErrInvalidInstructor, got %v", err)
+			}
+		})
+	}
+
+	// al editar vale la organización de la actividad (la del request)
+	ctx := repository.WithTenant(context.Background(), "7")
+	id, err := svc.Create(ctx, &domain.Activity{OwnerUserID: "1", Nombre: "Spinning"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Update(ctx, id, map[string]any{"instructorUserId": "2"}); err != nil {
+		t.Errorf("club instructor should be assignable on update: %v", err)
+	}
+	if err := svc.Update(ctx, id, map[string]any{"instructorUserId": "3"}); !errors.Is(err, services.ErrInvalidInstructor) {
+		t.Errorf("expected ErrInvalidInstructor on update, got %v", err)
+	}
+}
+
+func TestRepositoriesOnlySeeTheRequestsOrg(t *testing.T) {
+	enrollments := repository.NewEnrollmentsMemory()
+	sessions := repository.NewSessionsMemory(enrollments)
+	activities := repository.NewActivitiesMemory()
+	club7 := repository.WithTenant(context.Background(), "7")
+	club8 := repository.WithTenant(context.Background(), "8")
+
+	act, _ := activities.Create(club7, &domain.Activity{OwnerUserID: "1", Nombre: "Yoga"})
+	sess, _ := sessions.Create(club7, &domain.Session{ActivityID: act, Fecha: "2030-01-01", Inicio: "10:00", Capacidad: 5})
+	enr, _ := enrollments.Create(club7, &domain.Enrollment{SessionID: sess, ActivityID: act, UserID: "3", Estado: "confirmada"})
+
+	if a, err := activities.GetByID(club7, act); err != nil || a.OrgID != "7" {
+		t.Fatalf("the activity should be saved in the request's org, got %+v, %v", a, err)
+	}
+	if _, err := activities.GetByID(club8, act); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("another org must not see the activity, got %v", err)
+	}
+	if list, total, _ := activities.List(club8, 0, 10); len(list) != 0 || total != 0 {
+		t.Errorf("another org must not list the activity, got %d", total)
+	}
+	if _, err := sessions.GetByID(club8, sess); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("another org must not see the session, got %v", err)
+	}
+	if _, err := enrollments.GetByID(club8, enr); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("another org must not see the enrollment, got %v", err)
+	}
+	// "" = todas (consumers y rutas internas)
+	if _, err := activities.GetByID(context.Background(), act); err != nil {
+		t.Errorf("the unscoped context should see every org: %v", err)
+	}
+
+	// con manage-any de otro club tampoco se llega a la actividad
+	svc := services.NewActivitiesService(activities, nil, nil, testConfig())
+	if _, err := svc.Authorize(club8, act, "9", true); !errors.Is(err, services.ErrNotFound) {
+		t.Errorf("expected ErrNotFound from another org, got %v", err)
+	}
+}
+
+// jwksServer publica la clave pública de key como lo hace users-api
+func jwksServer(t *testing.T, key *rsa.PrivateKey, kid string) *httptest.Server {
+	t.Helper()
+	enc := base64.RawURLEncoding
+	body := gin.H{"keys": []gin.H{{
+		"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+		"n": enc.EncodeToString(key.N.Bytes()),
+		"e": enc.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) { c.JSON(http.StatusOK, body) })
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = kid
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestPermissionsComeFromTheRequestsOrg(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := jwksServer(t, key, "k1")
+	cfg := testConfig()
+
+	r := gin.New()
+	r.Use(middleware.Tenant(cfg.DefaultOrgID))
+	auth := middleware.JWTAuth(jwks.New(srv.URL+"/.well-known/jwks.json", time.Minute), cfg.JWTIssuer, cfg.JWTAudience, nil)
+	r.POST("/activities", auth, authz.RequirePermission(authz.PermActivityCreate), func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	exp := time.Now().Add(time.Minute).Unix()
+	token := func(rol string, orgs map[string][]string) string {
+		return signToken(t, key, "k1", jwt.MapClaims{
+			"sub": 2, "rol": rol, "iss": cfg.JWTIssuer, "aud": cfg.JWTAudience, "exp": exp,
+			"perms": authz.PermissionsFor(rol), "orgs": orgs,
+		})
+	}
+	clubInstructor := token(authz.RoleUser, map[string][]string{
+		"1": authz.PermissionsFor(authz.RoleUser),
+		"7": authz.OrgPermissionsFor(authz.OrgRoleInstructor),
+	})
+	admin := token(authz.RoleAdmin, map[string][]string{"1": authz.PermissionsFor(authz.RoleAdmin)})
+
+	tests := []struct {
+		name  string
+		token string
+		org   string
+		want  int
+	}{
+		{"club instructor in their club", clubInstructor, "7", http.StatusCreated},
+		{"club instructor in another club", clubInstructor, "8", http.StatusForbidden},
+		{"club instructor in the default org", clubInstructor, "", http.StatusForbidden},
+		{"global admin in any club", admin, "8", http.StatusCreated},
+		{"invalid org id", clubInstructor, "7;drop", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/activities", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			if tt.org != "" {
+				req.Header.Set(middleware.TenantHeader, tt.org)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestJWTAuthRequiresExpiration(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := jwksServer(t, key, "k1")
+	cfg := testConfig()
+
+	r := gin.New()
+	auth := middleware.JWTAuth(jwks.New(srv.URL+"/.well-known/jwks.json", time.Minute), cfg.JWTIssuer, cfg.JWTAudience, nil)
+	r.GET("/me", auth, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	exp := time.Now().Add(time.Minute).Unix()
+	tests := []struct {
+		name string
+		exp  any
+		want int
+	}{
+		{"without exp", nil, http.StatusUnauthorized},
+		{"with exp", exp, http.StatusOK},
+	}
+	for _, tt := range tests {
+		claims := jwt.MapClaims{"sub": 2, "rol": authz.RoleUser, "iss": cfg.JWTIssuer, "aud": cfg.JWTAudience}
+		if tt.exp != nil {
+			claims["exp"] = tt.exp
+		}
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+signToken(t, key, "k1", claims))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestRevocationListSendsTheServiceToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/token", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"access_token": "service-token", "expires_in": 3600})
+	})
+	r.GET("/auth/revoked", func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer service-token" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing service token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"revoked": []gin.H{{"jti": "abc", "exp": time.Now().Add(time.Hour).Unix()}},
+			"now":     time.Now().Unix(),
+		})
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	list := clients.NewRevocationList(clients.NewUsersClient(srv.URL, "activities-api", "secret"), time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // Run sincroniza una vez antes de mirar ctx
+	list.Run(ctx)
+	if !list.IsRevoked("abc") {
+		t.Error("expected the revoked jti after the first sync")
+	}
+	if list.IsRevoked("other") {
+		t.Error("unexpected revoked jti")
+	}
+}
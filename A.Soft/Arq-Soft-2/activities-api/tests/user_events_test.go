@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sporthub/activities-api/internal/domain"
+	"github.com/sporthub/activities-api/internal/repository"
+	"github.com/sporthub/activities-api/internal/services"
+)
+
+func TestUserDeletedFreesFutureSeatsAndAnonymizes(t *testing.T) {
+	ctx := context.Background()
+	enrollments := repository.NewEnrollmentsMemory()
+	sessions := repository.NewSessionsMemory(enrollments)
+	activities := repository.NewActivitiesMemory()
+	bus := &fakePublisher{}
+	svc := services.NewUserEventsService(enrollments, sessions, activities, bus, testConfig())
+
+	tomorrow := time.Now().Add(24 * time.Hour).Format("2006-01-02")
+	yesterday := time.Now().Add(-24 * time.Hour).Format("2006-01-02")
+	future, _ := sessions.Create(ctx, &domain.Session{OrgID: "1", ActivityID: 1, Fecha: tomorrow, Inicio: "10:00", Capacidad: 1})
+	past, _ := sessions.Create(ctx, &domain.Session{OrgID: "1", ActivityID: 1, Fecha: yesterday, Inicio: "10:00", Capacidad: 1})
+	broken, _ := sessions.Create(ctx, &domain.Session{OrgID: "1", ActivityID: 1, Fecha: "mañana", Inicio: "10"})
+
+	confirmed, _ := enrollments.Create(ctx, &domain.Enrollment{OrgID: "1", SessionID: future, UserID: "7", Estado: "confirmada"})
+	pending, _ := enrollments.Create(ctx, &domain.Enrollment{OrgID: "2", SessionID: future, UserID: "7", Estado: "pendiente"})
+	attended, _ := enrollments.Create(ctx, &domain.Enrollment{OrgID: "1", SessionID: past, UserID: "7", Estado: "confirmada"})
+	unknown, _ := enrollments.Create(ctx, &domain.Enrollment{OrgID: "1", SessionID: broken, UserID: "7", Estado: "confirmada"})
+	other, _ := enrollments.Create(ctx, &domain.Enrollment{OrgID: "1", SessionID: future, UserID: "8", Estado: "confirmada"})
+
+	ev := services.UserEvent{Op: "deleted", UserID: "7"}
+	if err := svc.Handle(ctx, ev); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	want := map[uint64]string{confirmed: "cancelada", pending: "cancelada", attended: "confirmada", unknown: "cancelada", other: "confirmada"}
+	for id, estado := range want {
+		e, _ := enrollments.GetByID(ctx, id)
+		if e.Estado != estado {
+			t.Errorf("enrollment %d: expected %s, got %s", id, estado, e.Estado)
+		}
+		if anonymized := e.UserID == domain.DeletedUserID; anonymized != (id != other) {
+			t.Errorf("enrollment %d: unexpected userId %q", id, e.UserID)
+		}
+	}
+	if n, _ := sessions.CountEnrollments(ctx, future); n != 1 {
+		t.Errorf("the deleted user's seat should be free, %d confirmed left", n)
+	}
+	if n := bus.published("enrollment.cancelled"); n != 3 {
+		t.Errorf("expected 3 enrollment.cancelled events, got %d", n)
+	}
+
+	// el mismo evento otra vez (reentrega) no cambia nada
+	if err := svc.Handle(ctx, ev); err != nil {
+		t.Fatalf("Handle() again error: %v", err)
+	}
+	if n := bus.published("enrollment.cancelled"); n != 3 {
+		t.Errorf("redelivery must be idempotent, got %d cancellations", n)
+	}
+}
+
+func TestUserDeletedOrphansOwnedActivities(t *testing.T) {
+	ctx := context.Background()
+	enrollments := repository.NewEnrollmentsMemory()
+	activities := repository.NewActivitiesMemory()
+	own, _ := activities.Create(ctx, &domain.Activity{OrgID: "1", OwnerUserID: "7", Nombre: "Yoga"})
+	kept, _ := activities.Create(ctx, &domain.Activity{OrgID: "1", OwnerUserID: "8", Nombre: "Spinning"})
+
+	cfg := testConfig()
+	svc := services.NewUserEventsService(enrollments, repository.NewSessionsMemory(enrollments), activities, &fakePublisher{}, cfg)
+	if err := svc.Handle(ctx, services.UserEvent{Op: "deleted", UserID: "7"}); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	a, _ := activities.GetByID(ctx, own)
+	if a.OwnerUserID != "" || !a.OwnerDeleted {
+		t.Errorf("expected the activity to be flagged as orphaned, got %+v", a)
+	}
+	if b, _ := activities.GetByID(ctx, kept); b.OwnerUserID != "8" || b.OwnerDeleted {
+		t.Errorf("other activities must not change, got %+v", b)
+	}
+
+	// con ORPHAN_ACTIVITY_OWNER_ID se reasignan
+	cfg.OrphanActivityOwnerID = "1"
+	_ = activities.Update(ctx, own, map[string]any{"ownerUserId": "9", "ownerDeleted": false})
+	if err := svc.Handle(ctx, services.UserEvent{Op: "deleted", UserID: "9"}); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := activities.GetByID(ctx, own); a.OwnerUserID != "1" || a.OwnerDeleted {
+		t.Errorf("expected the activity to be reassigned, got %+v", a)
+	}
+}
@@ -1,7 +1,7 @@
 "use client"
 
 import { useEffect, useState } from "react"
-import { useAuth } from "@/context/auth-context"
+import { hasPermission, useAuth } from "@/context/auth-context"
 import { useRouter } from "next/navigation"
 import ActivityForm from "@/components/activity-form"
 import ActivityList from "@/components/activity-list"
@@ -19,9 +19,9 @@ export default function ManageActivitiesPage() {
   const [selectedActivity, setSelectedActivity] = useState<Activity | null>(null)
   const [editingActivity, setEditingActivity] = useState<Activity | null>(null)
 
-  // Redirect non-admin users
+  // Redirect users that can't manage activities (admins e instructores)
   useEffect(() => {
-    if (user && user.role !== "admin") {
+    if (user && !hasPermission(user, "activity:create")) {
       router.push("/home")
     }
   }, [user, router])
@@ -83,7 +83,7 @@ export default function ManageActivitiesPage() {
     }
   }
 
-  if (!user || user.role !== "admin") {
+  if (!user || !hasPermission(user, "activity:create")) {
     return null
   }
 
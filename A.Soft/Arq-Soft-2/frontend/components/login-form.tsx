@@ -2,7 +2,7 @@
 
 import type React from "react"
 import { useState } from "react"
-import { useAuth } from "@/context/auth-context"
+import { useAuth, MFARequiredError } from "@/context/auth-context"
 import { Spinner } from "@/components/ui/spinner"
 import { Eye, EyeOff } from "lucide-react"
 
@@ -13,6 +13,8 @@ interface LoginFormProps {
 export function LoginForm({ onToast }: LoginFormProps) {
   const [email, setEmail] = useState("")
   const [password, setPassword] = useState("")
+  const [mfaCode, setMfaCode] = useState("")
+  const [needsMfa, setNeedsMfa] = useState(false)
   const [showPassword, setShowPassword] = useState(false)
   const [isLoading, setIsLoading] = useState(false)
   const { login } = useAuth()
@@ -28,9 +30,12 @@ export function LoginForm({ onToast }: LoginFormProps) {
     setIsLoading(true)
 
     try {
-      await login(email, password)
+      await login(email, password, needsMfa ? mfaCode : undefined)
       onToast({ message: "¡Bienvenido!", type: "success" })
     } catch (error) {
+      if (error instanceof MFARequiredError) {
+        setNeedsMfa(true)
+      }
       onToast({
         message: error instanceof Error ? error.message : "Email o contraseña incorrectos",
         type: "error",
@@ -75,6 +80,22 @@ export function LoginForm({ onToast }: LoginFormProps) {
         </div>
       </div>
 
+      {needsMfa && (
+        <div>
+          <label className="block text-sm font-semibold mb-2 text-foreground">Código de verificación</label>
+          <input
+            type="text"
+            inputMode="numeric"
+            autoComplete="one-time-code"
+            value={mfaCode}
+            onChange={(e) => setMfaCode(e.target.value)}
+            placeholder="123456"
+            required
+            className="w-full px-4 py-2 border border-border rounded-lg focus:outline-none focus:ring-2 focus:ring-primary bg-background transition"
+          />
+        </div>
+      )}
+
       <button
         type="submit"
         disabled={isLoading}
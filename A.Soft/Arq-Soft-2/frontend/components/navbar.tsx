@@ -1,6 +1,6 @@
 "use client"
 
-import { useAuth } from "@/context/auth-context"
+import { hasPermission, useAuth } from "@/context/auth-context"
 import Link from "next/link"
 import { usePathname } from "next/navigation"
 import { LogOut, Home, TicketX as Tickets, Settings } from "lucide-react"
@@ -43,7 +43,7 @@ export function Navbar() {
               <span className="hidden sm:inline">Mis Reservas</span>
             </Link>
 
-            {user?.role === "admin" && (
+            {hasPermission(user, "activity:create") && (
               <Link
                 href="/manage-activities"
                 className={`flex items-center gap-2 transition ${
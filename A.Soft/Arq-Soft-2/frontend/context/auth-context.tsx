@@ -8,14 +8,15 @@ interface User {
   id: string | number
   username: string
   email: string
-  role: "user" | "admin"
+  role: "user" | "instructor" | "admin"
+  permissions?: string[]
 }
 
 interface AuthContextType {
   user: User | null
   token: string | null
   isLoading: boolean
-  login: (email: string, password: string) => Promise<void>
+  login: (email: string, password: string, mfaCode?: string) => Promise<void>
   logout: () => void
   register: (username: string, email: string, password: string) => Promise<void>
   isAuthenticated: boolean
@@ -29,6 +30,27 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   const [isLoading, setIsLoading] = useState(true)
   const router = useRouter()
 
+  // Renovar el access token (dura pocos minutos) con el refresh token un
+  // minuto antes de que expire
+  useEffect(() => {
+    if (!token || typeof window === "undefined") return
+    const refreshToken = localStorage.getItem("auth_refresh_token")
+    const expiresAt = Number(localStorage.getItem("auth_expires_at") || 0)
+    if (!refreshToken || !expiresAt) return
+
+    const delay = Math.max(expiresAt - Date.now() - 60_000, 0)
+    const timer = setTimeout(async () => {
+      try {
+        const data = await usersAPI.refresh(refreshToken)
+        storeTokens(data)
+        setToken(data.token)
+      } catch {
+        logout()
+      }
+    }, delay)
+    return () => clearTimeout(timer)
+  }, [token])
+
   // Initialize from localStorage
   useEffect(() => {
     if (typeof window === "undefined") {
@@ -51,20 +73,30 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     setIsLoading(false)
   }, [])
 
-  const login = async (email: string, password: string) => {
+  const login = async (email: string, password: string, mfaCode?: string) => {
     try {
-      const data = await usersAPI.login(email, password)
+      let data = await usersAPI.login(email, password)
+      if (data.mfaSetupRequired) {
+        throw new Error("Tu cuenta requiere configurar la verificación en dos pasos")
+      }
+      if (data.mfaRequired) {
+        if (!mfaCode) {
+          throw new MFARequiredError()
+        }
+        data = await usersAPI.verifyMFA(data.mfaToken, mfaCode)
+      }
 
       const userId = String(data.userId)
       const userData: User = {
         id: userId,
         username: email.split("@")[0],
         email,
-        role: (data.role || "user") as "user" | "admin",
+        role: (data.role || "user") as User["role"],
+        permissions: data.permissions || [],
       }
 
       if (typeof window !== "undefined") {
-      localStorage.setItem("auth_token", data.token)
+      storeTokens(data)
       localStorage.setItem("auth_user", JSON.stringify(userData))
       }
 
@@ -90,7 +122,12 @@ export function AuthProvider({ children }: { children: ReactNode }) {
 
   const logout = () => {
     if (typeof window !== "undefined") {
+    if (token) {
+      usersAPI.logout(token, localStorage.getItem("auth_refresh_token")).catch(() => {})
+    }
     localStorage.removeItem("auth_token")
+    localStorage.removeItem("auth_refresh_token")
+    localStorage.removeItem("auth_expires_at")
     localStorage.removeItem("auth_user")
     }
     setToken(null)
@@ -115,6 +152,24 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   )
 }
 
+function storeTokens(data: { token: string; refreshToken?: string; expiresIn?: number }) {
+  localStorage.setItem("auth_token", data.token)
+  if (data.refreshToken) {
+    localStorage.setItem("auth_refresh_token", data.refreshToken)
+  }
+  if (data.expiresIn) {
+    localStorage.setItem("auth_expires_at", String(Date.now() + data.expiresIn * 1000))
+  }
+}
+
+// MFARequiredError indica que hay que pedir el código de la app autenticadora
+export class MFARequiredError extends Error {
+  constructor() {
+    super("Ingresa el código de tu app autenticadora")
+    this.name = "MFARequiredError"
+  }
+}
+
 export function useAuth() {
   const context = useContext(AuthContext)
   if (!context) {
@@ -122,3 +177,8 @@ export function useAuth() {
   }
   return context
 }
+
+// hasPermission indica si el usuario tiene el permiso (claim perms del JWT)
+export function hasPermission(user: User | null, permission: string) {
+  return !!user?.permissions?.includes(permission)
+}
@@ -107,6 +107,34 @@ export const usersAPI = {
     return response.json()
   },
 
+  // Segundo paso del login con 2FA: token parcial + código TOTP o de recuperación
+  async verifyMFA(mfaToken: string, code: string) {
+    const response = await fetchWithAuth(`${USERS_API_BASE}/auth/2fa/verify`, {
+      method: "POST",
+      body: JSON.stringify({ mfaToken, code }),
+    })
+    return response.json()
+  },
+
+  async refresh(refreshToken: string) {
+    const response = await fetchWithAuth(`${USERS_API_BASE}/auth/refresh`, {
+      method: "POST",
+      body: JSON.stringify({ refreshToken }),
+    })
+    return response.json()
+  },
+
+  async logout(token: string, refreshToken?: string | null) {
+    await fetchWithAuth(
+      `${USERS_API_BASE}/auth/logout`,
+      {
+        method: "POST",
+        body: JSON.stringify({ refreshToken: refreshToken || "" }),
+      },
+      token
+    )
+  },
+
   async register(username: string, email: string, password: string) {
     const response = await fetchWithAuth(`${USERS_API_BASE}/users`, {
       method: "POST",
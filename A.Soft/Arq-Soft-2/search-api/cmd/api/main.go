@@ -11,10 +11,16 @@ import (
 
 	"github.com/gin-gonic/gin"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/mongo"
+	"github.com/sporthub/shared/authz"
+	"github.com/sporthub/shared/jwks"
+	"github.com/sporthub/shared/rabbit"
 
+	"github.com/sporthub/search-api/internal/clients"
 	"github.com/sporthub/search-api/internal/config"
 	"github.com/sporthub/search-api/internal/consumers"
 	"github.com/sporthub/search-api/internal/controllers"
+	"github.com/sporthub/search-api/internal/db"
 	"github.com/sporthub/search-api/internal/middleware"
 	"github.com/sporthub/search-api/internal/repository"
 	"github.com/sporthub/search-api/internal/services"
@@ -27,19 +33,85 @@ func main() {
 		cfg.Env, cfg.Port, cfg.SolrURL, cfg.MemcachedAddr, cfg.CacheTTLSeconds, cfg.ActivitiesAPI, cfg.RabbitURL)
 
 	// ---- Dependencies
-	solrRepo := repository.NewSolrRepo(cfg.SolrURL)
+	// Motor de búsqueda: "solr", "memory" (índice embebido, sin Solr) o
+	// "auto" (Solr con fallback al índice embebido si falla el health check)
+	var backend repository.SearchBackend
+	var failover *repository.FailoverRepo
+	activeBackend := func() string { return cfg.SearchBackend }
+	switch cfg.SearchBackend {
+	case repository.BackendSolr:
+		backend = repository.NewSolrRepo(cfg.SolrURL)
+	case repository.BackendMemory:
+		backend = repository.NewMemoryIndex()
+	default:
+		failover = repository.NewFailoverRepo(repository.NewSolrRepo(cfg.SolrURL), repository.NewMemoryIndex(),
+			time.Duration(cfg.SolrHealthIntervalSeconds)*time.Second)
+		backend = failover
+		activeBackend = failover.Active
+	}
+	log.Printf("[search-api] search backend=%s", cfg.SearchBackend)
+
 	local := repository.NewLocalCache(10_000)
 	dist := repository.NewMemcached(cfg.MemcachedAddr)
-	svc := services.NewSearchService(solrRepo, local, dist, time.Duration(cfg.CacheTTLSeconds)*time.Second)
+	analyticsSvc := services.NewAnalyticsService(repository.NewAnalyticsStore(cfg.AnalyticsCapacity))
+	svc := services.NewSearchService(backend, local, dist, time.Duration(cfg.CacheTTLSeconds)*time.Second, analyticsSvc)
+	activities := clients.NewActivitiesClient(cfg.ActivitiesAPI)
+	var database *mongo.Database
+	if cfg.MongoURI != "" {
+		_, database = db.MustMongo(cfg.MongoURI, cfg.MongoDB)
+	} else {
+		log.Printf("[search-api] WARN: MONGO_URI not set, saved searches and recommendation affinities are kept in memory")
+	}
+	recoSvc := services.NewRecommendationService(affinityStore(database), backend, activities)
+	// Alertas search.match: publisher confirms y mandatory, así una alerta que
+	// no llega a ninguna cola cuenta como no enviada (no gasta el cupo)
+	publisher := rabbit.New(cfg.RabbitURL, cfg.RabbitExchange, "topic", time.Duration(cfg.RabbitConfirmTimeoutMS)*time.Millisecond)
+	defer publisher.Close()
+	savedSvc := services.NewSavedSearchService(savedSearchStore(database), publisher, cfg.SavedSearchAlertsPerHour)
+
+	amqpConn := clients.NewAMQPConnection(cfg.RabbitURL)
+	recoConsumer := consumers.NewEnrollmentConsumer(recoSvc)
+	amqpConn.OnConnect(func(ctx context.Context, conn *amqp.Connection) error {
+		return recoConsumer.Start(ctx, conn, cfg.RabbitRecommendationsQueue, cfg.RabbitExchange, cfg.RabbitEnrollmentRoutingKey)
+	})
+	consumer := consumers.NewConsumer(backend, local, dist, activities, savedSvc, cfg.ConsumerWorkers, cfg.RabbitPrefetch)
+	amqpConn.OnConnect(func(ctx context.Context, conn *amqp.Connection) error {
+		return consumer.Start(ctx, conn, cfg.RabbitQueue, cfg.RabbitExchange, cfg.RabbitRoutingKey)
+	})
 
 	// ---- HTTP server (Gin)
 	r := gin.Default()
 	r.Use(middleware.CORS())
+	// Organización (club) de cada request: búsquedas y caché quedan dentro de ella
+	r.Use(middleware.Tenant(cfg.DefaultOrgID))
 
 	search := controllers.NewSearchHandler(svc)
+	analytics := controllers.NewAnalyticsHandler(analyticsSvc)
+	recommendations := controllers.NewRecommendationsHandler(recoSvc)
+	savedSearches := controllers.NewSavedSearchesHandler(savedSvc)
 
-	r.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true, "search_backend": activeBackend(), "rabbit": rabbitState(amqpConn)})
+	})
 	r.GET("/search", search.Search)
+	r.GET("/search/trending", analytics.Trending)
+	r.GET("/search/related/:activityId", search.Related)
+	r.POST("/search/click", middleware.RateLimit(cfg.ClickRateLimitPerMinute), analytics.Click)
+
+	auth := middleware.JWTAuth(jwks.New(cfg.JWKSURL, 10*time.Minute), cfg.JWTIssuer, cfg.JWTAudience)
+	r.GET("/recommendations/me", auth, recommendations.Me)
+
+	// Búsquedas guardadas del usuario del JWT
+	saved := r.Group("/saved-searches")
+	saved.Use(auth)
+	savedSearches.RegisterRoutes(saved)
+
+	// Reportes de búsquedas (search:reindex)
+	admin := r.Group("/admin/search")
+	admin.Use(auth, authz.RequirePermission(authz.PermSearchReindex))
+	admin.GET("/top", analytics.TopQueries)
+	admin.GET("/zero-results", analytics.ZeroResults)
+	admin.GET("/click-through", analytics.ClickThrough)
 
 	srv := &http.Server{
 		Addr:              ":" + cfg.Port,
@@ -47,45 +119,21 @@ func main() {
 		ReadHeaderTimeout: 10 * time.Second,
 	}
 
-	// ---- RabbitMQ consumer (async)
+	// ---- RabbitMQ (async)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	go func() {
-		// Retry con backoff exponencial para conectarse a RabbitMQ
-		maxRetries := 10
-		retryDelay := 2 * time.Second
-		
-		var conn *amqp.Connection
-		var err error
-		
-		for i := 0; i < maxRetries; i++ {
-			conn, err = amqp.Dial(cfg.RabbitURL)
-			if err == nil {
-				log.Printf("[rabbit] connected after %d attempts", i+1)
-				break
-			}
-			
-			if i < maxRetries-1 {
-				log.Printf("[rabbit] connection attempt %d/%d failed (%v), retrying in %v...", i+1, maxRetries, err, retryDelay)
-				time.Sleep(retryDelay)
-				retryDelay *= 2 // Backoff exponencial
-				if retryDelay > 30*time.Second {
-					retryDelay = 30 * time.Second // Máximo 30 segundos
-				}
-			}
-		}
-		
-		if err != nil {
-			log.Printf("[rabbit] WARN: connection failed after %d attempts (%v). search-api will still serve /search", maxRetries, err)
-			return
-		}
-		defer conn.Close()
+	if failover != nil {
+		go failover.Run(ctx)
+	}
 
-		consumer := consumers.NewConsumer(solrRepo, local, dist, cfg.ActivitiesAPI)
-		if err := consumer.Start(ctx, conn, cfg.RabbitQueue, cfg.RabbitExchange, cfg.RabbitRoutingKey); err != nil {
-			log.Printf("[rabbit] consumer error: %v", err)
-		}
+	// Conexión a RabbitMQ con reconexión automática: en cada reconexión se
+	// vuelven a declarar exchange/colas/bindings y se retoma el consumo.
+	// rabbitDone se cierra cuando los consumers terminaron lo que tenían en curso.
+	rabbitDone := make(chan struct{})
+	go func() {
+		defer close(rabbitDone)
+		amqpConn.Run(ctx)
 	}()
 
 	// ---- Start HTTP server
@@ -107,5 +155,45 @@ func main() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Printf("[shutdown] http error: %v", err)
 	}
+	// Cortar el consumer y esperar a que drene los eventos en curso
+	cancel()
+	select {
+	case <-rabbitDone:
+	case <-shutdownCtx.Done():
+		log.Printf("[shutdown] WARN: consumer did not drain in time")
+	}
 	log.Printf("[shutdown] bye")
 }
+
+// savedSearchStore usa MongoDB si hay MONGO_URI; si no, las búsquedas
+// guardadas quedan en memoria (se pierden al reiniciar)
+func savedSearchStore(database *mongo.Database) services.SavedSearchStore {
+	if database == nil {
+		return repository.NewSavedSearchStore()
+	}
+	st, err := repository.NewSavedSearchMongo(database)
+	if err != nil {
+		log.Fatalf("[saved-searches] mongo: %v", err)
+	}
+	return st
+}
+
+// affinityStore guarda en MongoDB las inscripciones de las que salen las
+// recomendaciones; sin MONGO_URI quedan en memoria
+func affinityStore(database *mongo.Database) services.AffinityStore {
+	if database == nil {
+		return repository.NewAffinityStore()
+	}
+	st, err := repository.NewAffinityMongo(database)
+	if err != nil {
+		log.Fatalf("[recommendations] mongo: %v", err)
+	}
+	return st
+}
+
+func rabbitState(r *clients.AMQPConnection) string {
+	if r.Connected() {
+		return "connected"
+	}
+	return "disconnected"
+}
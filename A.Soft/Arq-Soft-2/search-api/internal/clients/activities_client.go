@@ -0,0 +1,78 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sporthub/search-api/internal/domain"
+	"github.com/sporthub/search-api/internal/repository"
+)
+
+// ActivitiesClient consulta activities-api para completar documentos y sesiones
+type ActivitiesClient struct {
+	base string
+	http *http.Client
+}
+
+func NewActivitiesClient(base string) *ActivitiesClient {
+	return &ActivitiesClient{
+		base: strings.TrimRight(base, "/"),
+		http: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// SearchDoc obtiene el search-doc de una actividad de la organización de ctx
+func (c *ActivitiesClient) SearchDoc(ctx context.Context, activityID string) (*domain.SearchDoc, error) {
+	var doc domain.SearchDoc
+	if err := c.getJSON(ctx, fmt.Sprintf("%s/activities/%s/search-doc", c.base, activityID), &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Sessions obtiene las sesiones de una actividad de la organización de ctx
+func (c *ActivitiesClient) Sessions(ctx context.Context, activityID string) ([]domain.Session, error) {
+	var out []domain.Session
+	if err := c.getJSON(ctx, fmt.Sprintf("%s/activities/%s/sessions", c.base, activityID), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Enrollments obtiene las inscripciones confirmadas del usuario en la
+// organización de ctx
+func (c *ActivitiesClient) Enrollments(ctx context.Context, userID string) ([]domain.Enrollment, error) {
+	var out []domain.Enrollment
+	if err := c.getJSON(ctx, fmt.Sprintf("%s/enrollments/by-user/%s", c.base, url.PathEscape(userID)), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// getJSON manda la organización de ctx en X-Org-ID: activities-api solo
+// devuelve datos de esa organización (sin header usa la default)
+func (c *ActivitiesClient) getJSON(ctx context.Context, u string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	if org := repository.TenantFrom(ctx); org != "" {
+		req.Header.Set("X-Org-ID", org)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("activities-api: %s", string(b))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,116 @@
+package clients
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ConnectHook se ejecuta en cada (re)conexión. ctx se cancela cuando la
+// conexión se pierde o se apaga el servicio; los consumers deben devolver
+// control en ese momento. Si devuelve error se fuerza una reconexión.
+type ConnectHook func(ctx context.Context, conn *amqp.Connection) error
+
+// AMQPConnection mantiene una conexión a RabbitMQ que se reconecta sola con
+// backoff exponencial. Publishers y consumers se registran con OnConnect y se
+// vuelven a montar (canales, exchanges, colas y bindings) en cada reconexión.
+type AMQPConnection struct {
+	url      string
+	minDelay time.Duration
+	maxDelay time.Duration
+
+	mu        sync.RWMutex
+	hooks     []ConnectHook
+	connected bool
+}
+
+func NewAMQPConnection(url string) *AMQPConnection {
+	return &AMQPConnection{url: url, minDelay: time.Second, maxDelay: 30 * time.Second}
+}
+
+// OnConnect registra un hook; debe llamarse antes de Run
+func (a *AMQPConnection) OnConnect(h ConnectHook) {
+	a.mu.Lock()
+	a.hooks = append(a.hooks, h)
+	a.mu.Unlock()
+}
+
+// Connected indica si hay una conexión abierta (para /health)
+func (a *AMQPConnection) Connected() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.connected
+}
+
+// Run conecta y reconecta hasta que se cancele ctx. Al cancelar espera a que
+// los hooks terminen (los consumers drenan lo que tienen en curso) antes de
+// cerrar la conexión y devolver.
+func (a *AMQPConnection) Run(ctx context.Context) {
+	delay := a.minDelay
+	for attempt := 1; ; attempt++ {
+		conn, err := amqp.Dial(a.url)
+		if err != nil {
+			log.Printf("[rabbit] connection attempt %d failed (%v), retrying in %v...", attempt, err, delay)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay *= 2
+			if delay > a.maxDelay {
+				delay = a.maxDelay
+			}
+			continue
+		}
+		log.Printf("[rabbit] connected after %d attempts", attempt)
+		attempt, delay = 0, a.minDelay
+
+		a.serve(ctx, conn)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// serve corre los hooks sobre conn y bloquea hasta que la conexión se cierra
+// o se cancela ctx
+func (a *AMQPConnection) serve(ctx context.Context, conn *amqp.Connection) {
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	a.mu.Lock()
+	a.connected = true
+	hooks := append([]ConnectHook(nil), a.hooks...)
+	a.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, h := range hooks {
+		wg.Add(1)
+		go func(h ConnectHook) {
+			defer wg.Done()
+			if err := h(connCtx, conn); err != nil && connCtx.Err() == nil {
+				log.Printf("[rabbit] hook error (%v), reconnecting", err)
+				conn.Close()
+			}
+		}(h)
+	}
+
+	select {
+	case err := <-closed:
+		log.Printf("[rabbit] WARN: connection lost: %v", err)
+	case <-ctx.Done():
+	}
+	a.mu.Lock()
+	a.connected = false
+	a.mu.Unlock()
+
+	cancel()
+	wg.Wait()
+	if !conn.IsClosed() {
+		conn.Close()
+	}
+}
@@ -0,0 +1,80 @@
+package consumers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// DeadLetterer guarda los eventos que no se pudieron procesar
+type DeadLetterer interface {
+	DeadLetter(ctx context.Context, m amqp.Delivery, cause error) error
+}
+
+// queueDeadLetter copia las entregas a una cola durable (por el exchange por
+// defecto) en un canal propio con confirmaciones
+type queueDeadLetter struct {
+	mu    sync.Mutex
+	ch    *amqp.Channel
+	queue string
+}
+
+func newQueueDeadLetter(conn *amqp.Connection, queue string) (*queueDeadLetter, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("dlq channel: %w", err)
+	}
+	if _, err := ch.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("dlq declare: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("dlq confirm: %w", err)
+	}
+	return &queueDeadLetter{ch: ch, queue: queue}, nil
+}
+
+// DeadLetter publica la entrega con el último error y espera la confirmación
+func (d *queueDeadLetter) DeadLetter(ctx context.Context, m amqp.Delivery, cause error) error {
+	headers := amqp.Table{}
+	for k, v := range m.Headers {
+		headers[k] = v
+	}
+	headers["x-last-error"] = cause.Error()
+	if _, ok := headers["x-routing-key"]; !ok {
+		headers["x-routing-key"] = m.RoutingKey
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dc, err := d.ch.PublishWithDeferredConfirmWithContext(ctx, "", d.queue, true, false, amqp.Publishing{
+		ContentType:  m.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    m.MessageId,
+		Timestamp:    m.Timestamp,
+		Headers:      headers,
+		Body:         m.Body,
+	})
+	if err != nil {
+		return err
+	}
+	acked, err := dc.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return errors.New("broker nacked the message")
+	}
+	return nil
+}
+
+func (d *queueDeadLetter) Close() error {
+	return d.ch.Close()
+}
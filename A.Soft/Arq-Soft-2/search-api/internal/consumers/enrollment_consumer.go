@@ -0,0 +1,170 @@
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/sporthub/search-api/internal/repository"
+	"github.com/sporthub/search-api/internal/services"
+)
+
+// EnrollmentEvent es el evento que publica activities-api al inscribir o cancelar
+type EnrollmentEvent struct {
+	Op         string `json:"op"` // "enroll" | "cancel"
+	ID         uint64 `json:"id"`
+	OrgID      string `json:"orgId"`
+	SessionID  uint64 `json:"sessionId"`
+	ActivityID uint64 `json:"activityId"`
+	UserID     string `json:"userId"`
+}
+
+// enrollmentConsumerTag identifica al consumer de recomendaciones para poder cancelarlo
+const enrollmentConsumerTag = "search-api-recommendations"
+
+// enrollmentPrefetch son los eventos de inscripción sin ack en vuelo
+const enrollmentPrefetch = 10
+
+// EnrollmentConsumer alimenta las afinidades de recomendaciones con los
+// eventos enrollment.created / enrollment.cancelled. Procesa de a un evento,
+// en orden, y hace ack recién cuando quedó guardado.
+type EnrollmentConsumer struct {
+	svc *services.RecommendationService
+	// esperas entre reintentos de un evento que falla
+	retryDelays []time.Duration
+}
+
+func NewEnrollmentConsumer(svc *services.RecommendationService) *EnrollmentConsumer {
+	return &EnrollmentConsumer{svc: svc, retryDelays: defaultRetryDelays}
+}
+
+// SetRetryDelays cambia las esperas entre reintentos de un evento
+func (c *EnrollmentConsumer) SetRetryDelays(delays ...time.Duration) {
+	c.retryDelays = delays
+}
+
+// Start consume la cola (ver Serve). Los eventos que agotan los reintentos
+// se copian a <queue>.dlq.
+func (c *EnrollmentConsumer) Start(ctx context.Context, conn *amqp.Connection, queue, exchange, routingKey string) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("rabbit channel: %w", err)
+	}
+	defer ch.Close()
+
+	if err := ch.Qos(enrollmentPrefetch, 0, false); err != nil {
+		return fmt.Errorf("qos: %w", err)
+	}
+	dlq, err := newQueueDeadLetter(conn, queue+".dlq")
+	if err != nil {
+		return err
+	}
+	defer dlq.Close()
+	msgs, err := declareAndConsume(ch, queue, exchange, routingKey, enrollmentConsumerTag, false)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[recommendations] listening for enrollment events (queue=%s, routingKey=%s)", queue, routingKey)
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := ch.Cancel(enrollmentConsumerTag, false); err != nil {
+				log.Printf("[recommendations] WARN: cancel consumer: %v", err)
+			}
+		case <-stopped:
+		}
+	}()
+
+	c.Serve(ctx, msgs, dlq)
+	if ctx.Err() == nil {
+		return errChannelClosed
+	}
+	return nil
+}
+
+// Serve procesa las entregas de msgs en orden hasta que msgs se cierra. Un
+// evento que falla se reintenta con las esperas de retryDelays y después va
+// a la dlq; cancelar ctx corta las esperas y devuelve ese evento y los
+// siguientes a la cola.
+func (c *EnrollmentConsumer) Serve(ctx context.Context, msgs <-chan amqp.Delivery, dl DeadLetterer) {
+	workCtx := context.WithoutCancel(ctx)
+	requeue := false
+	for m := range msgs {
+		if requeue {
+			_ = m.Nack(false, true)
+			continue
+		}
+		requeue = !c.process(ctx, workCtx, m, dl)
+	}
+}
+
+// process maneja una entrega y hace el ack; devuelve false si se cortó un
+// reintento porque se canceló stop (la entrega vuelve a la cola)
+func (c *EnrollmentConsumer) process(stop, ctx context.Context, m amqp.Delivery, dl DeadLetterer) bool {
+	var ev EnrollmentEvent
+	if err := json.Unmarshal(m.Body, &ev); err != nil {
+		log.Printf("[recommendations] ERROR: invalid event JSON: %v", err)
+		c.deadLetter(ctx, m, dl, err)
+		return true
+	}
+	err := c.handle(ctx, ev)
+	for i := 0; err != nil && i < len(c.retryDelays); i++ {
+		log.Printf("[recommendations] WARN: %s enrollment=%d failed (%v), retry %d in %v", ev.Op, ev.ID, err, i+1, c.retryDelays[i])
+		t := time.NewTimer(c.retryDelays[i])
+		select {
+		case <-stop.Done():
+			t.Stop()
+			_ = m.Nack(false, true)
+			return false
+		case <-t.C:
+		}
+		err = c.handle(ctx, ev)
+	}
+	if err != nil {
+		log.Printf("[recommendations] ERROR: %s enrollment=%d failed after %d retries: %v", ev.Op, ev.ID, len(c.retryDelays), err)
+		c.deadLetter(ctx, m, dl, err)
+		return true
+	}
+	_ = m.Ack(false)
+	return true
+}
+
+// deadLetter copia la entrega a la dlq y la saca de la cola. Si no se puede
+// copiar se re-encola para no perderla.
+func (c *EnrollmentConsumer) deadLetter(ctx context.Context, m amqp.Delivery, dl DeadLetterer, cause error) {
+	if err := dl.DeadLetter(ctx, m, cause); err != nil {
+		log.Printf("[recommendations] WARN: could not dead-letter (%v), requeueing", err)
+		_ = m.Nack(false, true)
+		return
+	}
+	_ = m.Ack(false)
+}
+
+func (c *EnrollmentConsumer) handle(ctx context.Context, ev EnrollmentEvent) error {
+	if ev.ID == 0 || ev.UserID == "" || ev.ActivityID == 0 {
+		log.Printf("[recommendations] WARN: event %s without id/userId/activityId, skipping", ev.Op)
+		return nil
+	}
+	enrollmentID := fmt.Sprintf("%d", ev.ID)
+	activityID := fmt.Sprintf("%d", ev.ActivityID)
+	// los atributos de la actividad se piden a activities-api en su organización
+	ctx = repository.WithTenant(ctx, ev.OrgID)
+
+	switch ev.Op {
+	case "enroll":
+		return c.svc.RecordEnrollment(ctx, enrollmentID, ev.UserID, activityID)
+	case "cancel":
+		return c.svc.RecordCancellation(ctx, enrollmentID, ev.UserID, activityID)
+	default:
+		log.Printf("[recommendations] WARN: unknown op %q, skipping", ev.Op)
+		return nil
+	}
+}
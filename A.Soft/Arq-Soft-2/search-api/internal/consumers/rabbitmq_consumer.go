@@ -3,11 +3,14 @@ package consumers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"hash/fnv"
 	"log"
-	"net/http"
+	"sync"
+	"time"
 
+	"github.com/sporthub/search-api/internal/clients"
 	"github.com/sporthub/search-api/internal/domain"
 	"github.com/sporthub/search-api/internal/repository"
 
@@ -17,22 +20,58 @@ import (
 type Event struct {
 	Op         string `json:"op"` // "create" | "update" | "delete"
 	ActivityID string `json:"activityId"`
+	OrgID      string `json:"orgId"` // vacío en eventos anteriores a las organizaciones
 	SessionID  string `json:"sessionId"`
 	Timestamp  string `json:"timestamp"`
 }
 
+// docMatcher evalúa los documentos indexados contra las búsquedas guardadas
+type docMatcher interface {
+	Evaluate(doc domain.SearchDoc)
+}
+
+// errChannelClosed indica que el broker cerró el canal sin que se cancelara
+// ctx; el AMQPConnection reconecta y vuelve a llamar a Start
+var errChannelClosed = errors.New("rabbit consumer channel closed")
+
+// defaultRetryDelays son las esperas entre reintentos de un evento. Se
+// reintenta en el mismo worker (no se re-encola) para no adelantar los
+// eventos posteriores de la misma actividad.
+var defaultRetryDelays = []time.Duration{500 * time.Millisecond, 2 * time.Second, 5 * time.Second}
+
+// consumerTag identifica al consumer de search_sync para poder cancelarlo
+const consumerTag = "search-api-sync"
+
 // Consumer mantiene conexión y dependencias
 type Consumer struct {
-	repo     *repository.SolrRepo
-	cacheL   *repository.LocalCache
-	cacheD   *repository.DistCache
-	activity string // base URL de activities-api
+	repo       repository.SearchBackend
+	cacheL     *repository.LocalCache
+	cacheD     *repository.DistCache
+	activities *clients.ActivitiesClient
+	matcher    docMatcher
+	workers    int
+	prefetch   int
+	// esperas entre reintentos de un evento que falla
+	retryDelays []time.Duration
 }
 
-func NewConsumer(solr *repository.SolrRepo, local *repository.LocalCache, dist *repository.DistCache, activitiesAPI string) *Consumer {
-	return &Consumer{repo: solr, cacheL: local, cacheD: dist, activity: activitiesAPI}
+// NewConsumer crea el consumer con workers goroutines procesando en paralelo
+// y prefetch mensajes sin ack como máximo en vuelo.
+func NewConsumer(backend repository.SearchBackend, local *repository.LocalCache, dist *repository.DistCache, activities *clients.ActivitiesClient, matcher docMatcher, workers, prefetch int) *Consumer {
+	if workers < 1 {
+		workers = 1
+	}
+	if prefetch < workers {
+		prefetch = workers
+	}
+	return &Consumer{repo: backend, cacheL: local, cacheD: dist, activities: activities, matcher: matcher, workers: workers, prefetch: prefetch,
+		retryDelays: defaultRetryDelays}
 }
 
+// Start consume la cola con un pool de workers (ver Serve). Los eventos que
+// agotan los reintentos se copian a <queue>.dlq. Cuando se cancela ctx deja
+// de recibir mensajes, espera a que los workers terminen lo que tienen en
+// curso (ack incluido) y recién ahí devuelve.
 func (c *Consumer) Start(ctx context.Context, conn *amqp.Connection, queue, exchange, routingKey string) error {
 	ch, err := conn.Channel()
 	if err != nil {
@@ -40,99 +79,210 @@ func (c *Consumer) Start(ctx context.Context, conn *amqp.Connection, queue, exch
 	}
 	defer ch.Close()
 
-	err = ch.ExchangeDeclare(exchange, "topic", true, false, false, false, nil)
-	if err != nil {
-		return fmt.Errorf("exchange declare: %w", err)
+	if err := ch.Qos(c.prefetch, 0, false); err != nil {
+		return fmt.Errorf("qos: %w", err)
 	}
-	q, err := ch.QueueDeclare(queue, true, false, false, false, nil)
+	dlq, err := newQueueDeadLetter(conn, queue+".dlq")
 	if err != nil {
-		return fmt.Errorf("queue declare: %w", err)
+		return err
 	}
-	err = ch.QueueBind(q.Name, routingKey, exchange, false, nil)
+	defer dlq.Close()
+	msgs, err := declareAndConsume(ch, queue, exchange, routingKey, consumerTag, false)
 	if err != nil {
-		return fmt.Errorf("queue bind: %w", err)
+		return err
 	}
 
-	msgs, err := ch.Consume(q.Name, "", true, false, false, false, nil)
-	if err != nil {
-		return fmt.Errorf("consume: %w", err)
-	}
+	log.Printf("[consumer] listening for activity events (queue=%s, routingKey=%s, workers=%d, prefetch=%d)",
+		queue, routingKey, c.workers, c.prefetch)
 
-	log.Printf("[consumer] listening for activity events (queue=%s, routingKey=%s)", queue, routingKey)
+	stopped := make(chan struct{})
+	defer close(stopped)
 	go func() {
-		for m := range msgs {
-			var ev Event
-			if err := json.Unmarshal(m.Body, &ev); err != nil {
-				log.Printf("[consumer] ERROR: invalid event JSON: %v", err)
-				log.Printf("[consumer] Raw message: %s", string(m.Body))
-				continue
+		select {
+		case <-ctx.Done():
+			// deja de recibir: el broker cierra msgs y se re-encolan los no entregados
+			if err := ch.Cancel(consumerTag, false); err != nil {
+				log.Printf("[consumer] WARN: cancel consumer: %v", err)
 			}
-			log.Printf("[consumer] Received event: op=%s, activityId=%s, sessionId=%s", ev.Op, ev.ActivityID, ev.SessionID)
-			c.handle(ctx, ev)
+		case <-stopped:
 		}
 	}()
-	<-ctx.Done()
+
+	c.Serve(ctx, msgs, dlq)
+	if ctx.Err() == nil {
+		return errChannelClosed
+	}
+	log.Printf("[consumer] stopped, in-flight events drained")
 	return nil
 }
 
-func (c *Consumer) handle(ctx context.Context, ev Event) {
+// Serve reparte las entregas de msgs entre los workers y devuelve cuando msgs
+// se cierra y los workers terminaron. Los eventos de una misma actividad van
+// siempre al mismo worker y ahí se reintentan, así se mantiene su orden.
+// Cancelar ctx solo corta las esperas entre reintentos: ese evento y los que
+// le siguen en su worker se devuelven a la cola en orden.
+func (c *Consumer) Serve(ctx context.Context, msgs <-chan amqp.Delivery, dl DeadLetterer) {
+	// Los eventos en curso se terminan aunque se cancele ctx
+	workCtx := context.WithoutCancel(ctx)
+	jobs := make([]chan amqp.Delivery, c.workers)
+	var wg sync.WaitGroup
+	for i := range jobs {
+		jobs[i] = make(chan amqp.Delivery, c.prefetch)
+		wg.Add(1)
+		go func(in <-chan amqp.Delivery) {
+			defer wg.Done()
+			requeue := false
+			for m := range in {
+				if requeue {
+					_ = m.Nack(false, true)
+					continue
+				}
+				requeue = !c.process(ctx, workCtx, m, dl)
+			}
+		}(jobs[i])
+	}
+
+	for m := range msgs {
+		jobs[c.workerFor(m.Body)] <- m
+	}
+	for _, in := range jobs {
+		close(in)
+	}
+	wg.Wait()
+}
+
+// SetRetryDelays cambia las esperas entre reintentos de un evento
+func (c *Consumer) SetRetryDelays(delays ...time.Duration) {
+	c.retryDelays = delays
+}
+
+// workerFor elige el worker según el activityId del evento
+func (c *Consumer) workerFor(body []byte) int {
+	var ev Event
+	_ = json.Unmarshal(body, &ev)
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(ev.ActivityID))
+	return int(h.Sum32() % uint32(c.workers))
+}
+
+// process maneja una entrega y hace el ack. Si falla la reintenta en el
+// mismo worker con las esperas de retryDelays y, agotados los reintentos, la
+// pasa a la dlq. Devuelve false si se cortó un reintento porque se canceló
+// stop: la entrega vuelve a la cola.
+func (c *Consumer) process(stop, ctx context.Context, m amqp.Delivery, dl DeadLetterer) bool {
+	var ev Event
+	if err := json.Unmarshal(m.Body, &ev); err != nil {
+		log.Printf("[consumer] ERROR: invalid event JSON: %v", err)
+		log.Printf("[consumer] Raw message: %s", string(m.Body))
+		c.deadLetter(ctx, m, dl, err)
+		return true
+	}
+	log.Printf("[consumer] Received event: op=%s, activityId=%s, sessionId=%s", ev.Op, ev.ActivityID, ev.SessionID)
+	err := c.handle(ctx, ev)
+	for i := 0; err != nil && i < len(c.retryDelays); i++ {
+		log.Printf("[consumer] WARN: event for activity %s failed (%v), retry %d in %v", ev.ActivityID, err, i+1, c.retryDelays[i])
+		t := time.NewTimer(c.retryDelays[i])
+		select {
+		case <-stop.Done():
+			t.Stop()
+			log.Printf("[consumer] shutting down, requeueing event for activity %s", ev.ActivityID)
+			_ = m.Nack(false, true)
+			return false
+		case <-t.C:
+		}
+		err = c.handle(ctx, ev)
+	}
+	if err != nil {
+		log.Printf("[consumer] ERROR: event for activity %s failed after %d retries: %v", ev.ActivityID, len(c.retryDelays), err)
+		c.deadLetter(ctx, m, dl, err)
+		return true
+	}
+	_ = m.Ack(false)
+	return true
+}
+
+// deadLetter copia la entrega a la dlq y la saca de la cola. Si no se puede
+// copiar se re-encola para no perderla.
+func (c *Consumer) deadLetter(ctx context.Context, m amqp.Delivery, dl DeadLetterer, cause error) {
+	if err := dl.DeadLetter(ctx, m, cause); err != nil {
+		log.Printf("[consumer] WARN: could not dead-letter (%v), requeueing", err)
+		_ = m.Nack(false, true)
+		return
+	}
+	_ = m.Ack(false)
+}
+
+func (c *Consumer) handle(ctx context.Context, ev Event) error {
 	log.Printf("[consumer] Processing event: op=%s, activityId=%s, sessionId=%s", ev.Op, ev.ActivityID, ev.SessionID)
 	
 	// DISEÑO: Solo indexamos actividades en Solr, no sesiones individuales.
-	// Las sesiones se indexan como parte de la actividad (usando la primera sesión disponible).
-	// Por lo tanto, ignoramos eventos de sesiones individuales.
+	// Las sesiones se indexan como parte de la actividad (usando la primera sesión disponible),
+	// así que un evento activity.session.* (alta, cambio o baja) reindexa su actividad.
 	if ev.SessionID != "" && ev.SessionID != "0" {
-		log.Printf("[consumer] INFO: ignoring session event (sessionId=%s). Only activity events are indexed in Solr.", ev.SessionID)
-		return
+		log.Printf("[consumer] session %s %sd, reindexing activity %s", ev.SessionID, ev.Op, ev.ActivityID)
+		ev.Op = "update"
 	}
 	
 	// Validar que tenemos activityId
 	if ev.ActivityID == "" {
 		log.Printf("[consumer] WARN: event %s has empty activityId, skipping", ev.Op)
-		return
+		return nil
 	}
 	
 	switch ev.Op {
 	case "delete":
-		log.Printf("[consumer] Deleting activity %s from Solr", ev.ActivityID)
-		_ = c.repo.DeleteByID(ctx, ev.ActivityID)
+		log.Printf("[consumer] Deleting activity %s from search index", ev.ActivityID)
+		if err := c.repo.DeleteByID(ctx, ev.ActivityID); err != nil {
+			log.Printf("[consumer] ERROR: delete error for activity %s: %v", ev.ActivityID, err)
+			return err
+		}
 		c.cacheL.Delete(ev.ActivityID)
 		c.cacheD.Delete(ev.ActivityID)
 		log.Printf("[consumer] SUCCESS: deleted activity %s", ev.ActivityID)
 	default: // create/update
-		log.Printf("[consumer] Fetching search-doc for activity %s from %s/activities/%s/search-doc", ev.ActivityID, c.activity, ev.ActivityID)
-		doc, err := c.fetchActivityDoc(ev.ActivityID)
+		log.Printf("[consumer] Fetching search-doc for activity %s from activities-api", ev.ActivityID)
+		doc, err := c.activities.SearchDoc(repository.WithTenant(ctx, ev.OrgID), ev.ActivityID)
 		if err != nil {
 			log.Printf("[consumer] ERROR: fetch error for activity %s: %v", ev.ActivityID, err)
-			return
+			return err
 		}
 		log.Printf("[consumer] Fetched doc: id=%s, activityId=%s, name=%s", doc.ID, doc.ActivityID, doc.Name)
-		log.Printf("[consumer] Indexing activity %s in Solr", ev.ActivityID)
+		if doc.OrgID == "" {
+			doc.OrgID = ev.OrgID
+		}
+		log.Printf("[consumer] Indexing activity %s", ev.ActivityID)
 		log.Printf("[consumer] Document to index: id=%s, activityId=%s, name=%s, sport=%s, site=%s, start_dt=%s", 
 			doc.ID, doc.ActivityID, doc.Name, doc.Sport, doc.Site, doc.StartAt)
 		if err := c.repo.Upsert(ctx, *doc); err != nil {
 			log.Printf("[consumer] ERROR: solr upsert error for activity %s: %v", ev.ActivityID, err)
-			return
+			return err
 		}
 		log.Printf("[consumer] SUCCESS: indexed activity %s (name=%s)", ev.ActivityID, doc.Name)
+		if c.matcher != nil {
+			c.matcher.Evaluate(*doc)
+		}
 	}
+	return nil
 }
 
-// fetchActivityDoc obtiene el search-doc de una actividad
-func (c *Consumer) fetchActivityDoc(activityID string) (*domain.SearchDoc, error) {
-	url := fmt.Sprintf("%s/activities/%s/search-doc", c.activity, activityID)
-	resp, err := http.Get(url)
+// declareAndConsume declara exchange, cola y binding y empieza a consumir la cola
+func declareAndConsume(ch *amqp.Channel, queue, exchange, routingKey, tag string, autoAck bool) (<-chan amqp.Delivery, error) {
+	err := ch.ExchangeDeclare(exchange, "topic", true, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("exchange declare: %w", err)
+	}
+	q, err := ch.QueueDeclare(queue, true, false, false, false, nil)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("queue declare: %w", err)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("activities-api: %s", string(b))
+	err = ch.QueueBind(q.Name, routingKey, exchange, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("queue bind: %w", err)
 	}
-	var doc domain.SearchDoc
-	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
-		return nil, err
+
+	msgs, err := ch.Consume(q.Name, tag, autoAck, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("consume: %w", err)
 	}
-	return &doc, nil
+	return msgs, nil
 }
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/search-api/internal/services"
+)
+
+type AnalyticsHandler struct{ svc *services.AnalyticsService }
+
+func NewAnalyticsHandler(s *services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{svc: s}
+}
+
+type clickReq struct {
+	Query      string `json:"query"`
+	ActivityID string `json:"activityId" binding:"required"`
+	Position   int    `json:"position"`
+}
+
+// POST /search/click - el frontend reporta un click sobre un resultado
+func (h *AnalyticsHandler) Click(c *gin.Context) {
+	var req clickReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.svc.Click(c.GetString("orgId"), req.Query, req.ActivityID, req.Position)
+	c.Status(http.StatusNoContent)
+}
+
+// GET /search/trending - público, solo expone la query y la cantidad de
+// búsquedas de la organización del request
+func (h *AnalyticsHandler) Trending(c *gin.Context) {
+	limit := queryInt(c, "limit", 10)
+	out := []gin.H{}
+	for _, st := range h.svc.Trending(c.GetString("orgId"), limit) {
+		out = append(out, gin.H{"query": st.Query, "searches": st.Searches})
+	}
+	c.JSON(http.StatusOK, gin.H{"trending": out})
+}
+
+// GET /admin/search/top
+func (h *AnalyticsHandler) TopQueries(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"queries": h.svc.TopQueries(c.GetString("orgId"), since(c), queryInt(c, "limit", 20))})
+}
+
+// GET /admin/search/zero-results
+func (h *AnalyticsHandler) ZeroResults(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"queries": h.svc.ZeroResultQueries(c.GetString("orgId"), since(c), queryInt(c, "limit", 20))})
+}
+
+// GET /admin/search/click-through
+func (h *AnalyticsHandler) ClickThrough(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"queries": h.svc.ClickThrough(c.GetString("orgId"), since(c), queryInt(c, "limit", 20))})
+}
+
+// since lee ?hours=N (por defecto las últimas 24h)
+func since(c *gin.Context) time.Time {
+	hours := queryInt(c, "hours", 24)
+	return time.Now().Add(-time.Duration(hours) * time.Hour)
+}
+
+func queryInt(c *gin.Context, name string, def int) int {
+	n, err := strconv.Atoi(c.Query(name))
+	if err != nil || n <= 0 {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/search-api/internal/services"
+)
+
+type RecommendationsHandler struct{ svc *services.RecommendationService }
+
+func NewRecommendationsHandler(s *services.RecommendationService) *RecommendationsHandler {
+	return &RecommendationsHandler{svc: s}
+}
+
+// GET /recommendations/me - requiere JWT
+func (h *RecommendationsHandler) Me(c *gin.Context) {
+	userID := currentUser(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user id in token"})
+		return
+	}
+	limit := queryInt(c, "limit", 10)
+	if limit > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit cannot exceed 50"})
+		return
+	}
+
+	recs, strategy, err := h.svc.ForUser(c.Request.Context(), userID, limit)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"strategy": strategy, "recommendations": recs})
+}
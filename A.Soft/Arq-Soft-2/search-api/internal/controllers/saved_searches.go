@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/search-api/internal/domain"
+	"github.com/sporthub/search-api/internal/services"
+)
+
+type SavedSearchesHandler struct{ svc *services.SavedSearchService }
+
+func NewSavedSearchesHandler(s *services.SavedSearchService) *SavedSearchesHandler {
+	return &SavedSearchesHandler{svc: s}
+}
+
+type savedSearchReq struct {
+	Name     string  `json:"name" binding:"required,max=100"`
+	Query    string  `json:"query"`
+	Sport    string  `json:"sport"`
+	Site     string  `json:"site"`
+	MaxPrice float64 `json:"maxPrice" binding:"min=0"`
+}
+
+func (r savedSearchReq) toDomain() domain.SavedSearch {
+	return domain.SavedSearch{Name: r.Name, Query: r.Query, Sport: r.Sport, Site: r.Site, MaxPrice: r.MaxPrice}
+}
+
+// RegisterRoutes registra el CRUD de /saved-searches sobre un grupo autenticado
+func (h *SavedSearchesHandler) RegisterRoutes(g *gin.RouterGroup) {
+	g.POST("", h.Create)
+	g.GET("", h.List)
+	g.GET("/:id", h.Get)
+	g.PUT("/:id", h.Update)
+	g.DELETE("/:id", h.Delete)
+}
+
+func (h *SavedSearchesHandler) Create(c *gin.Context) {
+	var req savedSearchReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Query == "" && req.Sport == "" && req.Site == "" && req.MaxPrice == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one search criteria is required"})
+		return
+	}
+	in := req.toDomain()
+	// la alerta solo salta con actividades de la organización del request
+	in.OrgID = c.GetString("orgId")
+	ss, err := h.svc.Create(currentUser(c), in)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, ss)
+}
+
+func (h *SavedSearchesHandler) List(c *gin.Context) {
+	list, err := h.svc.List(currentUser(c))
+	if err != nil {
+		savedSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"savedSearches": list})
+}
+
+func (h *SavedSearchesHandler) Get(c *gin.Context) {
+	ss, err := h.svc.Get(currentUser(c), c.Param("id"))
+	if err != nil {
+		savedSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ss)
+}
+
+func (h *SavedSearchesHandler) Update(c *gin.Context) {
+	var req savedSearchReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ss, err := h.svc.Update(currentUser(c), c.Param("id"), req.toDomain())
+	if err != nil {
+		savedSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ss)
+}
+
+func (h *SavedSearchesHandler) Delete(c *gin.Context) {
+	if err := h.svc.Delete(currentUser(c), c.Param("id")); err != nil {
+		savedSearchError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func savedSearchError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrSavedSearchNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "saved search not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// currentUser devuelve el userId del JWT como string ("" si no hay)
+func currentUser(c *gin.Context) string {
+	if v, ok := c.Get("userId"); ok {
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
@@ -40,6 +40,27 @@ func (h *Handler) Search(c *gin.Context) {
 	c.JSON(http.StatusOK, res)
 }
 
+// GET /search/related/:activityId - actividades similares a la indicada
+func (h *Handler) Related(c *gin.Context) {
+	activityID := c.Param("activityId")
+	if _, err := strconv.ParseUint(activityID, 10, 64); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid activity id format"})
+		return
+	}
+	size := atoi(c.DefaultQuery("size", "5"))
+	if size > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size cannot exceed 50"})
+		return
+	}
+
+	res, err := h.svc.Related(c.Request.Context(), activityID, c.Query("sport"), c.Query("site"), c.Query("date"), size)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
 func atoi(s string) int {
 	i, err := strconv.Atoi(s)
 	if err != nil || i <= 0 {
@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+// Niveles de caché desde los que se resolvió una búsqueda
+const (
+	CacheTierLocal       = "local"
+	CacheTierDistributed = "distributed"
+	CacheTierSolr        = "solr"
+)
+
+// SearchEvent registra una búsqueda ejecutada contra /search
+type SearchEvent struct {
+	OrgID     string    `json:"org_id"`
+	Query     string    `json:"query"`
+	Sport     string    `json:"sport,omitempty"`
+	Site      string    `json:"site,omitempty"`
+	Date      string    `json:"date,omitempty"`
+	Sort      string    `json:"sort,omitempty"`
+	Page      int       `json:"page"`
+	Size      int       `json:"size"`
+	Results   int       `json:"results"`
+	LatencyMs int64     `json:"latency_ms"`
+	CacheTier string    `json:"cache_tier"`
+	Failed    bool      `json:"failed,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// SearchClick registra un click reportado por el frontend sobre un resultado
+type SearchClick struct {
+	OrgID      string    `json:"org_id"`
+	Query      string    `json:"query"`
+	ActivityID string    `json:"activity_id"`
+	Position   int       `json:"position"`
+	At         time.Time `json:"at"`
+}
+
+// QueryStat agrega las búsquedas de una misma query normalizada
+type QueryStat struct {
+	Query        string    `json:"query"`
+	Searches     int       `json:"searches"`
+	ZeroResults  int       `json:"zero_results"`
+	Clicks       int       `json:"clicks"`
+	ClickThrough float64   `json:"click_through"`
+	AvgLatencyMs float64   `json:"avg_latency_ms"`
+	LastSeen     time.Time `json:"last_seen"`
+}
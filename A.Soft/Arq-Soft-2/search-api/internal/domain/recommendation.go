@@ -0,0 +1,93 @@
+package domain
+
+// Session es la sesión de una actividad tal como la expone activities-api
+type Session struct {
+	ID         uint64 `json:"id"`
+	ActivityID uint64 `json:"activityId"`
+	Fecha      string `json:"fecha"`  // YYYY-MM-DD
+	Inicio     string `json:"inicio"` // HH:mm
+	Fin        string `json:"fin"`    // HH:mm
+	Capacidad  int    `json:"capacidad"`
+}
+
+// ActivityAttrs son los atributos de una actividad que alimentan las afinidades
+type ActivityAttrs struct {
+	Sport      string `bson:"sport"      json:"sport"`
+	Site       string `bson:"site"       json:"site"`
+	Instructor string `bson:"instructor" json:"instructor"`
+}
+
+// Enrollment es la inscripción tal como la expone activities-api
+type Enrollment struct {
+	ID         uint64 `json:"id"`
+	OrgID      string `json:"orgId"`
+	ActivityID uint64 `json:"activityId"`
+	SessionID  uint64 `json:"sessionId"`
+	UserID     string `json:"userId"`
+	Estado     string `json:"estado"` // pendiente|confirmada|cancelada
+}
+
+// TrackedEnrollment es una inscripción registrada para las afinidades. Las
+// afinidades y la popularidad se calculan con las activas; las canceladas
+// quedan para que una entrega repetida o atrasada del alta no vuelva a sumar.
+type TrackedEnrollment struct {
+	ID         string        `bson:"_id"        json:"id"` // id de la inscripción en activities-api
+	UserID     string        `bson:"userId"     json:"userId"`
+	ActivityID string        `bson:"activityId" json:"activityId"`
+	OrgID      string        `bson:"orgId"      json:"orgId"`
+	Attrs      ActivityAttrs `bson:"attrs"      json:"attrs"`
+	Active     bool          `bson:"active"     json:"active"`
+}
+
+// UserAffinity acumula, por usuario, el peso de cada deporte, sede e
+// instructor según sus inscripciones activas
+type UserAffinity struct {
+	UserID      string             `json:"userId"`
+	Sports      map[string]float64 `json:"sports"`
+	Sites       map[string]float64 `json:"sites"`
+	Instructors map[string]float64 `json:"instructors"`
+	Enrolled    map[string]int     `json:"enrolled"` // activityId -> inscripciones activas
+}
+
+// NewUserAffinity devuelve las afinidades vacías del usuario
+func NewUserAffinity(userID string) UserAffinity {
+	return UserAffinity{
+		UserID:      userID,
+		Sports:      map[string]float64{},
+		Sites:       map[string]float64{},
+		Instructors: map[string]float64{},
+		Enrolled:    map[string]int{},
+	}
+}
+
+// Add suma una inscripción activa a las afinidades
+func (a *UserAffinity) Add(e TrackedEnrollment) {
+	addWeight(a.Sports, e.Attrs.Sport)
+	addWeight(a.Sites, e.Attrs.Site)
+	addWeight(a.Instructors, e.Attrs.Instructor)
+	a.Enrolled[e.ActivityID]++
+}
+
+func addWeight(m map[string]float64, key string) {
+	if key != "" {
+		m[key]++
+	}
+}
+
+// Empty indica que el usuario todavía no tiene inscripciones activas
+func (a UserAffinity) Empty() bool {
+	return len(a.Enrolled) == 0
+}
+
+// Recommendation es una actividad recomendada con su próxima sesión
+type Recommendation struct {
+	Activity    SearchDoc `json:"activity"`
+	Score       float64   `json:"score"`
+	NextSession Session   `json:"nextSession"`
+}
+
+// Estrategias usadas para recomendar
+const (
+	RecommendationAffinity   = "affinity"
+	RecommendationPopularity = "popularity"
+)
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// SavedSearch es una búsqueda guardada por un usuario para recibir alertas
+// cuando se indexa una actividad que la cumple
+type SavedSearch struct {
+	ID        string    `bson:"_id"       json:"id"`
+	UserID    string    `bson:"userId"    json:"userId"`
+	OrgID     string    `bson:"orgId"     json:"orgId"` // organización donde se creó ("" = todas)
+	Name      string    `bson:"name"      json:"name"`
+	Query     string    `bson:"query"     json:"query"`
+	Sport     string    `bson:"sport"     json:"sport"`
+	Site      string    `bson:"site"      json:"site"`
+	MaxPrice  float64   `bson:"maxPrice"  json:"maxPrice"` // 0 = sin límite
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// SearchMatch es el evento search.match que se publica por cada usuario cuya
+// búsqueda guardada coincide con un documento recién indexado
+type SearchMatch struct {
+	UserID          string    `json:"userId"`
+	SavedSearchID   string    `json:"savedSearchId"`
+	SavedSearchName string    `json:"savedSearchName"`
+	ActivityID      string    `json:"activityId"`
+	ActivityName    string    `json:"activityName"`
+	Sport           string    `json:"sport"`
+	Site            string    `json:"site"`
+	Price           float64   `json:"price"`
+	Timestamp       time.Time `json:"timestamp"`
+}
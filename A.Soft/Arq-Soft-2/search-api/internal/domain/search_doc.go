@@ -3,6 +3,7 @@ package domain
 type SearchDoc struct {
 	ID         string   `json:"id"` // = session_id
 	ActivityID string   `json:"activity_id"`
+	OrgID      string   `json:"org_id"`
 	SessionID  string   `json:"session_id"`
 	Name       string   `json:"name"`
 	Sport      string   `json:"sport"`
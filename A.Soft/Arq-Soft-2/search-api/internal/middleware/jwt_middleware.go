@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sporthub/shared/authz"
+	"github.com/sporthub/shared/jwks"
+)
+
+// JWTAuth valida el token RS256 emitido por users-api (claves del JWKS,
+// iss y aud) y expone userId, role y los permisos globales del token en el
+// contexto
+func JWTAuth(keys *jwks.KeySet, issuer, audience string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := c.GetHeader("Authorization")
+		if !strings.HasPrefix(h, "Bearer ") {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
+			c.Abort()
+			return
+		}
+		tokenStr := strings.TrimPrefix(h, "Bearer ")
+		token, err := jwt.Parse(tokenStr, keys.Keyfunc,
+			jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+			jwt.WithIssuer(issuer),
+			jwt.WithAudience(audience),
+			jwt.WithExpirationRequired())
+		if err != nil || !token.Valid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			c.Abort()
+			return
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+			c.Abort()
+			return
+		}
+		// sub es el id numérico del usuario
+		if subF, ok := claims["sub"].(float64); ok {
+			c.Set("userId", uint64(subF))
+		}
+		if r, ok := claims["rol"].(string); ok {
+			c.Set("role", r)
+		}
+		// los reportes y el reindex son globales: no dependen del claim orgs
+		authz.SetPermissions(c, authz.TokenPermissions(claims))
+		c.Next()
+	}
+}
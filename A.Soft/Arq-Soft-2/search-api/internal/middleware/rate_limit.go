@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateWindow es la ventana fija del rate limit por IP
+const rateWindow = time.Minute
+
+type ipWindow struct {
+	start time.Time
+	n     int
+}
+
+// RateLimit deja pasar hasta perMinute requests por minuto de cada IP y
+// responde 429 con Retry-After al resto. Con perMinute <= 0 no limita.
+func RateLimit(perMinute int) gin.HandlerFunc {
+	if perMinute <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	var (
+		mu      sync.Mutex
+		windows = map[string]*ipWindow{}
+		swept   = time.Now()
+	)
+	return func(c *gin.Context) {
+		now := time.Now()
+		ip := c.ClientIP()
+
+		mu.Lock()
+		// las ventanas vencidas se limpian una vez por minuto, así el mapa
+		// no crece con cada IP que pasó alguna vez
+		if now.Sub(swept) >= rateWindow {
+			for k, w := range windows {
+				if now.Sub(w.start) >= rateWindow {
+					delete(windows, k)
+				}
+			}
+			swept = now
+		}
+		w, ok := windows[ip]
+		if !ok || now.Sub(w.start) >= rateWindow {
+			w = &ipWindow{start: now}
+			windows[ip] = w
+		}
+		w.n++
+		over := w.n > perMinute
+		retry := w.start.Add(rateWindow).Sub(now)
+		mu.Unlock()
+
+		if over {
+			c.Header("Retry-After", strconv.Itoa(int(retry.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many requests"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
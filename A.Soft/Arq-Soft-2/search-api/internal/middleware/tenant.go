@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/search-api/internal/repository"
+)
+
+// TenantHeader es el header con la organización (club) del request
+const TenantHeader = "X-Org-ID"
+
+var orgIDRe = regexp.MustCompile(`^[0-9]{1,20}$`)
+
+// Tenant resuelve la organización del request (header X-Org-ID o query
+// ?org=, si no defaultOrg) y la deja en el context del request, así las
+// búsquedas, el caché y las búsquedas guardadas quedan dentro de esa
+// organización. Los requests de defaultOrg ven además los documentos
+// indexados antes de las organizaciones (sin org_s).
+func Tenant(defaultOrg string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		org := c.GetHeader(TenantHeader)
+		if org == "" {
+			org = c.Query("org")
+		}
+		if org == "" {
+			org = defaultOrg
+		}
+		if !orgIDRe.MatchString(org) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid organization id"})
+			return
+		}
+		c.Set("orgId", org)
+		ctx := repository.WithTenant(c.Request.Context(), org)
+		if org == defaultOrg {
+			ctx = repository.WithLegacyDocs(ctx)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"sync"
+
+	"github.com/sporthub/search-api/internal/domain"
+)
+
+// AffinityStore mantiene en memoria las inscripciones de las que salen las
+// afinidades por usuario y la popularidad de cada actividad (se pierden al
+// reiniciar; con MONGO_URI se usa AffinityMongo)
+type AffinityStore struct {
+	mu          sync.RWMutex
+	enrollments map[string]domain.TrackedEnrollment // id de inscripción -> inscripción
+	activities  map[string]domain.ActivityAttrs
+	seeded      map[string]bool // usuario|organización ya sembrados
+}
+
+func NewAffinityStore() *AffinityStore {
+	return &AffinityStore{
+		enrollments: map[string]domain.TrackedEnrollment{},
+		activities:  map[string]domain.ActivityAttrs{},
+		seeded:      map[string]bool{},
+	}
+}
+
+// Attrs devuelve los atributos conocidos de una actividad
+func (s *AffinityStore) Attrs(activityID string) (domain.ActivityAttrs, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.activities[activityID]
+	return a, ok
+}
+
+// Enroll registra la inscripción como activa. Si ya estaba registrada (o
+// cancelada) no cambia nada, así las entregas repetidas no suman dos veces.
+func (s *AffinityStore) Enroll(e domain.TrackedEnrollment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.enrollments[e.ID]; ok {
+		return nil
+	}
+	e.Active = true
+	s.enrollments[e.ID] = e
+	s.activities[e.ActivityID] = e.Attrs
+	return nil
+}
+
+// Cancel marca la inscripción como cancelada. Si todavía no se conocía queda
+// registrada como cancelada, para que un alta que llegue después no sume.
+func (s *AffinityStore) Cancel(e domain.TrackedEnrollment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.enrollments[e.ID]; ok {
+		e = old
+	}
+	e.Active = false
+	s.enrollments[e.ID] = e
+	return nil
+}
+
+// Profile calcula las afinidades del usuario con sus inscripciones activas
+func (s *AffinityStore) Profile(userID string) (domain.UserAffinity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := domain.NewUserAffinity(userID)
+	for _, e := range s.enrollments {
+		if e.Active && e.UserID == userID {
+			out.Add(e)
+		}
+	}
+	return out, nil
+}
+
+// Popularity devuelve la cantidad de inscripciones activas por actividad
+func (s *AffinityStore) Popularity() (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := map[string]int{}
+	for _, e := range s.enrollments {
+		if e.Active {
+			out[e.ActivityID]++
+		}
+	}
+	return out, nil
+}
+
+// Seeded indica si ya se cargaron las inscripciones previas del usuario en
+// la organización
+func (s *AffinityStore) Seeded(userID, orgID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.seeded[userID+"|"+orgID], nil
+}
+
+func (s *AffinityStore) MarkSeeded(userID, orgID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seeded[userID+"|"+orgID] = true
+	return nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/sporthub/search-api/internal/domain"
+)
+
+// AffinityMongo guarda en MongoDB las inscripciones de las que salen las
+// afinidades y la popularidad, una por documento: cada alta o baja es una
+// sola escritura, así que reintentar un evento no lo aplica dos veces.
+type AffinityMongo struct {
+	enrollments *mongo.Collection
+	seeded      *mongo.Collection
+}
+
+// NewAffinityMongo crea el store y sus índices
+func NewAffinityMongo(db *mongo.Database) (*AffinityMongo, error) {
+	s := &AffinityMongo{
+		enrollments: db.Collection("reco_enrollments"),
+		seeded:      db.Collection("reco_seeded"),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	_, err := s.enrollments.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "active", Value: 1}}},
+		{Keys: bson.D{{Key: "activityId", Value: 1}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reco_enrollments indexes: %w", err)
+	}
+	return s, nil
+}
+
+// Attrs devuelve los atributos conocidos de una actividad. Un error de Mongo
+// cuenta como desconocido: se piden de nuevo a activities-api.
+func (s *AffinityMongo) Attrs(activityID string) (domain.ActivityAttrs, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	var e domain.TrackedEnrollment
+	// las canceladas antes de conocer el alta no tienen atributos
+	err := s.enrollments.FindOne(ctx, bson.M{"activityId": activityID, "attrs": bson.M{"$exists": true}}).Decode(&e)
+	if err != nil {
+		return domain.ActivityAttrs{}, false
+	}
+	return e.Attrs, true
+}
+
+// Enroll inserta la inscripción activa; si ya existía (repetida o cancelada
+// antes) no cambia nada
+func (s *AffinityMongo) Enroll(e domain.TrackedEnrollment) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	e.Active = true
+	_, err := s.enrollments.InsertOne(ctx, e)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// Cancel marca la inscripción como cancelada; si no existía la crea
+// cancelada, para que un alta que llegue después no sume
+func (s *AffinityMongo) Cancel(e domain.TrackedEnrollment) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	_, err := s.enrollments.UpdateOne(ctx, bson.M{"_id": e.ID}, bson.M{
+		"$set":         bson.M{"active": false},
+		"$setOnInsert": bson.M{"userId": e.UserID, "activityId": e.ActivityID, "orgId": e.OrgID},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+// Profile calcula las afinidades del usuario con sus inscripciones activas
+func (s *AffinityMongo) Profile(userID string) (domain.UserAffinity, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	out := domain.NewUserAffinity(userID)
+	cur, err := s.enrollments.Find(ctx, bson.M{"userId": userID, "active": true})
+	if err != nil {
+		return out, err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		var e domain.TrackedEnrollment
+		if err := cur.Decode(&e); err != nil {
+			return out, err
+		}
+		out.Add(e)
+	}
+	return out, cur.Err()
+}
+
+// Popularity devuelve la cantidad de inscripciones activas por actividad
+func (s *AffinityMongo) Popularity() (map[string]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	cur, err := s.enrollments.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"active": true}}},
+		{{Key: "$group", Value: bson.M{"_id": "$activityId", "n": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	out := map[string]int{}
+	for cur.Next(ctx) {
+		var row struct {
+			ActivityID string `bson:"_id"`
+			N          int    `bson:"n"`
+		}
+		if err := cur.Decode(&row); err != nil {
+			return nil, err
+		}
+		out[row.ActivityID] = row.N
+	}
+	return out, cur.Err()
+}
+
+// Seeded indica si ya se cargaron las inscripciones previas del usuario en
+// la organización
+func (s *AffinityMongo) Seeded(userID, orgID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	n, err := s.seeded.CountDocuments(ctx, bson.M{"_id": userID + "|" + orgID})
+	return n > 0, err
+}
+
+func (s *AffinityMongo) MarkSeeded(userID, orgID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	_, err := s.seeded.UpdateOne(ctx, bson.M{"_id": userID + "|" + orgID},
+		bson.M{"$set": bson.M{"at": time.Now()}}, options.Update().SetUpsert(true))
+	return err
+}
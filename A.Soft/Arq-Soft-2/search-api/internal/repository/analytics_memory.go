@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sporthub/search-api/internal/domain"
+)
+
+// AnalyticsStore guarda las últimas búsquedas y clicks en buffers circulares
+// de tamaño fijo: cuando se llena, los eventos más viejos se descartan. Cada
+// evento lleva su organización y se lee solo lo de una; los buffers son
+// compartidos para que la memoria no dependa de cuántas organizaciones
+// aparezcan en X-Org-ID.
+type AnalyticsStore struct {
+	mu     sync.RWMutex
+	events []domain.SearchEvent
+	clicks []domain.SearchClick
+	nextE  int
+	nextC  int
+	fullE  bool
+	fullC  bool
+}
+
+func NewAnalyticsStore(capacity int) *AnalyticsStore {
+	if capacity <= 0 {
+		capacity = 10_000
+	}
+	return &AnalyticsStore{
+		events: make([]domain.SearchEvent, capacity),
+		clicks: make([]domain.SearchClick, capacity),
+	}
+}
+
+func (a *AnalyticsStore) Record(ev domain.SearchEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events[a.nextE] = ev
+	a.nextE = (a.nextE + 1) % len(a.events)
+	if a.nextE == 0 {
+		a.fullE = true
+	}
+}
+
+func (a *AnalyticsStore) RecordClick(c domain.SearchClick) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.clicks[a.nextC] = c
+	a.nextC = (a.nextC + 1) % len(a.clicks)
+	if a.nextC == 0 {
+		a.fullC = true
+	}
+}
+
+// Events devuelve una copia de las búsquedas de orgID registradas desde since
+func (a *AnalyticsStore) Events(orgID string, since time.Time) []domain.SearchEvent {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	n := a.nextE
+	if a.fullE {
+		n = len(a.events)
+	}
+	out := make([]domain.SearchEvent, 0, n)
+	for i := 0; i < n; i++ {
+		if ev := a.events[i]; ev.OrgID == orgID && !ev.At.Before(since) {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// Clicks devuelve una copia de los clicks de orgID registrados desde since
+func (a *AnalyticsStore) Clicks(orgID string, since time.Time) []domain.SearchClick {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	n := a.nextC
+	if a.fullC {
+		n = len(a.clicks)
+	}
+	out := make([]domain.SearchClick, 0, n)
+	for i := 0; i < n; i++ {
+		if c := a.clicks[i]; c.OrgID == orgID && !c.At.Before(since) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
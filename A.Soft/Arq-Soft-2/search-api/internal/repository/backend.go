@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sporthub/search-api/internal/domain"
+)
+
+// SearchBackend es el motor de búsqueda detrás de search-api. Lo implementan
+// SolrRepo, MemoryIndex (índice embebido) y FailoverRepo (Solr con fallback).
+type SearchBackend interface {
+	Search(ctx context.Context, q, sport, site, date, sort string, page, size int) (*domain.Result, error)
+	Related(ctx context.Context, activityID, sport, site, date string, size int) (*domain.Result, error)
+	Upsert(ctx context.Context, docs ...domain.SearchDoc) error
+	DeleteByID(ctx context.Context, id string) error
+	Ping(ctx context.Context) error
+}
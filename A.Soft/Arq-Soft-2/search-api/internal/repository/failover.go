@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sporthub/search-api/internal/domain"
+)
+
+// Nombres de backend expuestos en /health
+const (
+	BackendSolr   = "solr"
+	BackendMemory = "memory"
+)
+
+// bootstrapRows es la cantidad máxima de documentos que se copian de Solr al
+// índice embebido al arrancar
+const bootstrapRows = 10_000
+
+// FailoverRepo usa Solr mientras responde al health check y cambia al índice
+// embebido cuando falla. El índice embebido recibe siempre todas las
+// escrituras; las que no llegan a Solr mientras está caído se reenvían cuando
+// se recupera.
+type FailoverRepo struct {
+	primary  SearchBackend
+	fallback *MemoryIndex
+	interval time.Duration
+	healthy  atomic.Bool
+
+	// gate: las escrituras lo toman en lectura y check en escritura para
+	// reenviar lo pendiente y volver a Solr de una vez, así ninguna escritura
+	// queda anotada en pending después del replay
+	gate    sync.RWMutex
+	mu      sync.Mutex
+	pending map[string]bool // id -> true si hay que hacer upsert, false si delete
+
+	// loaded indica si ya se copiaron los documentos de Solr al índice
+	// embebido (solo lo usa Run)
+	loaded bool
+}
+
+func NewFailoverRepo(primary SearchBackend, fallback *MemoryIndex, interval time.Duration) *FailoverRepo {
+	f := &FailoverRepo{primary: primary, fallback: fallback, interval: interval, pending: map[string]bool{}}
+	f.healthy.Store(true)
+	return f
+}
+
+// Active devuelve el backend que está respondiendo las búsquedas
+func (f *FailoverRepo) Active() string {
+	if f.healthy.Load() {
+		return BackendSolr
+	}
+	return BackendMemory
+}
+
+// Run chequea la salud de Solr cada interval hasta que se cancele ctx. La
+// primera vez que Solr responde se carga el índice embebido desde Solr; si
+// eso falla (o Solr estaba caído al arrancar) se reintenta en cada chequeo
+// en que Solr esté sano, hasta que salga.
+func (f *FailoverRepo) Run(ctx context.Context) {
+	t := time.NewTicker(f.interval)
+	defer t.Stop()
+	for {
+		f.check(ctx)
+		if f.healthy.Load() && !f.loaded {
+			f.loaded = f.bootstrap(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (f *FailoverRepo) check(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if err := f.primary.Ping(pingCtx); err != nil {
+		if f.healthy.Load() {
+			log.Printf("[failover] solr health check failed (%v), switching to embedded index", err)
+			f.healthy.Store(false)
+		}
+		return
+	}
+
+	// Solr responde: se reenvía lo pendiente (lo que no llegó mientras estuvo
+	// caído o falló suelto) y recién ahí se vuelve a Solr, sin escrituras
+	// en vuelo
+	f.gate.Lock()
+	defer f.gate.Unlock()
+	if f.healthy.Load() && f.pendingCount() == 0 {
+		return
+	}
+	if err := f.replay(ctx); err != nil {
+		log.Printf("[failover] solr is up but replay failed (%v), will retry", err)
+		return
+	}
+	if !f.healthy.Load() {
+		log.Printf("[failover] solr recovered, switching back")
+		f.healthy.Store(true)
+	}
+}
+
+// bootstrap copia al índice embebido los documentos de Solr que todavía no
+// tiene (los que ya están llegaron por el consumer y son más nuevos) y
+// devuelve si lo logró. Toma f.gate para que no se mezcle con escrituras a
+// Solr en vuelo.
+func (f *FailoverRepo) bootstrap(ctx context.Context) bool {
+	f.gate.Lock()
+	defer f.gate.Unlock()
+	res, err := f.primary.Search(ctx, "", "", "", "", "updated_dt desc", 1, bootstrapRows)
+	if err != nil {
+		log.Printf("[failover] WARN: could not load documents from solr (%v), will retry", err)
+		return false
+	}
+	missing := make([]domain.SearchDoc, 0, len(res.Docs))
+	for _, d := range res.Docs {
+		if _, ok := f.fallback.get(d.ID); !ok {
+			missing = append(missing, d)
+		}
+	}
+	if err := f.fallback.Upsert(ctx, missing...); err != nil {
+		log.Printf("[failover] WARN: could not load embedded index (%v), will retry", err)
+		return false
+	}
+	log.Printf("[failover] embedded index loaded with %d documents from solr", len(missing))
+	return true
+}
+
+// replay reenvía a Solr las escrituras hechas mientras estuvo caído (con
+// f.gate tomado)
+func (f *FailoverRepo) replay(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, upsert := range f.pending {
+		var err error
+		if upsert {
+			if doc, ok := f.fallback.get(id); ok {
+				err = f.primary.Upsert(ctx, doc)
+			}
+		} else {
+			err = f.primary.DeleteByID(ctx, id)
+		}
+		if err != nil {
+			return err
+		}
+		delete(f.pending, id)
+	}
+	return nil
+}
+
+func (f *FailoverRepo) markPending(id string, upsert bool) {
+	f.mu.Lock()
+	f.pending[id] = upsert
+	f.mu.Unlock()
+}
+
+func (f *FailoverRepo) pendingCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.pending)
+}
+
+func (f *FailoverRepo) Ping(ctx context.Context) error {
+	if f.healthy.Load() {
+		return f.primary.Ping(ctx)
+	}
+	return f.fallback.Ping(ctx)
+}
+
+func (f *FailoverRepo) Search(ctx context.Context, q, sport, site, date, sort string, page, size int) (*domain.Result, error) {
+	if f.healthy.Load() {
+		res, err := f.primary.Search(ctx, q, sport, site, date, sort, page, size)
+		if err == nil {
+			return res, nil
+		}
+		log.Printf("[failover] solr search failed (%v), answering from embedded index", err)
+	}
+	return f.fallback.Search(ctx, q, sport, site, date, sort, page, size)
+}
+
+func (f *FailoverRepo) Related(ctx context.Context, activityID, sport, site, date string, size int) (*domain.Result, error) {
+	if f.healthy.Load() {
+		res, err := f.primary.Related(ctx, activityID, sport, site, date, size)
+		if err == nil {
+			return res, nil
+		}
+		log.Printf("[failover] solr related failed (%v), answering from embedded index", err)
+	}
+	return f.fallback.Related(ctx, activityID, sport, site, date, size)
+}
+
+func (f *FailoverRepo) Upsert(ctx context.Context, docs ...domain.SearchDoc) error {
+	if err := f.fallback.Upsert(ctx, docs...); err != nil {
+		return err
+	}
+	f.gate.RLock()
+	defer f.gate.RUnlock()
+	if f.healthy.Load() {
+		err := f.primary.Upsert(ctx, docs...)
+		if err == nil {
+			return nil
+		}
+		log.Printf("[failover] solr upsert failed (%v), will retry when solr recovers", err)
+	}
+	for _, d := range docs {
+		f.markPending(d.ID, true)
+	}
+	return nil
+}
+
+func (f *FailoverRepo) DeleteByID(ctx context.Context, id string) error {
+	_ = f.fallback.DeleteByID(ctx, id)
+	f.gate.RLock()
+	defer f.gate.RUnlock()
+	if f.healthy.Load() {
+		err := f.primary.DeleteByID(ctx, id)
+		if err == nil {
+			return nil
+		}
+		log.Printf("[failover] solr delete failed (%v), will retry when solr recovers", err)
+	}
+	f.markPending(id, false)
+	return nil
+}
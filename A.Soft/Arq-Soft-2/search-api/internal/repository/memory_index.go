@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sporthub/search-api/internal/domain"
+)
+
+// MemoryIndex es un índice full-text en memoria que replica el comportamiento
+// básico de la consulta edismax de SolrRepo: palabras completas del nombre
+// (peso 2) y deporte, sede e instructor enteros (peso 1), sin prefijos ni
+// stemming; con varias palabras tienen que coincidir todas (mm=100%). Sin
+// sort ordena como Solr, por start_dt asc. Se usa cuando Solr no está
+// disponible y para desarrollo/tests sin Solr.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	docs     map[string]domain.SearchDoc
+	postings map[string]map[string]struct{} // token -> ids
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{docs: map[string]domain.SearchDoc{}, postings: map[string]map[string]struct{}{}}
+}
+
+func (m *MemoryIndex) Ping(ctx context.Context) error { return nil }
+
+// Len devuelve la cantidad de documentos indexados
+func (m *MemoryIndex) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.docs)
+}
+
+func (m *MemoryIndex) get(id string) (domain.SearchDoc, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	d, ok := m.docs[id]
+	return d, ok
+}
+
+func (m *MemoryIndex) Upsert(ctx context.Context, docs ...domain.SearchDoc) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range docs {
+		m.remove(d.ID)
+		m.docs[d.ID] = d
+		for _, tok := range docTokens(d) {
+			if m.postings[tok] == nil {
+				m.postings[tok] = map[string]struct{}{}
+			}
+			m.postings[tok][d.ID] = struct{}{}
+		}
+	}
+	return nil
+}
+
+func (m *MemoryIndex) DeleteByID(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(id)
+	return nil
+}
+
+func (m *MemoryIndex) remove(id string) {
+	old, ok := m.docs[id]
+	if !ok {
+		return
+	}
+	for _, tok := range docTokens(old) {
+		delete(m.postings[tok], id)
+		if len(m.postings[tok]) == 0 {
+			delete(m.postings, tok)
+		}
+	}
+	delete(m.docs, id)
+}
+
+type scoredDoc struct {
+	doc   domain.SearchDoc
+	score float64
+}
+
+func (m *MemoryIndex) Search(ctx context.Context, q, sport, site, date, sort string, page, size int) (*domain.Result, error) {
+	words := strings.Fields(strings.ToLower(q))
+	org, legacy := TenantFrom(ctx), legacyDocsFrom(ctx)
+
+	m.mu.RLock()
+	var hits []scoredDoc
+	for id, d := range m.docs {
+		if !matchesFilters(d, org, legacy, sport, site, date) {
+			continue
+		}
+		score, matched := 1.0, len(words) == 0
+		if !matched {
+			score, matched = m.score(id, d, words)
+		}
+		if matched {
+			hits = append(hits, scoredDoc{doc: d, score: score})
+		}
+	}
+	m.mu.RUnlock()
+
+	// mismo sort por defecto que SolrRepo.Search
+	if sort == "" {
+		sort = "start_dt asc"
+	}
+	sortHits(hits, sort)
+	return paginate(hits, page, size), nil
+}
+
+// score calcula la relevancia de d para las palabras buscadas: cada una tiene
+// que ser un término indexado del documento (palabra del nombre o campo
+// entero), con una o con varias.
+func (m *MemoryIndex) score(id string, d domain.SearchDoc, words []string) (float64, bool) {
+	nameToks := map[string]struct{}{}
+	for _, t := range strings.Fields(strings.ToLower(d.Name)) {
+		nameToks[t] = struct{}{}
+	}
+	score := 0.0
+	for _, w := range words {
+		if _, ok := m.postings[w][id]; !ok {
+			return 0, false
+		}
+		if _, ok := nameToks[w]; ok {
+			score += 2
+		}
+		for _, f := range []string{d.Sport, d.Site, d.Instructor} {
+			if strings.ToLower(f) == w {
+				score++
+			}
+		}
+	}
+	// boost de frase completa en el nombre (equivalente a pf=name_txt^3)
+	if len(words) > 1 && strings.Contains(strings.ToLower(d.Name), strings.Join(words, " ")) {
+		score += 3
+	}
+	return score, true
+}
+
+// Related puntúa por palabras del nombre en común y mismo deporte, sede e instructor
+func (m *MemoryIndex) Related(ctx context.Context, activityID, sport, site, date string, size int) (*domain.Result, error) {
+	org, legacy := TenantFrom(ctx), legacyDocsFrom(ctx)
+	m.mu.RLock()
+	src, ok := m.docs[activityID]
+	if !ok {
+		m.mu.RUnlock()
+		return &domain.Result{Page: 1, Size: size}, nil
+	}
+	srcToks := map[string]struct{}{}
+	for _, t := range strings.Fields(strings.ToLower(src.Name)) {
+		srcToks[t] = struct{}{}
+	}
+	var hits []scoredDoc
+	for id, d := range m.docs {
+		if id == activityID || !matchesFilters(d, org, legacy, sport, site, date) {
+			continue
+		}
+		score := 0.0
+		for _, t := range strings.Fields(strings.ToLower(d.Name)) {
+			if _, ok := srcToks[t]; ok {
+				score++
+			}
+		}
+		if d.Sport != "" && d.Sport == src.Sport {
+			score++
+		}
+		if d.Site != "" && d.Site == src.Site {
+			score++
+		}
+		if d.Instructor != "" && d.Instructor == src.Instructor {
+			score++
+		}
+		if score > 0 {
+			hits = append(hits, scoredDoc{doc: d, score: score})
+		}
+	}
+	m.mu.RUnlock()
+
+	sortHits(hits, "score desc")
+	return paginate(hits, 1, size), nil
+}
+
+// docTokens son los términos indexados: palabras del nombre y los campos
+// string completos (en minúsculas), como en el schema de Solr
+func docTokens(d domain.SearchDoc) []string {
+	toks := strings.Fields(strings.ToLower(d.Name))
+	for _, f := range []string{d.Sport, d.Site, d.Instructor} {
+		if f != "" {
+			toks = append(toks, strings.ToLower(f))
+		}
+	}
+	return toks
+}
+
+func matchesFilters(d domain.SearchDoc, org string, legacy bool, sport, site, date string) bool {
+	if org != "" && d.OrgID != org && !(legacy && d.OrgID == "") {
+		return false
+	}
+	if sport != "" && d.Sport != sport {
+		return false
+	}
+	if site != "" && d.Site != site {
+		return false
+	}
+	// las fechas ISO8601 se comparan bien como strings
+	if date != "" && (d.StartAt == "" || d.StartAt < date+"T00:00:00Z") {
+		return false
+	}
+	return true
+}
+
+// sortHits soporta los mismos sorts que se usan contra Solr
+// ("start_dt asc", "updated_dt desc", "price_f asc", "score desc", ...)
+func sortHits(hits []scoredDoc, spec string) {
+	field, dir := "score", "desc"
+	if parts := strings.Fields(spec); len(parts) == 2 {
+		field, dir = parts[0], strings.ToLower(parts[1])
+	}
+	less := func(a, b scoredDoc) int {
+		switch field {
+		case "start_dt":
+			return compareMissingLast(a.doc.StartAt, b.doc.StartAt)
+		case "updated_dt":
+			return compareMissingLast(a.doc.UpdatedAt, b.doc.UpdatedAt)
+		case "price_f":
+			return compareFloat(a.doc.Price, b.doc.Price)
+		case "name_txt":
+			return strings.Compare(strings.ToLower(a.doc.Name), strings.ToLower(b.doc.Name))
+		default:
+			return compareFloat(a.score, b.score)
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		c := less(hits[i], hits[j])
+		if c == 0 {
+			return hits[i].doc.ID < hits[j].doc.ID
+		}
+		if dir == "desc" {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+func compareMissingLast(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func paginate(hits []scoredDoc, page, size int) *domain.Result {
+	out := &domain.Result{Total: len(hits), Page: page, Size: size}
+	start := (page - 1) * size
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < len(hits) && i < start+size; i++ {
+		out.Docs = append(out.Docs, hits[i].doc)
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/karlseguin/ccache/v3"
+
+	"github.com/sporthub/search-api/internal/domain"
+)
+
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// NotifiedTTL es cuánto se recuerda que un par búsqueda/actividad ya se
+// notificó; pasado ese tiempo una actividad reindexada puede volver a avisar
+const NotifiedTTL = 30 * 24 * time.Hour
+
+// notifiedMax acota los pares notificados que se guardan en memoria (se
+// descartan los más viejos)
+const notifiedMax = 100_000
+
+// SavedSearchStore guarda las búsquedas guardadas en memoria
+type SavedSearchStore struct {
+	mu       sync.RWMutex
+	seq      uint64
+	byID     map[string]domain.SavedSearch
+	byUser   map[string]map[string]struct{}
+	notified *ccache.Cache[struct{}]
+}
+
+func NewSavedSearchStore() *SavedSearchStore {
+	return &SavedSearchStore{
+		byID:     map[string]domain.SavedSearch{},
+		byUser:   map[string]map[string]struct{}{},
+		notified: ccache.New(ccache.Configure[struct{}]().MaxSize(notifiedMax)),
+	}
+}
+
+func (s *SavedSearchStore) Create(ss *domain.SavedSearch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	ss.ID = strconv.FormatUint(s.seq, 10)
+	s.byID[ss.ID] = *ss
+	if s.byUser[ss.UserID] == nil {
+		s.byUser[ss.UserID] = map[string]struct{}{}
+	}
+	s.byUser[ss.UserID][ss.ID] = struct{}{}
+	return nil
+}
+
+func (s *SavedSearchStore) Get(id string) (*domain.SavedSearch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ss, ok := s.byID[id]
+	if !ok {
+		return nil, ErrSavedSearchNotFound
+	}
+	return &ss, nil
+}
+
+func (s *SavedSearchStore) Update(ss *domain.SavedSearch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byID[ss.ID]; !ok {
+		return ErrSavedSearchNotFound
+	}
+	s.byID[ss.ID] = *ss
+	return nil
+}
+
+func (s *SavedSearchStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ss, ok := s.byID[id]
+	if !ok {
+		return ErrSavedSearchNotFound
+	}
+	delete(s.byID, id)
+	delete(s.byUser[ss.UserID], id)
+	s.notified.DeletePrefix(id + "|")
+	return nil
+}
+
+// ListByUser devuelve las búsquedas del usuario
+func (s *SavedSearchStore) ListByUser(userID string) ([]domain.SavedSearch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]domain.SavedSearch, 0, len(s.byUser[userID]))
+	for id := range s.byUser[userID] {
+		out = append(out, s.byID[id])
+	}
+	// los ids son secuenciales: ordenar por id es ordenar por creación
+	sort.Slice(out, func(i, j int) bool {
+		a, _ := strconv.ParseUint(out[i].ID, 10, 64)
+		b, _ := strconv.ParseUint(out[j].ID, 10, 64)
+		return a < b
+	})
+	return out, nil
+}
+
+// All devuelve todas las búsquedas guardadas (para evaluar documentos nuevos)
+func (s *SavedSearchStore) All() ([]domain.SavedSearch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]domain.SavedSearch, 0, len(s.byID))
+	for _, ss := range s.byID {
+		out = append(out, ss)
+	}
+	return out, nil
+}
+
+// MarkNotified registra que la búsqueda ya avisó de la actividad. Devuelve
+// false si ya estaba registrado.
+func (s *SavedSearchStore) MarkNotified(searchID, activityID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := searchID + "|" + activityID
+	if it := s.notified.Get(key); it != nil && !it.Expired() {
+		return false, nil
+	}
+	s.notified.Set(key, struct{}{}, NotifiedTTL)
+	return true, nil
+}
+
+// UnmarkNotified deshace MarkNotified (la alerta no se pudo publicar)
+func (s *SavedSearchStore) UnmarkNotified(searchID, activityID string) error {
+	s.notified.Delete(searchID + "|" + activityID)
+	return nil
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/sporthub/search-api/internal/domain"
+)
+
+// mongoTimeout limita cada operación del store (la interfaz no recibe ctx)
+const mongoTimeout = 5 * time.Second
+
+// SavedSearchMongo guarda las búsquedas guardadas en MongoDB. Los pares
+// búsqueda/actividad ya notificados van en saved_search_alerts con un índice
+// TTL, así no crecen sin límite y sobreviven a los reinicios.
+type SavedSearchMongo struct {
+	searches *mongo.Collection
+	alerts   *mongo.Collection
+	counters *mongo.Collection
+}
+
+// NewSavedSearchMongo crea el store y sus índices
+func NewSavedSearchMongo(db *mongo.Database) (*SavedSearchMongo, error) {
+	s := &SavedSearchMongo{
+		searches: db.Collection("saved_searches"),
+		alerts:   db.Collection("saved_search_alerts"),
+		counters: db.Collection("counters"),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	if _, err := s.searches.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "userId", Value: 1}}}); err != nil {
+		return nil, fmt.Errorf("saved_searches index: %w", err)
+	}
+	_, err := s.alerts.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "searchId", Value: 1}, {Key: "activityId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(NotifiedTTL / time.Second))},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("saved_search_alerts indexes: %w", err)
+	}
+	return s, nil
+}
+
+func (s *SavedSearchMongo) Create(ss *domain.SavedSearch) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	// ids secuenciales como en el store en memoria
+	var counter struct {
+		Seq uint64 `bson:"seq"`
+	}
+	err := s.counters.FindOneAndUpdate(ctx, bson.M{"_id": "saved_searches"}, bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&counter)
+	if err != nil {
+		return fmt.Errorf("saved search id: %w", err)
+	}
+	ss.ID = strconv.FormatUint(counter.Seq, 10)
+	_, err = s.searches.InsertOne(ctx, ss)
+	return err
+}
+
+func (s *SavedSearchMongo) Get(id string) (*domain.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	var ss domain.SavedSearch
+	err := s.searches.FindOne(ctx, bson.M{"_id": id}).Decode(&ss)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrSavedSearchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ss, nil
+}
+
+func (s *SavedSearchMongo) Update(ss *domain.SavedSearch) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	res, err := s.searches.ReplaceOne(ctx, bson.M{"_id": ss.ID}, ss)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+func (s *SavedSearchMongo) Delete(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	res, err := s.searches.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrSavedSearchNotFound
+	}
+	_, err = s.alerts.DeleteMany(ctx, bson.M{"searchId": id})
+	return err
+}
+
+// ListByUser devuelve las búsquedas del usuario por orden de creación
+func (s *SavedSearchMongo) ListByUser(userID string) ([]domain.SavedSearch, error) {
+	return s.find(bson.M{"userId": userID}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}))
+}
+
+// All devuelve todas las búsquedas guardadas (para evaluar documentos nuevos)
+func (s *SavedSearchMongo) All() ([]domain.SavedSearch, error) {
+	return s.find(bson.M{}, nil)
+}
+
+func (s *SavedSearchMongo) find(filter bson.M, opts *options.FindOptions) ([]domain.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	cur, err := s.searches.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	out := []domain.SavedSearch{}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarkNotified registra que la búsqueda ya avisó de la actividad. Devuelve
+// false si ya estaba registrado (índice único).
+func (s *SavedSearchMongo) MarkNotified(searchID, activityID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	_, err := s.alerts.InsertOne(ctx, bson.M{"searchId": searchID, "activityId": activityID, "at": time.Now()})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnmarkNotified deshace MarkNotified (la alerta no se pudo publicar)
+func (s *SavedSearchMongo) UnmarkNotified(searchID, activityID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	_, err := s.alerts.DeleteOne(ctx, bson.M{"searchId": searchID, "activityId": activityID})
+	return err
+}
@@ -92,17 +92,7 @@ func (r *SolrRepo) Search(ctx context.Context, q, sport, site, date, sort string
 	// Esto es importante para búsquedas parciales
 	params.Set("q.op", "OR")
 
-	var fqs []string
-	if sport != "" {
-		fqs = append(fqs, fmt.Sprintf("sport_s:%q", sport))
-	}
-	if site != "" {
-		fqs = append(fqs, fmt.Sprintf("site_s:%q", site))
-	}
-	if date != "" {
-		fqs = append(fqs, fmt.Sprintf("start_dt:[%sT00:00:00Z TO *]", date))
-	}
-	for _, fq := range fqs {
+	for _, fq := range filterQueries(TenantFrom(ctx), legacyDocsFrom(ctx), sport, site, date) {
 		params.Add("fq", fq)
 	}
 
@@ -118,6 +108,49 @@ func (r *SolrRepo) Search(ctx context.Context, q, sport, site, date, sort string
 	params.Set("rows", fmt.Sprintf("%d", size))
 	params.Set("wt", "json")
 
+	return r.selectDocs(ctx, params, page, size)
+}
+
+// Related busca actividades similares a activityID (MoreLikeThis sobre nombre,
+// deporte, sede e instructor), excluyendo la actividad de origen y aplicando
+// los mismos filtros que Search.
+func (r *SolrRepo) Related(ctx context.Context, activityID, sport, site, date string, size int) (*domain.Result, error) {
+	params := url.Values{}
+	params.Set("q", fmt.Sprintf("{!mlt qf=name_txt,sport_s,site_s,instructor_s mintf=1 mindf=1}%s", activityID))
+	params.Add("fq", fmt.Sprintf("-id:%q", activityID))
+	for _, fq := range filterQueries(TenantFrom(ctx), legacyDocsFrom(ctx), sport, site, date) {
+		params.Add("fq", fq)
+	}
+	params.Set("start", "0")
+	params.Set("rows", fmt.Sprintf("%d", size))
+	params.Set("wt", "json")
+	return r.selectDocs(ctx, params, 1, size)
+}
+
+// filterQueries arma los fq comunes a todas las búsquedas (org = organización
+// del request, "" = todas; legacy suma los documentos sin org_s)
+func filterQueries(org string, legacy bool, sport, site, date string) []string {
+	var fqs []string
+	switch {
+	case org != "" && legacy:
+		fqs = append(fqs, fmt.Sprintf("org_s:%q OR (*:* -org_s:[* TO *])", org))
+	case org != "":
+		fqs = append(fqs, fmt.Sprintf("org_s:%q", org))
+	}
+	if sport != "" {
+		fqs = append(fqs, fmt.Sprintf("sport_s:%q", sport))
+	}
+	if site != "" {
+		fqs = append(fqs, fmt.Sprintf("site_s:%q", site))
+	}
+	if date != "" {
+		fqs = append(fqs, fmt.Sprintf("start_dt:[%sT00:00:00Z TO *]", date))
+	}
+	return fqs
+}
+
+// selectDocs ejecuta la consulta contra /select y mapea los documentos de Solr
+func (r *SolrRepo) selectDocs(ctx context.Context, params url.Values, page, size int) (*domain.Result, error) {
 	u := fmt.Sprintf("%s/select?%s", r.base, params.Encode())
 	log.Printf("[solr] Search query: %s", u)
 	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
@@ -141,6 +174,7 @@ func (r *SolrRepo) Search(ctx context.Context, q, sport, site, date, sort string
 		doc := domain.SearchDoc{
 			ID:         asString(d["id"]),
 			ActivityID: asString(d["activity_id"]),
+			OrgID:      asString(d["org_s"]),
 			SessionID:  asString(d["session_id"]),
 			Name:       asString(d["name_txt"]),
 			Sport:      asString(d["sport_s"]),
@@ -167,6 +201,7 @@ func (r *SolrRepo) Upsert(ctx context.Context, docs ...domain.SearchDoc) error {
 		solrDoc := map[string]any{
 			"id":            doc.ID,
 			"activity_id":   doc.ActivityID,
+			"org_s":         doc.OrgID,
 			"name_txt":      doc.Name,
 			"sport_s":       doc.Sport,
 			"site_s":        doc.Site,
@@ -188,13 +223,15 @@ func (r *SolrRepo) Upsert(ctx context.Context, docs ...domain.SearchDoc) error {
 		solrDocs = append(solrDocs, solrDoc)
 	}
 	
-	payload := map[string]any{"add": solrDocs, "commit": true}
+	// commitWithin en lugar de commit duro: con varios workers escribiendo a la
+	// vez, Solr agrupa los cambios en un soft commit (ver autoSoftCommit)
+	payload := map[string]any{"add": solrDocs}
 	b, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("marshal payload: %w", err)
 	}
 	
-	u := fmt.Sprintf("%s/update?commit=true", r.base)
+	u := fmt.Sprintf("%s/update?commitWithin=%d", r.base, commitWithinMs)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(b))
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
@@ -231,15 +268,35 @@ func (r *SolrRepo) Upsert(ctx context.Context, docs ...domain.SearchDoc) error {
 	return nil
 }
 func (r *SolrRepo) DeleteByID(ctx context.Context, id string) error {
-	payload := map[string]any{"delete": map[string]string{"id": id}, "commit": true}
+	payload := map[string]any{"delete": map[string]string{"id": id}}
 	b, _ := json.Marshal(payload)
-	u := fmt.Sprintf("%s/update?commit=true", r.base)
+	u := fmt.Sprintf("%s/update?commitWithin=%d", r.base, commitWithinMs)
 	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(b))
 	req.Header.Set("Content-Type", "application/json")
 	_, err := r.http.Do(req)
 	return err
 }
 
+// commitWithinMs es el plazo máximo para que una escritura sea visible en búsquedas
+const commitWithinMs = 1000
+
+// Ping verifica que el core de Solr responda (/admin/ping)
+func (r *SolrRepo) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.base+"/admin/ping?wt=json", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("solr ping returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 func escape(s string) string { return strings.ReplaceAll(s, " ", "\\ ") }
 
 // escapeForSolr escapa caracteres especiales de Solr (para uso con wildcards)
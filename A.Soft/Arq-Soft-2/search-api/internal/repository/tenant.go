@@ -0,0 +1,33 @@
+package repository
+
+import "context"
+
+type tenantKey struct{}
+
+type legacyDocsKey struct{}
+
+// WithTenant limita las búsquedas hechas con ctx a la organización orgID.
+// Con "" no se filtra.
+func WithTenant(ctx context.Context, orgID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, orgID)
+}
+
+// TenantFrom devuelve la organización de ctx ("" = todas)
+func TenantFrom(ctx context.Context) string {
+	org, _ := ctx.Value(tenantKey{}).(string)
+	return org
+}
+
+// WithLegacyDocs hace que las búsquedas de ctx incluyan además los documentos
+// indexados antes de las organizaciones (sin org_s). Es para la organización
+// por defecto, que es a la que activities-api asignó esas actividades, así
+// siguen apareciendo aunque todavía no se haya reindexado.
+func WithLegacyDocs(ctx context.Context) context.Context {
+	return context.WithValue(ctx, legacyDocsKey{}, true)
+}
+
+// legacyDocsFrom indica si las búsquedas de ctx incluyen los documentos sin org_s
+func legacyDocsFrom(ctx context.Context) bool {
+	ok, _ := ctx.Value(legacyDocsKey{}).(bool)
+	return ok
+}
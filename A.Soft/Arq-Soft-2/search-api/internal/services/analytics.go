@@ -0,0 +1,152 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sporthub/search-api/internal/domain"
+)
+
+type analyticsStore interface {
+	Record(ev domain.SearchEvent)
+	RecordClick(c domain.SearchClick)
+	Events(orgID string, since time.Time) []domain.SearchEvent
+	Clicks(orgID string, since time.Time) []domain.SearchClick
+}
+
+// trendingWindow es la ventana considerada para las búsquedas "trending"
+const trendingWindow = 24 * time.Hour
+
+type AnalyticsService struct {
+	store analyticsStore
+}
+
+func NewAnalyticsService(st analyticsStore) *AnalyticsService {
+	return &AnalyticsService{store: st}
+}
+
+// Record guarda una búsqueda con la query normalizada
+func (a *AnalyticsService) Record(ev domain.SearchEvent) {
+	ev.Query = normalizeQuery(ev.Query)
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+	a.store.Record(ev)
+}
+
+// Click guarda un click del frontend sobre un resultado de la query en la
+// organización orgID
+func (a *AnalyticsService) Click(orgID, query, activityID string, position int) {
+	a.store.RecordClick(domain.SearchClick{
+		OrgID:      orgID,
+		Query:      normalizeQuery(query),
+		ActivityID: activityID,
+		Position:   position,
+		At:         time.Now(),
+	})
+}
+
+// Los reportes son siempre de una organización: ninguno mezcla las búsquedas
+// de distintos clubes.
+
+// TopQueries devuelve las queries más buscadas desde since
+func (a *AnalyticsService) TopQueries(orgID string, since time.Time, limit int) []domain.QueryStat {
+	stats := a.aggregate(orgID, since)
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Searches > stats[j].Searches })
+	return head(stats, limit)
+}
+
+// ZeroResultQueries devuelve las queries que no devolvieron resultados,
+// ordenadas por cantidad de búsquedas sin resultados
+func (a *AnalyticsService) ZeroResultQueries(orgID string, since time.Time, limit int) []domain.QueryStat {
+	var out []domain.QueryStat
+	for _, st := range a.aggregate(orgID, since) {
+		if st.ZeroResults > 0 {
+			out = append(out, st)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ZeroResults > out[j].ZeroResults })
+	return head(out, limit)
+}
+
+// ClickThrough devuelve las queries con al menos un click, ordenadas por CTR
+func (a *AnalyticsService) ClickThrough(orgID string, since time.Time, limit int) []domain.QueryStat {
+	var out []domain.QueryStat
+	for _, st := range a.aggregate(orgID, since) {
+		if st.Clicks > 0 {
+			out = append(out, st)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].ClickThrough == out[j].ClickThrough {
+			return out[i].Searches > out[j].Searches
+		}
+		return out[i].ClickThrough > out[j].ClickThrough
+	})
+	return head(out, limit)
+}
+
+// Trending devuelve las queries con resultados más buscadas en las últimas 24h
+func (a *AnalyticsService) Trending(orgID string, limit int) []domain.QueryStat {
+	var out []domain.QueryStat
+	for _, st := range a.aggregate(orgID, time.Now().Add(-trendingWindow)) {
+		if st.Searches > st.ZeroResults {
+			out = append(out, st)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Searches > out[j].Searches })
+	return head(out, limit)
+}
+
+// aggregate agrupa por query normalizada. Solo cuenta la primera página para
+// que paginar una misma búsqueda no infle los números, y descarta las
+// búsquedas vacías (listado sin texto).
+func (a *AnalyticsService) aggregate(orgID string, since time.Time) []domain.QueryStat {
+	byQuery := map[string]*domain.QueryStat{}
+	latency := map[string]int64{}
+	for _, ev := range a.store.Events(orgID, since) {
+		if ev.Query == "" || ev.Page > 1 || ev.Failed {
+			continue
+		}
+		st, ok := byQuery[ev.Query]
+		if !ok {
+			st = &domain.QueryStat{Query: ev.Query}
+			byQuery[ev.Query] = st
+		}
+		st.Searches++
+		if ev.Results == 0 {
+			st.ZeroResults++
+		}
+		if ev.At.After(st.LastSeen) {
+			st.LastSeen = ev.At
+		}
+		latency[ev.Query] += ev.LatencyMs
+	}
+	for _, c := range a.store.Clicks(orgID, since) {
+		if st, ok := byQuery[c.Query]; ok {
+			st.Clicks++
+		}
+	}
+
+	out := make([]domain.QueryStat, 0, len(byQuery))
+	for q, st := range byQuery {
+		st.AvgLatencyMs = float64(latency[q]) / float64(st.Searches)
+		st.ClickThrough = float64(st.Clicks) / float64(st.Searches)
+		out = append(out, *st)
+	}
+	// orden estable de base para que los empates salgan siempre igual
+	sort.Slice(out, func(i, j int) bool { return out[i].Query < out[j].Query })
+	return out
+}
+
+func normalizeQuery(q string) string {
+	return strings.Join(strings.Fields(strings.ToLower(q)), " ")
+}
+
+func head(stats []domain.QueryStat, limit int) []domain.QueryStat {
+	if limit > 0 && len(stats) > limit {
+		return stats[:limit]
+	}
+	return stats
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sporthub/search-api/internal/domain"
+	"github.com/sporthub/search-api/internal/repository"
+)
+
+// AffinityStore guarda las inscripciones de las que salen las afinidades y la
+// popularidad (repository.AffinityStore en memoria o repository.AffinityMongo)
+type AffinityStore interface {
+	Enroll(e domain.TrackedEnrollment) error
+	Cancel(e domain.TrackedEnrollment) error
+	Attrs(activityID string) (domain.ActivityAttrs, bool)
+	Profile(userID string) (domain.UserAffinity, error)
+	Popularity() (map[string]int, error)
+	Seeded(userID, orgID string) (bool, error)
+	MarkSeeded(userID, orgID string) error
+}
+
+type activitiesSource interface {
+	SearchDoc(ctx context.Context, activityID string) (*domain.SearchDoc, error)
+	Sessions(ctx context.Context, activityID string) ([]domain.Session, error)
+	Enrollments(ctx context.Context, userID string) ([]domain.Enrollment, error)
+}
+
+// candidatePool es la cantidad de actividades que se traen de Solr para rankear
+const candidatePool = 200
+
+// Pesos de cada afinidad en el score final
+const (
+	weightSport      = 0.5
+	weightInstructor = 0.3
+	weightSite       = 0.2
+	weightPopularity = 0.1
+)
+
+// sessionsTTL es cuánto se reusan las sesiones de una actividad entre pedidos
+// de recomendaciones; sessionFetchers, cuántas se piden en paralelo
+const (
+	sessionsTTL     = time.Minute
+	sessionFetchers = 8
+)
+
+type cachedSessions struct {
+	sessions []domain.Session
+	at       time.Time
+}
+
+type RecommendationService struct {
+	store      AffinityStore
+	repo       solrRepo
+	activities activitiesSource
+	now        func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]cachedSessions // organización|actividad -> sesiones
+}
+
+func NewRecommendationService(st AffinityStore, r solrRepo, a activitiesSource) *RecommendationService {
+	return &RecommendationService{store: st, repo: r, activities: a, now: time.Now, sessions: map[string]cachedSessions{}}
+}
+
+// RecordEnrollment suma la inscripción a las afinidades del usuario. La
+// inscripción se identifica por su id: repetir el evento no suma dos veces.
+func (s *RecommendationService) RecordEnrollment(ctx context.Context, enrollmentID, userID, activityID string) error {
+	attrs, err := s.attrs(ctx, activityID)
+	if err != nil {
+		return err
+	}
+	return s.store.Enroll(domain.TrackedEnrollment{ID: enrollmentID, UserID: userID, ActivityID: activityID,
+		OrgID: repository.TenantFrom(ctx), Attrs: attrs})
+}
+
+// RecordCancellation resta la inscripción de las afinidades del usuario
+func (s *RecommendationService) RecordCancellation(ctx context.Context, enrollmentID, userID, activityID string) error {
+	return s.store.Cancel(domain.TrackedEnrollment{ID: enrollmentID, UserID: userID, ActivityID: activityID,
+		OrgID: repository.TenantFrom(ctx)})
+}
+
+// ForUser devuelve hasta limit actividades con sesiones próximas en las que el
+// usuario no está inscripto, junto con la estrategia usada: afinidad si el
+// usuario tiene historial, popularidad si es nuevo.
+func (s *RecommendationService) ForUser(ctx context.Context, userID string, limit int) ([]domain.Recommendation, string, error) {
+	candidates, err := s.repo.Search(ctx, "", "", "", "", "updated_dt desc", 1, candidatePool)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.seed(ctx, userID)
+	profile, err := s.store.Profile(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	strategy := domain.RecommendationAffinity
+	if profile.Empty() {
+		strategy = domain.RecommendationPopularity
+	}
+
+	pop, err := s.store.Popularity()
+	if err != nil {
+		return nil, "", err
+	}
+	maxPop := 0
+	for _, n := range pop {
+		if n > maxPop {
+			maxPop = n
+		}
+	}
+
+	scored := make([]domain.Recommendation, 0, len(candidates.Docs))
+	for _, doc := range candidates.Docs {
+		if profile.Enrolled[doc.ActivityID] > 0 {
+			continue
+		}
+		score := 0.0
+		if maxPop > 0 {
+			score = weightPopularity * float64(pop[doc.ActivityID]) / float64(maxPop)
+		}
+		if strategy == domain.RecommendationAffinity {
+			score += weightSport*share(profile.Sports, doc.Sport) +
+				weightInstructor*share(profile.Instructors, doc.Instructor) +
+				weightSite*share(profile.Sites, doc.Site)
+		}
+		scored = append(scored, domain.Recommendation{Activity: doc, Score: score})
+	}
+	// a igual score se mantiene el orden de Solr (más recientes primero)
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+
+	// las sesiones se piden de a tandas del tamaño de lo que falta, en
+	// paralelo: casi siempre alcanza con la primera
+	out := make([]domain.Recommendation, 0, limit)
+	for len(out) < limit && len(scored) > 0 {
+		batch := scored[:min(limit-len(out), len(scored))]
+		scored = scored[len(batch):]
+		for i, next := range s.nextSessions(ctx, batch) {
+			if next != nil {
+				batch[i].NextSession = *next
+				out = append(out, batch[i])
+			}
+		}
+	}
+	return out, strategy, nil
+}
+
+// seed carga una vez por usuario y organización las inscripciones que ya
+// tenía en activities-api (anteriores al consumer o a la base). Si falla se
+// recomienda con lo que haya y se reintenta en el próximo pedido.
+func (s *RecommendationService) seed(ctx context.Context, userID string) {
+	org := repository.TenantFrom(ctx)
+	if done, err := s.store.Seeded(userID, org); err != nil || done {
+		if err != nil {
+			log.Printf("[recommendations] WARN: seed check user=%s org=%s: %v", userID, org, err)
+		}
+		return
+	}
+	enrollments, err := s.activities.Enrollments(ctx, userID)
+	if err != nil {
+		log.Printf("[recommendations] WARN: seed user=%s org=%s: %v", userID, org, err)
+		return
+	}
+	for _, e := range enrollments {
+		if e.Estado != "confirmada" {
+			continue
+		}
+		err := s.RecordEnrollment(ctx, fmt.Sprintf("%d", e.ID), userID, fmt.Sprintf("%d", e.ActivityID))
+		if err != nil {
+			log.Printf("[recommendations] WARN: seed user=%s enrollment=%d: %v", userID, e.ID, err)
+			return
+		}
+	}
+	if err := s.store.MarkSeeded(userID, org); err != nil {
+		log.Printf("[recommendations] WARN: mark seeded user=%s org=%s: %v", userID, org, err)
+	}
+}
+
+// attrs usa los atributos ya conocidos de la actividad o los pide a activities-api
+func (s *RecommendationService) attrs(ctx context.Context, activityID string) (domain.ActivityAttrs, error) {
+	if a, ok := s.store.Attrs(activityID); ok {
+		return a, nil
+	}
+	doc, err := s.activities.SearchDoc(ctx, activityID)
+	if err != nil {
+		return domain.ActivityAttrs{}, err
+	}
+	return domain.ActivityAttrs{Sport: doc.Sport, Site: doc.Site, Instructor: doc.Instructor}, nil
+}
+
+// nextSessions busca en paralelo la próxima sesión de cada recomendación (nil
+// si no tiene sesiones futuras)
+func (s *RecommendationService) nextSessions(ctx context.Context, recs []domain.Recommendation) []*domain.Session {
+	out := make([]*domain.Session, len(recs))
+	sem := make(chan struct{}, sessionFetchers)
+	var wg sync.WaitGroup
+	for i, rec := range recs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, activityID string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if next, ok := s.nextSession(ctx, activityID); ok {
+				out[i] = &next
+			}
+		}(i, rec.Activity.ActivityID)
+	}
+	wg.Wait()
+	return out
+}
+
+// activitySessions devuelve las sesiones de la actividad, cacheadas sessionsTTL
+func (s *RecommendationService) activitySessions(ctx context.Context, activityID string) ([]domain.Session, error) {
+	key := repository.TenantFrom(ctx) + "|" + activityID
+	now := s.now()
+	s.mu.Lock()
+	c, ok := s.sessions[key]
+	s.mu.Unlock()
+	if ok && now.Sub(c.at) < sessionsTTL {
+		return c.sessions, nil
+	}
+	sessions, err := s.activities.Sessions(ctx, activityID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// se limpian las vencidas para que el mapa no crezca sin límite
+	for k, v := range s.sessions {
+		if now.Sub(v.at) >= sessionsTTL {
+			delete(s.sessions, k)
+		}
+	}
+	s.sessions[key] = cachedSessions{sessions: sessions, at: now}
+	return sessions, nil
+}
+
+// nextSession devuelve la próxima sesión futura de la actividad, si existe
+func (s *RecommendationService) nextSession(ctx context.Context, activityID string) (domain.Session, bool) {
+	sessions, err := s.activitySessions(ctx, activityID)
+	if err != nil {
+		return domain.Session{}, false
+	}
+	now := s.now()
+	today, clock := now.Format("2006-01-02"), now.Format("15:04")
+
+	var next domain.Session
+	found := false
+	for _, ses := range sessions {
+		// fecha YYYY-MM-DD y hora HH:mm se comparan bien como strings
+		if ses.Fecha < today || (ses.Fecha == today && ses.Inicio <= clock) {
+			continue
+		}
+		if !found || ses.Fecha < next.Fecha || (ses.Fecha == next.Fecha && ses.Inicio < next.Inicio) {
+			next, found = ses, true
+		}
+	}
+	return next, found
+}
+
+// share es el peso relativo de key dentro de las afinidades del usuario
+func share(weights map[string]float64, key string) float64 {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		return 0
+	}
+	return weights[key] / total
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sporthub/search-api/internal/domain"
+	"github.com/sporthub/search-api/internal/repository"
+)
+
+// ErrSavedSearchNotFound es el mismo error que devuelven los stores
+var ErrSavedSearchNotFound = repository.ErrSavedSearchNotFound
+
+// SearchMatchRoutingKey es la routing key de las alertas de búsquedas guardadas
+const SearchMatchRoutingKey = "search.match"
+
+// SavedSearchStore guarda las búsquedas guardadas y los pares ya notificados
+type SavedSearchStore interface {
+	Create(ss *domain.SavedSearch) error
+	Get(id string) (*domain.SavedSearch, error)
+	Update(ss *domain.SavedSearch) error
+	Delete(id string) error
+	ListByUser(userID string) ([]domain.SavedSearch, error)
+	All() ([]domain.SavedSearch, error)
+	// MarkNotified registra el par búsqueda/actividad (false si ya estaba);
+	// UnmarkNotified lo borra si la alerta no se pudo publicar
+	MarkNotified(searchID, activityID string) (bool, error)
+	UnmarkNotified(searchID, activityID string) error
+}
+
+type eventPublisher interface {
+	Publish(routing string, payload any) error
+}
+
+type SavedSearchService struct {
+	store       SavedSearchStore
+	pub         eventPublisher
+	alertsPerHr int
+	mu          sync.Mutex
+	sent        map[string][]time.Time // userId -> alertas enviadas en la última hora
+	now         func() time.Time
+}
+
+// NewSavedSearchService crea el servicio. alertsPerHour limita cuántas
+// alertas search.match recibe cada usuario por hora (0 = sin límite).
+func NewSavedSearchService(st SavedSearchStore, pub eventPublisher, alertsPerHour int) *SavedSearchService {
+	return &SavedSearchService{
+		store:       st,
+		pub:         pub,
+		alertsPerHr: alertsPerHour,
+		sent:        map[string][]time.Time{},
+		now:         time.Now,
+	}
+}
+
+func (s *SavedSearchService) Create(userID string, ss domain.SavedSearch) (*domain.SavedSearch, error) {
+	now := s.now()
+	ss.UserID = userID
+	ss.CreatedAt, ss.UpdatedAt = now, now
+	if err := s.store.Create(&ss); err != nil {
+		return nil, err
+	}
+	return &ss, nil
+}
+
+func (s *SavedSearchService) List(userID string) ([]domain.SavedSearch, error) {
+	return s.store.ListByUser(userID)
+}
+
+// Get devuelve la búsqueda solo si pertenece al usuario
+func (s *SavedSearchService) Get(userID, id string) (*domain.SavedSearch, error) {
+	ss, err := s.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if ss.UserID != userID {
+		return nil, ErrSavedSearchNotFound
+	}
+	return ss, nil
+}
+
+func (s *SavedSearchService) Update(userID, id string, in domain.SavedSearch) (*domain.SavedSearch, error) {
+	ss, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	ss.Name, ss.Query, ss.Sport, ss.Site, ss.MaxPrice = in.Name, in.Query, in.Sport, in.Site, in.MaxPrice
+	ss.UpdatedAt = s.now()
+	if err := s.store.Update(ss); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+func (s *SavedSearchService) Delete(userID, id string) error {
+	if _, err := s.Get(userID, id); err != nil {
+		return err
+	}
+	return s.store.Delete(id)
+}
+
+// Evaluate compara un documento recién indexado contra todas las búsquedas
+// guardadas y publica un search.match por cada coincidencia. Cada par
+// búsqueda/actividad se notifica una sola vez y cada usuario recibe como
+// máximo alertsPerHr alertas por hora. Si la publicación falla se devuelve el
+// cupo y el par queda sin notificar: se avisa cuando la actividad se reindexe.
+func (s *SavedSearchService) Evaluate(doc domain.SearchDoc) {
+	all, err := s.store.All()
+	if err != nil {
+		log.Printf("[saved-searches] ERROR: load saved searches: %v", err)
+		return
+	}
+	for _, ss := range all {
+		if !Matches(ss, doc) {
+			continue
+		}
+		sentAt, ok := s.reserve(ss, doc.ActivityID)
+		if !ok {
+			continue
+		}
+		err := s.pub.Publish(SearchMatchRoutingKey, domain.SearchMatch{
+			UserID:          ss.UserID,
+			SavedSearchID:   ss.ID,
+			SavedSearchName: ss.Name,
+			ActivityID:      doc.ActivityID,
+			ActivityName:    doc.Name,
+			Sport:           doc.Sport,
+			Site:            doc.Site,
+			Price:           doc.Price,
+			Timestamp:       sentAt,
+		})
+		if err != nil {
+			log.Printf("[saved-searches] ERROR: publish search.match user=%s search=%s: %v", ss.UserID, ss.ID, err)
+			s.release(ss, doc.ActivityID, sentAt)
+			continue
+		}
+		log.Printf("[saved-searches] match user=%s search=%s activity=%s", ss.UserID, ss.ID, doc.ActivityID)
+	}
+}
+
+// reserve aplica el rate limit por usuario y la deduplicación, y reserva el
+// cupo de la alerta. Si la publicación falla se devuelve con release.
+func (s *SavedSearchService) reserve(ss domain.SavedSearch, activityID string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	recent := s.sent[ss.UserID][:0]
+	for _, t := range s.sent[ss.UserID] {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	if s.alertsPerHr > 0 && len(recent) >= s.alertsPerHr {
+		s.sent[ss.UserID] = recent
+		log.Printf("[saved-searches] rate limit reached for user %s, skipping alert", ss.UserID)
+		return time.Time{}, false
+	}
+	first, err := s.store.MarkNotified(ss.ID, activityID)
+	if err != nil {
+		log.Printf("[saved-searches] ERROR: mark notified search=%s activity=%s: %v", ss.ID, activityID, err)
+	}
+	if err != nil || !first {
+		if len(recent) == 0 {
+			delete(s.sent, ss.UserID)
+		} else {
+			s.sent[ss.UserID] = recent
+		}
+		return time.Time{}, false
+	}
+	s.sent[ss.UserID] = append(recent, now)
+	return now, true
+}
+
+// release devuelve el cupo reservado por reserve
+func (s *SavedSearchService) release(ss domain.SavedSearch, activityID string, sentAt time.Time) {
+	if err := s.store.UnmarkNotified(ss.ID, activityID); err != nil {
+		log.Printf("[saved-searches] ERROR: unmark notified search=%s activity=%s: %v", ss.ID, activityID, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sent := s.sent[ss.UserID]
+	for i, t := range sent {
+		if t.Equal(sentAt) {
+			s.sent[ss.UserID] = append(sent[:i], sent[i+1:]...)
+			break
+		}
+	}
+	if len(s.sent[ss.UserID]) == 0 {
+		delete(s.sent, ss.UserID)
+	}
+}
+
+// Matches indica si el documento cumple la búsqueda guardada: mismo deporte y
+// sede (sin distinguir mayúsculas), precio dentro del máximo y todas las
+// palabras de la query presentes en nombre, deporte, sede o instructor.
+func Matches(ss domain.SavedSearch, doc domain.SearchDoc) bool {
+	if ss.OrgID != "" && ss.OrgID != doc.OrgID {
+		return false
+	}
+	if ss.Sport != "" && !strings.EqualFold(ss.Sport, doc.Sport) {
+		return false
+	}
+	if ss.Site != "" && !strings.EqualFold(ss.Site, doc.Site) {
+		return false
+	}
+	if ss.MaxPrice > 0 && doc.Price > ss.MaxPrice {
+		return false
+	}
+	words := map[string]struct{}{}
+	for _, f := range []string{doc.Name, doc.Sport, doc.Site, doc.Instructor} {
+		for _, w := range strings.Fields(strings.ToLower(f)) {
+			words[w] = struct{}{}
+		}
+	}
+	for _, w := range strings.Fields(strings.ToLower(ss.Query)) {
+		if _, ok := words[w]; !ok {
+			return false
+		}
+	}
+	return true
+}
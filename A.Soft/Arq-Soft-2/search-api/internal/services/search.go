@@ -8,10 +8,12 @@ import (
 	"time"
 
 	"github.com/sporthub/search-api/internal/domain"
+	"github.com/sporthub/search-api/internal/repository"
 )
 
 type solrRepo interface {
 	Search(ctx context.Context, q, sport, site, date, sort string, page, size int) (*domain.Result, error)
+	Related(ctx context.Context, activityID, sport, site, date string, size int) (*domain.Result, error)
 }
 
 type localCache interface {
@@ -25,42 +27,95 @@ type distCache interface {
 	Delete(key string)
 }
 
+// searchRecorder recibe cada búsqueda ejecutada (analytics)
+type searchRecorder interface {
+	Record(ev domain.SearchEvent)
+}
+
 type Service struct {
 	repo solrRepo
 	lc   localCache
 	dc   distCache
 	ttl  time.Duration
+	rec  searchRecorder
 }
 
-func NewSearchService(r solrRepo, lc localCache, dc distCache, ttl time.Duration) *Service {
-	return &Service{repo: r, lc: lc, dc: dc, ttl: ttl}
+// NewSearchService crea el servicio de búsqueda. rec puede ser nil si no se
+// quieren registrar las búsquedas.
+func NewSearchService(r solrRepo, lc localCache, dc distCache, ttl time.Duration, rec searchRecorder) *Service {
+	return &Service{repo: r, lc: lc, dc: dc, ttl: ttl, rec: rec}
 }
 
 func (s *Service) Search(ctx context.Context, q, sport, site, date, sort string, page, size int) (*domain.Result, error) {
-	key := s.key(q, sport, site, date, sort, page, size)
+	start := time.Now()
+	res, tier, err := s.search(ctx, q, sport, site, date, sort, page, size)
+	if s.rec != nil {
+		ev := domain.SearchEvent{
+			OrgID: repository.TenantFrom(ctx), Query: q, Sport: sport, Site: site, Date: date, Sort: sort,
+			Page: page, Size: size, CacheTier: tier, Failed: err != nil,
+			LatencyMs: time.Since(start).Milliseconds(), At: start,
+		}
+		if res != nil {
+			ev.Results = res.Total
+		}
+		s.rec.Record(ev)
+	}
+	return res, err
+}
+
+// search resuelve la búsqueda y devuelve además el nivel de caché que respondió
+func (s *Service) search(ctx context.Context, q, sport, site, date, sort string, page, size int) (*domain.Result, string, error) {
+	key := s.key(repository.TenantFrom(ctx), q, sport, site, date, sort, page, size)
 
 	// 1) local cache
 	if v := s.lc.Get(key); v != nil {
 		if res, ok := v.(*domain.Result); ok {
-			return res, nil
+			return res, domain.CacheTierLocal, nil
 		}
 	}
 	// 2) distributed cache
 	var cached domain.Result
 	if s.dc.Get(key, &cached) {
 		s.lc.Set(key, &cached, s.ttl)
-		return &cached, nil
+		return &cached, domain.CacheTierDistributed, nil
 	}
 
 	// 3) Solr
 	res, err := s.repo.Search(ctx, q, sport, site, date, sort, page, size)
 	if err != nil {
-		return nil, err
+		return nil, domain.CacheTierSolr, err
 	}
 
 	// 4) save caches
 	s.lc.Set(key, res, s.ttl)
 	s.dc.Set(key, res, s.ttl)
+	return res, domain.CacheTierSolr, nil
+}
+
+// Related devuelve actividades similares a activityID, con el mismo esquema de
+// caché local -> distribuida -> Solr que Search.
+func (s *Service) Related(ctx context.Context, activityID, sport, site, date string, size int) (*domain.Result, error) {
+	raw := fmt.Sprintf("%s|%s|%s|%s|%s|%d", repository.TenantFrom(ctx), activityID, sport, site, date, size)
+	h := sha1.Sum([]byte(raw))
+	key := "rel:" + hex.EncodeToString(h[:])
+
+	if v := s.lc.Get(key); v != nil {
+		if res, ok := v.(*domain.Result); ok {
+			return res, nil
+		}
+	}
+	var cached domain.Result
+	if s.dc.Get(key, &cached) {
+		s.lc.Set(key, &cached, s.ttl)
+		return &cached, nil
+	}
+
+	res, err := s.repo.Related(ctx, activityID, sport, site, date, size)
+	if err != nil {
+		return nil, err
+	}
+	s.lc.Set(key, res, s.ttl)
+	s.dc.Set(key, res, s.ttl)
 	return res, nil
 }
 
@@ -69,8 +124,9 @@ func (s *Service) Bust(key string) {
 	s.dc.Delete(key)
 }
 
-func (s *Service) key(q, sport, site, date, sort string, page, size int) string {
-	raw := fmt.Sprintf("%s|%s|%s|%s|%s|%d|%d", q, sport, site, date, sort, page, size)
+// key incluye la organización: cada club tiene sus propios resultados cacheados
+func (s *Service) key(org, q, sport, site, date, sort string, page, size int) string {
+	raw := fmt.Sprintf("%s|%s|%s|%s|%s|%s|%d|%d", org, q, sport, site, date, sort, page, size)
 	h := sha1.Sum([]byte(raw))
 	return "q:" + hex.EncodeToString(h[:])
 }
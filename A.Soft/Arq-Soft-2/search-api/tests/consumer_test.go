@@ -0,0 +1,329 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/sporthub/search-api/internal/clients"
+	"github.com/sporthub/search-api/internal/consumers"
+	"github.com/sporthub/search-api/internal/domain"
+	"github.com/sporthub/search-api/internal/repository"
+	"github.com/sporthub/search-api/internal/services"
+)
+
+// fakeAcks registra qué se hizo con cada entrega (por delivery tag)
+type fakeAcks struct {
+	mu  sync.Mutex
+	got map[uint64]string
+}
+
+func newFakeAcks() *fakeAcks { return &fakeAcks{got: map[uint64]string{}} }
+
+func (a *fakeAcks) set(tag uint64, v string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.got[tag] = v
+	return nil
+}
+
+func (a *fakeAcks) Ack(tag uint64, _ bool) error { return a.set(tag, "ack") }
+func (a *fakeAcks) Nack(tag uint64, _ bool, requeue bool) error {
+	if requeue {
+		return a.set(tag, "requeue")
+	}
+	return a.set(tag, "nack")
+}
+func (a *fakeAcks) Reject(tag uint64, _ bool) error { return a.set(tag, "reject") }
+
+func (a *fakeAcks) of(tag uint64) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.got[tag]
+}
+
+// recordingBackend anota el orden de las escrituras sobre el índice embebido
+type recordingBackend struct {
+	*repository.MemoryIndex
+	mu  sync.Mutex
+	ops []string
+}
+
+func (r *recordingBackend) Upsert(ctx context.Context, docs ...domain.SearchDoc) error {
+	r.mu.Lock()
+	for _, d := range docs {
+		r.ops = append(r.ops, "upsert:"+d.ActivityID)
+	}
+	r.mu.Unlock()
+	return r.MemoryIndex.Upsert(ctx, docs...)
+}
+
+func (r *recordingBackend) DeleteByID(ctx context.Context, id string) error {
+	r.mu.Lock()
+	r.ops = append(r.ops, "delete:"+id)
+	r.mu.Unlock()
+	return r.MemoryIndex.DeleteByID(ctx, id)
+}
+
+func (r *recordingBackend) opsFor(id string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []string
+	for _, op := range r.ops {
+		if strings.HasSuffix(op, ":"+id) {
+			out = append(out, op)
+		}
+	}
+	return out
+}
+
+// fakeDLQ guarda las entregas que le llegan (o falla si err no es nil)
+type fakeDLQ struct {
+	mu   sync.Mutex
+	err  error
+	tags []uint64
+}
+
+func (d *fakeDLQ) DeadLetter(_ context.Context, m amqp.Delivery, _ error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	d.tags = append(d.tags, m.DeliveryTag)
+	return nil
+}
+
+// activitiesServer sirve search-docs; las actividades de fails fallan esa
+// cantidad de veces antes de responder (-1: siempre)
+func activitiesServer(t *testing.T, fails map[string]int) (*clients.ActivitiesClient, func(string) int) {
+	t.Helper()
+	var mu sync.Mutex
+	calls := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Split(strings.TrimPrefix(r.URL.Path, "/activities/"), "/")[0]
+		mu.Lock()
+		calls[id]++
+		n := calls[id]
+		mu.Unlock()
+		if f, ok := fails[id]; ok && (f < 0 || n <= f) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(domain.SearchDoc{ID: id, ActivityID: id, Name: "Actividad " + id, StartAt: "2030-01-01T10:00:00Z"})
+	}))
+	t.Cleanup(srv.Close)
+	return clients.NewActivitiesClient(srv.URL), func(id string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls[id]
+	}
+}
+
+func delivery(acks *fakeAcks, tag uint64, op, activityID string) amqp.Delivery {
+	body, _ := json.Marshal(consumers.Event{Op: op, ActivityID: activityID})
+	return amqp.Delivery{Acknowledger: acks, DeliveryTag: tag, Body: body}
+}
+
+func newTestConsumer(backend repository.SearchBackend, activities *clients.ActivitiesClient, workers int, delays ...time.Duration) *consumers.Consumer {
+	c := consumers.NewConsumer(backend, repository.NewLocalCache(100), repository.NewMemcached(""), activities, nil, workers, 10)
+	c.SetRetryDelays(delays...)
+	return c
+}
+
+// server es el Serve de Consumer y de EnrollmentConsumer
+type server interface {
+	Serve(ctx context.Context, msgs <-chan amqp.Delivery, dl consumers.DeadLetterer)
+}
+
+// serve corre Serve con las entregas dadas y espera a que termine
+func serve(t *testing.T, ctx context.Context, c server, dl consumers.DeadLetterer, ds ...amqp.Delivery) {
+	t.Helper()
+	msgs := make(chan amqp.Delivery, len(ds))
+	for _, d := range ds {
+		msgs <- d
+	}
+	close(msgs)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Serve(ctx, msgs, dl)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return")
+	}
+}
+
+func TestConsumerKeepsOrderPerActivityWhileRetrying(t *testing.T) {
+	// el primer fetch de la actividad 1 falla: el delete que viene detrás
+	// tiene que esperar a que el update se reintente
+	activities, calls := activitiesServer(t, map[string]int{"1": 1})
+	backend := &recordingBackend{MemoryIndex: repository.NewMemoryIndex()}
+	c := newTestConsumer(backend, activities, 4, 10*time.Millisecond)
+	acks := newFakeAcks()
+
+	serve(t, context.Background(), c, &fakeDLQ{},
+		delivery(acks, 1, "update", "1"),
+		delivery(acks, 2, "update", "2"),
+		delivery(acks, 3, "delete", "1"),
+		delivery(acks, 4, "update", "3"),
+	)
+
+	if got := backend.opsFor("1"); fmt.Sprint(got) != "[upsert:1 delete:1]" {
+		t.Fatalf("activity 1 ops out of order: %v", got)
+	}
+	if calls("1") != 2 {
+		t.Fatalf("expected the failed fetch to be retried once, got %d calls", calls("1"))
+	}
+	for tag := uint64(1); tag <= 4; tag++ {
+		if got := acks.of(tag); got != "ack" {
+			t.Fatalf("delivery %d: expected ack, got %q", tag, got)
+		}
+	}
+	res, _ := backend.Search(context.Background(), "", "", "", "", "", 1, 10)
+	if res.Total != 2 {
+		t.Fatalf("expected activities 2 and 3 indexed, got %d docs", res.Total)
+	}
+}
+
+func TestConsumerDeadLettersAfterRetries(t *testing.T) {
+	activities, calls := activitiesServer(t, map[string]int{"1": -1})
+	c := newTestConsumer(repository.NewMemoryIndex(), activities, 1, time.Millisecond, time.Millisecond)
+	acks := newFakeAcks()
+	dlq := &fakeDLQ{}
+
+	serve(t, context.Background(), c, dlq,
+		delivery(acks, 1, "update", "1"),
+		amqp.Delivery{Acknowledger: acks, DeliveryTag: 2, Body: []byte("{not json")},
+	)
+
+	if calls("1") != 3 {
+		t.Fatalf("expected 1 attempt + 2 retries, got %d", calls("1"))
+	}
+	if fmt.Sprint(dlq.tags) != "[1 2]" {
+		t.Fatalf("expected both deliveries in the dlq, got %v", dlq.tags)
+	}
+	if acks.of(1) != "ack" || acks.of(2) != "ack" {
+		t.Fatalf("dead-lettered deliveries must leave the queue, got %q %q", acks.of(1), acks.of(2))
+	}
+
+	// si la dlq no está disponible el evento vuelve a la cola, no se pierde
+	acks = newFakeAcks()
+	serve(t, context.Background(), c, &fakeDLQ{err: errors.New("dlq down")}, delivery(acks, 1, "update", "1"))
+	if got := acks.of(1); got != "requeue" {
+		t.Fatalf("expected requeue when the dlq fails, got %q", got)
+	}
+}
+
+func TestConsumerDrainsOnShutdown(t *testing.T) {
+	activities, _ := activitiesServer(t, map[string]int{"9": -1})
+	backend := &recordingBackend{MemoryIndex: repository.NewMemoryIndex()}
+	c := newTestConsumer(backend, activities, 3, time.Hour)
+	acks := newFakeAcks()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var ds []amqp.Delivery
+	for i := 1; i <= 12; i++ {
+		ds = append(ds, delivery(acks, uint64(i), "update", fmt.Sprint(i)))
+	}
+	// la actividad 9 falla: con el shutdown en curso no se espera el
+	// reintento, vuelve a la cola junto con lo que le sigue de esa actividad
+	ds = append(ds, delivery(acks, 13, "delete", "9"))
+	dlq := &fakeDLQ{}
+	serve(t, ctx, c, dlq, ds...)
+
+	// ninguna entrega se pierde: se procesa o vuelve a la cola (las que le
+	// tocan al worker de la actividad 9 detrás de ella también vuelven)
+	for i := 1; i <= 13; i++ {
+		if got := acks.of(uint64(i)); got != "ack" && got != "requeue" {
+			t.Fatalf("delivery %d: expected ack or requeue, got %q", i, got)
+		}
+	}
+	if acks.of(9) != "requeue" || acks.of(13) != "requeue" {
+		t.Fatalf("the failing event and the later one of its activity must be requeued, got %q %q", acks.of(9), acks.of(13))
+	}
+	if ops := backend.opsFor("9"); len(ops) != 0 {
+		t.Fatalf("activity 9 must not be touched out of order, got %v", ops)
+	}
+	if len(dlq.tags) != 0 {
+		t.Fatalf("nothing should be dead-lettered on shutdown, got %v", dlq.tags)
+	}
+}
+
+func TestConsumerReindexesTheActivityOnSessionEvents(t *testing.T) {
+	activities, calls := activitiesServer(t, nil)
+	backend := &recordingBackend{MemoryIndex: repository.NewMemoryIndex()}
+	c := newTestConsumer(backend, activities, 1)
+	acks := newFakeAcks()
+
+	// la baja de una sesión no borra la actividad: se vuelve a pedir su doc
+	body, _ := json.Marshal(consumers.Event{Op: "delete", ActivityID: "5", SessionID: "12"})
+	serve(t, context.Background(), c, &fakeDLQ{}, amqp.Delivery{Acknowledger: acks, DeliveryTag: 1, Body: body})
+
+	if got := backend.opsFor("5"); fmt.Sprint(got) != "[upsert:5]" || calls("5") != 1 {
+		t.Fatalf("expected activity 5 to be reindexed, got ops %v (%d fetches)", got, calls("5"))
+	}
+	if acks.of(1) != "ack" {
+		t.Fatalf("expected ack, got %q", acks.of(1))
+	}
+}
+
+func enrollmentDelivery(acks *fakeAcks, tag uint64, op string, id, activityID uint64) amqp.Delivery {
+	body, _ := json.Marshal(consumers.EnrollmentEvent{Op: op, ID: id, ActivityID: activityID, UserID: "7", OrgID: "1"})
+	return amqp.Delivery{Acknowledger: acks, DeliveryTag: tag, Body: body}
+}
+
+func TestEnrollmentConsumerAcksOnlyWhatWasSaved(t *testing.T) {
+	// el search-doc de la actividad 1 falla una vez; el de la 2, siempre
+	activities, calls := activitiesServer(t, map[string]int{"1": 1, "2": -1})
+	store := repository.NewAffinityStore()
+	c := consumers.NewEnrollmentConsumer(services.NewRecommendationService(store, &fakeSolr{}, activities))
+	c.SetRetryDelays(time.Millisecond, time.Millisecond)
+	acks := newFakeAcks()
+	dlq := &fakeDLQ{}
+
+	serve(t, context.Background(), c, dlq,
+		enrollmentDelivery(acks, 1, "enroll", 10, 1),
+		enrollmentDelivery(acks, 2, "enroll", 20, 2),
+		amqp.Delivery{Acknowledger: acks, DeliveryTag: 3, Body: []byte("{not json")},
+		// repetida: no suma dos veces
+		enrollmentDelivery(acks, 4, "enroll", 10, 1),
+	)
+
+	if calls("1") != 2 || calls("2") != 3 {
+		t.Fatalf("expected a retry for activity 1 and 1 attempt + 2 retries for activity 2, got %d and %d", calls("1"), calls("2"))
+	}
+	if fmt.Sprint(dlq.tags) != "[2 3]" {
+		t.Fatalf("expected the failing and the malformed events in the dlq, got %v", dlq.tags)
+	}
+	for tag := uint64(1); tag <= 4; tag++ {
+		if acks.of(tag) != "ack" {
+			t.Fatalf("delivery %d: expected ack, got %q", tag, acks.of(tag))
+		}
+	}
+	if pop, _ := store.Popularity(); fmt.Sprint(pop) != "map[1:1]" {
+		t.Fatalf("expected only activity 1 enrolled once, got %v", pop)
+	}
+
+	// durante el apagado el evento que falla y los siguientes vuelven a la cola
+	c.SetRetryDelays(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	acks = newFakeAcks()
+	serve(t, ctx, c, dlq, enrollmentDelivery(acks, 1, "enroll", 30, 2), enrollmentDelivery(acks, 2, "cancel", 10, 1))
+	if acks.of(1) != "requeue" || acks.of(2) != "requeue" {
+		t.Fatalf("expected both deliveries requeued on shutdown, got %q %q", acks.of(1), acks.of(2))
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/sporthub/search-api/internal/middleware"
+	"github.com/sporthub/shared/authz"
+	"github.com/sporthub/shared/jwks"
+)
+
+// jwksServer publica la clave pública de key como lo hace users-api
+func jwksServer(t *testing.T, key *rsa.PrivateKey, kid string) *httptest.Server {
+	t.Helper()
+	enc := base64.RawURLEncoding
+	body := gin.H{"keys": []gin.H{{
+		"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+		"n": enc.EncodeToString(key.N.Bytes()),
+		"e": enc.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) { c.JSON(http.StatusOK, body) })
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = kid
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestJWTAuthValidatesWithJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := jwksServer(t, key, "k1")
+
+	r := gin.New()
+	auth := middleware.JWTAuth(jwks.New(srv.URL+"/.well-known/jwks.json", time.Minute), "users-api", "sporthub")
+	r.GET("/me", auth, func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"userId": c.GetUint64("userId")}) })
+
+	exp := time.Now().Add(time.Minute).Unix()
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"valid", signToken(t, key, "k1", jwt.MapClaims{"sub": 7, "rol": "user", "iss": "users-api", "aud": "sporthub", "exp": exp}), http.StatusOK},
+		{"wrong audience", signToken(t, key, "k1", jwt.MapClaims{"sub": 7, "iss": "users-api", "aud": "other", "exp": exp}), http.StatusUnauthorized},
+		{"wrong issuer", signToken(t, key, "k1", jwt.MapClaims{"sub": 7, "iss": "evil", "aud": "sporthub", "exp": exp}), http.StatusUnauthorized},
+		{"unknown key", signToken(t, other, "k2", jwt.MapClaims{"sub": 7, "iss": "users-api", "aud": "sporthub", "exp": exp}), http.StatusUnauthorized},
+		{"hs256", func() string {
+			s, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": 7, "iss": "users-api", "aud": "sporthub", "exp": exp}).SignedString([]byte("change_me"))
+			return s
+		}(), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSearchReportsRequireSearchReindex(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := jwksServer(t, key, "k1")
+
+	r := gin.New()
+	auth := middleware.JWTAuth(jwks.New(srv.URL+"/.well-known/jwks.json", time.Minute), "users-api", "sporthub")
+	r.GET("/admin/search/top", auth, authz.RequirePermission(authz.PermSearchReindex), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	exp := time.Now().Add(time.Minute).Unix()
+	base := func(extra jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{"sub": 7, "iss": "users-api", "aud": "sporthub", "exp": exp}
+		for k, v := range extra {
+			claims[k] = v
+		}
+		return claims
+	}
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   int
+	}{
+		{"permission in perms", base(jwt.MapClaims{"rol": "user", "perms": []string{authz.PermSearchReindex}}), http.StatusOK},
+		// el rol solo no alcanza: manda el claim perms
+		{"admin role without the permission", base(jwt.MapClaims{"rol": "admin", "perms": []string{authz.PermUserRead}}), http.StatusForbidden},
+		{"instructor", base(jwt.MapClaims{"rol": "instructor", "perms": authz.PermissionsFor(authz.RoleInstructor)}), http.StatusForbidden},
+		// tokens sin perms: los permisos de su rol, igual que en los otros servicios
+		{"old admin token without perms", base(jwt.MapClaims{"rol": "admin"}), http.StatusOK},
+		{"old user token without perms", base(jwt.MapClaims{"rol": "user"}), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/search/top", nil)
+			req.Header.Set("Authorization", "Bearer "+signToken(t, key, "k1", tt.claims))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/search-api/internal/domain"
+	"github.com/sporthub/search-api/internal/middleware"
+	"github.com/sporthub/search-api/internal/repository"
+)
+
+func seedIndex(t *testing.T, idx repository.SearchBackend) {
+	t.Helper()
+	err := idx.Upsert(context.Background(),
+		domain.SearchDoc{ID: "1", ActivityID: "1", Name: "Futbol 5", Sport: "Futbol", Site: "Sede Norte", Price: 3000},
+		domain.SearchDoc{ID: "2", ActivityID: "2", Name: "Futbol 7", Sport: "Futbol", Site: "Sede Sur", Price: 4000},
+		domain.SearchDoc{ID: "3", ActivityID: "3", Name: "Yoga matutino", Sport: "Yoga", Site: "Sede Norte", Price: 2000},
+	)
+	if err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+}
+
+func TestMemoryIndexSearch(t *testing.T) {
+	idx := repository.NewMemoryIndex()
+	seedIndex(t, idx)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		q    string
+		site string
+		sort string
+		want []string
+	}{
+		{"single word matches whole terms", "futbol", "", "price_f asc", []string{"1", "2"}},
+		// como en Solr no hay prefijos: "fut" no es un término indexado
+		{"no prefix match", "fut", "", "", nil},
+		{"all words must match", "futbol 5", "", "", []string{"1"}},
+		{"case insensitive", "YOGA", "", "", []string{"3"}},
+		{"site filter", "", "Sede Norte", "price_f desc", []string{"1", "3"}},
+		{"no match", "natacion", "", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := idx.Search(ctx, tt.q, "", tt.site, "", tt.sort, 1, 10)
+			if err != nil {
+				t.Fatalf("Search() error: %v", err)
+			}
+			if res.Total != len(tt.want) {
+				t.Fatalf("expected %d results, got %d", len(tt.want), res.Total)
+			}
+			for i, id := range tt.want {
+				if res.Docs[i].ID != id {
+					t.Errorf("result %d: expected id %s, got %s", i, id, res.Docs[i].ID)
+				}
+			}
+		})
+	}
+
+	if err := idx.DeleteByID(ctx, "1"); err != nil {
+		t.Fatalf("DeleteByID() error: %v", err)
+	}
+	res, _ := idx.Search(ctx, "futbol", "", "", "", "", 1, 10)
+	if res.Total != 1 || res.Docs[0].ID != "2" {
+		t.Errorf("unexpected results after delete: %+v", res.Docs)
+	}
+
+	related, _ := idx.Related(ctx, "2", "", "", "", 5)
+	for _, d := range related.Docs {
+		if d.ID == "2" {
+			t.Error("related must exclude the source activity")
+		}
+	}
+}
+
+// downSolr simula un Solr caído: todas las operaciones fallan
+type downSolr struct{ *repository.MemoryIndex }
+
+var errSolrDown = errors.New("solr down")
+
+func (downSolr) Ping(ctx context.Context) error { return errSolrDown }
+func (downSolr) Search(ctx context.Context, q, sport, site, date, sort string, page, size int) (*domain.Result, error) {
+	return nil, errSolrDown
+}
+func (downSolr) Upsert(ctx context.Context, docs ...domain.SearchDoc) error { return errSolrDown }
+
+func TestFailoverUsesEmbeddedIndex(t *testing.T) {
+	fo := repository.NewFailoverRepo(downSolr{repository.NewMemoryIndex()}, repository.NewMemoryIndex(), time.Hour)
+	seedIndex(t, fo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // Run hace un único health check y termina
+	fo.Run(ctx)
+
+	if fo.Active() != repository.BackendMemory {
+		t.Fatalf("expected embedded backend after failed health check, got %s", fo.Active())
+	}
+	res, err := fo.Search(context.Background(), "futbol", "", "", "", "", 1, 10)
+	if err != nil {
+		t.Fatalf("Search() error: %v", err)
+	}
+	if res.Total != 2 {
+		t.Errorf("expected 2 results from embedded index, got %d", res.Total)
+	}
+}
+
+func TestMemoryIndexScopesByOrganization(t *testing.T) {
+	idx := repository.NewMemoryIndex()
+	_ = idx.Upsert(context.Background(),
+		domain.SearchDoc{ID: "1", ActivityID: "1", OrgID: "1", Name: "Futbol 5", Sport: "Futbol"},
+		domain.SearchDoc{ID: "2", ActivityID: "2", OrgID: "7", Name: "Futbol 7", Sport: "Futbol"},
+	)
+
+	res, _ := idx.Search(repository.WithTenant(context.Background(), "7"), "futbol", "", "", "", "", 1, 10)
+	if res.Total != 1 || res.Docs[0].ID != "2" {
+		t.Errorf("org 7 must only see its own activities: %+v", res.Docs)
+	}
+	related, _ := idx.Related(repository.WithTenant(context.Background(), "7"), "1", "", "", "", 5)
+	for _, d := range related.Docs {
+		if d.OrgID != "7" {
+			t.Errorf("related returned an activity of another org: %+v", d)
+		}
+	}
+	if all, _ := idx.Search(context.Background(), "futbol", "", "", "", "", 1, 10); all.Total != 2 {
+		t.Errorf("without a tenant every org is searched, got %d", all.Total)
+	}
+}
+
+func TestDocsWithoutOrgBelongToTheDefaultOrg(t *testing.T) {
+	idx := repository.NewMemoryIndex()
+	_ = idx.Upsert(context.Background(),
+		domain.SearchDoc{ID: "1", ActivityID: "1", Name: "Futbol 5", Sport: "Futbol"}, // indexado antes de org_s
+		domain.SearchDoc{ID: "2", ActivityID: "2", OrgID: "7", Name: "Futbol 7", Sport: "Futbol"},
+	)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Tenant("1"))
+	r.GET("/search", func(c *gin.Context) {
+		res, _ := idx.Search(c.Request.Context(), "futbol", "", "", "", "", 1, 10)
+		ids := []string{}
+		for _, d := range res.Docs {
+			ids = append(ids, d.ID)
+		}
+		c.JSON(http.StatusOK, ids)
+	})
+	search := func(org string) string {
+		req := httptest.NewRequest(http.MethodGet, "/search", nil)
+		if org != "" {
+			req.Header.Set(middleware.TenantHeader, org)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	if got := search(""); got != `["1"]` {
+		t.Errorf("default org should see the activity without org_s, got %s", got)
+	}
+	if got := search("7"); got != `["2"]` {
+		t.Errorf("org 7 should not see the activity without org_s, got %s", got)
+	}
+}
+
+func TestMemoryIndexSortsLikeSolrByDefault(t *testing.T) {
+	idx := repository.NewMemoryIndex()
+	_ = idx.Upsert(context.Background(),
+		domain.SearchDoc{ID: "1", Name: "Futbol 5", StartAt: "2030-01-03T10:00:00Z"},
+		domain.SearchDoc{ID: "2", Name: "Futbol futbol", StartAt: "2030-01-01T10:00:00Z"},
+		domain.SearchDoc{ID: "3", Name: "Futbol 7"},
+		domain.SearchDoc{ID: "4", Name: "Futbol 11", StartAt: "2030-01-02T10:00:00Z"},
+	)
+	// sin sort SolrRepo usa start_dt asc (no score), con las sin fecha al final
+	res, _ := idx.Search(context.Background(), "futbol", "", "", "", "", 1, 10)
+	want := []string{"2", "4", "1", "3"}
+	for i, id := range want {
+		if res.Docs[i].ID != id {
+			t.Fatalf("expected order %v, got %+v", want, res.Docs)
+		}
+	}
+}
+
+// flakySolr es un Solr que se puede tirar y levantar; onUpsert corre antes de
+// cada upsert (para meter escrituras en medio del replay)
+type flakySolr struct {
+	*repository.MemoryIndex
+	down     atomic.Bool
+	onUpsert func(docs []domain.SearchDoc)
+}
+
+func (s *flakySolr) Ping(ctx context.Context) error {
+	if s.down.Load() {
+		return errSolrDown
+	}
+	return nil
+}
+
+func (s *flakySolr) Upsert(ctx context.Context, docs ...domain.SearchDoc) error {
+	if s.down.Load() {
+		return errSolrDown
+	}
+	if s.onUpsert != nil {
+		s.onUpsert(docs)
+	}
+	return s.MemoryIndex.Upsert(ctx, docs...)
+}
+
+func (s *flakySolr) has(t *testing.T, id string) bool {
+	t.Helper()
+	res, err := s.MemoryIndex.Search(context.Background(), "", "", "", "", "", 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range res.Docs {
+		if d.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFailoverDoesNotLoseWritesDuringReplay(t *testing.T) {
+	solr := &flakySolr{MemoryIndex: repository.NewMemoryIndex()}
+	fo := repository.NewFailoverRepo(solr, repository.NewMemoryIndex(), time.Hour)
+	done, cancel := context.WithCancel(context.Background())
+	cancel() // Run hace un único health check y termina
+	ctx := context.Background()
+
+	solr.down.Store(true)
+	fo.Run(done)
+	if err := fo.Upsert(ctx, domain.SearchDoc{ID: "a", Name: "Yoga"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Solr vuelve; mientras se reenvía "a" llega una escritura nueva
+	var wg sync.WaitGroup
+	var once sync.Once
+	solr.onUpsert = func(docs []domain.SearchDoc) {
+		if docs[0].ID != "a" {
+			return
+		}
+		once.Do(func() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := fo.Upsert(ctx, domain.SearchDoc{ID: "b", Name: "Pilates"}); err != nil {
+					t.Error(err)
+				}
+			}()
+			time.Sleep(50 * time.Millisecond)
+		})
+	}
+	solr.down.Store(false)
+	fo.Run(done)
+	wg.Wait()
+
+	if fo.Active() != repository.BackendSolr {
+		t.Fatalf("expected solr to be active again, got %s", fo.Active())
+	}
+	for _, id := range []string{"a", "b"} {
+		if !solr.has(t, id) {
+			t.Errorf("document %s never reached solr", id)
+		}
+	}
+}
+
+func TestFailoverRetriesWritesThatFailedWhileHealthy(t *testing.T) {
+	solr := &flakySolr{MemoryIndex: repository.NewMemoryIndex()}
+	fo := repository.NewFailoverRepo(solr, repository.NewMemoryIndex(), time.Hour)
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// un upsert falla suelto pero el health check sigue bien
+	solr.down.Store(true)
+	_ = fo.Upsert(context.Background(), domain.SearchDoc{ID: "a", Name: "Yoga"})
+	solr.down.Store(false)
+	fo.Run(done)
+
+	if !solr.has(t, "a") {
+		t.Error("the failed write should be replayed on the next health check")
+	}
+}
+
+func TestFailoverLoadsEmbeddedIndexOnceSolrComesUp(t *testing.T) {
+	solr := &flakySolr{MemoryIndex: repository.NewMemoryIndex()}
+	_ = solr.MemoryIndex.Upsert(context.Background(), domain.SearchDoc{ID: "old", Name: "Natacion"})
+	fo := repository.NewFailoverRepo(solr, repository.NewMemoryIndex(), time.Hour)
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Solr caído al arrancar: no hay de dónde cargar el índice embebido
+	solr.down.Store(true)
+	fo.Run(done)
+	// Solr vuelve: se carga en ese chequeo
+	solr.down.Store(false)
+	fo.Run(done)
+	// y se vuelve a caer: el índice embebido tiene lo que había en Solr
+	solr.down.Store(true)
+	fo.Run(done)
+
+	if fo.Active() != repository.BackendMemory {
+		t.Fatalf("expected embedded backend, got %s", fo.Active())
+	}
+	res, err := fo.Search(context.Background(), "natacion", "", "", "", "", 1, 10)
+	if err != nil || res.Total != 1 {
+		t.Errorf("expected the solr document in the embedded index, got %+v, %v", res, err)
+	}
+}
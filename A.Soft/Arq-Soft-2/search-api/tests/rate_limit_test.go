@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/search-api/internal/middleware"
+)
+
+func TestRateLimitIsPerIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/search/click", middleware.RateLimit(2), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	click := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/search/click", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := click("10.0.0.1"); w.Code != http.StatusNoContent {
+			t.Fatalf("click %d: expected 204, got %d", i+1, w.Code)
+		}
+	}
+	w := click("10.0.0.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 after the limit, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+	// otra IP tiene su propio cupo
+	if w := click("10.0.0.2"); w.Code != http.StatusNoContent {
+		t.Errorf("other IP: expected 204, got %d", w.Code)
+	}
+}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sporthub/search-api/internal/domain"
+	"github.com/sporthub/search-api/internal/repository"
+	"github.com/sporthub/search-api/internal/services"
+)
+
+type fakeActivities struct {
+	docs        map[string]domain.SearchDoc
+	sessions    map[string][]domain.Session
+	enrollments map[string][]domain.Enrollment // por usuario
+
+	mu              sync.Mutex
+	sessionCalls    int
+	enrollmentCalls int
+}
+
+func (f *fakeActivities) SearchDoc(_ context.Context, activityID string) (*domain.SearchDoc, error) {
+	d, ok := f.docs[activityID]
+	if !ok {
+		return nil, fmt.Errorf("activity %s not found", activityID)
+	}
+	return &d, nil
+}
+
+func (f *fakeActivities) Sessions(_ context.Context, activityID string) ([]domain.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sessionCalls++
+	return f.sessions[activityID], nil
+}
+
+func (f *fakeActivities) Enrollments(_ context.Context, userID string) ([]domain.Enrollment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.enrollmentCalls++
+	return f.enrollments[userID], nil
+}
+
+func (f *fakeActivities) calls() (sessions, enrollments int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sessionCalls, f.enrollmentCalls
+}
+
+// recommendationFixture arma un servicio con cuatro actividades: 1 y 2 de
+// padel, 3 de yoga y 4 sin sesiones futuras
+func recommendationFixture() (*services.RecommendationService, *fakeActivities, *repository.AffinityStore) {
+	docs := []domain.SearchDoc{
+		{ActivityID: "1", Name: "Padel inicial", Sport: "Padel", Site: "Sede Norte"},
+		{ActivityID: "2", Name: "Padel avanzado", Sport: "Padel", Site: "Sede Sur"},
+		{ActivityID: "3", Name: "Yoga", Sport: "Yoga", Site: "Sede Norte"},
+		{ActivityID: "4", Name: "Natacion", Sport: "Natacion", Site: "Sede Sur"},
+	}
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	activities := &fakeActivities{docs: map[string]domain.SearchDoc{}, sessions: map[string][]domain.Session{
+		"1": {{ID: 10, Fecha: tomorrow, Inicio: "10:00"}},
+		"2": {{ID: 20, Fecha: yesterday, Inicio: "10:00"}, {ID: 21, Fecha: tomorrow, Inicio: "09:00"}},
+		"3": {{ID: 30, Fecha: tomorrow, Inicio: "18:00"}},
+		"4": {{ID: 40, Fecha: yesterday, Inicio: "18:00"}}, // sin sesiones futuras
+	}}
+	for _, d := range docs {
+		activities.docs[d.ActivityID] = d
+	}
+	store := repository.NewAffinityStore()
+	return services.NewRecommendationService(store, &fakeSolr{docs: docs}, activities), activities, store
+}
+
+func TestRecommendations(t *testing.T) {
+	svc, _, _ := recommendationFixture()
+	ctx := context.Background()
+
+	// usuario nuevo: fallback por popularidad (la actividad 3 es la más popular)
+	_ = svc.RecordEnrollment(ctx, "100", "99", "3")
+	recs, strategy, err := svc.ForUser(ctx, "7", 10)
+	if err != nil {
+		t.Fatalf("ForUser() error: %v", err)
+	}
+	if strategy != domain.RecommendationPopularity {
+		t.Errorf("expected popularity strategy, got %s", strategy)
+	}
+	if len(recs) != 3 || recs[0].Activity.ActivityID != "3" {
+		t.Fatalf("unexpected popularity recommendations: %+v", recs)
+	}
+
+	// con historial de padel: se excluye la actividad inscripta y se prioriza padel
+	if err := svc.RecordEnrollment(ctx, "101", "7", "1"); err != nil {
+		t.Fatalf("RecordEnrollment() error: %v", err)
+	}
+	recs, strategy, _ = svc.ForUser(ctx, "7", 10)
+	if strategy != domain.RecommendationAffinity {
+		t.Errorf("expected affinity strategy, got %s", strategy)
+	}
+	if len(recs) != 2 || recs[0].Activity.ActivityID != "2" || recs[0].NextSession.ID != 21 {
+		t.Fatalf("unexpected affinity recommendations: %+v", recs)
+	}
+
+	// al cancelar vuelve a ser un usuario sin historial
+	_ = svc.RecordCancellation(ctx, "101", "7", "1")
+	if _, strategy, _ = svc.ForUser(ctx, "7", 10); strategy != domain.RecommendationPopularity {
+		t.Errorf("expected popularity strategy after cancellation, got %s", strategy)
+	}
+}
+
+func TestRecommendationEventsAreIdempotent(t *testing.T) {
+	svc, _, store := recommendationFixture()
+	ctx := context.Background()
+
+	// la misma inscripción entregada dos veces cuenta una sola vez
+	for i := 0; i < 2; i++ {
+		if err := svc.RecordEnrollment(ctx, "100", "7", "1"); err != nil {
+			t.Fatalf("RecordEnrollment() error: %v", err)
+		}
+	}
+	if pop, _ := store.Popularity(); pop["1"] != 1 {
+		t.Errorf("expected popularity 1 after a repeated event, got %d", pop["1"])
+	}
+
+	// la cancelación llega antes que el alta: el alta atrasada no suma
+	if err := svc.RecordCancellation(ctx, "200", "7", "3"); err != nil {
+		t.Fatalf("RecordCancellation() error: %v", err)
+	}
+	if err := svc.RecordEnrollment(ctx, "200", "7", "3"); err != nil {
+		t.Fatalf("RecordEnrollment() error: %v", err)
+	}
+	profile, _ := store.Profile("7")
+	if profile.Enrolled["3"] != 0 || profile.Sports["Yoga"] != 0 {
+		t.Errorf("a late enrollment for a cancelled one was counted: %+v", profile)
+	}
+
+	// cancelar dos veces resta una sola vez
+	_ = svc.RecordEnrollment(ctx, "300", "8", "1")
+	_ = svc.RecordCancellation(ctx, "100", "7", "1")
+	_ = svc.RecordCancellation(ctx, "100", "7", "1")
+	if pop, _ := store.Popularity(); pop["1"] != 1 {
+		t.Errorf("expected popularity 1 after a repeated cancellation, got %d", pop["1"])
+	}
+}
+
+func TestRecommendationsSeedEnrollmentsFromActivities(t *testing.T) {
+	svc, activities, _ := recommendationFixture()
+	activities.enrollments = map[string][]domain.Enrollment{
+		"7": {{ID: 50, ActivityID: 1, UserID: "7", Estado: "confirmada"}},
+	}
+	ctx := repository.WithTenant(context.Background(), "1")
+
+	// sin eventos: las inscripciones previas salen de activities-api
+	recs, strategy, err := svc.ForUser(ctx, "7", 10)
+	if err != nil {
+		t.Fatalf("ForUser() error: %v", err)
+	}
+	if strategy != domain.RecommendationAffinity || len(recs) != 2 || recs[0].Activity.ActivityID != "2" {
+		t.Fatalf("expected affinity recommendations from the seeded enrollment, got %s %+v", strategy, recs)
+	}
+
+	// se siembra una sola vez por organización; el evento de esa misma
+	// inscripción no vuelve a sumar
+	_ = svc.RecordEnrollment(ctx, "50", "7", "1")
+	if _, _, err := svc.ForUser(ctx, "7", 10); err != nil {
+		t.Fatalf("ForUser() error: %v", err)
+	}
+	if _, n := activities.calls(); n != 1 {
+		t.Errorf("expected enrollments to be fetched once, got %d", n)
+	}
+	_, _, _ = svc.ForUser(repository.WithTenant(context.Background(), "2"), "7", 10)
+	if _, n := activities.calls(); n != 2 {
+		t.Errorf("expected another fetch for a new organization, got %d", n)
+	}
+}
+
+func TestRecommendationsCacheSessions(t *testing.T) {
+	svc, activities, _ := recommendationFixture()
+	ctx := context.Background()
+
+	// limit 1: alcanza con pedir las sesiones de la primera tanda
+	if recs, _, _ := svc.ForUser(ctx, "7", 1); len(recs) != 1 {
+		t.Fatalf("expected 1 recommendation, got %+v", recs)
+	}
+	first, _ := activities.calls()
+	if first != 1 {
+		t.Errorf("expected 1 sessions call for limit 1, got %d", first)
+	}
+
+	// el segundo pedido usa las sesiones cacheadas
+	if recs, _, _ := svc.ForUser(ctx, "7", 10); len(recs) != 3 {
+		t.Fatalf("expected 3 recommendations, got %+v", recs)
+	}
+	_, _, _ = svc.ForUser(ctx, "7", 10)
+	if n, _ := activities.calls(); n != 4 {
+		t.Errorf("expected each activity's sessions to be fetched once, got %d calls", n)
+	}
+}
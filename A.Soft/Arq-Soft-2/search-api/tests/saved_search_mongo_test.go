@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/sporthub/search-api/internal/domain"
+	"github.com/sporthub/search-api/internal/repository"
+)
+
+// mongoForTest abre TEST_MONGO_URI (p.ej. mongodb://localhost:27017) en una
+// base descartable; sin la variable el test se saltea
+func mongoForTest(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("mongo connect: %v", err)
+	}
+	db := client.Database(fmt.Sprintf("search_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		_ = db.Drop(context.Background())
+		_ = client.Disconnect(context.Background())
+	})
+	return db
+}
+
+func TestSavedSearchMongoStore(t *testing.T) {
+	st, err := repository.NewSavedSearchMongo(mongoForTest(t))
+	if err != nil {
+		t.Fatalf("NewSavedSearchMongo() error: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	a := &domain.SavedSearch{UserID: "7", Name: "padel", Query: "padel", CreatedAt: now, UpdatedAt: now}
+	b := &domain.SavedSearch{UserID: "7", Name: "yoga", Query: "yoga", CreatedAt: now.Add(time.Second), UpdatedAt: now}
+	for _, ss := range []*domain.SavedSearch{a, b} {
+		if err := st.Create(ss); err != nil {
+			t.Fatalf("Create() error: %v", err)
+		}
+	}
+	if a.ID == "" || a.ID == b.ID {
+		t.Fatalf("expected distinct ids, got %q and %q", a.ID, b.ID)
+	}
+
+	a.MaxPrice = 5000
+	if err := st.Update(a); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+	got, err := st.Get(a.ID)
+	if err != nil || got.MaxPrice != 5000 || got.UserID != "7" {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+	list, err := st.ListByUser("7")
+	if err != nil || len(list) != 2 || list[0].ID != a.ID {
+		t.Fatalf("ListByUser() = %+v, %v", list, err)
+	}
+
+	if first, err := st.MarkNotified(a.ID, "1"); err != nil || !first {
+		t.Fatalf("MarkNotified() = %v, %v", first, err)
+	}
+	if first, _ := st.MarkNotified(a.ID, "1"); first {
+		t.Fatal("second MarkNotified must report a duplicate")
+	}
+	if err := st.UnmarkNotified(a.ID, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if first, _ := st.MarkNotified(a.ID, "1"); !first {
+		t.Fatal("UnmarkNotified must forget the pair")
+	}
+
+	if err := st.Delete(a.ID); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if _, err := st.Get(a.ID); !errors.Is(err, repository.ErrSavedSearchNotFound) {
+		t.Fatalf("expected ErrSavedSearchNotFound, got %v", err)
+	}
+	if err := st.Delete(a.ID); !errors.Is(err, repository.ErrSavedSearchNotFound) {
+		t.Fatalf("expected ErrSavedSearchNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestAffinityMongoStore(t *testing.T) {
+	st, err := repository.NewAffinityMongo(mongoForTest(t))
+	if err != nil {
+		t.Fatalf("NewAffinityMongo() error: %v", err)
+	}
+	padel := domain.ActivityAttrs{Sport: "Padel", Site: "Sede Norte"}
+
+	// alta repetida: cuenta una vez
+	for i := 0; i < 2; i++ {
+		if err := st.Enroll(domain.TrackedEnrollment{ID: "1", UserID: "7", ActivityID: "10", OrgID: "1", Attrs: padel}); err != nil {
+			t.Fatalf("Enroll() error: %v", err)
+		}
+	}
+	_ = st.Enroll(domain.TrackedEnrollment{ID: "2", UserID: "8", ActivityID: "10", OrgID: "1", Attrs: padel})
+	// cancelación antes del alta: el alta atrasada no suma
+	if err := st.Cancel(domain.TrackedEnrollment{ID: "3", UserID: "7", ActivityID: "11", OrgID: "1"}); err != nil {
+		t.Fatalf("Cancel() error: %v", err)
+	}
+	_ = st.Enroll(domain.TrackedEnrollment{ID: "3", UserID: "7", ActivityID: "11", OrgID: "1", Attrs: domain.ActivityAttrs{Sport: "Yoga"}})
+
+	profile, err := st.Profile("7")
+	if err != nil {
+		t.Fatalf("Profile() error: %v", err)
+	}
+	if fmt.Sprint(profile.Enrolled) != "map[10:1]" || profile.Sports["Padel"] != 1 || profile.Sports["Yoga"] != 0 {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+	if pop, _ := st.Popularity(); fmt.Sprint(pop) != "map[10:2]" {
+		t.Fatalf("unexpected popularity: %v", pop)
+	}
+	if attrs, ok := st.Attrs("10"); !ok || attrs != padel {
+		t.Fatalf("expected known attrs for activity 10, got %+v %v", attrs, ok)
+	}
+	if _, ok := st.Attrs("11"); ok {
+		t.Fatal("a cancellation without its enrollment has no attrs")
+	}
+
+	_ = st.Cancel(domain.TrackedEnrollment{ID: "1", UserID: "7", ActivityID: "10"})
+	if profile, _ := st.Profile("7"); !profile.Empty() {
+		t.Fatalf("expected an empty profile after cancelling, got %+v", profile)
+	}
+
+	if done, _ := st.Seeded("7", "1"); done {
+		t.Fatal("user 7 was not seeded yet")
+	}
+	if err := st.MarkSeeded("7", "1"); err != nil {
+		t.Fatalf("MarkSeeded() error: %v", err)
+	}
+	if done, _ := st.Seeded("7", "1"); !done {
+		t.Fatal("expected user 7 seeded in org 1")
+	}
+	if done, _ := st.Seeded("7", "2"); done {
+		t.Fatal("seeding is per organization")
+	}
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sporthub/shared/rabbit"
+
+	"github.com/sporthub/search-api/internal/domain"
+	"github.com/sporthub/search-api/internal/repository"
+	"github.com/sporthub/search-api/internal/services"
+)
+
+type fakePublisher struct {
+	events []domain.SearchMatch
+	err    error // si no es nil, Publish falla (RabbitMQ caído, sin cola, nack)
+}
+
+func (f *fakePublisher) Publish(routing string, payload any) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.events = append(f.events, payload.(domain.SearchMatch))
+	return nil
+}
+
+func TestSavedSearchMatches(t *testing.T) {
+	ss := domain.SavedSearch{Query: "pádel", Site: "Sede Norte", MaxPrice: 5000}
+	tests := []struct {
+		name string
+		doc  domain.SearchDoc
+		want bool
+	}{
+		{"match", domain.SearchDoc{Name: "Pádel inicial", Site: "sede norte", Price: 4500}, true},
+		{"other site", domain.SearchDoc{Name: "Pádel inicial", Site: "Sede Sur", Price: 4500}, false},
+		{"too expensive", domain.SearchDoc{Name: "Pádel inicial", Site: "Sede Norte", Price: 6000}, false},
+		{"other sport", domain.SearchDoc{Name: "Yoga", Site: "Sede Norte", Price: 3000}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := services.Matches(ss, tt.doc); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	ss.OrgID = "7"
+	if services.Matches(ss, domain.SearchDoc{OrgID: "1", Name: "Pádel inicial", Site: "Sede Norte", Price: 4500}) {
+		t.Error("a saved search must not match activities of another organization")
+	}
+}
+
+func TestSavedSearchAlerts(t *testing.T) {
+	pub := &fakePublisher{}
+	svc := services.NewSavedSearchService(repository.NewSavedSearchStore(), pub, 2)
+
+	created, err := svc.Create("7", domain.SavedSearch{Name: "padel", Query: "padel"})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if _, err := svc.Get("8", created.ID); err != services.ErrSavedSearchNotFound {
+		t.Errorf("other users must not see the saved search, got %v", err)
+	}
+
+	doc := domain.SearchDoc{ActivityID: "1", Name: "Padel inicial"}
+	svc.Evaluate(doc)
+	svc.Evaluate(doc) // mismo par búsqueda/actividad: no se repite
+	if len(pub.events) != 1 || pub.events[0].UserID != "7" || pub.events[0].SavedSearchID != created.ID {
+		t.Fatalf("unexpected events: %+v", pub.events)
+	}
+
+	// límite de 2 alertas por hora para el usuario
+	svc.Evaluate(domain.SearchDoc{ActivityID: "2", Name: "Padel avanzado"})
+	svc.Evaluate(domain.SearchDoc{ActivityID: "3", Name: "Padel mixto"})
+	if len(pub.events) != 2 {
+		t.Errorf("expected rate limit to stop at 2 alerts, got %d", len(pub.events))
+	}
+}
+
+func TestSavedSearchAlertsSurviveAPublishFailure(t *testing.T) {
+	// sin cola bindeada a search.match el broker devuelve el mensaje (mandatory)
+	pub := &fakePublisher{err: fmt.Errorf("publish search.match: %w", rabbit.ErrUnroutable)}
+	svc := services.NewSavedSearchService(repository.NewSavedSearchStore(), pub, 1)
+	created, err := svc.Create("7", domain.SavedSearch{Name: "padel", Query: "padel"})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+
+	doc := domain.SearchDoc{ActivityID: "1", Name: "Padel inicial"}
+	svc.Evaluate(doc)
+	svc.Evaluate(doc)
+	if len(pub.events) != 0 {
+		t.Fatalf("an unroutable alert must not count as published, got %+v", pub.events)
+	}
+
+	// la alerta que volvió no gastó ni la deduplicación ni el cupo por hora
+	pub.err = nil
+	svc.Evaluate(doc)
+	if len(pub.events) != 1 || pub.events[0].SavedSearchID != created.ID || pub.events[0].ActivityID != "1" {
+		t.Fatalf("expected the alert once it can be routed, got %+v", pub.events)
+	}
+}
+
+func TestSavedSearchDeleteForgetsNotifiedActivities(t *testing.T) {
+	st := repository.NewSavedSearchStore()
+	ss := &domain.SavedSearch{UserID: "7", Name: "padel"}
+	if err := st.Create(ss); err != nil {
+		t.Fatal(err)
+	}
+	if first, _ := st.MarkNotified(ss.ID, "1"); !first {
+		t.Fatal("first MarkNotified must report a new pair")
+	}
+	if first, _ := st.MarkNotified(ss.ID, "1"); first {
+		t.Fatal("second MarkNotified must report a duplicate")
+	}
+	if err := st.Delete(ss.ID); err != nil {
+		t.Fatal(err)
+	}
+	if first, _ := st.MarkNotified(ss.ID, "1"); !first {
+		t.Fatal("pairs of a deleted saved search must be forgotten")
+	}
+	if _, err := st.Get(ss.ID); !errors.Is(err, services.ErrSavedSearchNotFound) {
+		t.Fatalf("expected ErrSavedSearchNotFound, got %v", err)
+	}
+}
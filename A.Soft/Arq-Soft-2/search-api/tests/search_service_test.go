@@ -1 +1,159 @@
 package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sporthub/search-api/internal/domain"
+	"github.com/sporthub/search-api/internal/repository"
+	"github.com/sporthub/search-api/internal/services"
+)
+
+type fakeSolr struct {
+	calls int
+	total int
+	docs  []domain.SearchDoc
+}
+
+func (f *fakeSolr) Search(ctx context.Context, q, sport, site, date, sort string, page, size int) (*domain.Result, error) {
+	f.calls++
+	return &domain.Result{Total: f.total, Page: page, Size: size, Docs: f.docs}, nil
+}
+
+func (f *fakeSolr) Related(ctx context.Context, activityID, sport, site, date string, size int) (*domain.Result, error) {
+	f.calls++
+	return &domain.Result{Total: f.total, Page: 1, Size: size}, nil
+}
+
+func newTestService(solr *fakeSolr) (*services.Service, *services.AnalyticsService, *repository.AnalyticsStore) {
+	store := repository.NewAnalyticsStore(100)
+	analytics := services.NewAnalyticsService(store)
+	svc := services.NewSearchService(solr, repository.NewLocalCache(100), repository.NewMemcached(""), time.Minute, analytics)
+	return svc, analytics, store
+}
+
+func TestSearchRecordsCacheTier(t *testing.T) {
+	solr := &fakeSolr{total: 3}
+	svc, _, store := newTestService(solr)
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Search(context.Background(), "Padel", "", "", "", "", 1, 10); err != nil {
+			t.Fatalf("Search() error: %v", err)
+		}
+	}
+	if solr.calls != 1 {
+		t.Errorf("expected 1 call to solr, got %d", solr.calls)
+	}
+
+	// sin tenant en el contexto los eventos quedan en la organización ""
+	events := store.Events("", time.Time{})
+	if len(events) != 2 {
+		t.Fatalf("expected 2 recorded searches, got %d", len(events))
+	}
+	if events[0].CacheTier != domain.CacheTierSolr || events[1].CacheTier != domain.CacheTierLocal {
+		t.Errorf("unexpected cache tiers: %s, %s", events[0].CacheTier, events[1].CacheTier)
+	}
+	if events[0].Query != "padel" || events[0].Results != 3 {
+		t.Errorf("unexpected event: %+v", events[0])
+	}
+}
+
+func TestAnalyticsReports(t *testing.T) {
+	solr := &fakeSolr{}
+	svc, analytics, _ := newTestService(solr)
+	ctx := repository.WithTenant(context.Background(), "1")
+
+	// "yoga" sin resultados, "futbol 5" con resultados y un click
+	_, _ = svc.Search(ctx, "yoga", "", "", "", "", 1, 10)
+	solr.total = 4
+	_, _ = svc.Search(ctx, "Futbol  5", "", "", "", "", 1, 10)
+	_, _ = svc.Search(ctx, "futbol 5", "", "", "", "", 2, 10) // segunda página: no cuenta
+	_, _ = svc.Search(ctx, "futbol 5", "Futbol", "", "", "", 1, 10)
+	analytics.Click("1", "FUTBOL 5", "12", 1)
+
+	since := time.Now().Add(-time.Hour)
+	top := analytics.TopQueries("1", since, 10)
+	if len(top) != 2 || top[0].Query != "futbol 5" || top[0].Searches != 2 {
+		t.Fatalf("unexpected top queries: %+v", top)
+	}
+
+	zero := analytics.ZeroResultQueries("1", since, 10)
+	if len(zero) != 1 || zero[0].Query != "yoga" {
+		t.Errorf("unexpected zero-result queries: %+v", zero)
+	}
+
+	ctr := analytics.ClickThrough("1", since, 10)
+	if len(ctr) != 1 || ctr[0].Clicks != 1 || ctr[0].ClickThrough != 0.5 {
+		t.Errorf("unexpected click-through: %+v", ctr)
+	}
+
+	trending := analytics.Trending("1", 10)
+	if len(trending) != 1 || trending[0].Query != "futbol 5" {
+		t.Errorf("unexpected trending: %+v", trending)
+	}
+}
+
+func TestAnalyticsAreScopedByOrg(t *testing.T) {
+	solr := &fakeSolr{total: 1}
+	svc, analytics, _ := newTestService(solr)
+
+	_, _ = svc.Search(repository.WithTenant(context.Background(), "1"), "padel", "", "", "", "", 1, 10)
+	_, _ = svc.Search(repository.WithTenant(context.Background(), "2"), "natacion", "", "", "", "", 1, 10)
+	analytics.Click("2", "natacion", "9", 1)
+
+	trending := analytics.Trending("1", 10)
+	if len(trending) != 1 || trending[0].Query != "padel" {
+		t.Errorf("org 1 should only see its own searches, got %+v", trending)
+	}
+	since := time.Now().Add(-time.Hour)
+	if ctr := analytics.ClickThrough("1", since, 10); len(ctr) != 0 {
+		t.Errorf("org 1 should not see clicks from org 2, got %+v", ctr)
+	}
+	if top := analytics.TopQueries("3", since, 10); len(top) != 0 {
+		t.Errorf("org without searches should get nothing, got %+v", top)
+	}
+}
+
+func TestAnalyticsStoreIsBounded(t *testing.T) {
+	store := repository.NewAnalyticsStore(3)
+	for i := 0; i < 5; i++ {
+		store.Record(domain.SearchEvent{Query: "q", Page: i, At: time.Now()})
+	}
+	events := store.Events("", time.Time{})
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+}
+
+func TestRelatedIsCached(t *testing.T) {
+	solr := &fakeSolr{total: 2}
+	svc, _, _ := newTestService(solr)
+
+	for i := 0; i < 3; i++ {
+		res, err := svc.Related(context.Background(), "7", "", "", "", 5)
+		if err != nil {
+			t.Fatalf("Related() error: %v", err)
+		}
+		if res.Total != 2 {
+			t.Errorf("expected total 2, got %d", res.Total)
+		}
+	}
+	if solr.calls != 1 {
+		t.Errorf("expected 1 call to solr, got %d", solr.calls)
+	}
+}
+
+func TestSearchCacheIsPerOrganization(t *testing.T) {
+	solr := &fakeSolr{total: 1}
+	svc, _, _ := newTestService(solr)
+
+	for _, org := range []string{"1", "7", "1"} {
+		if _, err := svc.Search(repository.WithTenant(context.Background(), org), "Padel", "", "", "", "", 1, 10); err != nil {
+			t.Fatalf("Search() error: %v", err)
+		}
+	}
+	if solr.calls != 2 {
+		t.Errorf("expected one call to solr per organization, got %d", solr.calls)
+	}
+}
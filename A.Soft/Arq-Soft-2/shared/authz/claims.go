@@ -0,0 +1,49 @@
+package authz
+
+import "github.com/golang-jwt/jwt/v5"
+
+// TokenPermissions lee el claim perms. Los tokens emitidos antes de que
+// existiera no lo traen y toman los permisos de su rol (claim rol); esto vale
+// igual en los tres servicios.
+func TokenPermissions(claims jwt.MapClaims) []string {
+	raw, ok := claims["perms"].([]any)
+	if !ok {
+		rol, _ := claims["rol"].(string)
+		return PermissionsFor(rol)
+	}
+	return claimStrings(raw)
+}
+
+// TokenOrgPermissions lee el claim orgs (orgId -> permisos); los tokens
+// anteriores a las organizaciones no lo traen y no tienen permisos en
+// ningún club hasta el próximo /auth/refresh
+func TokenOrgPermissions(claims jwt.MapClaims) map[string][]string {
+	out := map[string][]string{}
+	raw, _ := claims["orgs"].(map[string]any)
+	for org, list := range raw {
+		perms, _ := list.([]any)
+		out[org] = claimStrings(perms)
+	}
+	return out
+}
+
+// PermissionsIn son los permisos del token dentro de la organización org: un
+// admin global conserva los de su rol en todas, el resto los de esa
+// organización en el claim orgs
+func PermissionsIn(claims jwt.MapClaims, org string) []string {
+	if rol, _ := claims["rol"].(string); rol == RoleAdmin {
+		return TokenPermissions(claims)
+	}
+	return TokenOrgPermissions(claims)[org]
+}
+
+// claimStrings convierte un claim lista de strings ([]any al decodificar)
+func claimStrings(raw []any) []string {
+	out := make([]string, 0, len(raw))
+	for _, p := range raw {
+		if ps, ok := p.(string); ok {
+			out = append(out, ps)
+		}
+	}
+	return out
+}
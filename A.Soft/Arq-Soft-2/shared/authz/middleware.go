@@ -0,0 +1,45 @@
+package authz
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKey es donde el JWTAuth de cada servicio deja los permisos del
+// token ([]string) para HasPermission y RequirePermission
+const ContextKey = "perms"
+
+// SetPermissions guarda los permisos del token en el contexto del request
+func SetPermissions(c *gin.Context, perms []string) {
+	if perms == nil {
+		perms = []string{}
+	}
+	c.Set(ContextKey, perms)
+}
+
+// HasPermission indica si el token del request tiene el permiso
+func HasPermission(c *gin.Context, perm string) bool {
+	perms, _ := c.Get(ContextKey)
+	list, _ := perms.([]string)
+	return slices.Contains(list, perm)
+}
+
+// RequirePermission corta con 401 si el request no pasó por JWTAuth (no hay
+// permisos en el contexto) y con 403 si el token no tiene el permiso
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(ContextKey); !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authentication"})
+			c.Abort()
+			return
+		}
+		if !HasPermission(c, perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "missing permission " + perm})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
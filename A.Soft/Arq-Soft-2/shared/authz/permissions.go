@@ -0,0 +1,81 @@
+// Package authz son los permisos que users-api pone en el access token y
+// cómo los exigen users-api, activities-api y search-api. Es la única copia:
+// los tres servicios la importan.
+package authz
+
+import "slices"
+
+// Permisos del claim perms (y de cada organización en el claim orgs)
+const (
+	PermEnrollmentCreate = "enrollment:create"
+	// cancelar inscripciones de otros usuarios
+	PermEnrollmentManageAny  = "enrollment:manage-any"
+	PermEnrollmentViewRoster = "enrollment:view-roster"
+
+	PermActivityCreate = "activity:create"
+	PermActivityUpdate = "activity:update"
+	PermActivityDelete = "activity:delete"
+	PermSessionManage  = "session:manage"
+	// sin este permiso update/delete/sesiones/roster solo aplican a las
+	// actividades de las que el usuario es dueño o instructor
+	PermActivityManageAny = "activity:manage-any"
+	// reindexar y ver los reportes de búsqueda
+	PermSearchReindex = "search:reindex"
+
+	PermUserRead         = "user:read"
+	PermUserManage       = "user:manage"
+	PermInvitationCreate = "invitation:create"
+
+	PermOrgCreate = "org:create"
+	// gestionar los miembros de una organización: global (admin) para
+	// cualquiera, o dentro del claim orgs para el admin de ese club
+	PermOrgManage = "org:manage"
+)
+
+// Roles globales (claim rol) y roles dentro de una organización
+const (
+	RoleUser       = "user"
+	RoleInstructor = "instructor"
+	RoleAdmin      = "admin"
+
+	OrgRoleMember     = "member"
+	OrgRoleInstructor = "instructor"
+	OrgRoleAdmin      = "admin"
+)
+
+var userPermissions = []string{PermEnrollmentCreate}
+
+var instructorPermissions = append(slices.Clone(userPermissions),
+	PermActivityCreate, PermActivityUpdate, PermActivityDelete, PermSessionManage, PermEnrollmentViewRoster)
+
+var adminPermissions = append(slices.Clone(instructorPermissions),
+	PermActivityManageAny, PermEnrollmentManageAny, PermSearchReindex, PermUserRead, PermUserManage, PermInvitationCreate, PermOrgCreate, PermOrgManage)
+
+// el admin de un club gestiona todo lo del club, pero nada global
+var orgAdminPermissions = append(slices.Clone(instructorPermissions),
+	PermActivityManageAny, PermEnrollmentManageAny, PermOrgManage)
+
+// RolePermissions es el mapa rol -> permisos (GET /roles)
+var RolePermissions = map[string][]string{
+	RoleUser:       userPermissions,
+	RoleInstructor: instructorPermissions,
+	RoleAdmin:      adminPermissions,
+}
+
+// OrgRolePermissions es el mapa rol de organización -> permisos en ese club
+var OrgRolePermissions = map[string][]string{
+	OrgRoleMember:     userPermissions,
+	OrgRoleInstructor: instructorPermissions,
+	OrgRoleAdmin:      orgAdminPermissions,
+}
+
+// PermissionsFor devuelve una copia de los permisos del rol (nil si no existe)
+func PermissionsFor(role string) []string {
+	return slices.Clone(RolePermissions[role])
+}
+
+// OrgPermissionsFor devuelve una copia de los permisos del rol de
+// organización (nil si no existe)
+func OrgPermissionsFor(role string) []string {
+	return slices.Clone(OrgRolePermissions[role])
+}
@@ -0,0 +1,124 @@
+// Package jwks resuelve las claves públicas con las que se validan los JWT de
+// users-api; lo usan activities-api y search-api.
+package jwks
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySet cachea las claves públicas de users-api (/.well-known/jwks.json).
+// Se vuelven a pedir cuando vence el TTL o cuando llega un token con un kid
+// desconocido (rotación), como mucho una vez cada minRefresh.
+type KeySet struct {
+	url        string
+	http       *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func New(url string, ttl time.Duration) *KeySet {
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	return &KeySet{
+		url:        url,
+		http:       &http.Client{Timeout: 5 * time.Second},
+		ttl:        ttl,
+		minRefresh: 30 * time.Second,
+		keys:       map[string]*rsa.PublicKey{},
+	}
+}
+
+// Keyfunc resuelve la clave pública por kid para jwt.Parse
+func (j *KeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("missing kid")
+	}
+	j.mu.RLock()
+	key, ok := j.keys[kid]
+	stale := time.Since(j.fetchedAt) > j.ttl
+	canRefresh := time.Since(j.fetchedAt) > j.minRefresh
+	j.mu.RUnlock()
+
+	if (!ok && canRefresh) || stale {
+		if err := j.refresh(); err != nil {
+			log.Printf("[jwks] WARN: refresh failed: %v", err)
+		}
+		j.mu.RLock()
+		key, ok = j.keys[kid]
+		j.mu.RUnlock()
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (j *KeySet) refresh() error {
+	res, err := j.http.Get(j.url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwks returned %d", res.StatusCode)
+	}
+	var body struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range body.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		pub, err := rsaKey(k)
+		if err != nil {
+			log.Printf("[jwks] WARN: skipping key %s: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	j.mu.Lock()
+	j.keys, j.fetchedAt = keys, time.Now()
+	j.mu.Unlock()
+	return nil
+}
+
+func rsaKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
@@ -0,0 +1,228 @@
+// Package rabbit es el publisher de eventos a RabbitMQ con reconexión y
+// publisher confirms; lo usan users-api (relay del outbox), activities-api y
+// search-api (alertas search.match).
+package rabbit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Errores de Publish
+var (
+	// ErrNotConnected se devuelve al publicar mientras no hay conexión a RabbitMQ
+	ErrNotConnected = errors.New("rabbitmq not connected")
+	// ErrNacked: el broker no pudo aceptar el mensaje
+	ErrNacked = errors.New("rabbitmq nacked the message")
+	// ErrUnroutable: ninguna cola está bindeada a la routing key (p.ej. el
+	// consumer todavía no declaró su cola)
+	ErrUnroutable = errors.New("rabbitmq message unroutable")
+	// ErrConfirmTimeout: el broker no confirmó el mensaje a tiempo
+	ErrConfirmTimeout = errors.New("rabbitmq confirm timeout")
+)
+
+// Publisher publica eventos en un exchange y se reconecta solo con backoff
+// exponencial cuando se cae la conexión (o si RabbitMQ no está al arrancar).
+// Mientras está desconectado Publish falla rápido con ErrNotConnected.
+//
+// El canal está en modo confirm y los mensajes se publican persistentes y
+// con mandatory: Publish espera el ack del broker (hasta confirmTimeout) y
+// devuelve ErrUnroutable si ninguna cola recibió el mensaje.
+type Publisher struct {
+	url            string
+	exch           string
+	exchType       string
+	confirmTimeout time.Duration
+
+	mu        sync.RWMutex
+	conn      *amqp091.Connection
+	channel   *amqp091.Channel
+	returns   chan amqp091.Return
+	connected bool
+
+	// pubMu serializa los publish: así un Return recibido antes del ack
+	// corresponde siempre al mensaje que se está confirmando
+	pubMu sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New arranca el loop de conexión en background y devuelve enseguida
+func New(url, exchange, exchangeType string, confirmTimeout time.Duration) *Publisher {
+	if confirmTimeout <= 0 {
+		confirmTimeout = 5 * time.Second
+	}
+	r := &Publisher{
+		url:            url,
+		exch:           exchange,
+		exchType:       exchangeType,
+		confirmTimeout: confirmTimeout,
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Connected indica si hay conexión y canal abiertos (para /health)
+func (r *Publisher) Connected() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.connected
+}
+
+func (r *Publisher) Close() {
+	close(r.stop)
+	<-r.done
+}
+
+func (r *Publisher) run() {
+	defer close(r.done)
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		conn, ch, err := r.connect()
+		if err != nil {
+			log.Printf("[rabbit] connection attempt %d failed (%v), retrying in %v...", attempt, err, delay)
+			select {
+			case <-r.stop:
+				return
+			case <-time.After(delay):
+			}
+			delay *= 2
+			if delay > 30*time.Second {
+				delay = 30 * time.Second
+			}
+			continue
+		}
+		log.Printf("[rabbit] connected after %d attempts", attempt)
+		attempt, delay = 0, time.Second
+
+		closed := conn.NotifyClose(make(chan *amqp091.Error, 1))
+		chClosed := ch.NotifyClose(make(chan *amqp091.Error, 1))
+		returns := ch.NotifyReturn(make(chan amqp091.Return, 16))
+		r.mu.Lock()
+		r.conn, r.channel, r.returns, r.connected = conn, ch, returns, true
+		r.mu.Unlock()
+
+		select {
+		case err := <-closed:
+			log.Printf("[rabbit] WARN: connection lost: %v", err)
+		case err := <-chClosed:
+			log.Printf("[rabbit] WARN: channel closed: %v", err)
+		case <-r.stop:
+		}
+
+		r.mu.Lock()
+		r.conn, r.channel, r.returns, r.connected = nil, nil, nil, false
+		r.mu.Unlock()
+		ch.Close()
+		conn.Close()
+
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+	}
+}
+
+// connect abre conexión y canal en modo confirm y (re)declara el exchange
+func (r *Publisher) connect() (*amqp091.Connection, *amqp091.Channel, error) {
+	conn, err := amqp091.Dial(r.url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("rabbit dial: %w", err)
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("rabbit channel: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("confirm mode: %w", err)
+	}
+	if err := ch.ExchangeDeclare(r.exch, r.exchType, true, false, false, false, nil); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("exchange declare: %w", err)
+	}
+	return conn, ch, nil
+}
+
+// Publish publica payload como JSON y espera la confirmación del broker
+func (r *Publisher) Publish(routing string, payload any) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	r.pubMu.Lock()
+	defer r.pubMu.Unlock()
+	r.mu.RLock()
+	ch, returns := r.channel, r.returns
+	r.mu.RUnlock()
+	if ch == nil {
+		return fmt.Errorf("publish %s: %w", routing, ErrNotConnected)
+	}
+	// descartar returns viejos (de publish que vencieron por timeout)
+	drainReturns(returns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.confirmTimeout)
+	defer cancel()
+	msgID := newMessageID()
+	dc, err := ch.PublishWithDeferredConfirmWithContext(ctx, r.exch, routing, true, false, amqp091.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp091.Persistent,
+		MessageId:    msgID,
+		Timestamp:    time.Now(),
+		Body:         b,
+	})
+	if err != nil {
+		return fmt.Errorf("publish %s: %w", routing, err)
+	}
+	acked, err := dc.WaitContext(ctx)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("publish %s: %w", routing, ErrConfirmTimeout)
+		}
+		return fmt.Errorf("publish %s: %w", routing, err)
+	}
+	if !acked {
+		return fmt.Errorf("publish %s: %w", routing, ErrNacked)
+	}
+	// El broker manda el basic.return antes del ack, así que si el mensaje
+	// no se pudo rutear ya está en el canal
+	select {
+	case ret := <-returns:
+		if ret.MessageId == msgID {
+			return fmt.Errorf("publish %s: %w (%s)", routing, ErrUnroutable, ret.ReplyText)
+		}
+	default:
+	}
+	return nil
+}
+
+func drainReturns(returns <-chan amqp091.Return) {
+	for {
+		select {
+		case <-returns:
+		default:
+			return
+		}
+	}
+}
+
+func newMessageID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
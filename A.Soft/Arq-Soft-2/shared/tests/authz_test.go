@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/sporthub/shared/authz"
+)
+
+// claims como quedan al decodificar el JWT (listas como []any)
+func decoded(rol string, perms []string, orgs map[string][]string) jwt.MapClaims {
+	claims := jwt.MapClaims{"rol": rol}
+	list := func(in []string) []any {
+		out := make([]any, len(in))
+		for i, p := range in {
+			out[i] = p
+		}
+		return out
+	}
+	if perms != nil {
+		claims["perms"] = list(perms)
+	}
+	if orgs != nil {
+		m := map[string]any{}
+		for org, p := range orgs {
+			m[org] = list(p)
+		}
+		claims["orgs"] = m
+	}
+	return claims
+}
+
+func TestTokenWithoutPermsFallsBackToItsRole(t *testing.T) {
+	got := authz.TokenPermissions(decoded(authz.RoleInstructor, nil, nil))
+	if !slices.Equal(got, authz.PermissionsFor(authz.RoleInstructor)) {
+		t.Errorf("expected the instructor's permissions, got %v", got)
+	}
+	// con claim perms manda el claim, aunque el rol diga otra cosa
+	got = authz.TokenPermissions(decoded(authz.RoleAdmin, []string{authz.PermEnrollmentCreate}, nil))
+	if !slices.Equal(got, []string{authz.PermEnrollmentCreate}) {
+		t.Errorf("expected the perms claim, got %v", got)
+	}
+	if got := authz.TokenPermissions(decoded("normal", nil, nil)); len(got) != 0 {
+		t.Errorf("an unknown role has no permissions, got %v", got)
+	}
+}
+
+func TestPermissionsInOrganization(t *testing.T) {
+	member := decoded(authz.RoleUser, authz.PermissionsFor(authz.RoleUser), map[string][]string{
+		"1": authz.PermissionsFor(authz.RoleUser),
+		"7": authz.OrgPermissionsFor(authz.OrgRoleInstructor),
+	})
+	if got := authz.PermissionsIn(member, "7"); !slices.Contains(got, authz.PermActivityCreate) {
+		t.Errorf("an org instructor should create activities in their org, got %v", got)
+	}
+	if got := authz.PermissionsIn(member, "1"); slices.Contains(got, authz.PermActivityCreate) {
+		t.Errorf("the org role must not leak into other orgs, got %v", got)
+	}
+	if got := authz.PermissionsIn(member, "9"); len(got) != 0 {
+		t.Errorf("no permissions outside the user's orgs, got %v", got)
+	}
+	// el admin global tiene los de su rol en todas
+	admin := decoded(authz.RoleAdmin, nil, map[string][]string{})
+	if got := authz.PermissionsIn(admin, "9"); !slices.Contains(got, authz.PermActivityManageAny) {
+		t.Errorf("a global admin keeps their permissions in every org, got %v", got)
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/anon", authz.RequirePermission(authz.PermUserRead), func(c *gin.Context) { c.Status(http.StatusOK) })
+	with := func(perms ...string) gin.HandlerFunc {
+		return func(c *gin.Context) { authz.SetPermissions(c, perms) }
+	}
+	r.GET("/user", with(authz.PermEnrollmentCreate), authz.RequirePermission(authz.PermUserRead), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/admin", with(authz.PermUserRead), authz.RequirePermission(authz.PermUserRead), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for path, want := range map[string]int{"/anon": http.StatusUnauthorized, "/user": http.StatusForbidden, "/admin": http.StatusOK} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/sporthub/shared/jwks"
+)
+
+// rotatingJWKS sirve la clave actual con su kid; set la rota
+type rotatingJWKS struct {
+	mu  sync.Mutex
+	kid string
+	key *rsa.PublicKey
+}
+
+func (j *rotatingJWKS) set(kid string, key *rsa.PublicKey) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.kid, j.key = kid, key
+}
+
+func (j *rotatingJWKS) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	enc := base64.RawURLEncoding
+	_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA", "kid": j.kid, "use": "sig", "alg": "RS256",
+		"n": enc.EncodeToString(j.key.N.Bytes()),
+		"e": enc.EncodeToString(big.NewInt(int64(j.key.E)).Bytes()),
+	}}})
+}
+
+func TestKeySetFollowsKeyRotation(t *testing.T) {
+	k1, _ := rsa.GenerateKey(rand.Reader, 2048)
+	k2, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server := &rotatingJWKS{}
+	server.set("k1", &k1.PublicKey)
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	// TTL mínimo: cada resolución vuelve a pedir el JWKS
+	keys := jwks.New(srv.URL, time.Millisecond)
+	token := func(kid string) *jwt.Token {
+		tok := jwt.New(jwt.SigningMethodRS256)
+		tok.Header["kid"] = kid
+		return tok
+	}
+
+	got, err := keys.Keyfunc(token("k1"))
+	if err != nil || got.(*rsa.PublicKey).N.Cmp(k1.N) != 0 {
+		t.Fatalf("Keyfunc(k1) = %v, %v", got, err)
+	}
+
+	server.set("k2", &k2.PublicKey)
+	time.Sleep(5 * time.Millisecond)
+	got, err = keys.Keyfunc(token("k2"))
+	if err != nil || got.(*rsa.PublicKey).N.Cmp(k2.N) != 0 {
+		t.Fatalf("Keyfunc(k2) after rotation = %v, %v", got, err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := keys.Keyfunc(token("k1")); err == nil {
+		t.Fatal("a key removed from the JWKS must not be accepted anymore")
+	}
+	if _, err := keys.Keyfunc(jwt.New(jwt.SigningMethodRS256)); err == nil {
+		t.Fatal("a token without kid must be rejected")
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/db"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+// runBootstrapAdmin implementa "users-api bootstrap-admin [-ttl 24h]": si no
+// hay ningún admin genera una invitación de admin y escribe el token solo en
+// stdout (nunca en los logs del servicio). Lo corre un operador, una vez; el
+// servidor ya no la genera al arrancar.
+func runBootstrapAdmin(cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("bootstrap-admin", flag.ContinueOnError)
+	ttl := fs.Duration("ttl", 24*time.Hour, "validity of the invitation")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *ttl <= 0 {
+		fmt.Fprintln(os.Stderr, "bootstrap-admin: -ttl must be positive")
+		return 2
+	}
+
+	gdb := db.MustInitMySQL(cfg)
+	defer db.Close(gdb)
+	repos := repository.NewMySQLRepos(gdb, cfg.LoginAttemptsStore)
+	users := services.NewUsersService(repos, services.NewLoginGuard(repos.LoginAttempts, cfg), cfg)
+
+	token, err := users.BootstrapAdminInvite(*ttl)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bootstrap-admin: %v\n", err)
+		return 1
+	}
+	if token == "" {
+		fmt.Fprintln(os.Stderr, "bootstrap-admin: there is already an admin, use POST /admin/invitations")
+		return 1
+	}
+	fmt.Printf("register with inviteToken=%s (valid %s)\n", token, *ttl)
+	return 0
+}
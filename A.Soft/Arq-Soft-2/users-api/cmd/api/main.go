@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sporthub/shared/authz"
 	"github.com/sporthub/users-api/internal/config"
 	"github.com/sporthub/users-api/internal/controllers"
 	"github.com/sporthub/users-api/internal/db"
+	"github.com/sporthub/users-api/internal/events"
 	"github.com/sporthub/users-api/internal/middleware"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
 	"github.com/sporthub/users-api/internal/utils"
 )
 
@@ -24,29 +31,133 @@ func main() {
 		log.Fatalf("Failed to wait for MySQL: %v", err)
 	}
 
-	// DB
-	sqlDB := db.MustInitMySQL(cfg)
-	defer sqlDB.Close()
+	// "users-api migrate up|down|status" aplica las migraciones y termina
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, os.Args[2:]))
+	}
+	// "users-api bootstrap-admin" imprime la invitación del primer admin
+	if len(os.Args) > 1 && os.Args[1] == "bootstrap-admin" {
+		os.Exit(runBootstrapAdmin(cfg, os.Args[2:]))
+	}
+
+	// DB: un solo pool para todo el proceso (no arranca si faltan migraciones)
+	gdb := db.MustInitMySQL(cfg)
+	defer db.Close(gdb)
+	repos := repository.NewMySQLRepos(gdb, cfg.LoginAttemptsStore)
+
+	// Eventos de usuario: los servicios los guardan en el outbox junto con el
+	// cambio y el relay los publica en RabbitMQ
+	pub := events.NewPublisher(cfg)
+	defer pub.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go events.NewRelay(repos.Outbox, pub, time.Second).Run(ctx)
 
 	// Router
 	r := gin.Default()
+	if err := middleware.TrustProxies(r, cfg); err != nil {
+		log.Fatalf("TRUSTED_PROXIES: %v", err)
+	}
 	r.Use(middleware.CORS())
 
 	// Routes
 	api := r.Group("/")
 
-	authCtl := controllers.NewAuthController(cfg)
-	userCtl := controllers.NewUsersController()
+	// Services: todos sobre los mismos repos (y el mismo guard de login)
+	guard := services.NewLoginGuard(repos.LoginAttempts, cfg)
+	users := services.NewUsersService(repos, guard, cfg)
+	verification := services.NewVerificationService(repos, cfg)
+	tokens := services.NewTokenService(repos, cfg)
+	mfa := services.NewMFAService(repos, guard, cfg)
+	orgs := services.NewOrgsService(repos, cfg)
+	oidc := services.NewOIDCService(repos, cfg)
+
+	authCtl := controllers.NewAuthController(users, tokens, mfa)
+	mfaCtl := controllers.NewMFAController(mfa, tokens)
+	userCtl := controllers.NewUsersController(users, verification)
+	passwordCtl := controllers.NewPasswordController(services.NewPasswordService(repos, tokens, guard, cfg), tokens)
+	verifyCtl := controllers.NewVerificationController(verification)
+	clientsCtl := controllers.NewClientsController(services.NewClientsService(cfg))
+	exportCtl := controllers.NewExportController(services.NewExportService(repos, cfg))
+	orgsCtl := controllers.NewOrgsController(orgs)
+	oidcCtl := controllers.NewOIDCController(oidc, mfa, tokens)
+	keys, err := utils.DefaultKeySet(cfg)
+	if err != nil {
+		log.Fatalf("jwt keys: %v", err)
+	}
+	auth := middleware.JWTAuth(keys, cfg, tokens)
+
+	// Claves públicas para que los otros servicios validen los tokens
+	api.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, keys.JWKS())
+	})
 
 	api.POST("/auth/login", authCtl.Login)
+	api.POST("/auth/refresh", authCtl.Refresh)
+	api.POST("/auth/logout", auth, authCtl.Logout)
+	api.GET("/auth/revoked", middleware.RequireServiceToken(cfg), authCtl.Revoked)
+	api.POST("/auth/password/forgot", passwordCtl.Forgot)
+	api.POST("/auth/password/reset", passwordCtl.Reset)
+	api.GET("/auth/verify", verifyCtl.Verify)
+	api.POST("/auth/verify", verifyCtl.Verify)
+	api.POST("/auth/verify/resend", auth, verifyCtl.Resend)
+
+	// Login con proveedores externos (OIDC): el callback vuelve al frontend
+	// con un código que se canjea por los tokens en /auth/oidc/exchange
+	api.GET("/auth/oidc/providers", oidcCtl.Providers)
+	api.GET("/auth/oidc/:provider/login", oidcCtl.Login)
+	api.GET("/auth/oidc/:provider/callback", oidcCtl.Callback)
+	api.POST("/auth/oidc/:provider/link", auth, oidcCtl.Link)
+	api.POST("/auth/oidc/:provider/reauth", auth, oidcCtl.Reauth)
+	api.POST("/auth/oidc/exchange", oidcCtl.Exchange)
+
+	// 2FA: setup/enable aceptan también el token parcial de setup del login
+	setupAuth := middleware.AllowMFASetupToken(cfg, auth)
+	api.POST("/auth/2fa/verify", mfaCtl.Verify)
+	api.GET("/auth/2fa", auth, mfaCtl.Status)
+	api.POST("/auth/2fa/setup", setupAuth, mfaCtl.Setup)
+	api.POST("/auth/2fa/enable", setupAuth, mfaCtl.Enable)
+	api.POST("/auth/2fa/disable", auth, mfaCtl.Disable)
+	api.POST("/auth/2fa/recovery-codes", auth, mfaCtl.RecoveryCodes)
 	api.POST("/users", userCtl.CreateUser)
-	api.GET("/users/:id", userCtl.GetByID)
+	api.POST("/auth/token", clientsCtl.Token)
+	api.GET("/users/:id", middleware.AllowServiceToken(cfg, auth), userCtl.GetByID)
+	api.GET("/users/me", auth, userCtl.Me)
+	api.PATCH("/users/me", auth, userCtl.UpdateMe)
+	api.DELETE("/users/me", auth, userCtl.DeleteMe)
+	api.POST("/users/me/password", auth, passwordCtl.Change)
+	api.POST("/users/me/export", auth, exportCtl.Start)
+	api.GET("/users/me/export", auth, exportCtl.Latest)
+	api.GET("/users/me/export/:id", auth, exportCtl.Status)
+	api.GET("/users/me/export/:id/download", auth, exportCtl.Download)
+
+	// Roles y sus permisos (los mismos que van en el claim perms)
+	api.GET("/roles", userCtl.Roles)
 
-	// Protected admin-only route for deleting users
+	// Protected routes (users, roles, invitations): cada una exige su permiso
 	protected := r.Group("/")
-	protected.Use(middleware.JWTAuth(cfg.JWTSecret))
-	protected.Use(middleware.AdminOnly())
-	protected.DELETE("/users/:id", userCtl.Delete)
+	protected.Use(auth)
+	protected.GET("/users", authz.RequirePermission(authz.PermUserRead), userCtl.List)
+	protected.DELETE("/users/:id", authz.RequirePermission(authz.PermUserManage), userCtl.Delete)
+	protected.PATCH("/users/:id/role", authz.RequirePermission(authz.PermUserManage), userCtl.ChangeRole)
+	protected.DELETE("/users/:id/lockout", authz.RequirePermission(authz.PermUserManage), userCtl.UnlockLogin)
+	protected.POST("/admin/invitations", authz.RequirePermission(authz.PermInvitationCreate), userCtl.CreateInvitation)
+
+	// Organizaciones (clubes): los miembros los gestiona el admin del club
+	// (org:manage en su claim orgs) o un admin global
+	protected.POST("/orgs", authz.RequirePermission(authz.PermOrgCreate), orgsCtl.Create)
+	protected.GET("/orgs", orgsCtl.Mine)
+	protected.GET("/orgs/:id", orgsCtl.Get)
+	protected.GET("/orgs/:id/members", middleware.RequireOrgPermission("id", authz.PermOrgManage), orgsCtl.Members)
+	api.GET("/orgs/:id/members/:userId", middleware.AllowServiceToken(cfg, auth), orgsCtl.Member)
+	protected.PUT("/orgs/:id/members/:userId", middleware.RequireOrgPermission("id", authz.PermOrgManage), orgsCtl.SetMember)
+	protected.DELETE("/orgs/:id/members/:userId", middleware.RequireOrgPermission("id", authz.PermOrgManage), orgsCtl.RemoveMember)
+
+	// Los datos anteriores a los clubes son de la organización por defecto
+	if err := orgs.EnsureDefault(); err != nil {
+		log.Printf("WARN: default organization: %v", err)
+	}
 
 	addr := ":" + cfg.AppPort
 	log.Printf("users-api listening on %s", addr)
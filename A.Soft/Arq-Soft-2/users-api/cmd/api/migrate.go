@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/db"
+)
+
+const migrateUsage = `usage: users-api migrate <command>
+
+commands:
+  up           apply every pending migration
+  down [n]     roll back the last n migrations (default 1)
+  status       list migrations and whether they are applied`
+
+// runMigrate implementa "users-api migrate up|down|status" y devuelve el
+// exit code
+func runMigrate(cfg config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	gdb := db.MustOpenMySQL(cfg)
+	defer db.Close(gdb)
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+	runner, err := db.NewMigrationRunner(sqlDB)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := runner.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate up: %v\n", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "migrate down: invalid number of steps %q\n", args[1])
+				return 2
+			}
+			steps = n
+		}
+		reverted, err := runner.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate down: %v\n", err)
+			return 1
+		}
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate status: %v\n", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, st := range statuses {
+			state, at := "pending", ""
+			if st.Applied {
+				state, at = "applied", st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if st.Dirty {
+				state = "dirty"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, state, at)
+		}
+		w.Flush()
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
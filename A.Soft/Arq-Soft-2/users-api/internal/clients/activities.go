@@ -0,0 +1,55 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+// ActivitiesClient consulta la API interna de activities-api con un token
+// de servicio firmado por users-api (aud ACTIVITIES_API_AUDIENCE)
+type ActivitiesClient struct {
+	base string
+	http *http.Client
+	cfg  config.Config
+}
+
+func NewActivitiesClient(cfg config.Config) *ActivitiesClient {
+	return &ActivitiesClient{
+		base: cfg.ActivitiesAPIBase,
+		http: &http.Client{Timeout: 10 * time.Second},
+		cfg:  cfg,
+	}
+}
+
+// UserEnrollments devuelve todas las inscripciones del usuario, incluidas
+// las canceladas, tal cual las guarda activities-api
+func (c *ActivitiesClient) UserEnrollments(ctx context.Context, userID uint64) ([]json.RawMessage, error) {
+	token, err := utils.GenerateServiceTokenFor(c.cfg, c.cfg.JWTIssuer, c.cfg.ActivitiesAPIAudience)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/internal/users/%d/enrollments", c.base, userID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("activities-api returned %d", res.StatusCode)
+	}
+	var out []json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
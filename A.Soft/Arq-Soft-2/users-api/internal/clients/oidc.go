@@ -0,0 +1,263 @@
+package clients
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sporthub/users-api/internal/config"
+)
+
+// OIDCProvider habla con un proveedor OpenID Connect: lee su configuración
+// de /.well-known/openid-configuration (una vez), arma la URL de
+// autorización, canjea el code (con el code verifier de PKCE) y valida el
+// id_token con las claves de su JWKS.
+type OIDCProvider struct {
+	cfg  config.OIDCProvider
+	http *http.Client
+
+	mu        sync.Mutex
+	meta      *oidcMetadata
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+type oidcMetadata struct {
+	Issuer        string `json:"issuer"`
+	AuthEndpoint  string `json:"authorization_endpoint"`
+	TokenEndpoint string `json:"token_endpoint"`
+	JWKSURI       string `json:"jwks_uri"`
+}
+
+// IDClaims son los datos del id_token que usa users-api
+type IDClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+func NewOIDCProvider(p config.OIDCProvider) *OIDCProvider {
+	return &OIDCProvider{cfg: p, http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *OIDCProvider) ID() string   { return p.cfg.ID }
+func (p *OIDCProvider) Name() string { return p.cfg.Name }
+
+func (p *OIDCProvider) metadata(ctx context.Context) (*oidcMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+	var m oidcMetadata
+	if err := p.getJSON(ctx, strings.TrimRight(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", &m); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	// el issuer publicado tiene que ser el configurado (OIDC Discovery 4.3)
+	if strings.TrimRight(m.Issuer, "/") != strings.TrimRight(p.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", m.Issuer, p.cfg.Issuer)
+	}
+	if m.AuthEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: incomplete provider metadata")
+	}
+	p.meta = &m
+	return p.meta, nil
+}
+
+// AuthURL es la URL del proveedor a la que se manda al usuario
+// (authorization code con PKCE S256)
+func (p *OIDCProvider) AuthURL(ctx context.Context, redirectURI, state, nonce, codeChallenge string) (string, error) {
+	m, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {p.cfg.Scopes},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(m.AuthEndpoint, "?") {
+		sep = "&"
+	}
+	return m.AuthEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange canjea el code en el token endpoint y devuelve el id_token
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, redirectURI string) (string, error) {
+	m, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	res, err := p.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	var body struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil && res.StatusCode == http.StatusOK {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %d %s", res.StatusCode, body.Error)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("token response without id_token")
+	}
+	return body.IDToken, nil
+}
+
+// VerifyIDToken valida firma, iss, aud, exp y nonce del id_token
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, raw, nonce string) (*IDClaims, error) {
+	m, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) { return p.key(ctx, t) },
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(m.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	claims, _ := t.Claims.(jwt.MapClaims)
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+	out := &IDClaims{}
+	out.Subject, _ = claims["sub"].(string)
+	out.Email, _ = claims["email"].(string)
+	out.Name, _ = claims["name"].(string)
+	// algunos proveedores lo mandan como string. Microsoft no manda
+	// email_verified: manda xms_edov (el dominio del email es del tenant que
+	// lo emitió) si se lo pide como claim opcional. Sin ninguno de los dos el
+	// email no cuenta como verificado y la cuenta solo se vincula a mano
+	if v, ok := claims["email_verified"]; ok {
+		out.EmailVerified = claimTrue(v)
+	} else {
+		out.EmailVerified = claimTrue(claims["xms_edov"])
+	}
+	if out.Subject == "" {
+		return nil, errors.New("id_token without sub")
+	}
+	return out, nil
+}
+
+func claimTrue(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true" || v == "1"
+	}
+	return false
+}
+
+// key resuelve la clave por kid; si no está se vuelve a pedir el JWKS
+// (rotación del proveedor), como mucho una vez cada 30s
+func (p *OIDCProvider) key(ctx context.Context, t *jwt.Token) (*rsa.PublicKey, error) {
+	kid, _ := t.Header["kid"].(string)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key, ok := p.keys[kid]
+	if !ok && time.Since(p.fetchedAt) > 30*time.Second {
+		if err := p.refreshKeys(ctx); err != nil {
+			log.Printf("[oidc] WARN: %s jwks refresh failed: %v", p.cfg.ID, err)
+		}
+		key, ok = p.keys[kid]
+	}
+	// sin kid vale la única clave publicada
+	if !ok && kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			key, ok = k, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+// refreshKeys se llama con p.mu tomado (después de metadata)
+func (p *OIDCProvider) refreshKeys(ctx context.Context) error {
+	var body struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.meta.JWKSURI, &body); err != nil {
+		return err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range body.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			log.Printf("[oidc] WARN: %s skipping malformed key %s", p.cfg.ID, k.Kid)
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	p.keys, p.fetchedAt = keys, time.Now()
+	return nil
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, u string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	res, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", u, res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 )
 
 type Config struct {
@@ -12,9 +13,103 @@ type Config struct {
 	MySQLUser     string
 	MySQLPassword string
 	MySQLDB       string
+	// Pool de conexiones a MySQL, compartido por todos los repositorios:
+	// máximo de conexiones abiertas, ociosas y vida de cada conexión
+	MySQLMaxOpenConns           string
+	MySQLMaxIdleConns           string
+	MySQLConnMaxLifetimeMinutes string
 
-	JWTSecret     string
-	JWTExpMinutes string
+	// Firma RS256: clave privada actual (PEM) y claves anteriores que se
+	// siguen publicando en el JWKS durante una rotación (coma separadas)
+	JWTSigningKeyFile string
+	JWTVerifyKeyFiles string
+	JWTIssuer         string
+	JWTAudience       string
+	JWTExpMinutes     string
+	// Duración de los refresh tokens (rotan en cada /auth/refresh)
+	RefreshTokenTTLHours string
+
+	// Emails: MailSender es smtp, file (un .eml por email en MailDir) o log
+	MailSender   string
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	// Reset de contraseña: link del frontend al que se agrega ?token=,
+	// vigencia del token y pedidos por hora (por email y por IP)
+	PasswordResetURL          string
+	PasswordResetTTLMinutes   string
+	PasswordForgotMaxPerEmail string
+	PasswordForgotMaxPerIP    string
+	// Verificación de email: link (se agrega ?token=), vigencia del token y
+	// límites de reenvío (segundos entre envíos y máximo por hora)
+	EmailVerifyURL        string
+	EmailVerifyTTLHours   string
+	EmailVerifyResendSecs string
+	EmailVerifyMaxPerHour string
+
+	// Protección de login: fallos antes del bloqueo (por cuenta y por IP),
+	// duración del bloqueo, ventana en la que se cuentan los fallos y store
+	// (mysql para varias réplicas, memory para una sola instancia)
+	LoginMaxFailures    string
+	LoginIPMaxFailures  string
+	LoginLockoutMinutes string
+	LoginWindowMinutes  string
+	LoginAttemptsStore  string
+	// Proxies (IPs o CIDRs, coma separados) de los que se cree
+	// X-Forwarded-For para la IP del cliente; vacío: ninguno
+	TrustedProxies string
+
+	// 2FA (TOTP): emisor que muestra la app autenticadora, vida del token
+	// parcial del primer paso del login y si los admins deben tener 2FA
+	MFAIssuer          string
+	MFATokenTTLMinutes string
+	RequireAdmin2FA    bool
+
+	// Credenciales de servicio (client credentials) para las llamadas entre
+	// servicios: "client_id:secret" coma separados, y vida del token
+	ServiceClients         string
+	ServiceTokenTTLMinutes string
+
+	// Eventos de usuario (user.created/updated/deleted). Sin RabbitURL solo
+	// se loguean.
+	RabbitURL           string
+	UsersEventsExchange string
+
+	// Exportación de datos personales: activities-api (inscripciones), aud
+	// que exige a los tokens de servicio y cuántas horas queda el zip
+	ActivitiesAPIBase     string
+	ActivitiesAPIAudience string
+	ExportTTLHours        string
+
+	// Organización (club) a la que pertenecen los datos sin orgId y en la
+	// que todos tienen los permisos de su rol global
+	DefaultOrgID string
+
+	// Login con proveedores externos (OpenID Connect, authorization code +
+	// PKCE). OIDC_PROVIDERS lista los ids y cada uno se configura con
+	// OIDC_<ID>_ISSUER, _CLIENT_ID, _CLIENT_SECRET y opcionalmente _NAME y
+	// _SCOPES
+	OIDCProviders []OIDCProvider
+	// URL pública de users-api (el callback es <url>/auth/oidc/<id>/callback),
+	// página del frontend que recibe ?code= (o ?error=) al terminar y vida del
+	// login en curso
+	OIDCRedirectBaseURL string
+	OIDCFrontendURL     string
+	OIDCStateTTLMinutes string
+}
+
+// OIDCProvider es un proveedor de identidad (Google, Microsoft...) que se
+// descubre en <Issuer>/.well-known/openid-configuration
+type OIDCProvider struct {
+	ID           string
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       string
 }
 
 func Load() Config {
@@ -25,13 +120,96 @@ func Load() Config {
 		MySQLUser:     getEnv("MYSQL_USER", "root"),
 		MySQLPassword: getEnv("MYSQL_PASSWORD", "secret"),
 		MySQLDB:       getEnv("MYSQL_DB", "sporthub_users"),
-		JWTSecret:     getEnv("JWT_SECRET", "change_me"),
-		JWTExpMinutes: getEnv("JWT_EXP_MINUTES", "60"),
+
+		MySQLMaxOpenConns:           getEnv("MYSQL_MAX_OPEN_CONNS", "20"),
+		MySQLMaxIdleConns:           getEnv("MYSQL_MAX_IDLE_CONNS", "10"),
+		MySQLConnMaxLifetimeMinutes: getEnv("MYSQL_CONN_MAX_LIFETIME_MINUTES", "30"),
+
+		JWTSigningKeyFile: getEnv("JWT_SIGNING_KEY_FILE", ""),
+		JWTVerifyKeyFiles: getEnv("JWT_VERIFY_KEY_FILES", ""),
+		JWTIssuer:         getEnv("JWT_ISSUER", "users-api"),
+		JWTAudience:       getEnv("JWT_AUDIENCE", "sporthub"),
+		JWTExpMinutes:     getEnv("JWT_EXP_MINUTES", "15"),
+
+		RefreshTokenTTLHours: getEnv("REFRESH_TOKEN_TTL_HOURS", "720"),
+
+		MailSender:   getEnv("MAIL_SENDER", "log"),
+		MailFrom:     getEnv("MAIL_FROM", "SportHub <no-reply@sporthub.local>"),
+		MailDir:      getEnv("MAIL_DIR", "./mail"),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUser:     getEnv("SMTP_USER", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+
+		PasswordResetURL:          getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+		PasswordResetTTLMinutes:   getEnv("PASSWORD_RESET_TTL_MINUTES", "60"),
+		PasswordForgotMaxPerEmail: getEnv("PASSWORD_FORGOT_MAX_PER_EMAIL", "3"),
+		PasswordForgotMaxPerIP:    getEnv("PASSWORD_FORGOT_MAX_PER_IP", "20"),
+
+		EmailVerifyURL:        getEnv("EMAIL_VERIFY_URL", "http://localhost:8081/auth/verify"),
+		EmailVerifyTTLHours:   getEnv("EMAIL_VERIFY_TTL_HOURS", "48"),
+		EmailVerifyResendSecs: getEnv("EMAIL_VERIFY_RESEND_SECONDS", "60"),
+		EmailVerifyMaxPerHour: getEnv("EMAIL_VERIFY_MAX_PER_HOUR", "5"),
+
+		LoginMaxFailures:    getEnv("LOGIN_MAX_FAILURES", "5"),
+		LoginIPMaxFailures:  getEnv("LOGIN_IP_MAX_FAILURES", "20"),
+		LoginLockoutMinutes: getEnv("LOGIN_LOCKOUT_MINUTES", "15"),
+		LoginWindowMinutes:  getEnv("LOGIN_WINDOW_MINUTES", "15"),
+		LoginAttemptsStore:  getEnv("LOGIN_ATTEMPTS_STORE", "mysql"),
+		TrustedProxies:      getEnv("TRUSTED_PROXIES", ""),
+
+		MFAIssuer:          getEnv("MFA_ISSUER", "SportHub"),
+		MFATokenTTLMinutes: getEnv("MFA_TOKEN_TTL_MINUTES", "5"),
+		RequireAdmin2FA:    getEnv("REQUIRE_ADMIN_2FA", "false") == "true",
+
+		ServiceClients:         getEnv("SERVICE_CLIENTS", ""),
+		ServiceTokenTTLMinutes: getEnv("SERVICE_TOKEN_TTL_MINUTES", "10"),
+
+		RabbitURL:           getEnv("RABBITMQ_URL", ""),
+		UsersEventsExchange: getEnv("USERS_EVENTS_EXCHANGE", "users.events"),
+
+		ActivitiesAPIBase:     getEnv("ACTIVITIES_API_BASE_URL", "http://localhost:8082"),
+		ActivitiesAPIAudience: getEnv("ACTIVITIES_API_AUDIENCE", "activities-api"),
+		ExportTTLHours:        getEnv("EXPORT_TTL_HOURS", "24"),
+
+		DefaultOrgID: getEnv("DEFAULT_ORG_ID", "1"),
+
+		OIDCProviders:       loadOIDCProviders(getEnv("OIDC_PROVIDERS", "")),
+		OIDCRedirectBaseURL: getEnv("OIDC_REDIRECT_BASE_URL", "http://localhost:8081"),
+		OIDCFrontendURL:     getEnv("OIDC_FRONTEND_URL", "http://localhost:3000/auth/callback"),
+		OIDCStateTTLMinutes: getEnv("OIDC_STATE_TTL_MINUTES", "10"),
 	}
 	log.Printf("config loaded: APP_PORT=%s MYSQL=%s:%s/%s", cfg.AppPort, cfg.MySQLHost, cfg.MySQLPort, cfg.MySQLDB)
 	return cfg
 }
 
+// loadOIDCProviders lee OIDC_<ID>_* de cada id de la lista; los que no tienen
+// issuer o client id se ignoran
+func loadOIDCProviders(ids string) []OIDCProvider {
+	var out []OIDCProvider
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if id == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(id, "-", "_")) + "_"
+		p := OIDCProvider{
+			ID:           id,
+			Name:         getEnv(prefix+"NAME", id),
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       getEnv(prefix+"SCOPES", "openid email profile"),
+		}
+		if p.Issuer == "" || p.ClientID == "" {
+			log.Printf("WARN: OIDC provider %q needs %sISSUER and %sCLIENT_ID, ignoring it", id, prefix, prefix)
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
 func getEnv(k, def string) string {
 	if v := os.Getenv(k); v != "" {
 		return v
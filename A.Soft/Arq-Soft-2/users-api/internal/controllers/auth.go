@@ -1,19 +1,26 @@
 package controllers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
 	"github.com/sporthub/users-api/internal/services"
+	"github.com/sporthub/users-api/internal/utils"
 )
 
 type AuthController struct {
-	svc services.UsersService
+	svc    services.UsersService
+	tokens services.TokenService
+	mfa    services.MFAService
 }
 
-func NewAuthController(cfg config.Config) *AuthController {
-	return &AuthController{svc: services.NewUsersService(cfg)}
+func NewAuthController(svc services.UsersService, tokens services.TokenService, mfa services.MFAService) *AuthController {
+	return &AuthController{svc: svc, tokens: tokens, mfa: mfa}
 }
 
 type loginReq struct {
@@ -27,14 +34,140 @@ func (a *AuthController) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	u, token, err := a.svc.Login(req.Login, req.Password)
+	u, err := a.svc.Authenticate(req.Login, req.Password, c.ClientIP())
 	if err != nil {
+		if writeThrottled(c, err) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
+	completeLogin(c, u, a.mfa, a.tokens)
+}
+
+// completeLogin es la respuesta de un login ya autenticado (contraseña o
+// proveedor OIDC): el token parcial si falta el 2FA, si no los tokens
+func completeLogin(c *gin.Context, u *domain.User, mfa services.MFAService, tokenSvc services.TokenService) {
+	// con 2FA el primer paso solo entrega un token parcial para /auth/2fa/*
+	challenge, err := mfa.Challenge(u)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not login"})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"mfaRequired":      challenge.Purpose == utils.MFAPurposeVerify,
+			"mfaSetupRequired": challenge.Purpose == utils.MFAPurposeSetup,
+			"mfaToken":         challenge.Token,
+			"expiresIn":        challenge.ExpiresIn,
+			"userId":           u.ID,
+		})
+		return
+	}
+
+	tokens, err := tokenSvc.Issue(u)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not issue tokens"})
+		return
+	}
+	writeTokens(c, u, tokens)
+}
+
+// writeTokens es la respuesta de login, refresh y segundo paso de 2FA
+func writeTokens(c *gin.Context, u *domain.User, tokens *services.Tokens) {
 	c.JSON(http.StatusOK, gin.H{
-		"token":  token,
-		"role":   u.Role,
-		"userId": u.ID,
+		"token":         tokens.AccessToken,
+		"refreshToken":  tokens.RefreshToken,
+		"expiresIn":     tokens.ExpiresIn,
+		"role":          u.Role,
+		"userId":        u.ID,
+		"emailVerified": u.EmailVerified,
+		"permissions":   domain.PermissionsFor(u.Role),
 	})
 }
+
+// writeThrottled responde 429 con Retry-After si err es un *ThrottledError
+func writeThrottled(c *gin.Context, err error) bool {
+	var throttled *services.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	return true
+}
+
+type refreshReq struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// Refresh rota el refresh token y devuelve un access token nuevo
+func (a *AuthController) Refresh(c *gin.Context) {
+	var req refreshReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	u, tokens, err := a.tokens.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not refresh token"})
+		return
+	}
+	writeTokens(c, u, tokens)
+}
+
+type logoutReq struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Logout revoca el access token actual y la sesión del refresh token (si viene)
+func (a *AuthController) Logout(c *gin.Context) {
+	var req logoutReq
+	_ = c.ShouldBindJSON(&req)
+
+	userID, _ := c.Get("userId")
+	uid, _ := userID.(uint64)
+	jti := c.GetString("jti")
+	exp, _ := c.Get("exp")
+	expAt, _ := exp.(time.Time)
+	if err := a.tokens.Logout(uid, req.RefreshToken, jti, expAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not logout"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type revokedItem struct {
+	JTI string `json:"jti"`
+	Exp int64  `json:"exp"`
+}
+
+// Revoked lista los access tokens revocados desde ?since (unix) que aún no
+// expiraron. Lo consultan periódicamente los otros servicios para rechazar
+// tokens revocados en su JWTAuth.
+func (a *AuthController) Revoked(c *gin.Context) {
+	var since time.Time
+	if s := c.Query("since"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since"})
+			return
+		}
+		since = time.Unix(n, 0)
+	}
+	now := time.Now()
+	list, err := a.tokens.RevokedSince(since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list revoked tokens"})
+		return
+	}
+	out := make([]revokedItem, 0, len(list))
+	for _, t := range list {
+		out = append(out, revokedItem{JTI: t.JTI, Exp: t.ExpiresAt.Unix()})
+	}
+	c.JSON(http.StatusOK, gin.H{"revoked": out, "now": now.Unix()})
+}
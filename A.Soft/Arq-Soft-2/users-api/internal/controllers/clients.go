@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+type ClientsController struct {
+	svc services.ClientsService
+}
+
+func NewClientsController(svc services.ClientsService) *ClientsController {
+	return &ClientsController{svc: svc}
+}
+
+// tokenReq sigue OAuth 2.0: form o JSON con snake_case
+type tokenReq struct {
+	GrantType    string `form:"grant_type"    json:"grant_type"    binding:"required"`
+	ClientID     string `form:"client_id"     json:"client_id"`
+	ClientSecret string `form:"client_secret" json:"client_secret"`
+}
+
+// Token emite un token de servicio (grant client_credentials). Las
+// credenciales pueden venir en el body o por HTTP Basic.
+func (cc *ClientsController) Token(c *gin.Context) {
+	var req tokenReq
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+	if req.GrantType != "client_credentials" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+	token, expiresIn, err := cc.svc.Token(req.ClientID, req.ClientSecret)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidClient) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not issue token"})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"access_token": token, "token_type": "Bearer", "expires_in": expiresIn})
+}
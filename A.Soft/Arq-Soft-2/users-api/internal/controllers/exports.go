@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+type ExportController struct {
+	svc services.ExportService
+}
+
+func NewExportController(svc services.ExportService) *ExportController {
+	return &ExportController{svc: svc}
+}
+
+func exportJSON(j *domain.ExportJob) gin.H {
+	h := gin.H{"id": j.ID, "status": j.Status, "createdAt": j.CreatedAt}
+	if j.CompletedAt != nil {
+		h["completedAt"] = j.CompletedAt
+	}
+	if j.Status == domain.ExportDone {
+		h["expiresAt"] = j.ExpiresAt
+		h["downloadUrl"] = fmt.Sprintf("/users/me/export/%s/download", j.ID)
+	}
+	if j.Status == domain.ExportFailed {
+		h["error"] = "export failed, try again later"
+	}
+	return h
+}
+
+// Start pide una exportación de los datos personales. Se arma en segundo
+// plano: responde 202 con el job y se consulta en GET /users/me/export/:id.
+func (e *ExportController) Start(c *gin.Context) {
+	job, err := e.svc.Start(c.GetUint64("userId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not start export"})
+		return
+	}
+	c.Header("Location", "/users/me/export/"+job.ID)
+	c.JSON(http.StatusAccepted, exportJSON(job))
+}
+
+// Latest devuelve el estado de la última exportación del usuario
+func (e *ExportController) Latest(c *gin.Context) {
+	job, err := e.svc.Latest(c.GetUint64("userId"))
+	if err != nil {
+		writeExportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, exportJSON(job))
+}
+
+func (e *ExportController) Status(c *gin.Context) {
+	job, err := e.svc.Get(c.GetUint64("userId"), c.Param("id"))
+	if err != nil {
+		writeExportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, exportJSON(job))
+}
+
+// Download entrega el zip (un sporthub-export.json adentro)
+func (e *ExportController) Download(c *gin.Context) {
+	data, err := e.svc.Download(c.GetUint64("userId"), c.Param("id"))
+	if err != nil {
+		writeExportError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.DataFromReader(http.StatusOK, int64(len(data)), "application/zip", bytes.NewReader(data), map[string]string{
+		"Content-Disposition": `attachment; filename="sporthub-export.zip"`,
+	})
+}
+
+func writeExportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrExportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrExportNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not get export"})
+	}
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+type MFAController struct {
+	svc    services.MFAService
+	tokens services.TokenService
+}
+
+func NewMFAController(svc services.MFAService, tokens services.TokenService) *MFAController {
+	return &MFAController{svc: svc, tokens: tokens}
+}
+
+type mfaVerifyReq struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+	Code     string `json:"code"     binding:"required"`
+}
+
+// Verify es el segundo paso del login: token parcial + código TOTP o de
+// recuperación, devuelve los tokens completos
+func (m *MFAController) Verify(c *gin.Context) {
+	var req mfaVerifyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	u, err := m.svc.Verify(req.MFAToken, req.Code, c.ClientIP())
+	if err != nil {
+		if writeThrottled(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidMFAToken) || errors.Is(err, services.ErrInvalidMFACode) || errors.Is(err, services.ErrMFANotEnabled) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify code"})
+		return
+	}
+	tokens, err := m.tokens.Issue(u)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not issue tokens"})
+		return
+	}
+	writeTokens(c, u, tokens)
+}
+
+// Status indica si el usuario autenticado tiene 2FA y cuántos códigos de
+// recuperación le quedan
+func (m *MFAController) Status(c *gin.Context) {
+	enabled, left, err := m.svc.Status(c.GetUint64("userId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not get two-factor status"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": enabled, "recoveryCodesLeft": left})
+}
+
+// Setup genera el secreto TOTP; se confirma con Enable
+func (m *MFAController) Setup(c *gin.Context) {
+	secret, uri, err := m.svc.Setup(c.GetUint64("userId"))
+	if err != nil {
+		if errors.Is(err, services.ErrMFAAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not start two-factor setup"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"secret": secret, "otpauthUri": uri})
+}
+
+type mfaCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// Enable activa 2FA con un código de la app y devuelve los códigos de
+// recuperación (solo se muestran esta vez). Si se llegó con el token parcial
+// de setup, también devuelve los tokens completos.
+func (m *MFAController) Enable(c *gin.Context) {
+	var req mfaCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.GetUint64("userId")
+	codes, err := m.svc.Enable(userID, req.Code)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	out := gin.H{"recoveryCodes": codes}
+	if c.GetBool("mfaSetup") {
+		u, tokens, err := m.issueFor(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not issue tokens"})
+			return
+		}
+		out["token"], out["refreshToken"], out["expiresIn"] = tokens.AccessToken, tokens.RefreshToken, tokens.ExpiresIn
+		out["role"], out["userId"], out["emailVerified"] = u.Role, u.ID, u.EmailVerified
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+func (m *MFAController) Disable(c *gin.Context) {
+	var req mfaCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := m.svc.Disable(c.GetUint64("userId"), req.Code, c.ClientIP()); err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RecoveryCodes reemplaza los códigos de recuperación (pide un código TOTP)
+func (m *MFAController) RecoveryCodes(c *gin.Context) {
+	var req mfaCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := m.svc.RegenerateRecoveryCodes(c.GetUint64("userId"), req.Code, c.ClientIP())
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
+func (m *MFAController) issueFor(userID uint64) (*domain.User, *services.Tokens, error) {
+	u, err := m.svc.User(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	tokens, err := m.tokens.Issue(u)
+	return u, tokens, err
+}
+
+func writeMFAError(c *gin.Context, err error) {
+	if writeThrottled(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMFARequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMFAAlreadyEnabled), errors.Is(err, services.ErrMFANotEnabled), errors.Is(err, services.ErrMFASetupMissing):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "two-factor operation failed"})
+	}
+}
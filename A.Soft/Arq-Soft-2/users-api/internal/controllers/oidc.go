@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+// OIDCController es el login con Google, Microsoft, etc.: /login manda al
+// proveedor, el proveedor vuelve a /callback y este redirige al frontend con
+// un código que el frontend canjea en /auth/oidc/exchange
+type OIDCController struct {
+	svc    services.OIDCService
+	mfa    services.MFAService
+	tokens services.TokenService
+}
+
+func NewOIDCController(svc services.OIDCService, mfa services.MFAService, tokens services.TokenService) *OIDCController {
+	return &OIDCController{svc: svc, mfa: mfa, tokens: tokens}
+}
+
+// Providers lista los proveedores configurados (para los botones del login)
+func (o *OIDCController) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": o.svc.Providers()})
+}
+
+// oidcStateCookie ata el login al navegador que lo empezó
+const oidcStateCookie = "oidc_state"
+
+// Link devuelve la URL a la que el frontend manda al usuario logueado para
+// vincular su cuenta externa; el callback vuelve con ?linked=<proveedor>
+func (o *OIDCController) Link(c *gin.Context) {
+	o.ticket(c, domain.OIDCPurposeLink)
+}
+
+// Reauth es como Link pero para volver a probar quién es con una cuenta ya
+// vinculada; el callback vuelve con ?reauth=<código>, que sirve en vez de
+// la contraseña para DELETE /users/me
+func (o *OIDCController) Reauth(c *gin.Context) {
+	o.ticket(c, domain.OIDCPurposeReauth)
+}
+
+func (o *OIDCController) ticket(c *gin.Context, purpose string) {
+	u, err := o.svc.Ticket(c.GetUint64("userId"), c.Param("provider"), purpose)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not start the link"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"url": u})
+}
+
+// Login redirige al proveedor (authorization code + PKCE) y deja el state
+// en una cookie HttpOnly; Lax para que viaje en el redirect de vuelta. Con
+// ?ticket= (ver Link) no es un login sino la vinculación de la cuenta
+func (o *OIDCController) Login(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+	start, err := o.svc.Start(ctx, c.Param("provider"), c.Query("ticket"))
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidOIDCTicket) {
+			c.Redirect(http.StatusFound, o.svc.FrontendURL(url.Values{"error": {services.OIDCErrorCode(err)}}))
+			return
+		}
+		log.Printf("[auth] ERROR: oidc login with %s: %v", c.Param("provider"), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider unavailable"})
+		return
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    start.State,
+		Path:     start.CookiePath,
+		Expires:  start.ExpiresAt,
+		MaxAge:   int(time.Until(start.ExpiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   start.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Redirect(http.StatusFound, start.AuthURL)
+}
+
+// Callback es la vuelta del proveedor. Siempre termina en el frontend:
+// con ?code= si salió bien o con ?error= si no. El state tiene que ser el de
+// la cookie: si no, alguien está completando en este navegador un login que
+// empezó él (y el usuario quedaría logueado en la cuenta del atacante)
+func (o *OIDCController) Callback(c *gin.Context) {
+	provider := c.Param("provider")
+	bound, _ := c.Cookie(oidcStateCookie)
+	// la cookie es de un solo uso
+	http.SetCookie(c.Writer, &http.Cookie{Name: oidcStateCookie, Path: c.Request.URL.Path, MaxAge: -1, HttpOnly: true})
+	if e := c.Query("error"); e != "" {
+		// el usuario canceló o el proveedor rechazó el pedido
+		c.Redirect(http.StatusFound, o.svc.FrontendURL(url.Values{"error": {e}}))
+		return
+	}
+	state := c.Query("state")
+	if bound == "" || subtle.ConstantTimeCompare([]byte(bound), []byte(state)) != 1 {
+		log.Printf("[auth] oidc callback from %s without a matching state cookie", provider)
+		c.Redirect(http.StatusFound, o.svc.FrontendURL(url.Values{"error": {services.OIDCErrorCode(services.ErrInvalidOIDCState)}}))
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+	res, err := o.svc.Callback(ctx, provider, state, c.Query("code"))
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("[auth] oidc callback from %s failed: %v", provider, err)
+		c.Redirect(http.StatusFound, o.svc.FrontendURL(url.Values{"error": {services.OIDCErrorCode(err)}}))
+		return
+	}
+	switch res.Purpose {
+	case domain.OIDCPurposeLink:
+		c.Redirect(http.StatusFound, o.svc.FrontendURL(url.Values{"linked": {res.Provider}}))
+		return
+	case domain.OIDCPurposeReauth:
+		c.Redirect(http.StatusFound, o.svc.FrontendURL(url.Values{"reauth": {res.Code}}))
+		return
+	}
+	c.Redirect(http.StatusFound, o.svc.FrontendURL(url.Values{"code": {res.Code}}))
+}
+
+type oidcExchangeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// Exchange canjea el código del callback; responde igual que /auth/login
+func (o *OIDCController) Exchange(c *gin.Context) {
+	var req oidcExchangeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	u, err := o.svc.Exchange(req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidOIDCCode) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not login"})
+		return
+	}
+	completeLogin(c, u, o.mfa, o.tokens)
+}
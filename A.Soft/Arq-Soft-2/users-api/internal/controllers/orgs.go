@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sporthub/shared/authz"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/middleware"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+type OrgsController struct {
+	svc services.OrgsService
+}
+
+func NewOrgsController(svc services.OrgsService) *OrgsController {
+	return &OrgsController{svc: svc}
+}
+
+type createOrgReq struct {
+	Name string `json:"name" binding:"required,min=2,max=120"`
+	Slug string `json:"slug" binding:"required"`
+}
+
+// Create da de alta un club; quien lo crea queda como su admin
+func (o *OrgsController) Create(c *gin.Context) {
+	var req createOrgReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	org, err := o.svc.Create(req.Name, req.Slug, c.GetUint64("userId"))
+	if err != nil {
+		writeOrgError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, org)
+}
+
+// Mine son los clubes del usuario con su rol en cada uno. La organización
+// por defecto no aparece si no es miembro: ahí vale su rol global.
+func (o *OrgsController) Mine(c *gin.Context) {
+	out, err := o.svc.ListForUser(c.GetUint64("userId"))
+	if err != nil {
+		writeOrgError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"organizations": out})
+}
+
+func (o *OrgsController) Get(c *gin.Context) {
+	id, ok := orgParam(c)
+	if !ok {
+		return
+	}
+	org, err := o.svc.Get(id)
+	if err != nil {
+		writeOrgError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+func (o *OrgsController) Members(c *gin.Context) {
+	id, ok := orgParam(c)
+	if !ok {
+		return
+	}
+	out, err := o.svc.Members(id)
+	if err != nil {
+		writeOrgError(c, err)
+		return
+	}
+	if out == nil {
+		out = []domain.Membership{}
+	}
+	c.JSON(http.StatusOK, gin.H{"members": out})
+}
+
+// Member devuelve la membresía de un usuario en el club (404 si no es
+// miembro). La ven el propio usuario, quien gestiona el club y los servicios
+// (activities-api la usa para validar instructores por club).
+func (o *OrgsController) Member(c *gin.Context) {
+	id, ok := orgParam(c)
+	if !ok {
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	if c.GetString("service") == "" && c.GetUint64("userId") != userID && !middleware.HasOrgPermission(c, c.Param("id"), authz.PermOrgManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "missing permission " + authz.PermOrgManage + " in organization"})
+		return
+	}
+	m, err := o.svc.Member(id, userID)
+	if err != nil {
+		writeOrgError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+type setMemberReq struct {
+	Role domain.OrgRole `json:"role" binding:"required,oneof=member instructor admin"`
+}
+
+// SetMember agrega un usuario al club o le cambia el rol. Los permisos nuevos
+// llegan en su próximo access token.
+func (o *OrgsController) SetMember(c *gin.Context) {
+	id, ok := orgParam(c)
+	if !ok {
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	var req setMemberReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	m, err := o.svc.SetMember(id, userID, req.Role)
+	if err != nil {
+		writeOrgError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+func (o *OrgsController) RemoveMember(c *gin.Context) {
+	id, ok := orgParam(c)
+	if !ok {
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	if err := o.svc.RemoveMember(id, userID); err != nil {
+		writeOrgError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func orgParam(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeOrgError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOrgNotFound), errors.Is(err, services.ErrNotOrgMember), errors.Is(err, services.ErrOrgUserMissing):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidOrgSlug), errors.Is(err, services.ErrInvalidOrgRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOrgSlugTaken), errors.Is(err, services.ErrLastOrgAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not process organization request"})
+	}
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+type PasswordController struct {
+	svc    services.PasswordService
+	tokens services.TokenService
+}
+
+func NewPasswordController(svc services.PasswordService, tokens services.TokenService) *PasswordController {
+	return &PasswordController{svc: svc, tokens: tokens}
+}
+
+type forgotPasswordReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// Forgot siempre responde 202 con el mismo mensaje (o 429 si se pidieron
+// demasiados resets para ese email o desde esa IP). El envío corre en
+// background para que tampoco el tiempo de respuesta revele si el email existe.
+func (p *PasswordController) Forgot(c *gin.Context) {
+	var req forgotPasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := p.svc.RequestReset(req.Email, c.ClientIP()); err != nil {
+		var throttled *services.ThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(throttled.RetryAfter.Seconds())))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many password reset requests"})
+			return
+		}
+		log.Printf("[auth] ERROR: password reset request: %v", err)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the email is registered, a reset link has been sent"})
+}
+
+type resetPasswordReq struct {
+	Token    string `json:"token"    binding:"required"`
+	Password string `json:"password" binding:"required,min=6,max=72"`
+}
+
+// Reset cambia la contraseña con el token del email y cierra las sesiones abiertas
+func (p *PasswordController) Reset(c *gin.Context) {
+	var req resetPasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := p.svc.Reset(req.Token, req.Password); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not reset password"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type changePasswordReq struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword"     binding:"required,min=6,max=72"`
+}
+
+// Change cambia la contraseña del usuario del JWT. Cierra todas sus sesiones y
+// devuelve tokens nuevos para que la actual siga logueada. Con demasiados
+// intentos fallidos responde 429 como el login.
+func (p *PasswordController) Change(c *gin.Context) {
+	var req changePasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	u, err := p.svc.Change(c.GetUint64("userId"), req.CurrentPassword, req.NewPassword, c.ClientIP())
+	if err != nil {
+		if writeThrottled(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrWrongPassword) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not change password"})
+		return
+	}
+	tokens, err := p.tokens.Issue(u)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not issue tokens"})
+		return
+	}
+	writeTokens(c, u, tokens)
+}
@@ -1,29 +1,42 @@
 package controllers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/shared/authz"
 	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
 	"github.com/sporthub/users-api/internal/services"
 )
 
 type UsersController struct {
 	svc services.UsersService
+	// verify manda el email de verificación al registrarse (nil = no se manda)
+	verify services.VerificationService
 }
 
-func NewUsersController() *UsersController {
-	cfg := config.Load()
-	return &UsersController{svc: services.NewUsersService(cfg)}
+func NewUsersController(svc services.UsersService, verify services.VerificationService) *UsersController {
+	return &UsersController{svc: svc, verify: verify}
 }
 
+// NewUsersControllerWithService permite inyectar el servicio (tests)
+func NewUsersControllerWithService(svc services.UsersService) *UsersController {
+	return &UsersController{svc: svc}
+}
+
+// createUserReq es el alta pública: el rol no se elige, siempre es user salvo
+// que venga una invitación que otorgue otro
 type createUserReq struct {
-	Username string      `json:"username" binding:"required,min=3,max=50"`
-	Email    string      `json:"email"    binding:"required,email"`
-	Password string      `json:"password" binding:"required,min=6,max=72"`
-	Role     domain.Role `json:"rol"     binding:"required,oneof=user normal admin"`
+	Username    string `json:"username" binding:"required,min=3,max=50"`
+	Email       string `json:"email"    binding:"required,email"`
+	Password    string `json:"password" binding:"required,min=6,max=72"`
+	InviteToken string `json:"inviteToken"`
 }
 
 func (c *UsersController) CreateUser(ctx *gin.Context) {
@@ -32,13 +45,24 @@ func (c *UsersController) CreateUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	u, err := c.svc.Create(req.Username, req.Email, req.Password, req.Role)
+	u, err := c.svc.Register(req.Username, req.Email, req.Password, req.InviteToken)
 	if err != nil {
-		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrInvalidInvitation):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrUsernameTaken), errors.Is(err, services.ErrEmailTaken):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("[auth] ERROR: register %q: %v", req.Username, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not create user"})
+		}
 		return
 	}
+	if c.verify != nil {
+		sendVerificationAsync(c.verify, u.ID)
+	}
 	ctx.JSON(http.StatusCreated, gin.H{
-		"id": u.ID, "username": u.Username, "email": u.Email, "role": u.Role,
+		"id": u.ID, "username": u.Username, "email": u.Email, "role": u.Role, "emailVerified": u.EmailVerified,
 	})
 }
 
@@ -49,16 +73,190 @@ func (c *UsersController) GetByID(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
+	// cada usuario se ve a sí mismo; con user:read y los servicios ven a cualquiera
+	if ctx.GetString("service") == "" && !authz.HasPermission(ctx, authz.PermUserRead) && ctx.GetUint64("userId") != id {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
 	u, err := c.svc.GetByID(id)
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
+	// vista resumida: los datos de contacto quedan solo en /users/me
 	ctx.JSON(http.StatusOK, gin.H{
 		"id": u.ID, "username": u.Username, "email": u.Email, "role": u.Role,
+		"displayName": u.DisplayName, "emailVerified": u.EmailVerified,
 	})
 }
 
+// Roles devuelve los roles y los permisos de cada uno
+func (c *UsersController) Roles(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"roles": authz.RolePermissions})
+}
+
+// profileJSON es el perfil completo, solo para el propio usuario
+func profileJSON(u *domain.User) gin.H {
+	var dob string
+	if u.DateOfBirth != nil {
+		dob = u.DateOfBirth.Format("2006-01-02")
+	}
+	return gin.H{
+		"id": u.ID, "username": u.Username, "email": u.Email, "role": u.Role,
+		"emailVerified": u.EmailVerified, "displayName": u.DisplayName, "phone": u.Phone,
+		"dateOfBirth": dob, "emergencyContactName": u.EmergencyContactName,
+		"emergencyContactPhone": u.EmergencyContactPhone, "createdAt": u.CreatedAt,
+	}
+}
+
+// Me devuelve el perfil del usuario del JWT
+func (c *UsersController) Me(ctx *gin.Context) {
+	u, err := c.svc.GetByID(ctx.GetUint64("userId"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, profileJSON(u))
+}
+
+type updateProfileReq struct {
+	Username              *string `json:"username"              binding:"omitempty,min=3,max=50"`
+	Email                 *string `json:"email"                 binding:"omitempty,email"`
+	DisplayName           *string `json:"displayName"           binding:"omitempty,max=100"`
+	Phone                 *string `json:"phone"                 binding:"omitempty,max=30"`
+	DateOfBirth           *string `json:"dateOfBirth"`
+	EmergencyContactName  *string `json:"emergencyContactName"  binding:"omitempty,max=100"`
+	EmergencyContactPhone *string `json:"emergencyContactPhone" binding:"omitempty,max=30"`
+}
+
+// UpdateMe edita el perfil propio. Cambiar el email deja la cuenta sin
+// verificar, manda un link nuevo a la dirección nueva y avisa a la anterior.
+func (c *UsersController) UpdateMe(ctx *gin.Context) {
+	var req updateProfileReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	u, previousEmail, err := c.svc.UpdateProfile(ctx.GetUint64("userId"), services.ProfileUpdate{
+		Username:              req.Username,
+		Email:                 req.Email,
+		DisplayName:           req.DisplayName,
+		Phone:                 req.Phone,
+		DateOfBirth:           req.DateOfBirth,
+		EmergencyContactName:  req.EmergencyContactName,
+		EmergencyContactPhone: req.EmergencyContactPhone,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUsernameTaken), errors.Is(err, services.ErrEmailTaken):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidDateOfBirth):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not update profile"})
+		}
+		return
+	}
+	if previousEmail != "" && c.verify != nil {
+		sendVerificationAsync(c.verify, u.ID)
+		notifyEmailChangedAsync(c.verify, previousEmail, u)
+	}
+	ctx.JSON(http.StatusOK, profileJSON(u))
+}
+
+type listUsersQuery struct {
+	Search      string `form:"q"`
+	Role        string `form:"rol"         binding:"omitempty,oneof=user instructor admin"`
+	CreatedFrom string `form:"createdFrom"`
+	CreatedTo   string `form:"createdTo"`
+	Sort        string `form:"sort,default=-createdAt"`
+	Page        int    `form:"page,default=1"  binding:"min=1"`
+	Size        int    `form:"size,default=20" binding:"min=1,max=100"`
+}
+
+// List es el directorio de usuarios (solo admin). sort acepta username, email
+// o createdAt, con "-" adelante para orden descendente. Las fechas son
+// YYYY-MM-DD y createdTo incluye ese día.
+func (c *UsersController) List(ctx *gin.Context) {
+	var q listUsersQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f := repository.UserFilter{
+		Search: q.Search,
+		Role:   domain.Role(q.Role),
+		Sort:   strings.TrimPrefix(q.Sort, "-"),
+		Desc:   strings.HasPrefix(q.Sort, "-"),
+		Offset: (q.Page - 1) * q.Size,
+		Limit:  q.Size,
+	}
+	for _, d := range []struct {
+		value string
+		dst   **time.Time
+		days  int
+	}{{q.CreatedFrom, &f.CreatedFrom, 0}, {q.CreatedTo, &f.CreatedTo, 1}} {
+		if d.value == "" {
+			continue
+		}
+		t, err := time.ParseInLocation("2006-01-02", d.value, time.Local)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+			return
+		}
+		t = t.AddDate(0, 0, d.days)
+		*d.dst = &t
+	}
+
+	users, total, err := c.svc.List(f)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSort) || errors.Is(err, services.ErrInvalidRole) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not list users"})
+		return
+	}
+	items := make([]gin.H, 0, len(users))
+	for _, u := range users {
+		items = append(items, gin.H{
+			"id": u.ID, "username": u.Username, "email": u.Email, "role": u.Role,
+			"displayName": u.DisplayName, "emailVerified": u.EmailVerified, "createdAt": u.CreatedAt,
+		})
+	}
+	ctx.JSON(http.StatusOK, gin.H{"users": items, "total": total, "page": q.Page, "size": q.Size})
+}
+
+type deleteAccountReq struct {
+	Password   string `json:"password" binding:"required_without=ReauthCode"`
+	ReauthCode string `json:"reauthCode"`
+}
+
+// DeleteMe da de baja la cuenta del usuario del JWT (pide la contraseña o,
+// si entra con Google/Microsoft, el código de POST /auth/oidc/:provider/reauth)
+func (c *UsersController) DeleteMe(ctx *gin.Context) {
+	var req deleteAccountReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := c.svc.DeleteAccount(ctx.GetUint64("userId"), req.Password, req.ReauthCode, ctx.ClientIP()); err != nil {
+		if writeThrottled(ctx, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrWrongPassword), errors.Is(err, services.ErrInvalidReauth):
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrLastAdmin):
+			ctx.JSON(http.StatusConflict, gin.H{"error": "cannot delete the last admin"})
+		default:
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		}
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
 func (c *UsersController) Delete(ctx *gin.Context) {
 	idStr := ctx.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
@@ -76,3 +274,75 @@ func (c *UsersController) Delete(ctx *gin.Context) {
 	}
 	ctx.Status(http.StatusNoContent)
 }
+
+type changeRoleReq struct {
+	Role domain.Role `json:"rol" binding:"required,oneof=user instructor admin"`
+}
+
+// ChangeRole cambia el rol de un usuario (solo admin)
+func (c *UsersController) ChangeRole(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req changeRoleReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	u, err := c.svc.ChangeRole(id, req.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidRole):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrLastAdmin):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"id": u.ID, "username": u.Username, "email": u.Email, "role": u.Role,
+	})
+}
+
+type createInvitationReq struct {
+	Role     domain.Role `json:"rol"      binding:"required,oneof=user instructor admin"`
+	Email    string      `json:"email"    binding:"omitempty,email"`
+	TTLHours int         `json:"ttlHours" binding:"omitempty,min=1,max=720"`
+}
+
+// CreateInvitation genera un token de invitación de un solo uso (solo admin).
+// El token se devuelve solo en esta respuesta.
+func (c *UsersController) CreateInvitation(ctx *gin.Context) {
+	var req createInvitationReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminID := ctx.GetUint64("userId")
+	token, inv, err := c.svc.CreateInvitation(req.Role, req.Email, time.Duration(req.TTLHours)*time.Hour, adminID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not create invitation"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{
+		"inviteToken": token, "rol": inv.Role, "email": inv.Email, "expiresAt": inv.ExpiresAt,
+	})
+}
+
+// UnlockLogin levanta el bloqueo por logins fallidos de un usuario (solo admin)
+func (c *UsersController) UnlockLogin(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := c.svc.UnlockLogin(id, ctx.GetUint64("userId")); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+type VerificationController struct {
+	svc services.VerificationService
+}
+
+func NewVerificationController(svc services.VerificationService) *VerificationController {
+	return &VerificationController{svc: svc}
+}
+
+type verifyReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// Verify acepta el token por query (GET, el link del email) o en el body
+// (POST, si el frontend muestra su propia página)
+func (v *VerificationController) Verify(c *gin.Context) {
+	token := c.Query("token")
+	if c.Request.Method == http.MethodPost {
+		var req verifyReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		token = req.Token
+	}
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing token"})
+		return
+	}
+	u, err := v.svc.Verify(token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidVerifyToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify email"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"userId": u.ID, "email": u.Email, "emailVerified": u.EmailVerified})
+}
+
+// Resend vuelve a mandar el email de verificación al usuario autenticado
+func (v *VerificationController) Resend(c *gin.Context) {
+	userID := c.GetUint64("userId")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	if err := v.svc.Send(ctx, userID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrAlreadyVerified):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrVerifyRateLimited):
+			c.Header("Retry-After", strconv.Itoa(int(v.svc.ResendInterval().Seconds())))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			log.Printf("[auth] ERROR: verification email for user %d: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not send verification email"})
+		}
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// sendVerificationAsync manda el primer email de verificación en background,
+// sin demorar la respuesta del registro
+func sendVerificationAsync(svc services.VerificationService, userID uint64) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := svc.Send(ctx, userID); err != nil {
+			log.Printf("[auth] ERROR: verification email for user %d: %v", userID, err)
+		}
+	}()
+}
+
+// notifyEmailChangedAsync avisa del cambio de email a la dirección anterior en
+// background
+func notifyEmailChangedAsync(svc services.VerificationService, previousEmail string, u *domain.User) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := svc.NotifyEmailChanged(ctx, previousEmail, u); err != nil {
+			log.Printf("[auth] ERROR: email change notice for user %d: %v", u.ID, err)
+		}
+	}()
+}
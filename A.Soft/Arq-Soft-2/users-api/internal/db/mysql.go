@@ -1,34 +1,86 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
 	"github.com/sporthub/users-api/internal/config"
-	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/migrations"
 )
 
-func MustInitMySQL(cfg config.Config) *sql.DB {
+// MustInitMySQL abre la conexión (el único pool del proceso, que se inyecta en
+// los repositorios) y verifica que el esquema esté al día: con migraciones
+// pendientes o una migración a medio aplicar users-api no arranca (se migra
+// con "users-api migrate up").
+func MustInitMySQL(cfg config.Config) *gorm.DB {
+	gdb := MustOpenMySQL(cfg)
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		log.Fatalf("mysql db error: %v", err)
+	}
+
+	runner, err := NewMigrationRunner(sqlDB)
+	if err != nil {
+		log.Fatalf("mysql migrations: %v", err)
+	}
+	if err := runner.Check(context.Background()); err != nil {
+		if errors.Is(err, migrations.ErrPending) {
+			log.Fatalf("mysql schema is not up to date (%v): run `users-api migrate up`", err)
+		}
+		log.Fatalf("mysql schema check: %v", err)
+	}
+	return gdb
+}
+
+// MustOpenMySQL abre la conexión con los límites de pool de la config, sin
+// mirar el esquema (la usa "migrate")
+func MustOpenMySQL(cfg config.Config) *gorm.DB {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&charset=utf8mb4&loc=Local",
 		cfg.MySQLUser, cfg.MySQLPassword, cfg.MySQLHost, cfg.MySQLPort, cfg.MySQLDB)
 
-	gdb, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// TranslateError: los duplicados llegan como gorm.ErrDuplicatedKey
+	gdb, err := gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatalf("mysql connect error: %v", err)
 	}
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		log.Fatalf("mysql db error: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(intOr(cfg.MySQLMaxOpenConns, 20))
+	sqlDB.SetMaxIdleConns(intOr(cfg.MySQLMaxIdleConns, 10))
+	sqlDB.SetConnMaxLifetime(time.Duration(intOr(cfg.MySQLConnMaxLifetimeMinutes, 30)) * time.Minute)
+	return gdb
+}
+
+// Close cierra el pool de gdb
+func Close(gdb *gorm.DB) {
+	if sqlDB, err := gdb.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
 
-	// Auto-migrate
-	if err := gdb.AutoMigrate(&domain.User{}); err != nil {
-		log.Fatalf("mysql migrate error: %v", err)
+func intOr(v string, def int) int {
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return def
 	}
+	return n
+}
 
-	sqlDB, err := gdb.DB()
+// NewMigrationRunner arma el runner con las migraciones embebidas en el binario
+func NewMigrationRunner(sqlDB *sql.DB) (*migrations.Runner, error) {
+	migs, err := migrations.Embedded()
 	if err != nil {
-		log.Fatalf("mysql db error: %v", err)
+		return nil, err
 	}
-	return sqlDB
+	return migrations.NewRunner(migrations.NewMySQLStore(sqlDB), migs), nil
 }
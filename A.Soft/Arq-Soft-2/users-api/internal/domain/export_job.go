@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// Estados de ExportJob
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportDone    = "done"
+	ExportFailed  = "failed"
+)
+
+// ExportJob es una exportación de datos personales pedida por el usuario.
+// Se arma en segundo plano; cuando termina, Data guarda el zip con el JSON
+// hasta ExpiresAt. Mientras está pending o running ActiveUserID es el
+// usuario (índice único: una sola activa por usuario) y StartedAt dice desde
+// cuándo; si no termina a tiempo se da por perdida.
+type ExportJob struct {
+	ID           string  `gorm:"primaryKey;size:32"`
+	UserID       uint64  `gorm:"index;not null"`
+	ActiveUserID *uint64 `gorm:"uniqueIndex"`
+	Status       string  `gorm:"size:16;not null"`
+	Error        string  `gorm:"size:255"`
+	Data         []byte  `gorm:"type:longblob"`
+	CreatedAt    time.Time
+	StartedAt    *time.Time
+	CompletedAt  *time.Time
+	ExpiresAt    *time.Time `gorm:"index"`
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// ExternalIdentity vincula un usuario con su cuenta en un proveedor OIDC
+// (Google, Microsoft...). La identifica el par proveedor + claim sub: el
+// email solo se usa la primera vez, para encontrar al usuario.
+type ExternalIdentity struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      uint64     `gorm:"index;not null" json:"userId"`
+	Provider    string     `gorm:"size:32;uniqueIndex:idx_external_identities_provider_subject;not null" json:"provider"`
+	Subject     string     `gorm:"size:255;uniqueIndex:idx_external_identities_provider_subject;not null" json:"subject"`
+	Email       string     `gorm:"size:120" json:"email"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	LastLoginAt *time.Time `json:"lastLoginAt,omitempty"`
+}
+
+// Para qué se manda al usuario al proveedor
+const (
+	OIDCPurposeLogin = "login"
+	// un usuario logueado vincula su cuenta externa
+	OIDCPurposeLink = "link"
+	// un usuario logueado vuelve a probar que es él (p.ej. para darse de
+	// baja si entró siempre con el proveedor y no conoce su contraseña)
+	OIDCPurposeReauth = "reauth"
+)
+
+// OIDCLogin es un login con un proveedor externo en curso: se guarda al
+// mandar al usuario al proveedor y se consume en el callback. El state es de
+// un solo uso (se guarda su hash); el code verifier (PKCE) y el nonce se
+// usan para canjear el code y validar el id_token. Si no es un login UserID
+// es el usuario logueado que lo empezó.
+type OIDCLogin struct {
+	StateHash    string    `gorm:"primaryKey;size:64"`
+	Provider     string    `gorm:"size:32;not null"`
+	Purpose      string    `gorm:"size:16;not null;default:login"`
+	CodeVerifier string    `gorm:"size:128;not null"`
+	Nonce        string    `gorm:"size:64;not null"`
+	ExpiresAt    time.Time `gorm:"index;not null"`
+	CreatedAt    time.Time
+	UserID       *uint64
+}
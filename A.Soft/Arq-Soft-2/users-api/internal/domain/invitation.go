@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// Invitation permite registrarse con un rol distinto de user. El token se
+// entrega una sola vez al crearla y solo se guarda su hash; es de un solo uso
+// y vence en ExpiresAt. Si Email no está vacío, solo sirve para ese email.
+type Invitation struct {
+	ID        uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Role      Role       `gorm:"type:enum('user','instructor','admin');not null" json:"rol"`
+	Email     string     `gorm:"size:120" json:"email,omitempty"`
+	CreatedBy uint64     `json:"createdBy"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
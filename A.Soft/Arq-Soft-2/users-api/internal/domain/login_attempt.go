@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// LoginAttempt cuenta los logins fallidos de una clave ("user:<id>",
+// "login:<nombre>" o "ip:<dirección>") dentro de la ventana configurada.
+// LockedUntil se setea al superar el máximo de fallos.
+type LoginAttempt struct {
+	Key           string     `gorm:"primaryKey;size:191"`
+	Failures      int        `gorm:"not null;default:0"`
+	LastFailureAt time.Time  `gorm:"not null"`
+	LockedUntil   *time.Time `gorm:"index"`
+}
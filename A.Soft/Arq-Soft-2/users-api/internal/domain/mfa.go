@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// MFA es la configuración TOTP de un usuario. El secreto se guarda al pedir
+// el setup y recién cuenta como activado cuando se confirma con un código.
+// LastStep es el último paso TOTP aceptado: no se acepta dos veces el mismo código.
+type MFA struct {
+	UserID    uint64 `gorm:"primaryKey"`
+	Secret    string `gorm:"size:64;not null"`
+	Enabled   bool   `gorm:"not null;default:false"`
+	LastStep  int64  `gorm:"not null;default:0"`
+	EnabledAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RecoveryCode es un código de recuperación de un solo uso (se guarda el hash)
+type RecoveryCode struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement"`
+	UserID    uint64 `gorm:"index;not null"`
+	CodeHash  string `gorm:"size:64;uniqueIndex;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
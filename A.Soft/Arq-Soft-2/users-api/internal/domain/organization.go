@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// Organization es un club. En activities-api sus actividades, sesiones e
+// inscripciones llevan su id como orgId y search-api indexa cada documento
+// con él.
+type Organization struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Slug      string    `gorm:"size:60;uniqueIndex;not null" json:"slug"`
+	Name      string    `gorm:"size:120;not null" json:"name"`
+	CreatedBy uint64    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// OrgRole es el rol de un usuario dentro de una organización (independiente
+// de su rol global)
+type OrgRole string
+
+const (
+	OrgRoleMember     OrgRole = "member"
+	OrgRoleInstructor OrgRole = "instructor"
+	OrgRoleAdmin      OrgRole = "admin"
+)
+
+// ValidOrgRole indica si role es uno de los roles de organización
+func ValidOrgRole(role OrgRole) bool {
+	return role == OrgRoleMember || role == OrgRoleInstructor || role == OrgRoleAdmin
+}
+
+// Membership es la pertenencia de un usuario a una organización
+type Membership struct {
+	OrgID     uint64    `gorm:"primaryKey;autoIncrement:false" json:"orgId"`
+	UserID    uint64    `gorm:"primaryKey;autoIncrement:false;index" json:"userId"`
+	Role      OrgRole   `gorm:"type:enum('member','instructor','admin');not null" json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// OutboxEvent es un evento para los otros servicios guardado en la misma
+// transacción que el cambio que lo produce (transactional outbox): si el
+// proceso se cae después del commit el evento sigue ahí y events.Relay lo
+// publica cuando puede.
+type OutboxEvent struct {
+	ID          uint64  `gorm:"primaryKey;autoIncrement"`
+	RoutingKey  string  `gorm:"size:64;not null"`
+	UserID      *uint64 `gorm:"index"`              // usuario del evento (exportación de datos)
+	Payload     string  `gorm:"type:text;not null"` // JSON
+	CreatedAt   time.Time
+	Attempts    int
+	LastError   string `gorm:"size:255"`
+	LockedBy    string `gorm:"size:32"`
+	LockedUntil *time.Time
+	PublishedAt *time.Time `gorm:"index"`
+}
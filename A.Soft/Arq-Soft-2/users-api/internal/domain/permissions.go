@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/sporthub/shared/authz"
+)
+
+// Los permisos y qué rol tiene cuáles están en shared/authz, que también
+// usan activities-api y search-api para exigirlos.
+
+// PermissionsFor devuelve una copia de los permisos del rol (nil si no existe)
+func PermissionsFor(role Role) []string {
+	return authz.PermissionsFor(string(role))
+}
+
+// OrgPermissions arma el claim orgs (orgId -> permisos en esa organización).
+// En la organización por defecto todos tienen además los de su rol global,
+// que es como funcionaba todo antes de que existieran los clubes.
+func OrgPermissions(defaultOrgID string, role Role, memberships []Membership) map[string][]string {
+	out := map[string][]string{}
+	if defaultOrgID != "" {
+		out[defaultOrgID] = PermissionsFor(role)
+	}
+	for _, m := range memberships {
+		id := strconv.FormatUint(m.OrgID, 10)
+		for _, p := range authz.OrgPermissionsFor(string(m.Role)) {
+			if !slices.Contains(out[id], p) {
+				out[id] = append(out[id], p)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// RefreshToken guarda el hash (SHA-256) de un refresh token emitido. Los
+// tokens que salen de rotar uno mismo comparten FamilyID, así si se reusa un
+// token ya rotado se puede revocar toda la familia. AccessJTI es el access
+// token que se emitió junto con él, para revocarlo con la sesión.
+type RefreshToken struct {
+	ID              uint64     `gorm:"primaryKey;autoIncrement"`
+	UserID          uint64     `gorm:"index;not null"`
+	FamilyID        string     `gorm:"size:64;index;not null"`
+	TokenHash       string     `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt       time.Time  `gorm:"not null"`
+	RevokedAt       *time.Time `gorm:"index"`
+	CreatedAt       time.Time
+	AccessJTI       string `gorm:"size:64"`
+	AccessExpiresAt *time.Time
+}
+
+// RevokedToken es un access token revocado (por jti) antes de expirar
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+	RevokedAt time.Time `gorm:"index;not null"`
+}
@@ -5,17 +5,33 @@ import "time"
 type Role string
 
 const (
-	RoleUser   Role = "user"
-	RoleNormal Role = "normal" // deprecated, use RoleUser instead
-	RoleAdmin  Role = "admin"
+	RoleUser       Role = "user"
+	RoleInstructor Role = "instructor"
+	RoleAdmin      Role = "admin"
 )
 
+// ValidRole indica si role es uno de los roles existentes
+func ValidRole(role Role) bool {
+	return role == RoleUser || role == RoleInstructor || role == RoleAdmin
+}
+
 type User struct {
 	ID           uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
 	Username     string    `gorm:"size:50;uniqueIndex;not null" json:"username"`
 	Email        string    `gorm:"size:120;uniqueIndex;not null" json:"email"`
 	PasswordHash string    `gorm:"size:255;not null" json:"-"`
-	Role         Role      `gorm:"type:enum('user','normal','admin');default:'user';not null" json:"rol"`
+	Role         Role      `gorm:"type:enum('user','instructor','admin');default:'user';not null" json:"rol"`
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
+
+	// EmailVerified se marca al usar el link de verificación enviado al registrarse
+	EmailVerified   bool       `gorm:"not null;default:false" json:"emailVerified"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+
+	// Perfil: lo edita el propio usuario desde /users/me
+	DisplayName           string     `gorm:"size:100" json:"displayName"`
+	Phone                 string     `gorm:"size:30" json:"phone"`
+	DateOfBirth           *time.Time `gorm:"type:date" json:"dateOfBirth,omitempty"`
+	EmergencyContactName  string     `gorm:"size:100" json:"emergencyContactName"`
+	EmergencyContactPhone string     `gorm:"size:30" json:"emergencyContactPhone"`
 }
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// Propósitos de UserToken
+const (
+	TokenPurposePasswordReset = "password_reset"
+	TokenPurposeEmailVerify   = "email_verify"
+	// código que recibe el frontend al terminar un login OIDC y canjea por tokens
+	TokenPurposeOIDCLogin = "oidc_login"
+	// ticket de un usuario logueado para ir al proveedor a vincular su cuenta
+	TokenPurposeOIDCLink = "oidc_link"
+	// ticket para ir al proveedor a reautenticarse
+	TokenPurposeOIDCReauth = "oidc_reauth"
+	// prueba de que el usuario se acaba de reautenticar con el proveedor (se
+	// usa en vez de la contraseña para darse de baja)
+	TokenPurposeReauth = "reauth"
+)
+
+// UserToken es un token opaco de un solo uso asociado a un usuario (reset de
+// contraseña, etc.). Como con las invitaciones, solo se guarda el hash.
+type UserToken struct {
+	ID        uint64     `gorm:"primaryKey;autoIncrement"`
+	UserID    uint64     `gorm:"index;not null"`
+	Purpose   string     `gorm:"size:32;index;not null"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time `gorm:"index"`
+	CreatedAt time.Time
+	// Email es la dirección a la que se mandó el token (verificación de email)
+	Email string `gorm:"size:120"`
+}
@@ -0,0 +1,79 @@
+// Package events publica los eventos de usuario (user.created, user.updated,
+// user.deleted) que consumen los otros servicios. Los servicios los guardan
+// en el outbox (tabla outbox_events) junto con el cambio y Relay los publica:
+// con RABBITMQ_URL van a un exchange topic; sin él solo se loguean
+// (desarrollo local).
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/sporthub/shared/rabbit"
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+)
+
+// Routing keys de los eventos de usuario
+const (
+	UserCreated = "user.created"
+	UserUpdated = "user.updated"
+	UserDeleted = "user.deleted"
+)
+
+type Publisher interface {
+	Publish(routing string, payload any) error
+	Close()
+}
+
+// UserEvent es el payload de los eventos de usuario. userId va como string
+// porque así lo guardan activities-api (enrollments, ownerUserId) y el resto.
+type UserEvent struct {
+	Op        string `json:"op"` // created | updated | deleted
+	UserID    string `json:"userId"`
+	Username  string `json:"username,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+// NewUserEvent arma el payload; para deleted solo va el id
+func NewUserEvent(op string, u *domain.User) UserEvent {
+	ev := UserEvent{Op: op, UserID: strconv.FormatUint(u.ID, 10), Timestamp: time.Now().Format(time.RFC3339)}
+	if op != "deleted" {
+		ev.Username, ev.Email, ev.Role = u.Username, u.Email, string(u.Role)
+	}
+	return ev
+}
+
+// NewPublisher arma el Publisher de la config. Lo usa solo el relay del
+// outbox: los servicios guardan los eventos con Enqueue.
+func NewPublisher(cfg config.Config) Publisher {
+	if cfg.RabbitURL == "" {
+		log.Printf("[rabbit] RABBITMQ_URL not set, user events are only logged")
+		return NewLogPublisher()
+	}
+	return rabbit.New(cfg.RabbitURL, cfg.UsersEventsExchange, "topic", 5*time.Second)
+}
+
+// Enqueue guarda el evento en el outbox. Se llama dentro de la transacción
+// del cambio (repository.Repos.InTx): si el proceso se cae después del
+// commit, el evento no se pierde y Relay lo publica al volver. La entrega es
+// "al menos una vez", así que los consumers tienen que ser idempotentes.
+func Enqueue(outbox repository.OutboxRepo, routing string, payload any) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", routing, err)
+	}
+	ev := &domain.OutboxEvent{RoutingKey: routing, Payload: string(b)}
+	if ue, ok := payload.(UserEvent); ok {
+		if id, err := strconv.ParseUint(ue.UserID, 10, 64); err == nil {
+			ev.UserID = &id
+		}
+	}
+	return outbox.Add(ev)
+}
@@ -0,0 +1,21 @@
+package events
+
+import (
+	"encoding/json"
+	"log"
+)
+
+type logPublisher struct{}
+
+// NewLogPublisher solo loguea los eventos (sin RabbitMQ)
+func NewLogPublisher() Publisher {
+	return logPublisher{}
+}
+
+func (logPublisher) Publish(routing string, payload any) error {
+	b, _ := json.Marshal(payload)
+	log.Printf("[rabbit] event %s %s", routing, b)
+	return nil
+}
+
+func (logPublisher) Close() {}
@@ -0,0 +1,94 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/sporthub/users-api/internal/repository"
+)
+
+const (
+	relayBatch = 100
+	// relayLease es cuánto tiempo un lote queda tomado por una réplica; si se
+	// cae a mitad de la publicación, otra lo toma después
+	relayLease = time.Minute
+	// los eventos publicados se guardan una semana (para investigar)
+	relayKeep = 7 * 24 * time.Hour
+)
+
+// Relay publica en RabbitMQ los eventos del outbox en orden de alta. Si un
+// publish falla corta el lote (el resto queda pendiente, en el mismo orden)
+// y vuelve a probar con backoff exponencial. Con varias réplicas cada una
+// toma lotes distintos.
+type Relay struct {
+	outbox   repository.OutboxRepo
+	pub      Publisher
+	owner    string
+	interval time.Duration
+}
+
+// NewRelay arma el relay; interval es la espera entre rondas sin errores
+func NewRelay(outbox repository.OutboxRepo, pub Publisher, interval time.Duration) *Relay {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return &Relay{outbox: outbox, pub: pub, owner: hex.EncodeToString(b), interval: interval}
+}
+
+// Run publica hasta que se cancele ctx
+func (r *Relay) Run(ctx context.Context) {
+	delay := r.interval
+	lastPurge := time.Time{}
+	for {
+		n, err := r.Flush(ctx)
+		if err != nil {
+			log.Printf("[outbox] WARN: publish failed (%v), retrying in %v", err, delay)
+			delay = min(delay*2, time.Minute)
+		} else {
+			delay = r.interval
+		}
+		if time.Since(lastPurge) > time.Hour {
+			if _, err := r.outbox.DeletePublished(time.Now().Add(-relayKeep)); err != nil {
+				log.Printf("[outbox] WARN: purge: %v", err)
+			}
+			lastPurge = time.Now()
+		}
+		// lote lleno: probablemente quedan más
+		if err == nil && n == relayBatch {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Flush publica un lote de eventos pendientes y devuelve cuántos publicó
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	now := time.Now()
+	evs, err := r.outbox.Claim(r.owner, now, now.Add(relayLease), relayBatch)
+	if err != nil {
+		return 0, err
+	}
+	for i, ev := range evs {
+		if ctx.Err() != nil {
+			return i, r.outbox.Release(r.owner, 0, "")
+		}
+		if err := r.pub.Publish(ev.RoutingKey, json.RawMessage(ev.Payload)); err != nil {
+			if relErr := r.outbox.Release(r.owner, ev.ID, err.Error()); relErr != nil {
+				log.Printf("[outbox] WARN: release: %v", relErr)
+			}
+			return i, err
+		}
+		if err := r.outbox.MarkPublished(ev.ID, time.Now()); err != nil {
+			// se vuelve a publicar cuando venza el lease (los consumers son idempotentes)
+			return i, err
+		}
+	}
+	return len(evs), nil
+}
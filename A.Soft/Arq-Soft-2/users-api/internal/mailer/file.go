@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type fileSender struct {
+	dir  string
+	from string
+}
+
+// NewFileSender guarda cada email como un .eml en dir (desarrollo local)
+func NewFileSender(dir, from string) Sender {
+	return &fileSender{dir: dir, from: from}
+}
+
+func (s *fileSender) Send(_ context.Context, msg Message) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("mail dir: %w", err)
+	}
+	now := time.Now()
+	f, err := os.CreateTemp(s.dir, now.Format("20060102-150405")+"-*.eml")
+	if err != nil {
+		return fmt.Errorf("mail file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(format(s.from, msg, now)); err != nil {
+		return fmt.Errorf("mail file: %w", err)
+	}
+	log.Printf("[mail] %q for %s written to %s", msg.Subject, msg.To, filepath.Base(f.Name()))
+	return nil
+}
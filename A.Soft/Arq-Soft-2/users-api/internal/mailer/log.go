@@ -0,0 +1,29 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"regexp"
+)
+
+type logSender struct{}
+
+// NewLogSender loguea el email (desarrollo local, default). Los tokens de
+// los links (reset, verificación) se tapan: los logs los lee más gente que
+// el dueño de la cuenta. Para usar los links en local está MAIL_SENDER=file.
+func NewLogSender() Sender {
+	return logSender{}
+}
+
+// secretParamRe encuentra ?token= / &code= en los links del cuerpo
+var secretParamRe = regexp.MustCompile(`([?&](?:token|code)=)[^&\s]+`)
+
+func (logSender) Send(_ context.Context, msg Message) error {
+	log.Printf("[mail] to=%s subject=%q\n%s", msg.To, msg.Subject, Redact(msg.Body))
+	return nil
+}
+
+// Redact tapa los tokens de los links de un email
+func Redact(body string) string {
+	return secretParamRe.ReplaceAllString(body, "${1}[redacted]")
+}
@@ -0,0 +1,55 @@
+// Package mailer envía los emails transaccionales de users-api (reset de
+// contraseña, etc.). El Sender se elige por configuración: SMTP en
+// producción, archivo o log para desarrollo local.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"time"
+
+	"github.com/sporthub/users-api/internal/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string // texto plano
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// FromConfig arma el Sender según MAIL_SENDER (smtp, file o log)
+func FromConfig(cfg config.Config) Sender {
+	switch cfg.MailSender {
+	case "smtp":
+		return NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.MailFrom)
+	case "file":
+		return NewFileSender(cfg.MailDir, cfg.MailFrom)
+	case "", "log":
+		return NewLogSender()
+	default:
+		log.Printf("[mail] WARN: unknown MAIL_SENDER=%q, using log", cfg.MailSender)
+		return NewLogSender()
+	}
+}
+
+// format arma el mensaje RFC 5322 (headers + cuerpo) que se manda por SMTP o
+// se guarda en disco
+func format(from string, msg Message, now time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	return b.Bytes()
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type smtpSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPSender manda por SMTP (STARTTLS si el servidor lo ofrece). Sin user
+// no se autentica, para relays internos o Mailpit/MailHog.
+func NewSMTPSender(host, port, user, password, from string) Sender {
+	var auth smtp.Auth
+	if user != "" {
+		auth = smtp.PlainAuth("", user, password, host)
+	}
+	return &smtpSender{addr: net.JoinHostPort(host, port), auth: auth, from: from}
+}
+
+func (s *smtpSender) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+	envelopeFrom := s.from
+	if a, err := mail.ParseAddress(s.from); err == nil {
+		envelopeFrom = a.Address
+	}
+	// net/smtp no acepta contexto: se manda en una goroutine y se deja de
+	// esperar si ctx vence
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, s.auth, envelopeFrom, []string{msg.To}, format(s.from, msg, time.Now()))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("smtp send: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
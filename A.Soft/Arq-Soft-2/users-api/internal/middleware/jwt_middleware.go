@@ -2,13 +2,25 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/sporthub/shared/authz"
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/utils"
 )
 
-func JWTAuth(secret string) gin.HandlerFunc {
+// RevocationChecker indica si un access token (por jti) fue revocado
+type RevocationChecker interface {
+	IsRevoked(jti string) bool
+}
+
+// JWTAuth valida tokens RS256 firmados por este servicio (kid del KeySet),
+// con iss/aud de la config
+func JWTAuth(keys *utils.KeySet, cfg config.Config, revoked RevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := c.GetHeader("Authorization")
 		if !strings.HasPrefix(h, "Bearer ") {
@@ -17,32 +29,114 @@ func JWTAuth(secret string) gin.HandlerFunc {
 			return
 		}
 		tokenStr := strings.TrimPrefix(h, "Bearer ")
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
+		token, err := jwt.Parse(tokenStr, keys.Keyfunc,
+			jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+			jwt.WithIssuer(cfg.JWTIssuer),
+			jwt.WithAudience(cfg.JWTAudience),
+			jwt.WithExpirationRequired())
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			c.Abort()
 			return
 		}
 		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+			jti, _ := claims["jti"].(string)
+			if revoked != nil && revoked.IsRevoked(jti) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+				c.Abort()
+				return
+			}
 			c.Set("rol", claims["rol"]) // expose role to handlers
+			authz.SetPermissions(c, authz.TokenPermissions(claims))
+			c.Set("orgPerms", authz.TokenOrgPermissions(claims))
+			c.Set("jti", jti)
+			if sub, ok := claims["sub"].(float64); ok {
+				c.Set("userId", uint64(sub))
+			}
+			if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+				c.Set("exp", exp.Time)
+			} else {
+				c.Set("exp", time.Time{})
+			}
 		}
 		c.Next()
 	}
 }
 
-func AdminOnly() gin.HandlerFunc {
+// AllowMFASetupToken acepta, además de un access token normal (full), el
+// token parcial de setup que entrega el login cuando REQUIRE_ADMIN_2FA exige
+// configurar 2FA. Con ese token solo se setean userId y mfaSetup.
+func AllowMFASetupToken(cfg config.Config, full gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h := c.GetHeader("Authorization"); strings.HasPrefix(h, "Bearer ") {
+			id, purpose, err := utils.ParseMFAToken(cfg, strings.TrimPrefix(h, "Bearer "))
+			if err == nil && purpose == utils.MFAPurposeSetup {
+				c.Set("userId", id)
+				c.Set("mfaSetup", true)
+				c.Next()
+				return
+			}
+		}
+		full(c)
+	}
+}
+
+// AllowServiceToken acepta, además de un access token de usuario (full), un
+// token de servicio de /auth/token. Con ese token se setean service (el
+// client_id) y rol "service"; no hay userId.
+func AllowServiceToken(cfg config.Config, full gin.HandlerFunc) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		roleVal, exists := c.Get("rol")
-		if !exists {
-			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		if h := c.GetHeader("Authorization"); strings.HasPrefix(h, "Bearer ") {
+			if clientID, err := utils.ParseServiceToken(cfg, strings.TrimPrefix(h, "Bearer ")); err == nil {
+				c.Set("service", clientID)
+				c.Set("rol", "service")
+				c.Next()
+				return
+			}
+		}
+		full(c)
+	}
+}
+
+// RequireServiceToken solo deja pasar tokens de servicio de /auth/token (rutas
+// que consultan los otros servicios, como /auth/revoked)
+func RequireServiceToken(cfg config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := c.GetHeader("Authorization")
+		if !strings.HasPrefix(h, "Bearer ") {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing service token"})
 			c.Abort()
 			return
 		}
-		roleStr, _ := roleVal.(string)
-		if roleStr != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "admin required"})
+		clientID, err := utils.ParseServiceToken(cfg, strings.TrimPrefix(h, "Bearer "))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid service token"})
+			c.Abort()
+			return
+		}
+		c.Set("service", clientID)
+		c.Set("rol", "service")
+		c.Next()
+	}
+}
+
+// HasOrgPermission indica si el token tiene el permiso en la organización
+// orgID: global (rol) o solo en esa organización (claim orgs)
+func HasOrgPermission(c *gin.Context, orgID, perm string) bool {
+	if authz.HasPermission(c, perm) {
+		return true
+	}
+	orgs, _ := c.Get("orgPerms")
+	byOrg, _ := orgs.(map[string][]string)
+	return slices.Contains(byOrg[orgID], perm)
+}
+
+// RequireOrgPermission es authz.RequirePermission para la organización del
+// path param (va después de JWTAuth)
+func RequireOrgPermission(param, perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasOrgPermission(c, c.Param(param), perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "missing permission " + perm + " in organization"})
 			c.Abort()
 			return
 		}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sporthub/users-api/internal/config"
+)
+
+// TrustProxies configura de qué proxies se cree X-Forwarded-For y X-Real-IP
+// (c.ClientIP, que usa el bloqueo de login por IP). Gin por defecto confía en
+// todos: cualquiera elegiría su IP con el header y esquivaría el bloqueo. Sin
+// TRUSTED_PROXIES vale la IP de la conexión.
+func TrustProxies(r *gin.Engine, cfg config.Config) error {
+	var proxies []string
+	for _, p := range strings.Split(cfg.TrustedProxies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return r.SetTrustedProxies(proxies)
+}
@@ -0,0 +1,162 @@
+// Package migrations aplica las migraciones SQL numeradas de users-api
+// (sql/NNNN_nombre.up.sql y .down.sql, embebidas en el binario) y lleva
+// registro de las aplicadas en la tabla schema_migrations.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+// Migration es un paso del esquema: Up lo aplica y Down lo deshace
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+var fileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Embedded devuelve las migraciones que vienen con el binario
+func Embedded() ([]Migration, error) {
+	sub, err := fs.Sub(embedded, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return Load(sub)
+}
+
+// Load lee las migraciones de fsys ordenadas por versión. Cada versión tiene
+// que tener su .up.sql y su .down.sql.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := fileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migrations: unexpected file %q (want NNNN_name.up.sql or NNNN_name.down.sql)", e.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migrations: version %d is used by %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if strings.TrimSpace(mig.Up) == "" {
+			return nil, fmt.Errorf("migrations: %04d_%s has no up migration", mig.Version, mig.Name)
+		}
+		if strings.TrimSpace(mig.Down) == "" {
+			return nil, fmt.Errorf("migrations: %04d_%s has no down migration", mig.Version, mig.Name)
+		}
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Statements separa un archivo en sentencias: cada una termina con ";" al
+// final de una línea. Las líneas de comentario (--) se descartan.
+func Statements(script string) []string {
+	var out []string
+	var cur strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		cur.WriteString(line)
+		cur.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			out = append(out, strings.TrimSuffix(strings.TrimSpace(cur.String()), ";"))
+			cur.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(cur.String()); rest != "" {
+		out = append(out, rest)
+	}
+	return out
+}
+
+var createTableRe = regexp.MustCompile("(?is)^CREATE\\s+TABLE\\s+IF\\s+NOT\\s+EXISTS\\s+`?(\\w+)`?\\s*\\((.*)\\)[^)]*$")
+
+// CreatedTable devuelve la tabla y las columnas de un CREATE TABLE IF NOT
+// EXISTS (ok = false para cualquier otra sentencia). Con IF NOT EXISTS una
+// tabla que ya existe con otra forma no se toca, así que el runner compara
+// estas columnas con las de la base antes de aplicar la migración.
+func CreatedTable(stmt string) (table string, columns []string, ok bool) {
+	m := createTableRe.FindStringSubmatch(strings.TrimSpace(stmt))
+	if m == nil {
+		return "", nil, false
+	}
+	for _, def := range splitTopLevel(m[2]) {
+		fields := strings.Fields(def)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "PRIMARY", "KEY", "UNIQUE", "INDEX", "CONSTRAINT", "FOREIGN", "FULLTEXT", "CHECK":
+			continue
+		}
+		columns = append(columns, strings.Trim(fields[0], "`"))
+	}
+	return m[1], columns, true
+}
+
+// splitTopLevel separa las definiciones de un CREATE TABLE por las comas que
+// no están entre paréntesis ni comillas (ENUM('a', 'b'), KEY (a, b))
+func splitTopLevel(body string) []string {
+	var out []string
+	depth, start := 0, 0
+	var quote rune
+	for i, c := range body {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			out = append(out, strings.TrimSpace(body[start:i]))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(body[start:]); rest != "" {
+		out = append(out, rest)
+	}
+	return out
+}
@@ -0,0 +1,262 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrPending: hay migraciones sin aplicar (correr "users-api migrate up")
+	ErrPending = errors.New("pending migrations")
+	// ErrDirty: una migración falló a mitad de camino y hay que arreglar el
+	// esquema a mano antes de seguir
+	ErrDirty = errors.New("schema is dirty")
+	// ErrUnknownVersion: la base tiene una versión que este binario no conoce
+	// (por ejemplo, se volvió a una versión anterior de users-api)
+	ErrUnknownVersion = errors.New("unknown migration version")
+	// ErrSchemaMismatch: una tabla que la migración crea con IF NOT EXISTS ya
+	// existe con otras columnas (p.ej. la dejó una versión vieja de
+	// AutoMigrate); no se aplica nada hasta que se arregle a mano
+	ErrSchemaMismatch = errors.New("existing table does not match the migration")
+)
+
+// Applied es una fila de schema_migrations
+type Applied struct {
+	Version   int64
+	Name      string
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// Store es donde se registran las migraciones aplicadas y se ejecuta el SQL
+type Store interface {
+	// Init crea schema_migrations si no existe
+	Init(ctx context.Context) error
+	// Lock evita que dos procesos migren a la vez; unlock lo libera
+	Lock(ctx context.Context) (unlock func(), err error)
+	// Applied devuelve las versiones aplicadas ordenadas de menor a mayor
+	Applied(ctx context.Context) ([]Applied, error)
+	// Begin registra la versión como dirty antes de ejecutar su SQL
+	Begin(ctx context.Context, version int64, name string) error
+	// Finish la marca como aplicada (dirty = false)
+	Finish(ctx context.Context, version int64) error
+	// Remove borra la versión (después de un down)
+	Remove(ctx context.Context, version int64) error
+	Exec(ctx context.Context, statement string) error
+	// Columns devuelve las columnas de la tabla, o nil si no existe
+	Columns(ctx context.Context, table string) ([]string, error)
+}
+
+// Status es el estado de una migración para "migrate status"
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt *time.Time
+}
+
+type Runner struct {
+	store      Store
+	migrations []Migration
+}
+
+func NewRunner(store Store, migrations []Migration) *Runner {
+	return &Runner{store: store, migrations: migrations}
+}
+
+// Up aplica en orden todas las migraciones pendientes y devuelve las aplicadas
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	unlock, applied, err := r.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	done := map[int64]bool{}
+	for _, a := range applied {
+		done[a.Version] = true
+	}
+	var out []Migration
+	for _, m := range r.migrations {
+		if done[m.Version] {
+			continue
+		}
+		log.Printf("[migrate] up %04d_%s", m.Version, m.Name)
+		if err := r.verifyTables(ctx, m); err != nil {
+			return out, err
+		}
+		if err := r.store.Begin(ctx, m.Version, m.Name); err != nil {
+			return out, err
+		}
+		if err := r.exec(ctx, m, m.Up); err != nil {
+			return out, err
+		}
+		if err := r.store.Finish(ctx, m.Version); err != nil {
+			return out, err
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// Down deshace las últimas steps migraciones aplicadas, de la más nueva a la
+// más vieja, y devuelve las revertidas
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	unlock, applied, err := r.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var out []Migration
+	for i := len(applied) - 1; i >= 0 && len(out) < steps; i-- {
+		m, _ := r.find(applied[i].Version)
+		log.Printf("[migrate] down %04d_%s", m.Version, m.Name)
+		if err := r.store.Begin(ctx, m.Version, m.Name); err != nil {
+			return out, err
+		}
+		if err := r.exec(ctx, m, m.Down); err != nil {
+			return out, err
+		}
+		if err := r.store.Remove(ctx, m.Version); err != nil {
+			return out, err
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// Status lista las migraciones conocidas y si están aplicadas
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	if err := r.store.Init(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := r.store.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]Applied{}
+	for _, a := range applied {
+		byVersion[a.Version] = a
+	}
+	out := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		st := Status{Version: m.Version, Name: m.Name}
+		if a, ok := byVersion[m.Version]; ok {
+			at := a.AppliedAt
+			st.Applied, st.Dirty, st.AppliedAt = true, a.Dirty, &at
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// Check devuelve error si el esquema no está al día: migraciones pendientes
+// (ErrPending), una migración a medio aplicar (ErrDirty) o versiones que este
+// binario no conoce (ErrUnknownVersion). users-api no arranca en esos casos.
+func (r *Runner) Check(ctx context.Context) error {
+	if err := r.store.Init(ctx); err != nil {
+		return err
+	}
+	applied, err := r.store.Applied(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.validate(applied); err != nil {
+		return err
+	}
+	if pending := len(r.migrations) - len(applied); pending > 0 {
+		return fmt.Errorf("%w: %d not applied, latest is %04d", ErrPending, pending, r.migrations[len(r.migrations)-1].Version)
+	}
+	return nil
+}
+
+// prepare toma el lock y valida el estado antes de migrar
+func (r *Runner) prepare(ctx context.Context) (func(), []Applied, error) {
+	if err := r.store.Init(ctx); err != nil {
+		return nil, nil, err
+	}
+	unlock, err := r.store.Lock(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	applied, err := r.store.Applied(ctx)
+	if err == nil {
+		err = r.validate(applied)
+	}
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return unlock, applied, nil
+}
+
+func (r *Runner) validate(applied []Applied) error {
+	for _, a := range applied {
+		if a.Dirty {
+			return fmt.Errorf("%w: version %04d_%s failed halfway, fix it by hand and delete or clean its row in schema_migrations", ErrDirty, a.Version, a.Name)
+		}
+		if _, ok := r.find(a.Version); !ok {
+			return fmt.Errorf("%w: %04d_%s is applied but this binary does not have it", ErrUnknownVersion, a.Version, a.Name)
+		}
+	}
+	return nil
+}
+
+// verifyTables compara las tablas que m crea con IF NOT EXISTS y que ya
+// existen con las columnas de la migración: si falta alguna, la tabla tiene
+// una forma anterior y registrar la versión como aplicada dejaría el esquema
+// a medias
+func (r *Runner) verifyTables(ctx context.Context, m Migration) error {
+	for _, stmt := range Statements(m.Up) {
+		table, want, ok := CreatedTable(stmt)
+		if !ok {
+			continue
+		}
+		have, err := r.store.Columns(ctx, table)
+		if err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		if have == nil {
+			continue
+		}
+		existing := map[string]bool{}
+		for _, c := range have {
+			existing[strings.ToLower(c)] = true
+		}
+		var missing []string
+		for _, c := range want {
+			if !existing[strings.ToLower(c)] {
+				missing = append(missing, c)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("%w: migration %04d_%s, table %s exists without columns %s; add them by hand (ALTER TABLE) and run migrate up again",
+				ErrSchemaMismatch, m.Version, m.Name, table, strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
+
+func (r *Runner) exec(ctx context.Context, m Migration, script string) error {
+	for _, stmt := range Statements(script) {
+		if err := r.store.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) find(version int64) (Migration, bool) {
+	for _, m := range r.migrations {
+		if m.Version == version {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS memberships;
+DROP TABLE IF EXISTS organizations;
+DROP TABLE IF EXISTS export_jobs;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS mfas;
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS user_tokens;
+DROP TABLE IF EXISTS invitations;
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,138 @@
+-- Esquema de users-api tal como lo dejaba el AutoMigrate de GORM. Con
+-- IF NOT EXISTS, en bases que ya venían de AutoMigrate solo se registra la versión.
+CREATE TABLE IF NOT EXISTS users (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  username VARCHAR(50) NOT NULL,
+  email VARCHAR(120) NOT NULL,
+  password_hash VARCHAR(255) NOT NULL,
+  role ENUM('user', 'instructor', 'admin') NOT NULL DEFAULT 'user',
+  created_at DATETIME(3) NULL,
+  updated_at DATETIME(3) NULL,
+  email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+  email_verified_at DATETIME(3) NULL,
+  display_name VARCHAR(100) NULL,
+  phone VARCHAR(30) NULL,
+  date_of_birth DATE NULL,
+  emergency_contact_name VARCHAR(100) NULL,
+  emergency_contact_phone VARCHAR(30) NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY idx_users_username (username),
+  UNIQUE KEY idx_users_email (email)
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  user_id BIGINT UNSIGNED NOT NULL,
+  family_id VARCHAR(64) NOT NULL,
+  token_hash VARCHAR(64) NOT NULL,
+  expires_at DATETIME(3) NOT NULL,
+  revoked_at DATETIME(3) NULL,
+  created_at DATETIME(3) NULL,
+  PRIMARY KEY (id),
+  KEY idx_refresh_tokens_user_id (user_id),
+  KEY idx_refresh_tokens_family_id (family_id),
+  UNIQUE KEY idx_refresh_tokens_token_hash (token_hash),
+  KEY idx_refresh_tokens_revoked_at (revoked_at)
+);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+  jti VARCHAR(64) NOT NULL,
+  expires_at DATETIME(3) NOT NULL,
+  revoked_at DATETIME(3) NOT NULL,
+  PRIMARY KEY (jti),
+  KEY idx_revoked_tokens_expires_at (expires_at),
+  KEY idx_revoked_tokens_revoked_at (revoked_at)
+);
+
+CREATE TABLE IF NOT EXISTS invitations (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  token_hash VARCHAR(64) NOT NULL,
+  role ENUM('user', 'instructor', 'admin') NOT NULL,
+  email VARCHAR(120) NULL,
+  created_by BIGINT UNSIGNED NULL,
+  expires_at DATETIME(3) NOT NULL,
+  used_at DATETIME(3) NULL,
+  created_at DATETIME(3) NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY idx_invitations_token_hash (token_hash)
+);
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  user_id BIGINT UNSIGNED NOT NULL,
+  purpose VARCHAR(32) NOT NULL,
+  token_hash VARCHAR(64) NOT NULL,
+  expires_at DATETIME(3) NOT NULL,
+  used_at DATETIME(3) NULL,
+  created_at DATETIME(3) NULL,
+  PRIMARY KEY (id),
+  KEY idx_user_tokens_user_id (user_id),
+  KEY idx_user_tokens_purpose (purpose),
+  UNIQUE KEY idx_user_tokens_token_hash (token_hash),
+  KEY idx_user_tokens_used_at (used_at)
+);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+  `key` VARCHAR(191) NOT NULL,
+  failures BIGINT NOT NULL DEFAULT 0,
+  last_failure_at DATETIME(3) NOT NULL,
+  locked_until DATETIME(3) NULL,
+  PRIMARY KEY (`key`),
+  KEY idx_login_attempts_locked_until (locked_until)
+);
+
+CREATE TABLE IF NOT EXISTS mfas (
+  user_id BIGINT UNSIGNED NOT NULL,
+  secret VARCHAR(64) NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT FALSE,
+  last_step BIGINT NOT NULL DEFAULT 0,
+  enabled_at DATETIME(3) NULL,
+  created_at DATETIME(3) NULL,
+  updated_at DATETIME(3) NULL,
+  PRIMARY KEY (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  user_id BIGINT UNSIGNED NOT NULL,
+  code_hash VARCHAR(64) NOT NULL,
+  used_at DATETIME(3) NULL,
+  created_at DATETIME(3) NULL,
+  PRIMARY KEY (id),
+  KEY idx_recovery_codes_user_id (user_id),
+  UNIQUE KEY idx_recovery_codes_code_hash (code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS export_jobs (
+  id VARCHAR(32) NOT NULL,
+  user_id BIGINT UNSIGNED NOT NULL,
+  status VARCHAR(16) NOT NULL,
+  error VARCHAR(255) NULL,
+  data LONGBLOB NULL,
+  created_at DATETIME(3) NULL,
+  completed_at DATETIME(3) NULL,
+  expires_at DATETIME(3) NULL,
+  PRIMARY KEY (id),
+  KEY idx_export_jobs_user_id (user_id),
+  KEY idx_export_jobs_expires_at (expires_at)
+);
+
+CREATE TABLE IF NOT EXISTS organizations (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  slug VARCHAR(60) NOT NULL,
+  name VARCHAR(120) NOT NULL,
+  created_by BIGINT UNSIGNED NULL,
+  created_at DATETIME(3) NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY idx_organizations_slug (slug)
+);
+
+CREATE TABLE IF NOT EXISTS memberships (
+  org_id BIGINT UNSIGNED NOT NULL,
+  user_id BIGINT UNSIGNED NOT NULL,
+  role ENUM('member', 'instructor', 'admin') NOT NULL,
+  created_at DATETIME(3) NULL,
+  updated_at DATETIME(3) NULL,
+  PRIMARY KEY (org_id, user_id),
+  KEY idx_memberships_user_id (user_id)
+);
@@ -0,0 +1,4 @@
+-- Solo vuelve a aceptar 'normal' en el enum: qué usuarios eran 'normal' no
+-- se puede recuperar.
+ALTER TABLE users MODIFY role ENUM('normal', 'user', 'instructor', 'admin') NOT NULL DEFAULT 'user';
+ALTER TABLE invitations MODIFY role ENUM('normal', 'user', 'instructor', 'admin') NOT NULL;
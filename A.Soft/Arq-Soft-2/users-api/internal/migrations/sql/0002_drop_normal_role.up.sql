@@ -0,0 +1,8 @@
+-- El rol 'normal' se reemplazó por 'user'. Primero los datos y después se
+-- achica el enum (con filas 'normal' el ALTER fallaría).
+ALTER TABLE users MODIFY role ENUM('normal', 'user', 'instructor', 'admin') NOT NULL DEFAULT 'user';
+ALTER TABLE invitations MODIFY role ENUM('normal', 'user', 'instructor', 'admin') NOT NULL;
+UPDATE users SET role = 'user' WHERE role = 'normal';
+UPDATE invitations SET role = 'user' WHERE role = 'normal';
+ALTER TABLE users MODIFY role ENUM('user', 'instructor', 'admin') NOT NULL DEFAULT 'user';
+ALTER TABLE invitations MODIFY role ENUM('user', 'instructor', 'admin') NOT NULL;
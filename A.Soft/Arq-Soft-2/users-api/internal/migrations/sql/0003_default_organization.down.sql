@@ -0,0 +1,2 @@
+DELETE FROM memberships WHERE org_id = 1;
+DELETE FROM organizations WHERE id = 1 AND slug = 'default';
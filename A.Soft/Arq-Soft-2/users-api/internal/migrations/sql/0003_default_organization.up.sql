@@ -0,0 +1,3 @@
+-- Organización por defecto (DEFAULT_ORG_ID = 1): ahí quedan los datos
+-- anteriores a los clubes. Con otro DEFAULT_ORG_ID la crea users-api al arrancar.
+INSERT IGNORE INTO organizations (id, slug, name, created_at) VALUES (1, 'default', 'SportHub', NOW(3));
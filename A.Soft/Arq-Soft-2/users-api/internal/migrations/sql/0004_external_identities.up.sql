@@ -0,0 +1,25 @@
+-- Login con proveedores OIDC: cuentas externas vinculadas a cada usuario y
+-- logins en curso (state, PKCE y nonce hasta que vuelve el callback)
+CREATE TABLE IF NOT EXISTS external_identities (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  user_id BIGINT UNSIGNED NOT NULL,
+  provider VARCHAR(32) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  email VARCHAR(120) NULL,
+  created_at DATETIME(3) NULL,
+  last_login_at DATETIME(3) NULL,
+  PRIMARY KEY (id),
+  KEY idx_external_identities_user_id (user_id),
+  UNIQUE KEY idx_external_identities_provider_subject (provider, subject)
+);
+
+CREATE TABLE IF NOT EXISTS oidc_logins (
+  state_hash VARCHAR(64) NOT NULL,
+  provider VARCHAR(32) NOT NULL,
+  code_verifier VARCHAR(128) NOT NULL,
+  nonce VARCHAR(64) NOT NULL,
+  expires_at DATETIME(3) NOT NULL,
+  created_at DATETIME(3) NULL,
+  PRIMARY KEY (state_hash),
+  KEY idx_oidc_logins_expires_at (expires_at)
+);
@@ -0,0 +1,15 @@
+-- Outbox de eventos de usuario: se escriben en la misma transacción que el
+-- cambio y los publica en RabbitMQ el relay de users-api
+CREATE TABLE IF NOT EXISTS outbox_events (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  routing_key VARCHAR(64) NOT NULL,
+  payload TEXT NOT NULL,
+  created_at DATETIME(3) NULL,
+  attempts BIGINT NOT NULL DEFAULT 0,
+  last_error VARCHAR(255) NULL,
+  locked_by VARCHAR(32) NULL,
+  locked_until DATETIME(3) NULL,
+  published_at DATETIME(3) NULL,
+  PRIMARY KEY (id),
+  KEY idx_outbox_events_published_at (published_at)
+);
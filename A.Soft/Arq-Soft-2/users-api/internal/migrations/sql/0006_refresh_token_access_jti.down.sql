@@ -0,0 +1,3 @@
+ALTER TABLE refresh_tokens
+  DROP COLUMN access_jti,
+  DROP COLUMN access_expires_at;
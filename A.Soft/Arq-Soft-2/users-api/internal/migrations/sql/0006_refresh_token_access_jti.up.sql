@@ -0,0 +1,5 @@
+-- Access token emitido con cada refresh token: al revocar todas las sesiones
+-- de un usuario (baja, cambio de contraseña) también se revocan sus jti
+ALTER TABLE refresh_tokens
+  ADD COLUMN access_jti VARCHAR(64) NULL,
+  ADD COLUMN access_expires_at DATETIME(3) NULL;
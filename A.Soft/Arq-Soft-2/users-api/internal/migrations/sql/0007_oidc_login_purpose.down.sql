@@ -0,0 +1,3 @@
+ALTER TABLE oidc_logins
+  DROP COLUMN purpose,
+  DROP COLUMN user_id;
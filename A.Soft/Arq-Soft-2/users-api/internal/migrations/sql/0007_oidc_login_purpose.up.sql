@@ -0,0 +1,5 @@
+-- Los usuarios logueados también pasan por el proveedor para vincular su
+-- cuenta externa a mano: el login en curso guarda para qué es y quién lo empezó
+ALTER TABLE oidc_logins
+  ADD COLUMN purpose VARCHAR(16) NOT NULL DEFAULT 'login',
+  ADD COLUMN user_id BIGINT UNSIGNED NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE export_jobs
+  DROP INDEX idx_export_jobs_active_user_id,
+  DROP COLUMN active_user_id,
+  DROP COLUMN started_at;
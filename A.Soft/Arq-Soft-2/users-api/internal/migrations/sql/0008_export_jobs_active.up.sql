@@ -0,0 +1,10 @@
+-- Una sola exportación activa por usuario (índice único sobre active_user_id,
+-- que queda NULL cuando el job termina) y started_at para dar por perdidos
+-- los jobs que no terminan. Los que estaban activos no los corre nadie.
+ALTER TABLE export_jobs
+  ADD COLUMN active_user_id BIGINT UNSIGNED NULL,
+  ADD COLUMN started_at DATETIME(3) NULL,
+  ADD UNIQUE KEY idx_export_jobs_active_user_id (active_user_id);
+
+UPDATE export_jobs SET status = 'failed', error = 'interrupted', completed_at = NOW(3)
+WHERE status IN ('pending', 'running');
@@ -0,0 +1,2 @@
+UPDATE users SET email_verified = FALSE, email_verified_at = NULL
+WHERE email_verified = TRUE AND email_verified_at = created_at;
@@ -0,0 +1,7 @@
+-- Las cuentas anteriores a la verificación de email nunca recibieron el link:
+-- se dan por verificadas (con REQUIRE_VERIFIED_EMAIL no podrían inscribirse).
+-- Las que ya tienen un link de verificación son altas nuevas y siguen pendientes.
+-- email_verified_at = created_at marca las de este backfill para el down.
+UPDATE users SET email_verified = TRUE, email_verified_at = COALESCE(created_at, NOW(3))
+WHERE email_verified = FALSE
+  AND NOT EXISTS (SELECT 1 FROM user_tokens t WHERE t.user_id = users.id AND t.purpose = 'email_verify');
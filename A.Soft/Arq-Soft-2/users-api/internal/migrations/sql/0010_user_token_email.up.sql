@@ -0,0 +1,3 @@
+-- Los links de verificación quedan atados a la dirección a la que se
+-- mandaron: si el email cambia, el link viejo no verifica el nuevo
+ALTER TABLE user_tokens ADD COLUMN email VARCHAR(120) NULL;
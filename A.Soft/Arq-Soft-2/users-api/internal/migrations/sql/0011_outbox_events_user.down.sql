@@ -0,0 +1,2 @@
+DROP INDEX idx_outbox_events_user_id ON outbox_events;
+ALTER TABLE outbox_events DROP COLUMN user_id;
@@ -0,0 +1,6 @@
+-- Cada evento queda atado al usuario al que se refiere, para la exportación
+-- de datos personales. Los anteriores se completan con el userId del payload.
+ALTER TABLE outbox_events ADD COLUMN user_id BIGINT UNSIGNED NULL;
+CREATE INDEX idx_outbox_events_user_id ON outbox_events (user_id);
+UPDATE outbox_events SET user_id = CAST(JSON_UNQUOTE(JSON_EXTRACT(payload, '$.userId')) AS UNSIGNED)
+WHERE user_id IS NULL AND JSON_VALID(payload) AND JSON_EXTRACT(payload, '$.userId') IS NOT NULL;
@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore es un Store en memoria para tests: guarda las sentencias
+// ejecutadas y, si FailOn devuelve error para una, la hace fallar. Tables son
+// las tablas que ya existen (nombre -> columnas).
+type MemoryStore struct {
+	mu       sync.Mutex
+	applied  map[int64]Applied
+	Executed []string
+	FailOn   func(statement string) error
+	Tables   map[string][]string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{applied: map[int64]Applied{}}
+}
+
+func (s *MemoryStore) Init(ctx context.Context) error { return nil }
+
+func (s *MemoryStore) Lock(ctx context.Context) (func(), error) {
+	s.mu.Lock()
+	return s.mu.Unlock, nil
+}
+
+func (s *MemoryStore) Applied(ctx context.Context) ([]Applied, error) {
+	out := make([]Applied, 0, len(s.applied))
+	for _, a := range s.applied {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+func (s *MemoryStore) Begin(ctx context.Context, version int64, name string) error {
+	s.applied[version] = Applied{Version: version, Name: name, Dirty: true, AppliedAt: time.Now()}
+	return nil
+}
+
+func (s *MemoryStore) Finish(ctx context.Context, version int64) error {
+	a := s.applied[version]
+	a.Dirty = false
+	s.applied[version] = a
+	return nil
+}
+
+func (s *MemoryStore) Remove(ctx context.Context, version int64) error {
+	delete(s.applied, version)
+	return nil
+}
+
+func (s *MemoryStore) Exec(ctx context.Context, statement string) error {
+	if s.FailOn != nil {
+		if err := s.FailOn(statement); err != nil {
+			return err
+		}
+	}
+	s.Executed = append(s.Executed, statement)
+	return nil
+}
+
+func (s *MemoryStore) Columns(ctx context.Context, table string) ([]string, error) {
+	return s.Tables[table], nil
+}
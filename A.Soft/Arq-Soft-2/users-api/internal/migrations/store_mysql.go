@@ -0,0 +1,109 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// lockName es el lock de MySQL (GET_LOCK) que toma quien migra
+const lockName = "users-api.schema_migrations"
+
+type mysqlStore struct {
+	db *sql.DB
+}
+
+// NewMySQLStore guarda las versiones en schema_migrations de db
+func NewMySQLStore(db *sql.DB) Store {
+	return &mysqlStore{db: db}
+}
+
+func (s *mysqlStore) Init(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version BIGINT NOT NULL,
+  name VARCHAR(191) NOT NULL,
+  dirty BOOLEAN NOT NULL DEFAULT FALSE,
+  applied_at DATETIME(3) NOT NULL,
+  PRIMARY KEY (version)
+)`)
+	return err
+}
+
+// Lock usa GET_LOCK, que vale por conexión: se reserva una del pool hasta unlock
+func (s *mysqlStore) Lock(ctx context.Context) (func(), error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 30)", lockName).Scan(&got); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if got.Int64 != 1 {
+		conn.Close()
+		return nil, fmt.Errorf("migrations: another process is migrating (lock %s)", lockName)
+	}
+	return func() {
+		_, _ = conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+		conn.Close()
+	}, nil
+}
+
+func (s *mysqlStore) Applied(ctx context.Context) ([]Applied, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT version, name, dirty, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Applied
+	for rows.Next() {
+		var a Applied
+		if err := rows.Scan(&a.Version, &a.Name, &a.Dirty, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (s *mysqlStore) Begin(ctx context.Context, version int64, name string) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, name, dirty, applied_at) VALUES (?, ?, TRUE, ?) ON DUPLICATE KEY UPDATE dirty = TRUE",
+		version, name, time.Now())
+	return err
+}
+
+func (s *mysqlStore) Finish(ctx context.Context, version int64) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE schema_migrations SET dirty = FALSE, applied_at = ? WHERE version = ?", time.Now(), version)
+	return err
+}
+
+func (s *mysqlStore) Remove(ctx context.Context, version int64) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", version)
+	return err
+}
+
+func (s *mysqlStore) Exec(ctx context.Context, statement string) error {
+	_, err := s.db.ExecContext(ctx, statement)
+	return err
+}
+
+func (s *mysqlStore) Columns(ctx context.Context, table string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
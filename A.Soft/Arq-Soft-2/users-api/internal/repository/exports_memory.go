@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+// exportsMemory es un ExportsRepo en memoria, para tests y desarrollo sin MySQL
+type exportsMemory struct {
+	mu   sync.Mutex
+	byID map[string]*domain.ExportJob
+}
+
+func NewExportsMemory() ExportsRepo {
+	return &exportsMemory{byID: map[string]*domain.ExportJob{}}
+}
+
+func (r *exportsMemory) Create(j *domain.ExportJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if j.ActiveUserID != nil {
+		for _, other := range r.byID {
+			if other.ActiveUserID != nil && *other.ActiveUserID == *j.ActiveUserID {
+				return gorm.ErrDuplicatedKey
+			}
+		}
+	}
+	if j.CreatedAt.IsZero() {
+		j.CreatedAt = time.Now()
+	}
+	cp := *j
+	r.byID[j.ID] = &cp
+	return nil
+}
+
+func (r *exportsMemory) FindByID(id string) (*domain.ExportJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if j, ok := r.byID[id]; ok {
+		cp := *j
+		cp.Data = nil
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *exportsMemory) Data(id string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.byID[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return j.Data, nil
+}
+
+func (r *exportsMemory) LatestByUser(userID uint64) (*domain.ExportJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var latest *domain.ExportJob
+	for _, j := range r.byID {
+		if j.UserID == userID && (latest == nil || j.CreatedAt.After(latest.CreatedAt)) {
+			latest = j
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	cp := *latest
+	cp.Data = nil
+	return &cp, nil
+}
+
+func (r *exportsMemory) Save(j *domain.ExportJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cp := *j
+	r.byID[j.ID] = &cp
+	return nil
+}
+
+func (r *exportsMemory) FailStale(before time.Time, reason string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var n int64
+	for _, j := range r.byID {
+		if j.ActiveUserID != nil && j.StartedAt != nil && j.StartedAt.Before(before) &&
+			(j.Status == domain.ExportPending || j.Status == domain.ExportRunning) {
+			j.Status, j.Error, j.ActiveUserID, j.CompletedAt = domain.ExportFailed, reason, nil, &now
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *exportsMemory) DeleteExpired(now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for id, j := range r.byID {
+		if j.ExpiresAt != nil && j.ExpiresAt.Before(now) {
+			delete(r.byID, id)
+			n++
+		}
+	}
+	return n, nil
+}
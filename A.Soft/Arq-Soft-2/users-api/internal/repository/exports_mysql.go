@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+type ExportsRepo interface {
+	// Create devuelve gorm.ErrDuplicatedKey si el usuario ya tiene una
+	// exportación activa (ActiveUserID)
+	Create(j *domain.ExportJob) error
+	// FindByID devuelve el job sin el zip; nil, nil si no existe
+	FindByID(id string) (*domain.ExportJob, error)
+	// Data devuelve el zip de un job
+	Data(id string) ([]byte, error)
+	// LatestByUser devuelve la última exportación del usuario (sin el zip) o nil, nil
+	LatestByUser(userID uint64) (*domain.ExportJob, error)
+	// Save guarda el estado, el error y el resultado del job
+	Save(j *domain.ExportJob) error
+	// FailStale marca como fallados los jobs activos que empezaron antes de
+	// before (la réplica que los corría se cayó) y libera al usuario
+	FailStale(before time.Time, reason string) (int64, error)
+	// DeleteExpired borra las exportaciones vencidas a now
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+type exportsMySQL struct{ gdb *gorm.DB }
+
+func NewExportsMySQL(gdb *gorm.DB) ExportsRepo {
+	return &exportsMySQL{gdb: gdb}
+}
+
+func (r *exportsMySQL) Create(j *domain.ExportJob) error {
+	return r.gdb.Create(j).Error
+}
+
+func (r *exportsMySQL) FindByID(id string) (*domain.ExportJob, error) {
+	var j domain.ExportJob
+	// sin el zip (puede pesar varios MB y el estado se consulta seguido)
+	if err := r.gdb.Omit("data").Where("id = ?", id).First(&j).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &j, nil
+}
+
+func (r *exportsMySQL) Data(id string) ([]byte, error) {
+	var j domain.ExportJob
+	if err := r.gdb.Select("data").Where("id = ?", id).First(&j).Error; err != nil {
+		return nil, err
+	}
+	return j.Data, nil
+}
+
+func (r *exportsMySQL) LatestByUser(userID uint64) (*domain.ExportJob, error) {
+	var j domain.ExportJob
+	// sin el zip: solo se quiere el estado
+	err := r.gdb.Omit("data").Where("user_id = ?", userID).Order("created_at DESC").First(&j).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &j, nil
+}
+
+func (r *exportsMySQL) Save(j *domain.ExportJob) error {
+	return r.gdb.Save(j).Error
+}
+
+func (r *exportsMySQL) FailStale(before time.Time, reason string) (int64, error) {
+	now := time.Now()
+	res := r.gdb.Model(&domain.ExportJob{}).
+		Where("active_user_id IS NOT NULL AND status IN ? AND started_at < ?", []string{domain.ExportPending, domain.ExportRunning}, before).
+		Updates(map[string]any{"status": domain.ExportFailed, "error": reason, "active_user_id": nil, "completed_at": now})
+	return res.RowsAffected, res.Error
+}
+
+func (r *exportsMySQL) DeleteExpired(now time.Time) (int64, error) {
+	res := r.gdb.Where("expires_at IS NOT NULL AND expires_at < ?", now).Delete(&domain.ExportJob{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+// identitiesMemory es un IdentitiesRepo en memoria, para tests y desarrollo sin MySQL
+type identitiesMemory struct {
+	mu     sync.Mutex
+	nextID uint64
+	byID   map[uint64]*domain.ExternalIdentity
+	logins map[string]domain.OIDCLogin
+}
+
+func NewIdentitiesMemory() IdentitiesRepo {
+	return &identitiesMemory{byID: map[uint64]*domain.ExternalIdentity{}, logins: map[string]domain.OIDCLogin{}}
+}
+
+func (r *identitiesMemory) Find(provider, subject string) (*domain.ExternalIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, i := range r.byID {
+		if i.Provider == provider && i.Subject == subject {
+			cp := *i
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *identitiesMemory) Create(i *domain.ExternalIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, other := range r.byID {
+		if other.Provider == i.Provider && other.Subject == i.Subject {
+			return errors.New("duplicate provider subject")
+		}
+	}
+	r.nextID++
+	i.ID = r.nextID
+	if i.CreatedAt.IsZero() {
+		i.CreatedAt = time.Now()
+	}
+	cp := *i
+	r.byID[i.ID] = &cp
+	return nil
+}
+
+func (r *identitiesMemory) Touch(id uint64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i, ok := r.byID[id]; ok {
+		i.LastLoginAt = &at
+	}
+	return nil
+}
+
+func (r *identitiesMemory) Delete(id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.byID, id)
+	return nil
+}
+
+func (r *identitiesMemory) ListByUser(userID uint64) ([]domain.ExternalIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.ExternalIdentity
+	for _, i := range r.byID {
+		if i.UserID == userID {
+			out = append(out, *i)
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].ID < out[b].ID })
+	return out, nil
+}
+
+func (r *identitiesMemory) DeleteByUser(userID uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, i := range r.byID {
+		if i.UserID == userID {
+			delete(r.byID, id)
+		}
+	}
+	return nil
+}
+
+func (r *identitiesMemory) SaveLogin(l *domain.OIDCLogin) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for k, old := range r.logins {
+		if old.ExpiresAt.Before(now) {
+			delete(r.logins, k)
+		}
+	}
+	if l.CreatedAt.IsZero() {
+		l.CreatedAt = now
+	}
+	r.logins[l.StateHash] = *l
+	return nil
+}
+
+func (r *identitiesMemory) ConsumeLogin(stateHash string) (*domain.OIDCLogin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.logins[stateHash]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.logins, stateHash)
+	return &l, nil
+}
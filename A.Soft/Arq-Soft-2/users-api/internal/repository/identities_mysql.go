@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+type IdentitiesRepo interface {
+	// Find devuelve nil, nil si la cuenta externa no está vinculada
+	Find(provider, subject string) (*domain.ExternalIdentity, error)
+	Create(i *domain.ExternalIdentity) error
+	// Touch registra el último login con la identidad
+	Touch(id uint64, at time.Time) error
+	Delete(id uint64) error
+	// ListByUser devuelve las cuentas externas vinculadas al usuario
+	ListByUser(userID uint64) ([]domain.ExternalIdentity, error)
+	// DeleteByUser desvincula todas las cuentas externas del usuario (baja)
+	DeleteByUser(userID uint64) error
+	// SaveLogin guarda un login en curso (y de paso limpia los vencidos)
+	SaveLogin(l *domain.OIDCLogin) error
+	// ConsumeLogin borra y devuelve el login del state; nil, nil si no
+	// existe o ya se usó
+	ConsumeLogin(stateHash string) (*domain.OIDCLogin, error)
+}
+
+type identitiesMySQL struct{ gdb *gorm.DB }
+
+func NewIdentitiesMySQL(gdb *gorm.DB) IdentitiesRepo {
+	return &identitiesMySQL{gdb: gdb}
+}
+
+func (r *identitiesMySQL) Find(provider, subject string) (*domain.ExternalIdentity, error) {
+	var i domain.ExternalIdentity
+	err := r.gdb.Where("provider = ? AND subject = ?", provider, subject).First(&i).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *identitiesMySQL) Create(i *domain.ExternalIdentity) error {
+	return r.gdb.Create(i).Error
+}
+
+func (r *identitiesMySQL) Touch(id uint64, at time.Time) error {
+	return r.gdb.Model(&domain.ExternalIdentity{}).Where("id = ?", id).Update("last_login_at", at).Error
+}
+
+func (r *identitiesMySQL) Delete(id uint64) error {
+	return r.gdb.Delete(&domain.ExternalIdentity{}, id).Error
+}
+
+func (r *identitiesMySQL) ListByUser(userID uint64) ([]domain.ExternalIdentity, error) {
+	var out []domain.ExternalIdentity
+	err := r.gdb.Where("user_id = ?", userID).Order("id").Find(&out).Error
+	return out, err
+}
+
+func (r *identitiesMySQL) DeleteByUser(userID uint64) error {
+	return r.gdb.Where("user_id = ?", userID).Delete(&domain.ExternalIdentity{}).Error
+}
+
+func (r *identitiesMySQL) SaveLogin(l *domain.OIDCLogin) error {
+	if err := r.gdb.Where("expires_at < ?", time.Now()).Delete(&domain.OIDCLogin{}).Error; err != nil {
+		return err
+	}
+	return r.gdb.Create(l).Error
+}
+
+func (r *identitiesMySQL) ConsumeLogin(stateHash string) (*domain.OIDCLogin, error) {
+	var l domain.OIDCLogin
+	err := r.gdb.Where("state_hash = ?", stateHash).First(&l).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// solo uno de dos callbacks concurrentes con el mismo state lo borra
+	res := r.gdb.Where("state_hash = ?", stateHash).Delete(&domain.OIDCLogin{})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected != 1 {
+		return nil, nil
+	}
+	return &l, nil
+}
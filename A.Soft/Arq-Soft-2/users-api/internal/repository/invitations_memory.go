@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+// invitationsMemory es un InvitationsRepo en memoria, para tests y desarrollo sin MySQL
+type invitationsMemory struct {
+	mu     sync.Mutex
+	nextID uint64
+	byID   map[uint64]*domain.Invitation
+}
+
+func NewInvitationsMemory() InvitationsRepo {
+	return &invitationsMemory{byID: map[uint64]*domain.Invitation{}}
+}
+
+func (r *invitationsMemory) Create(inv *domain.Invitation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	inv.ID = r.nextID
+	if inv.CreatedAt.IsZero() {
+		inv.CreatedAt = time.Now()
+	}
+	cp := *inv
+	r.byID[inv.ID] = &cp
+	return nil
+}
+
+func (r *invitationsMemory) FindByHash(hash string) (*domain.Invitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, inv := range r.byID {
+		if inv.TokenHash == hash {
+			cp := *inv
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *invitationsMemory) MarkUsed(id uint64, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inv, ok := r.byID[id]
+	if !ok || inv.UsedAt != nil {
+		return false, nil
+	}
+	inv.UsedAt = &at
+	return true, nil
+}
+
+func (r *invitationsMemory) Release(id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if inv, ok := r.byID[id]; ok {
+		inv.UsedAt = nil
+	}
+	return nil
+}
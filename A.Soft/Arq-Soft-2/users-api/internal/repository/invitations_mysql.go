@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+type InvitationsRepo interface {
+	Create(inv *domain.Invitation) error
+	// FindByHash devuelve nil, nil si no existe
+	FindByHash(hash string) (*domain.Invitation, error)
+	// MarkUsed consume la invitación; devuelve false si ya estaba usada
+	MarkUsed(id uint64, at time.Time) (bool, error)
+	// Release vuelve a habilitar una invitación (si el alta falló después de consumirla)
+	Release(id uint64) error
+}
+
+type invitationsMySQL struct{ gdb *gorm.DB }
+
+func NewInvitationsMySQL(gdb *gorm.DB) InvitationsRepo {
+	return &invitationsMySQL{gdb: gdb}
+}
+
+func (r *invitationsMySQL) Create(inv *domain.Invitation) error {
+	return r.gdb.Create(inv).Error
+}
+
+func (r *invitationsMySQL) FindByHash(hash string) (*domain.Invitation, error) {
+	var inv domain.Invitation
+	err := r.gdb.Where("token_hash = ?", hash).First(&inv).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *invitationsMySQL) MarkUsed(id uint64, at time.Time) (bool, error) {
+	res := r.gdb.Model(&domain.Invitation{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *invitationsMySQL) Release(id uint64) error {
+	return r.gdb.Model(&domain.Invitation{}).Where("id = ?", id).Update("used_at", nil).Error
+}
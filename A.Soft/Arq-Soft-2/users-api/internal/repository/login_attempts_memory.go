@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+// loginAttemptsMemory es un LoginAttemptsRepo en memoria (una sola réplica o tests)
+type loginAttemptsMemory struct {
+	mu    sync.Mutex
+	byKey map[string]*domain.LoginAttempt
+}
+
+func NewLoginAttemptsMemory() LoginAttemptsRepo {
+	return &loginAttemptsMemory{byKey: map[string]*domain.LoginAttempt{}}
+}
+
+func (r *loginAttemptsMemory) Get(key string) (*domain.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if a, ok := r.byKey[key]; ok {
+		cp := *a
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *loginAttemptsMemory) RecordFailure(key string, at, windowStart time.Time) (*domain.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.byKey[key]
+	if !ok {
+		a = &domain.LoginAttempt{Key: key}
+		r.byKey[key] = a
+	}
+	if a.LastFailureAt.Before(windowStart) {
+		a.Failures = 0
+	}
+	a.Failures++
+	a.LastFailureAt = at
+	cp := *a
+	return &cp, nil
+}
+
+func (r *loginAttemptsMemory) Lock(key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if a, ok := r.byKey[key]; ok {
+		a.LockedUntil = &until
+	}
+	return nil
+}
+
+func (r *loginAttemptsMemory) Reset(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.byKey, key)
+	return nil
+}
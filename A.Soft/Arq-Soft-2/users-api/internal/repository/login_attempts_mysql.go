@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+// LoginAttemptsRepo guarda los logins fallidos. La versión MySQL es la que se
+// usa con varias réplicas; la de memoria sirve para tests y una sola instancia.
+type LoginAttemptsRepo interface {
+	// Get devuelve nil, nil si la clave no tiene fallos registrados
+	Get(key string) (*domain.LoginAttempt, error)
+	// RecordFailure suma un fallo (reinicia la cuenta si el anterior es de
+	// antes de windowStart) y devuelve el estado resultante
+	RecordFailure(key string, at, windowStart time.Time) (*domain.LoginAttempt, error)
+	Lock(key string, until time.Time) error
+	// Reset borra los fallos y el bloqueo de la clave
+	Reset(key string) error
+}
+
+type loginAttemptsMySQL struct{ gdb *gorm.DB }
+
+func NewLoginAttemptsMySQL(gdb *gorm.DB) LoginAttemptsRepo {
+	return &loginAttemptsMySQL{gdb: gdb}
+}
+
+func (r *loginAttemptsMySQL) Get(key string) (*domain.LoginAttempt, error) {
+	var a domain.LoginAttempt
+	err := r.gdb.Where("`key` = ?", key).First(&a).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *loginAttemptsMySQL) RecordFailure(key string, at, windowStart time.Time) (*domain.LoginAttempt, error) {
+	// upsert atómico: así varias réplicas cuentan sobre la misma fila. El
+	// orden del SET importa (failures se calcula con el last_failure_at viejo).
+	err := r.gdb.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "failures"}, Value: gorm.Expr("IF(last_failure_at < ?, 1, failures + 1)", windowStart)},
+			{Column: clause.Column{Name: "last_failure_at"}, Value: at},
+		},
+	}).Create(&domain.LoginAttempt{Key: key, Failures: 1, LastFailureAt: at}).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(key)
+}
+
+func (r *loginAttemptsMySQL) Lock(key string, until time.Time) error {
+	return r.gdb.Model(&domain.LoginAttempt{}).Where("`key` = ?", key).Update("locked_until", until).Error
+}
+
+func (r *loginAttemptsMySQL) Reset(key string) error {
+	return r.gdb.Where("`key` = ?", key).Delete(&domain.LoginAttempt{}).Error
+}
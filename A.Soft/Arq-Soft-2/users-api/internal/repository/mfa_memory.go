@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+// mfaMemory es un MFARepo en memoria, para tests y desarrollo sin MySQL
+type mfaMemory struct {
+	mu    sync.Mutex
+	byID  map[uint64]*domain.MFA
+	codes map[uint64][]*domain.RecoveryCode
+}
+
+func NewMFAMemory() MFARepo {
+	return &mfaMemory{byID: map[uint64]*domain.MFA{}, codes: map[uint64][]*domain.RecoveryCode{}}
+}
+
+func (r *mfaMemory) Get(userID uint64) (*domain.MFA, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.byID[userID]; ok {
+		cp := *m
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *mfaMemory) Save(m *domain.MFA) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cp := *m
+	r.byID[m.UserID] = &cp
+	return nil
+}
+
+func (r *mfaMemory) Delete(userID uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.byID, userID)
+	delete(r.codes, userID)
+	return nil
+}
+
+func (r *mfaMemory) UseStep(userID uint64, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.byID[userID]
+	if !ok || m.LastStep >= step {
+		return false, nil
+	}
+	m.LastStep = step
+	return true, nil
+}
+
+func (r *mfaMemory) ReplaceRecoveryCodes(userID uint64, hashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	codes := make([]*domain.RecoveryCode, len(hashes))
+	for i, h := range hashes {
+		codes[i] = &domain.RecoveryCode{UserID: userID, CodeHash: h, CreatedAt: time.Now()}
+	}
+	r.codes[userID] = codes
+	return nil
+}
+
+func (r *mfaMemory) UseRecoveryCode(userID uint64, hash string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.codes[userID] {
+		if c.CodeHash == hash && c.UsedAt == nil {
+			c.UsedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *mfaMemory) CountRecoveryCodes(userID uint64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for _, c := range r.codes[userID] {
+		if c.UsedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+type MFARepo interface {
+	// Get devuelve nil, nil si el usuario nunca configuró 2FA
+	Get(userID uint64) (*domain.MFA, error)
+	// Save crea o reemplaza la configuración del usuario
+	Save(m *domain.MFA) error
+	// Delete borra la configuración y los códigos de recuperación
+	Delete(userID uint64) error
+	// UseStep registra el paso TOTP usado; devuelve false si ya se usó ese
+	// paso o uno posterior (código repetido)
+	UseStep(userID uint64, step int64) (bool, error)
+	ReplaceRecoveryCodes(userID uint64, hashes []string) error
+	// UseRecoveryCode consume el código; devuelve false si no existe o ya se usó
+	UseRecoveryCode(userID uint64, hash string, at time.Time) (bool, error)
+	CountRecoveryCodes(userID uint64) (int64, error)
+}
+
+type mfaMySQL struct{ gdb *gorm.DB }
+
+func NewMFAMySQL(gdb *gorm.DB) MFARepo {
+	return &mfaMySQL{gdb: gdb}
+}
+
+func (r *mfaMySQL) Get(userID uint64) (*domain.MFA, error) {
+	var m domain.MFA
+	err := r.gdb.Where("user_id = ?", userID).First(&m).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *mfaMySQL) Save(m *domain.MFA) error {
+	return r.gdb.Clauses(clause.OnConflict{UpdateAll: true}).Create(m).Error
+}
+
+func (r *mfaMySQL) Delete(userID uint64) error {
+	return r.gdb.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&domain.MFA{}).Error
+	})
+}
+
+func (r *mfaMySQL) UseStep(userID uint64, step int64) (bool, error) {
+	res := r.gdb.Model(&domain.MFA{}).
+		Where("user_id = ? AND last_step < ?", userID, step).
+		Update("last_step", step)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *mfaMySQL) ReplaceRecoveryCodes(userID uint64, hashes []string) error {
+	return r.gdb.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]domain.RecoveryCode, len(hashes))
+		for i, h := range hashes {
+			codes[i] = domain.RecoveryCode{UserID: userID, CodeHash: h}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *mfaMySQL) UseRecoveryCode(userID uint64, hash string, at time.Time) (bool, error) {
+	res := r.gdb.Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *mfaMySQL) CountRecoveryCodes(userID uint64) (int64, error) {
+	var n int64
+	err := r.gdb.Model(&domain.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+// orgsMemory es un OrgsRepo en memoria, para tests y desarrollo sin MySQL
+type orgsMemory struct {
+	mu      sync.Mutex
+	nextID  uint64
+	orgs    map[uint64]*domain.Organization
+	members map[[2]uint64]*domain.Membership // {orgID, userID}
+}
+
+func NewOrgsMemory() OrgsRepo {
+	return &orgsMemory{orgs: map[uint64]*domain.Organization{}, members: map[[2]uint64]*domain.Membership{}}
+}
+
+func (r *orgsMemory) Create(o *domain.Organization) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, other := range r.orgs {
+		if other.Slug == o.Slug {
+			return errors.New("duplicate slug")
+		}
+	}
+	if o.ID == 0 {
+		r.nextID++
+		o.ID = r.nextID
+	} else if o.ID > r.nextID {
+		r.nextID = o.ID
+	}
+	if o.CreatedAt.IsZero() {
+		o.CreatedAt = time.Now()
+	}
+	cp := *o
+	r.orgs[o.ID] = &cp
+	return nil
+}
+
+func (r *orgsMemory) FindByID(id uint64) (*domain.Organization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if o, ok := r.orgs[id]; ok {
+		cp := *o
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *orgsMemory) FindBySlug(slug string) (*domain.Organization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, o := range r.orgs {
+		if o.Slug == slug {
+			cp := *o
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *orgsMemory) GetMembership(orgID, userID uint64) (*domain.Membership, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.members[[2]uint64{orgID, userID}]; ok {
+		cp := *m
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *orgsMemory) SaveMembership(m *domain.Membership) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	key := [2]uint64{m.OrgID, m.UserID}
+	if old, ok := r.members[key]; ok {
+		m.CreatedAt = old.CreatedAt
+	} else if m.CreatedAt.IsZero() {
+		m.CreatedAt = now
+	}
+	m.UpdatedAt = now
+	cp := *m
+	r.members[key] = &cp
+	return nil
+}
+
+func (r *orgsMemory) DeleteMembership(orgID, userID uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.members, [2]uint64{orgID, userID})
+	return nil
+}
+
+func (r *orgsMemory) ListMembers(orgID uint64) ([]domain.Membership, error) {
+	return r.list(func(m *domain.Membership) bool { return m.OrgID == orgID }), nil
+}
+
+func (r *orgsMemory) ListByUser(userID uint64) ([]domain.Membership, error) {
+	return r.list(func(m *domain.Membership) bool { return m.UserID == userID }), nil
+}
+
+func (r *orgsMemory) CountAdmins(orgID uint64) (int64, error) {
+	n := len(r.list(func(m *domain.Membership) bool { return m.OrgID == orgID && m.Role == domain.OrgRoleAdmin }))
+	return int64(n), nil
+}
+
+// list devuelve las membresías que cumplen keep, ordenadas por org y usuario
+func (r *orgsMemory) list(keep func(*domain.Membership) bool) []domain.Membership {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.Membership
+	for _, m := range r.members {
+		if keep(m) {
+			out = append(out, *m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].OrgID != out[j].OrgID {
+			return out[i].OrgID < out[j].OrgID
+		}
+		return out[i].UserID < out[j].UserID
+	})
+	return out
+}
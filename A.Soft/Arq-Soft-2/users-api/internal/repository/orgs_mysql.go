@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+type OrgsRepo interface {
+	Create(o *domain.Organization) error
+	// FindByID y FindBySlug devuelven nil, nil si no existe
+	FindByID(id uint64) (*domain.Organization, error)
+	FindBySlug(slug string) (*domain.Organization, error)
+	// GetMembership devuelve nil, nil si el usuario no es miembro
+	GetMembership(orgID, userID uint64) (*domain.Membership, error)
+	// SaveMembership agrega al miembro o le cambia el rol
+	SaveMembership(m *domain.Membership) error
+	DeleteMembership(orgID, userID uint64) error
+	ListMembers(orgID uint64) ([]domain.Membership, error)
+	// ListByUser son las membresías del usuario (para el claim orgs)
+	ListByUser(userID uint64) ([]domain.Membership, error)
+	CountAdmins(orgID uint64) (int64, error)
+}
+
+type orgsMySQL struct{ gdb *gorm.DB }
+
+func NewOrgsMySQL(gdb *gorm.DB) OrgsRepo {
+	return &orgsMySQL{gdb: gdb}
+}
+
+func (r *orgsMySQL) Create(o *domain.Organization) error {
+	return r.gdb.Create(o).Error
+}
+
+func (r *orgsMySQL) FindByID(id uint64) (*domain.Organization, error) {
+	return r.first("id = ?", id)
+}
+
+func (r *orgsMySQL) FindBySlug(slug string) (*domain.Organization, error) {
+	return r.first("slug = ?", slug)
+}
+
+func (r *orgsMySQL) first(query string, arg any) (*domain.Organization, error) {
+	var o domain.Organization
+	if err := r.gdb.Where(query, arg).First(&o).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *orgsMySQL) GetMembership(orgID, userID uint64) (*domain.Membership, error) {
+	var m domain.Membership
+	if err := r.gdb.Where("org_id = ? AND user_id = ?", orgID, userID).First(&m).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *orgsMySQL) SaveMembership(m *domain.Membership) error {
+	return r.gdb.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(m).Error
+}
+
+func (r *orgsMySQL) DeleteMembership(orgID, userID uint64) error {
+	return r.gdb.Where("org_id = ? AND user_id = ?", orgID, userID).Delete(&domain.Membership{}).Error
+}
+
+func (r *orgsMySQL) ListMembers(orgID uint64) ([]domain.Membership, error) {
+	var out []domain.Membership
+	err := r.gdb.Where("org_id = ?", orgID).Order("user_id").Find(&out).Error
+	return out, err
+}
+
+func (r *orgsMySQL) ListByUser(userID uint64) ([]domain.Membership, error) {
+	var out []domain.Membership
+	err := r.gdb.Where("user_id = ?", userID).Order("org_id").Find(&out).Error
+	return out, err
+}
+
+func (r *orgsMySQL) CountAdmins(orgID uint64) (int64, error) {
+	var n int64
+	err := r.gdb.Model(&domain.Membership{}).Where("org_id = ? AND role = ?", orgID, domain.OrgRoleAdmin).Count(&n).Error
+	return n, err
+}
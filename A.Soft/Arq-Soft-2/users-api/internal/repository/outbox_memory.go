@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+// outboxMemory es un OutboxRepo en memoria, para tests y desarrollo sin MySQL
+type outboxMemory struct {
+	mu     sync.Mutex
+	nextID uint64
+	byID   map[uint64]*domain.OutboxEvent
+}
+
+func NewOutboxMemory() OutboxRepo {
+	return &outboxMemory{byID: map[uint64]*domain.OutboxEvent{}}
+}
+
+func (r *outboxMemory) Add(ev *domain.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	ev.ID = r.nextID
+	if ev.CreatedAt.IsZero() {
+		ev.CreatedAt = time.Now()
+	}
+	cp := *ev
+	r.byID[ev.ID] = &cp
+	return nil
+}
+
+func (r *outboxMemory) Claim(owner string, now, until time.Time, limit int) ([]domain.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.OutboxEvent
+	for _, ev := range r.byID {
+		if ev.PublishedAt == nil && (ev.LockedUntil == nil || ev.LockedUntil.Before(now)) {
+			out = append(out, *ev)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	for i := range out {
+		ev := r.byID[out[i].ID]
+		ev.LockedBy, ev.LockedUntil = owner, &until
+		out[i] = *ev
+	}
+	return out, nil
+}
+
+func (r *outboxMemory) MarkPublished(id uint64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ev, ok := r.byID[id]; ok {
+		ev.PublishedAt, ev.LockedBy, ev.LockedUntil = &at, "", nil
+	}
+	return nil
+}
+
+func (r *outboxMemory) Release(owner string, failedID uint64, errMsg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ev, ok := r.byID[failedID]; ok {
+		ev.Attempts++
+		ev.LastError = errMsg
+	}
+	for _, ev := range r.byID {
+		if ev.LockedBy == owner && ev.PublishedAt == nil {
+			ev.LockedBy, ev.LockedUntil = "", nil
+		}
+	}
+	return nil
+}
+
+func (r *outboxMemory) DeletePublished(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for id, ev := range r.byID {
+		if ev.PublishedAt != nil && ev.PublishedAt.Before(before) {
+			delete(r.byID, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *outboxMemory) ListByUser(userID uint64) ([]domain.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.OutboxEvent
+	for _, ev := range r.byID {
+		if ev.UserID != nil && *ev.UserID == userID {
+			out = append(out, *ev)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
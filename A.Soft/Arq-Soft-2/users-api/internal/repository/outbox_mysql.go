@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+type OutboxRepo interface {
+	// Add guarda un evento pendiente; se llama dentro de la transacción del
+	// cambio (Repos.InTx)
+	Add(ev *domain.OutboxEvent) error
+	// Claim toma para owner, hasta until, como mucho limit eventos pendientes
+	// en orden de alta. Los que tiene tomados otra réplica se saltean hasta
+	// que vence su lease.
+	Claim(owner string, now, until time.Time, limit int) ([]domain.OutboxEvent, error)
+	MarkPublished(id uint64, at time.Time) error
+	// Release suelta los eventos de owner que quedaron sin publicar y le suma
+	// un intento (con errMsg) a failedID
+	Release(owner string, failedID uint64, errMsg string) error
+	// DeletePublished borra los eventos publicados antes de before
+	DeletePublished(before time.Time) (int64, error)
+	// ListByUser son los eventos del usuario que siguen guardados, en orden
+	// de alta (exportación de datos)
+	ListByUser(userID uint64) ([]domain.OutboxEvent, error)
+}
+
+type outboxMySQL struct{ gdb *gorm.DB }
+
+func NewOutboxMySQL(gdb *gorm.DB) OutboxRepo {
+	return &outboxMySQL{gdb: gdb}
+}
+
+func (r *outboxMySQL) Add(ev *domain.OutboxEvent) error {
+	return r.gdb.Create(ev).Error
+}
+
+func (r *outboxMySQL) Claim(owner string, now, until time.Time, limit int) ([]domain.OutboxEvent, error) {
+	// el UPDATE con ORDER BY/LIMIT toma el lote de forma atómica
+	err := r.gdb.Exec(`UPDATE outbox_events SET locked_by = ?, locked_until = ?
+		WHERE published_at IS NULL AND (locked_until IS NULL OR locked_until < ?)
+		ORDER BY id LIMIT ?`, owner, until, now, limit).Error
+	if err != nil {
+		return nil, err
+	}
+	var out []domain.OutboxEvent
+	err = r.gdb.Where("locked_by = ? AND locked_until = ? AND published_at IS NULL", owner, until).
+		Order("id").Find(&out).Error
+	return out, err
+}
+
+func (r *outboxMySQL) MarkPublished(id uint64, at time.Time) error {
+	return r.gdb.Model(&domain.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]any{"published_at": at, "locked_by": nil, "locked_until": nil}).Error
+}
+
+func (r *outboxMySQL) Release(owner string, failedID uint64, errMsg string) error {
+	return r.gdb.Transaction(func(tx *gorm.DB) error {
+		if failedID != 0 {
+			err := tx.Model(&domain.OutboxEvent{}).Where("id = ?", failedID).Updates(map[string]any{
+				"attempts": gorm.Expr("attempts + 1"), "last_error": truncate(errMsg, 255),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&domain.OutboxEvent{}).Where("locked_by = ? AND published_at IS NULL", owner).
+			Updates(map[string]any{"locked_by": nil, "locked_until": nil}).Error
+	})
+}
+
+func (r *outboxMySQL) DeletePublished(before time.Time) (int64, error) {
+	res := r.gdb.Where("published_at IS NOT NULL AND published_at < ?", before).Delete(&domain.OutboxEvent{})
+	return res.RowsAffected, res.Error
+}
+
+func (r *outboxMySQL) ListByUser(userID uint64) ([]domain.OutboxEvent, error) {
+	var out []domain.OutboxEvent
+	err := r.gdb.Where("user_id = ?", userID).Order("id").Find(&out).Error
+	return out, err
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,81 @@
+package repository
+
+import "gorm.io/gorm"
+
+// Repos agrupa los repositorios de users-api. Los de MySQL comparten una sola
+// conexión (un solo pool), que se abre en main y se inyecta acá.
+type Repos struct {
+	Users         UsersRepo
+	Tokens        TokensRepo
+	Invitations   InvitationsRepo
+	UserTokens    UserTokensRepo
+	LoginAttempts LoginAttemptsRepo
+	MFA           MFARepo
+	Exports       ExportsRepo
+	Orgs          OrgsRepo
+	Identities    IdentitiesRepo
+	Outbox        OutboxRepo
+
+	// tx corre fn en una transacción (nil en memoria)
+	tx func(fn func(tx Repos) error) error
+}
+
+// InTx corre fn con los repos atados a una transacción de MySQL: si fn
+// devuelve error no queda nada escrito. En memoria no hay transacciones y fn
+// recibe los mismos repos.
+func (r Repos) InTx(fn func(tx Repos) error) error {
+	if r.tx == nil {
+		return fn(r)
+	}
+	return r.tx(fn)
+}
+
+// NewMySQLRepos arma todos los repositorios sobre gdb. Los intentos de login
+// quedan en memoria si loginAttemptsStore es "memory" (una sola instancia).
+func NewMySQLRepos(gdb *gorm.DB, loginAttemptsStore string) Repos {
+	var attempts LoginAttemptsRepo
+	if loginAttemptsStore == "memory" {
+		attempts = NewLoginAttemptsMemory()
+	} else {
+		attempts = NewLoginAttemptsMySQL(gdb)
+	}
+	repos := newMySQLRepos(gdb, attempts)
+	repos.tx = func(fn func(tx Repos) error) error {
+		return gdb.Transaction(func(tx *gorm.DB) error {
+			return fn(newMySQLRepos(tx, attempts))
+		})
+	}
+	return repos
+}
+
+func newMySQLRepos(gdb *gorm.DB, attempts LoginAttemptsRepo) Repos {
+	return Repos{
+		Users:         NewUsersMySQL(gdb),
+		Tokens:        NewTokensMySQL(gdb),
+		Invitations:   NewInvitationsMySQL(gdb),
+		UserTokens:    NewUserTokensMySQL(gdb),
+		LoginAttempts: attempts,
+		MFA:           NewMFAMySQL(gdb),
+		Exports:       NewExportsMySQL(gdb),
+		Orgs:          NewOrgsMySQL(gdb),
+		Identities:    NewIdentitiesMySQL(gdb),
+		Outbox:        NewOutboxMySQL(gdb),
+	}
+}
+
+// NewMemoryRepos arma todos los repositorios en memoria (tests y desarrollo
+// sin MySQL)
+func NewMemoryRepos() Repos {
+	return Repos{
+		Users:         NewUsersMemory(),
+		Tokens:        NewTokensMemory(),
+		Invitations:   NewInvitationsMemory(),
+		UserTokens:    NewUserTokensMemory(),
+		LoginAttempts: NewLoginAttemptsMemory(),
+		MFA:           NewMFAMemory(),
+		Exports:       NewExportsMemory(),
+		Orgs:          NewOrgsMemory(),
+		Identities:    NewIdentitiesMemory(),
+		Outbox:        NewOutboxMemory(),
+	}
+}
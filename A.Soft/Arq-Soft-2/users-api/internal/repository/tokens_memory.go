@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+// tokensMemory es un TokensRepo en memoria, para tests y desarrollo sin MySQL
+type tokensMemory struct {
+	mu      sync.Mutex
+	nextID  uint64
+	refresh map[uint64]*domain.RefreshToken
+	revoked map[string]domain.RevokedToken
+}
+
+func NewTokensMemory() TokensRepo {
+	return &tokensMemory{
+		refresh: map[uint64]*domain.RefreshToken{},
+		revoked: map[string]domain.RevokedToken{},
+	}
+}
+
+func (r *tokensMemory) CreateRefresh(t *domain.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	t.ID = r.nextID
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	cp := *t
+	r.refresh[t.ID] = &cp
+	return nil
+}
+
+func (r *tokensMemory) FindRefreshByHash(hash string) (*domain.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.refresh {
+		if t.TokenHash == hash {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *tokensMemory) RevokeRefresh(id uint64, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.refresh[id]
+	if !ok || t.RevokedAt != nil {
+		return false, nil
+	}
+	t.RevokedAt = &at
+	return true, nil
+}
+
+func (r *tokensMemory) RevokeFamily(familyID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.refresh {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *tokensMemory) RevokeUser(userID uint64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.refresh {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *tokensMemory) ListByUser(userID uint64) ([]domain.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.RefreshToken
+	for _, t := range r.refresh {
+		if t.UserID == userID {
+			out = append(out, *t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *tokensMemory) RevokeAccess(t *domain.RevokedToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.revoked[t.JTI]; !ok {
+		r.revoked[t.JTI] = *t
+	}
+	return nil
+}
+
+func (r *tokensMemory) IsAccessRevoked(jti string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.revoked[jti]
+	return ok, nil
+}
+
+func (r *tokensMemory) RevokedAccessSince(since, now time.Time) ([]domain.RevokedToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.RevokedToken
+	for _, t := range r.revoked {
+		if t.RevokedAt.After(since) && t.ExpiresAt.After(now) {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].RevokedAt.Before(out[j].RevokedAt) })
+	return out, nil
+}
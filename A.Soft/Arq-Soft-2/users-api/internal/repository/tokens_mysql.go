@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+type TokensRepo interface {
+	CreateRefresh(t *domain.RefreshToken) error
+	// FindRefreshByHash devuelve nil, nil si no existe
+	FindRefreshByHash(hash string) (*domain.RefreshToken, error)
+	// RevokeRefresh marca el token como revocado; devuelve false si ya lo estaba
+	RevokeRefresh(id uint64, at time.Time) (bool, error)
+	RevokeFamily(familyID string, at time.Time) error
+	// RevokeUser revoca todos los refresh tokens vigentes del usuario
+	RevokeUser(userID uint64, at time.Time) error
+	// ListByUser devuelve todas las sesiones (refresh tokens) del usuario,
+	// las más nuevas primero
+	ListByUser(userID uint64) ([]domain.RefreshToken, error)
+	RevokeAccess(t *domain.RevokedToken) error
+	IsAccessRevoked(jti string) (bool, error)
+	// RevokedAccessSince devuelve los access tokens revocados después de since
+	// que todavía no expiraron a now
+	RevokedAccessSince(since, now time.Time) ([]domain.RevokedToken, error)
+}
+
+type tokensMySQL struct{ gdb *gorm.DB }
+
+func NewTokensMySQL(gdb *gorm.DB) TokensRepo {
+	return &tokensMySQL{gdb: gdb}
+}
+
+func (r *tokensMySQL) CreateRefresh(t *domain.RefreshToken) error {
+	return r.gdb.Create(t).Error
+}
+
+func (r *tokensMySQL) FindRefreshByHash(hash string) (*domain.RefreshToken, error) {
+	var t domain.RefreshToken
+	err := r.gdb.Where("token_hash = ?", hash).First(&t).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *tokensMySQL) RevokeRefresh(id uint64, at time.Time) (bool, error) {
+	res := r.gdb.Model(&domain.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *tokensMySQL) RevokeFamily(familyID string, at time.Time) error {
+	return r.gdb.Model(&domain.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+func (r *tokensMySQL) RevokeUser(userID uint64, at time.Time) error {
+	return r.gdb.Model(&domain.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
+
+func (r *tokensMySQL) ListByUser(userID uint64) ([]domain.RefreshToken, error) {
+	var out []domain.RefreshToken
+	err := r.gdb.Where("user_id = ?", userID).Order("created_at DESC").Find(&out).Error
+	return out, err
+}
+
+func (r *tokensMySQL) RevokeAccess(t *domain.RevokedToken) error {
+	return r.gdb.Clauses(clause.OnConflict{DoNothing: true}).Create(t).Error
+}
+
+func (r *tokensMySQL) IsAccessRevoked(jti string) (bool, error) {
+	var n int64
+	err := r.gdb.Model(&domain.RevokedToken{}).Where("jti = ?", jti).Count(&n).Error
+	return n > 0, err
+}
+
+func (r *tokensMySQL) RevokedAccessSince(since, now time.Time) ([]domain.RevokedToken, error) {
+	var out []domain.RevokedToken
+	err := r.gdb.Where("revoked_at > ? AND expires_at > ?", since, now).
+		Order("revoked_at").Find(&out).Error
+	return out, err
+}
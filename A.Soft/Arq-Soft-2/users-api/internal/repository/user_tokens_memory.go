@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+// userTokensMemory es un UserTokensRepo en memoria, para tests y desarrollo sin MySQL
+type userTokensMemory struct {
+	mu     sync.Mutex
+	nextID uint64
+	byID   map[uint64]*domain.UserToken
+}
+
+func NewUserTokensMemory() UserTokensRepo {
+	return &userTokensMemory{byID: map[uint64]*domain.UserToken{}}
+}
+
+func (r *userTokensMemory) Create(t *domain.UserToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	t.ID = r.nextID
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	cp := *t
+	r.byID[t.ID] = &cp
+	return nil
+}
+
+func (r *userTokensMemory) FindByHash(purpose, hash string) (*domain.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.byID {
+		if t.Purpose == purpose && t.TokenHash == hash {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *userTokensMemory) MarkUsed(id uint64, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.byID[id]
+	if !ok || t.UsedAt != nil {
+		return false, nil
+	}
+	t.UsedAt = &at
+	return true, nil
+}
+
+func (r *userTokensMemory) InvalidateUser(userID uint64, purpose string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.byID {
+		if t.UserID == userID && t.Purpose == purpose && t.UsedAt == nil {
+			t.UsedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *userTokensMemory) CountCreatedSince(userID uint64, purpose string, since time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for _, t := range r.byID {
+		if t.UserID == userID && t.Purpose == purpose && t.CreatedAt.After(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *userTokensMemory) ListByUser(userID uint64) ([]domain.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.UserToken
+	for _, t := range r.byID {
+		if t.UserID == userID {
+			out = append(out, *t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+type UserTokensRepo interface {
+	Create(t *domain.UserToken) error
+	// FindByHash devuelve nil, nil si no existe un token con ese propósito
+	FindByHash(purpose, hash string) (*domain.UserToken, error)
+	// MarkUsed consume el token; devuelve false si ya estaba usado
+	MarkUsed(id uint64, at time.Time) (bool, error)
+	// InvalidateUser consume todos los tokens pendientes del usuario para ese propósito
+	InvalidateUser(userID uint64, purpose string, at time.Time) error
+	// CountCreatedSince cuenta los tokens emitidos al usuario desde since (rate limit de reenvíos)
+	CountCreatedSince(userID uint64, purpose string, since time.Time) (int64, error)
+	// ListByUser devuelve todos los tokens emitidos al usuario, los más nuevos primero
+	ListByUser(userID uint64) ([]domain.UserToken, error)
+}
+
+type userTokensMySQL struct{ gdb *gorm.DB }
+
+func NewUserTokensMySQL(gdb *gorm.DB) UserTokensRepo {
+	return &userTokensMySQL{gdb: gdb}
+}
+
+func (r *userTokensMySQL) Create(t *domain.UserToken) error {
+	return r.gdb.Create(t).Error
+}
+
+func (r *userTokensMySQL) FindByHash(purpose, hash string) (*domain.UserToken, error) {
+	var t domain.UserToken
+	err := r.gdb.Where("purpose = ? AND token_hash = ?", purpose, hash).First(&t).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *userTokensMySQL) MarkUsed(id uint64, at time.Time) (bool, error) {
+	res := r.gdb.Model(&domain.UserToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *userTokensMySQL) InvalidateUser(userID uint64, purpose string, at time.Time) error {
+	return r.gdb.Model(&domain.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", at).Error
+}
+
+func (r *userTokensMySQL) CountCreatedSince(userID uint64, purpose string, since time.Time) (int64, error) {
+	var n int64
+	err := r.gdb.Model(&domain.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at > ?", userID, purpose, since).
+		Count(&n).Error
+	return n, err
+}
+
+func (r *userTokensMySQL) ListByUser(userID uint64) ([]domain.UserToken, error) {
+	var out []domain.UserToken
+	err := r.gdb.Where("user_id = ?", userID).Order("created_at DESC").Find(&out).Error
+	return out, err
+}
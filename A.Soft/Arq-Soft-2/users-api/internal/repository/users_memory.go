@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"cmp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sporthub/users-api/internal/domain"
+)
+
+// usersMemory es un UsersRepo en memoria, para tests y desarrollo sin MySQL.
+// Respeta lo mismo que la tabla: username y email únicos (gorm.ErrDuplicatedKey,
+// como MySQL con TranslateError) y FindByID con gorm.ErrRecordNotFound si no
+// existe.
+type usersMemory struct {
+	mu     sync.Mutex
+	nextID uint64
+	byID   map[uint64]*domain.User
+}
+
+func NewUsersMemory() UsersRepo {
+	return &usersMemory{byID: map[uint64]*domain.User{}}
+}
+
+func (r *usersMemory) Create(u *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, other := range r.byID {
+		if other.Username == u.Username || other.Email == u.Email {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	r.nextID++
+	u.ID = r.nextID
+	now := time.Now()
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = now
+	}
+	u.UpdatedAt = now
+	if u.Role == "" {
+		u.Role = domain.RoleUser
+	}
+	cp := *u
+	r.byID[u.ID] = &cp
+	return nil
+}
+
+func (r *usersMemory) FindByID(id uint64) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.byID[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	cp := *u
+	return &cp, nil
+}
+
+func (r *usersMemory) FindByUsernameOrEmail(login string) (*domain.User, error) {
+	return r.find(func(u *domain.User) bool { return u.Username == login || u.Email == login })
+}
+
+func (r *usersMemory) FindByEmail(email string) (*domain.User, error) {
+	return r.find(func(u *domain.User) bool { return u.Email == email })
+}
+
+func (r *usersMemory) FindByUsername(username string) (*domain.User, error) {
+	return r.find(func(u *domain.User) bool { return u.Username == username })
+}
+
+// find devuelve una copia del primer usuario que cumple match, o nil, nil
+func (r *usersMemory) find(match func(u *domain.User) bool) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.byID {
+		if match(u) {
+			cp := *u
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *usersMemory) DeleteUnlessLastAdmin(id uint64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.byID[id]; ok && u.Role == domain.RoleAdmin {
+		admins := 0
+		for _, other := range r.byID {
+			if other.Role == domain.RoleAdmin {
+				admins++
+			}
+		}
+		if admins <= 1 {
+			return false, nil
+		}
+	}
+	delete(r.byID, id)
+	return true, nil
+}
+
+func (r *usersMemory) UpdateRole(id uint64, role domain.Role) error {
+	return r.update(id, func(u *domain.User) { u.Role = role })
+}
+
+func (r *usersMemory) DemoteAdmin(id uint64, role domain.Role) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.byID[id]
+	if !ok {
+		return true, nil
+	}
+	if u.Role == domain.RoleAdmin {
+		admins := 0
+		for _, other := range r.byID {
+			if other.Role == domain.RoleAdmin {
+				admins++
+			}
+		}
+		if admins <= 1 {
+			return false, nil
+		}
+	}
+	u.Role = role
+	u.UpdatedAt = time.Now()
+	return true, nil
+}
+
+func (r *usersMemory) CountByRole(role domain.Role) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for _, u := range r.byID {
+		if u.Role == role {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *usersMemory) UpdatePassword(id uint64, passwordHash string) error {
+	return r.update(id, func(u *domain.User) { u.PasswordHash = passwordHash })
+}
+
+func (r *usersMemory) MarkEmailVerified(id uint64, email string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.byID[id]
+	if !ok || !strings.EqualFold(u.Email, email) {
+		return false, nil
+	}
+	u.EmailVerified, u.EmailVerifiedAt, u.UpdatedAt = true, &at, time.Now()
+	return true, nil
+}
+
+func (r *usersMemory) UpdateProfile(in *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, other := range r.byID {
+		if id != in.ID && (other.Username == in.Username || other.Email == in.Email) {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	u, ok := r.byID[in.ID]
+	if !ok {
+		return nil
+	}
+	u.Username, u.Email, u.EmailVerified, u.EmailVerifiedAt = in.Username, in.Email, in.EmailVerified, in.EmailVerifiedAt
+	u.DisplayName, u.Phone, u.DateOfBirth = in.DisplayName, in.Phone, in.DateOfBirth
+	u.EmergencyContactName, u.EmergencyContactPhone = in.EmergencyContactName, in.EmergencyContactPhone
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// update aplica fn al usuario id (como un UPDATE, no falla si no existe)
+func (r *usersMemory) update(id uint64, fn func(u *domain.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.byID[id]; ok {
+		fn(u)
+		u.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *usersMemory) List(f UserFilter) ([]domain.User, int64, error) {
+	r.mu.Lock()
+	search := strings.ToLower(f.Search)
+	var out []domain.User
+	for _, u := range r.byID {
+		if search != "" && !strings.Contains(strings.ToLower(u.Username), search) && !strings.Contains(strings.ToLower(u.Email), search) {
+			continue
+		}
+		if f.Role != "" && u.Role != f.Role {
+			continue
+		}
+		if f.CreatedFrom != nil && u.CreatedAt.Before(*f.CreatedFrom) {
+			continue
+		}
+		if f.CreatedTo != nil && !u.CreatedAt.Before(*f.CreatedTo) {
+			continue
+		}
+		out = append(out, *u)
+	}
+	r.mu.Unlock()
+
+	// mismo orden que la consulta de MySQL: columna y después id
+	compare := func(a, b domain.User) int {
+		switch f.Sort {
+		case "username":
+			return strings.Compare(strings.ToLower(a.Username), strings.ToLower(b.Username))
+		case "email":
+			return strings.Compare(strings.ToLower(a.Email), strings.ToLower(b.Email))
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		c := compare(out[i], out[j])
+		if c == 0 {
+			c = cmp.Compare(out[i].ID, out[j].ID)
+		}
+		if f.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	total := int64(len(out))
+	if f.Offset >= len(out) {
+		return nil, total, nil
+	}
+	out = out[f.Offset:]
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, total, nil
+}
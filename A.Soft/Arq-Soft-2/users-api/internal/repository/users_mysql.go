@@ -2,11 +2,13 @@ package repository
 
 import (
 	"errors"
+	"slices"
+	"strings"
+	"time"
 
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
-	"github.com/sporthub/users-api/internal/config"
 	"github.com/sporthub/users-api/internal/domain"
 )
 
@@ -14,17 +16,53 @@ type UsersRepo interface {
 	Create(u *domain.User) error
 	FindByID(id uint64) (*domain.User, error)
 	FindByUsernameOrEmail(login string) (*domain.User, error)
-	DeleteByID(id uint64) error
+	// FindByEmail devuelve nil, nil si no existe
+	FindByEmail(email string) (*domain.User, error)
+	// FindByUsername devuelve nil, nil si no existe
+	FindByUsername(username string) (*domain.User, error)
+	// DeleteUnlessLastAdmin borra el usuario salvo que sea el último admin
+	// (false). El chequeo y el borrado son atómicos, como en DemoteAdmin.
+	DeleteUnlessLastAdmin(id uint64) (bool, error)
+	UpdateRole(id uint64, role domain.Role) error
+	// DemoteAdmin cambia el rol de un admin solo si queda otro admin; false si
+	// es el último. El chequeo y el update son atómicos.
+	DemoteAdmin(id uint64, role domain.Role) (bool, error)
+	CountByRole(role domain.Role) (int64, error)
+	UpdatePassword(id uint64, passwordHash string) error
+	// MarkEmailVerified marca verificado el email de id solo si sigue siendo
+	// email (false si cambió en el medio)
+	MarkEmailVerified(id uint64, email string, at time.Time) (bool, error)
+	// UpdateProfile guarda username, email (y su estado de verificación) y los
+	// campos de perfil de u
+	UpdateProfile(u *domain.User) error
+	// List devuelve una página de usuarios que cumplen f y el total sin paginar
+	List(f UserFilter) ([]domain.User, int64, error)
+}
+
+// UserFilter son los filtros del listado de usuarios (GET /users)
+type UserFilter struct {
+	Search      string // substring de username o email
+	Role        domain.Role
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Sort        string // username, email o createdAt
+	Desc        bool
+	Offset      int
+	Limit       int
+}
+
+// userSortColumns mapea los campos de orden aceptados a columnas
+var userSortColumns = map[string]string{"username": "username", "email": "email", "createdAt": "created_at"}
+
+// ValidUserSort indica si sort es un campo de orden aceptado por List
+func ValidUserSort(sort string) bool {
+	_, ok := userSortColumns[sort]
+	return ok
 }
 
 type usersMySQL struct{ gdb *gorm.DB }
 
-func NewUsersMySQL(cfg config.Config) UsersRepo {
-	dsn := cfg.MySQLUser + ":" + cfg.MySQLPassword + "@tcp(" + cfg.MySQLHost + ":" + cfg.MySQLPort + ")/" + cfg.MySQLDB + "?parseTime=true&charset=utf8mb4&loc=Local"
-	gdb, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
-	if err != nil {
-		panic(err)
-	}
+func NewUsersMySQL(gdb *gorm.DB) UsersRepo {
 	return &usersMySQL{gdb: gdb}
 }
 
@@ -52,6 +90,133 @@ func (r *usersMySQL) FindByUsernameOrEmail(login string) (*domain.User, error) {
 	return &u, nil
 }
 
-func (r *usersMySQL) DeleteByID(id uint64) error {
-	return r.gdb.Delete(&domain.User{}, id).Error
+func (r *usersMySQL) FindByEmail(email string) (*domain.User, error) {
+	var u domain.User
+	err := r.gdb.Where("email = ?", email).First(&u).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *usersMySQL) FindByUsername(username string) (*domain.User, error) {
+	var u domain.User
+	err := r.gdb.Where("username = ?", username).First(&u).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *usersMySQL) DeleteUnlessLastAdmin(id uint64) (bool, error) {
+	ok := false
+	err := r.gdb.Transaction(func(tx *gorm.DB) error {
+		// mismo lock que DemoteAdmin: una baja y una degradación (o dos bajas)
+		// de admins a la vez se serializan
+		var ids []uint64
+		err := tx.Model(&domain.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("role = ?", domain.RoleAdmin).Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+		if slices.Contains(ids, id) && len(ids) <= 1 {
+			return nil
+		}
+		ok = true
+		return tx.Delete(&domain.User{}, id).Error
+	})
+	return ok, err
+}
+
+func (r *usersMySQL) UpdateRole(id uint64, role domain.Role) error {
+	return r.gdb.Model(&domain.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+func (r *usersMySQL) DemoteAdmin(id uint64, role domain.Role) (bool, error) {
+	ok := false
+	err := r.gdb.Transaction(func(tx *gorm.DB) error {
+		// FOR UPDATE sobre las filas de admins: dos degradaciones a la vez se
+		// serializan y la segunda ya cuenta sin la primera
+		var ids []uint64
+		err := tx.Model(&domain.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("role = ?", domain.RoleAdmin).Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+		if slices.Contains(ids, id) && len(ids) <= 1 {
+			return nil
+		}
+		ok = true
+		return tx.Model(&domain.User{}).Where("id = ?", id).Update("role", role).Error
+	})
+	return ok, err
+}
+
+func (r *usersMySQL) CountByRole(role domain.Role) (int64, error) {
+	var n int64
+	err := r.gdb.Model(&domain.User{}).Where("role = ?", role).Count(&n).Error
+	return n, err
+}
+
+func (r *usersMySQL) UpdatePassword(id uint64, passwordHash string) error {
+	return r.gdb.Model(&domain.User{}).Where("id = ?", id).Update("password_hash", passwordHash).Error
+}
+
+func (r *usersMySQL) MarkEmailVerified(id uint64, email string, at time.Time) (bool, error) {
+	res := r.gdb.Model(&domain.User{}).Where("id = ? AND email = ?", id, email).
+		Updates(map[string]any{"email_verified": true, "email_verified_at": at})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *usersMySQL) UpdateProfile(u *domain.User) error {
+	// Select para que también se guarden los valores vacíos (borrar un campo)
+	return r.gdb.Model(u).Select(
+		"username", "email", "email_verified", "email_verified_at",
+		"display_name", "phone", "date_of_birth", "emergency_contact_name", "emergency_contact_phone",
+	).Updates(u).Error
+}
+
+func (r *usersMySQL) List(f UserFilter) ([]domain.User, int64, error) {
+	q := r.gdb.Model(&domain.User{})
+	if f.Search != "" {
+		like := "%" + escapeLike(f.Search) + "%"
+		q = q.Where("username LIKE ? OR email LIKE ?", like, like)
+	}
+	if f.Role != "" {
+		q = q.Where("role = ?", f.Role)
+	}
+	if f.CreatedFrom != nil {
+		q = q.Where("created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		q = q.Where("created_at < ?", *f.CreatedTo)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	col, ok := userSortColumns[f.Sort]
+	if !ok {
+		col = "created_at"
+	}
+	dir := " ASC"
+	if f.Desc {
+		dir = " DESC"
+	}
+	var users []domain.User
+	err := q.Order(col + dir).Order("id" + dir).Offset(f.Offset).Limit(f.Limit).Find(&users).Error
+	return users, total, err
+}
+
+// escapeLike escapa los comodines de LIKE para buscar el texto literal
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+var ErrInvalidClient = errors.New("invalid client credentials")
+
+// ClientsService emite tokens de servicio a los clientes configurados en
+// SERVICE_CLIENTS (grant client_credentials)
+type ClientsService interface {
+	// Token valida client_id/secret y devuelve un token de servicio y su vida
+	// en segundos
+	Token(clientID, clientSecret string) (string, int64, error)
+}
+
+type clientsSvc struct {
+	secrets map[string]string // client_id -> hash del secret
+	cfg     config.Config
+}
+
+func NewClientsService(cfg config.Config) ClientsService {
+	s := &clientsSvc{secrets: map[string]string{}, cfg: cfg}
+	for _, entry := range strings.Split(cfg.ServiceClients, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" || secret == "" {
+			if entry != "" {
+				log.Printf("[auth] WARN: ignoring malformed SERVICE_CLIENTS entry")
+			}
+			continue
+		}
+		s.secrets[id] = utils.HashToken(secret)
+	}
+	return s
+}
+
+func (s *clientsSvc) Token(clientID, clientSecret string) (string, int64, error) {
+	want, ok := s.secrets[clientID]
+	// se compara igual aunque el cliente no exista, para no revelarlo por tiempo
+	got := utils.HashToken(clientSecret)
+	if subtle.ConstantTimeCompare([]byte(want), []byte(got)) != 1 || !ok {
+		return "", 0, ErrInvalidClient
+	}
+	token, err := utils.GenerateServiceToken(s.cfg, clientID)
+	if err != nil {
+		return "", 0, err
+	}
+	return token, int64(utils.ServiceTokenTTL(s.cfg).Seconds()), nil
+}
@@ -0,0 +1,450 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sporthub/users-api/internal/clients"
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+var (
+	ErrExportNotFound = errors.New("export not found")
+	ErrExportNotReady = errors.New("export not ready")
+)
+
+// nombre del JSON dentro del zip
+const ExportFileName = "sporthub-export.json"
+
+// tiempo máximo para juntar los datos de una exportación
+const exportTimeout = 2 * time.Minute
+
+// un job activo que no terminó en este tiempo se da por perdido: la réplica
+// que lo corría se cayó o se reinició (el job corre en una goroutine)
+const exportStale = exportTimeout + time.Minute
+
+// EnrollmentsSource trae las inscripciones del usuario de activities-api
+// (clients.ActivitiesClient; en tests un fake)
+type EnrollmentsSource interface {
+	UserEnrollments(ctx context.Context, userID uint64) ([]json.RawMessage, error)
+}
+
+// ExportService arma en segundo plano la exportación de los datos
+// personales de un usuario (perfil, 2FA, sesiones, tokens de cuenta, cuentas
+// externas vinculadas, organizaciones, registro de auditoría e inscripciones
+// de activities-api)
+type ExportService interface {
+	// Start lanza una exportación; si ya hay una en curso devuelve esa
+	Start(userID uint64) (*domain.ExportJob, error)
+	// Get devuelve el estado de un job del usuario (sin el zip)
+	Get(userID uint64, id string) (*domain.ExportJob, error)
+	// Latest devuelve la última exportación del usuario o ErrExportNotFound
+	Latest(userID uint64) (*domain.ExportJob, error)
+	// Download devuelve el zip de un job terminado
+	Download(userID uint64, id string) ([]byte, error)
+}
+
+type exportSvc struct {
+	jobs        repository.ExportsRepo
+	users       repository.UsersRepo
+	mfa         repository.MFARepo
+	tokens      repository.TokensRepo
+	userTokens  repository.UserTokensRepo
+	identities  repository.IdentitiesRepo
+	orgs        repository.OrgsRepo
+	outbox      repository.OutboxRepo
+	attempts    repository.LoginAttemptsRepo
+	enrollments EnrollmentsSource
+	cfg         config.Config
+}
+
+func NewExportService(repos repository.Repos, cfg config.Config) ExportService {
+	return NewExportServiceFromRepos(repos.Exports, repos.Users, repos.MFA, repos.Tokens, repos.UserTokens, repos.Identities,
+		repos.Orgs, repos.Outbox, repos.LoginAttempts, clients.NewActivitiesClient(cfg), cfg)
+}
+
+// NewExportServiceFromRepos permite inyectar los repos y la fuente de
+// inscripciones (tests, memoria)
+func NewExportServiceFromRepos(jobs repository.ExportsRepo, users repository.UsersRepo, mfa repository.MFARepo, tokens repository.TokensRepo,
+	userTokens repository.UserTokensRepo, identities repository.IdentitiesRepo, orgs repository.OrgsRepo, outbox repository.OutboxRepo,
+	attempts repository.LoginAttemptsRepo, enrollments EnrollmentsSource, cfg config.Config) ExportService {
+	return &exportSvc{jobs: jobs, users: users, mfa: mfa, tokens: tokens, userTokens: userTokens, identities: identities, orgs: orgs,
+		outbox: outbox, attempts: attempts, enrollments: enrollments, cfg: cfg}
+}
+
+func (s *exportSvc) ttl() time.Duration {
+	h, err := strconv.Atoi(s.cfg.ExportTTLHours)
+	if err != nil || h <= 0 {
+		h = 24
+	}
+	return time.Duration(h) * time.Hour
+}
+
+func (s *exportSvc) Start(userID uint64) (*domain.ExportJob, error) {
+	if n, err := s.jobs.DeleteExpired(time.Now()); err != nil {
+		log.Printf("[export] WARN: cleaning expired exports: %v", err)
+	} else if n > 0 {
+		log.Printf("[export] %d expired exports deleted", n)
+	}
+
+	if _, err := s.failStale(); err != nil {
+		return nil, err
+	}
+	latest, err := s.jobs.LatestByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && active(latest) {
+		return latest, nil
+	}
+
+	id, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	job := &domain.ExportJob{ID: id, UserID: userID, ActiveUserID: &userID, Status: domain.ExportPending, CreatedAt: now, StartedAt: &now}
+	if err := s.jobs.Create(job); err != nil {
+		// otro pedido (de esta u otra réplica) la lanzó recién: se devuelve esa
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			if latest, lerr := s.jobs.LatestByUser(userID); lerr == nil && latest != nil && active(latest) {
+				return latest, nil
+			}
+		}
+		return nil, err
+	}
+	go s.run(*job)
+	return job, nil
+}
+
+func (s *exportSvc) Get(userID uint64, id string) (*domain.ExportJob, error) {
+	return s.find(userID, id)
+}
+
+func (s *exportSvc) Latest(userID uint64) (*domain.ExportJob, error) {
+	job, err := s.jobs.LatestByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || expired(job) {
+		return nil, ErrExportNotFound
+	}
+	return s.recheck(job)
+}
+
+func (s *exportSvc) Download(userID uint64, id string) ([]byte, error) {
+	job, err := s.find(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != domain.ExportDone {
+		return nil, ErrExportNotReady
+	}
+	return s.jobs.Data(job.ID)
+}
+
+// find devuelve el job (sin el zip) solo si es del usuario y no venció (si
+// no, no existe)
+func (s *exportSvc) find(userID uint64, id string) (*domain.ExportJob, error) {
+	job, err := s.jobs.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || job.UserID != userID || expired(job) {
+		return nil, ErrExportNotFound
+	}
+	return s.recheck(job)
+}
+
+// failStale da por fallados los jobs que quedaron activos de más
+func (s *exportSvc) failStale() (int64, error) {
+	n, err := s.jobs.FailStale(time.Now().Add(-exportStale), "interrupted")
+	if n > 0 {
+		log.Printf("[export] WARN: %d interrupted exports marked as failed", n)
+	}
+	return n, err
+}
+
+// recheck: si el job sigue activo de más (nadie lo está corriendo) se marca
+// fallado para que el usuario lo vea y pueda pedir otro
+func (s *exportSvc) recheck(job *domain.ExportJob) (*domain.ExportJob, error) {
+	if !active(job) || job.StartedAt == nil || time.Since(*job.StartedAt) < exportStale {
+		return job, nil
+	}
+	if _, err := s.failStale(); err != nil {
+		return nil, err
+	}
+	fresh, err := s.jobs.FindByID(job.ID)
+	if err != nil || fresh == nil {
+		return job, err
+	}
+	return fresh, nil
+}
+
+func active(job *domain.ExportJob) bool {
+	return job.Status == domain.ExportPending || job.Status == domain.ExportRunning
+}
+
+func expired(job *domain.ExportJob) bool {
+	return job.ExpiresAt != nil && time.Now().After(*job.ExpiresAt)
+}
+
+func (s *exportSvc) run(job domain.ExportJob) {
+	started := time.Now()
+	job.Status = domain.ExportRunning
+	job.StartedAt = &started
+	if err := s.jobs.Save(&job); err != nil {
+		log.Printf("[export] job %s: %v", job.ID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	data, err := s.build(ctx, job.UserID)
+
+	now := time.Now()
+	job.CompletedAt = &now
+	job.ActiveUserID = nil
+	if err != nil {
+		log.Printf("[export] job %s for user %d failed: %v", job.ID, job.UserID, err)
+		job.Status = domain.ExportFailed
+		job.Error = truncate(err.Error(), 255)
+	} else {
+		exp := now.Add(s.ttl())
+		job.Status = domain.ExportDone
+		job.Data = data
+		job.ExpiresAt = &exp
+		log.Printf("[export] job %s for user %d done (%d bytes)", job.ID, job.UserID, len(data))
+	}
+	if err := s.jobs.Save(&job); err != nil {
+		log.Printf("[export] job %s: %v", job.ID, err)
+	}
+}
+
+// exportBundle es el JSON que se entrega. Nunca incluye hashes ni secretos
+// (contraseña, TOTP, tokens).
+type exportBundle struct {
+	GeneratedAt   time.Time          `json:"generatedAt"`
+	Profile       exportProfile      `json:"profile"`
+	TwoFactor     exportTwoFactor    `json:"twoFactor"`
+	Sessions      []exportSession    `json:"sessions"`
+	AccountTokens []exportUserToken  `json:"accountTokens"`
+	Identities    []exportIdentity   `json:"linkedAccounts"`
+	Organizations []exportMembership `json:"organizations"`
+	Audit         exportAudit        `json:"audit"`
+	Enrollments   []json.RawMessage  `json:"enrollments"`
+}
+
+type exportProfile struct {
+	ID                    uint64     `json:"id"`
+	Username              string     `json:"username"`
+	Email                 string     `json:"email"`
+	Role                  string     `json:"role"`
+	EmailVerified         bool       `json:"emailVerified"`
+	EmailVerifiedAt       *time.Time `json:"emailVerifiedAt,omitempty"`
+	DisplayName           string     `json:"displayName,omitempty"`
+	Phone                 string     `json:"phone,omitempty"`
+	DateOfBirth           string     `json:"dateOfBirth,omitempty"`
+	EmergencyContactName  string     `json:"emergencyContactName,omitempty"`
+	EmergencyContactPhone string     `json:"emergencyContactPhone,omitempty"`
+	CreatedAt             time.Time  `json:"createdAt"`
+}
+
+type exportTwoFactor struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabledAt,omitempty"`
+	RecoveryCodesLeft int64      `json:"recoveryCodesLeft"`
+}
+
+// exportSession es un inicio de sesión (refresh token emitido)
+type exportSession struct {
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// exportUserToken es un link de reset de contraseña o verificación de email
+type exportUserToken struct {
+	Purpose   string     `json:"purpose"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+}
+
+// exportIdentity es una cuenta de Google, Microsoft, etc. vinculada
+type exportIdentity struct {
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email,omitempty"`
+	LinkedAt    time.Time  `json:"linkedAt"`
+	LastLoginAt *time.Time `json:"lastLoginAt,omitempty"`
+}
+
+// exportMembership es una organización (club) de la que el usuario es miembro
+type exportMembership struct {
+	OrgID    uint64    `json:"orgId"`
+	Slug     string    `json:"slug,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// exportAudit es lo que users-api registró sobre la cuenta: los eventos
+// avisados a los otros servicios (los publicados se guardan una semana) y
+// los logins fallidos / bloqueo vigentes
+type exportAudit struct {
+	Events        []exportEvent       `json:"events"`
+	LoginAttempts *exportLoginAttempt `json:"loginAttempts,omitempty"`
+}
+
+// exportEvent es un evento de usuario (user.created, user.updated, user.deleted)
+type exportEvent struct {
+	RoutingKey  string          `json:"routingKey"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	PublishedAt *time.Time      `json:"publishedAt,omitempty"`
+}
+
+// exportLoginAttempt son los logins fallidos de la cuenta en la ventana actual
+type exportLoginAttempt struct {
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"lastFailureAt"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty"`
+}
+
+func (s *exportSvc) build(ctx context.Context, userID uint64) ([]byte, error) {
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	b := exportBundle{
+		GeneratedAt: time.Now(),
+		Profile: exportProfile{
+			ID: u.ID, Username: u.Username, Email: u.Email, Role: string(u.Role),
+			EmailVerified: u.EmailVerified, EmailVerifiedAt: u.EmailVerifiedAt,
+			DisplayName: u.DisplayName, Phone: u.Phone,
+			EmergencyContactName: u.EmergencyContactName, EmergencyContactPhone: u.EmergencyContactPhone,
+			CreatedAt: u.CreatedAt,
+		},
+		Sessions:      []exportSession{},
+		AccountTokens: []exportUserToken{},
+		Identities:    []exportIdentity{},
+		Organizations: []exportMembership{},
+		Audit:         exportAudit{Events: []exportEvent{}},
+		Enrollments:   []json.RawMessage{},
+	}
+	if u.DateOfBirth != nil {
+		b.Profile.DateOfBirth = u.DateOfBirth.Format("2006-01-02")
+	}
+
+	m, err := s.mfa.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil && m.Enabled {
+		left, err := s.mfa.CountRecoveryCodes(userID)
+		if err != nil {
+			return nil, err
+		}
+		b.TwoFactor = exportTwoFactor{Enabled: true, EnabledAt: m.EnabledAt, RecoveryCodesLeft: left}
+	}
+
+	sessions, err := s.tokens.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range sessions {
+		b.Sessions = append(b.Sessions, exportSession{CreatedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt, RevokedAt: t.RevokedAt})
+	}
+	tokens, err := s.userTokens.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		b.AccountTokens = append(b.AccountTokens, exportUserToken{Purpose: t.Purpose, CreatedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt, UsedAt: t.UsedAt})
+	}
+
+	identities, err := s.identities.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range identities {
+		b.Identities = append(b.Identities, exportIdentity{Provider: i.Provider, Subject: i.Subject, Email: i.Email, LinkedAt: i.CreatedAt, LastLoginAt: i.LastLoginAt})
+	}
+
+	memberships, err := s.orgs.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range memberships {
+		em := exportMembership{OrgID: m.OrgID, Role: string(m.Role), JoinedAt: m.CreatedAt}
+		org, err := s.orgs.FindByID(m.OrgID)
+		if err != nil {
+			return nil, err
+		}
+		if org != nil {
+			em.Slug, em.Name = org.Slug, org.Name
+		}
+		b.Organizations = append(b.Organizations, em)
+	}
+
+	events, err := s.outbox.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, ev := range events {
+		b.Audit.Events = append(b.Audit.Events, exportEvent{RoutingKey: ev.RoutingKey, Payload: json.RawMessage(ev.Payload),
+			CreatedAt: ev.CreatedAt, PublishedAt: ev.PublishedAt})
+	}
+	attempt, err := s.attempts.Get(AccountKey(u, ""))
+	if err != nil {
+		return nil, err
+	}
+	if attempt != nil {
+		b.Audit.LoginAttempts = &exportLoginAttempt{Failures: attempt.Failures, LastFailureAt: attempt.LastFailureAt, LockedUntil: attempt.LockedUntil}
+	}
+
+	// sin las inscripciones la exportación estaría incompleta: el job falla
+	enrollments, err := s.enrollments.UserEnrollments(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollments != nil {
+		b.Enrollments = enrollments
+	}
+
+	raw, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create(ExportFileName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+)
+
+// ErrLoginThrottled es la causa de todo ThrottledError (usar errors.Is)
+var ErrLoginThrottled = errors.New("too many failed login attempts")
+
+// ThrottledError rechaza un login por bloqueo temporal o por la demora
+// progresiva entre intentos fallidos
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string { return ErrLoginThrottled.Error() }
+func (e *ThrottledError) Unwrap() error { return ErrLoginThrottled }
+
+// LoginGuard limita los logins fallidos por cuenta y por IP. Por cuenta,
+// después de unos fallos libres cada intento tiene que esperar el doble que el
+// anterior; al llegar al máximo (por cuenta o por IP) se bloquea un rato.
+type LoginGuard interface {
+	// Check devuelve *ThrottledError si la cuenta o la IP no pueden intentar todavía
+	Check(account, ip string) error
+	Failure(account, ip string)
+	Success(account string)
+	// Unlock levanta el bloqueo de una cuenta (admin)
+	Unlock(userID, by uint64) error
+}
+
+// AccountKey es la clave de cuenta del guard. Los logins de usuarios que no
+// existen también se cuentan (por nombre) para no revelar cuáles existen.
+func AccountKey(u *domain.User, login string) string {
+	if u != nil {
+		return fmt.Sprintf("user:%d", u.ID)
+	}
+	return "login:" + strings.ToLower(strings.TrimSpace(login))
+}
+
+const (
+	// fallos por cuenta sin demora antes de empezar a duplicarla
+	freeFailures = 2
+	maxLoginWait = 30 * time.Second
+)
+
+type loginGuard struct {
+	repo       repository.LoginAttemptsRepo
+	maxAccount int
+	maxIP      int
+	lockout    time.Duration
+	window     time.Duration
+}
+
+// NewLoginGuard arma el guard sobre repo. main crea uno solo y lo comparten
+// los servicios de usuarios y de 2FA (importa con LOGIN_ATTEMPTS_STORE=memory)
+func NewLoginGuard(repo repository.LoginAttemptsRepo, cfg config.Config) LoginGuard {
+	return &loginGuard{
+		repo:       repo,
+		maxAccount: envInt(cfg.LoginMaxFailures, 5),
+		maxIP:      envInt(cfg.LoginIPMaxFailures, 20),
+		lockout:    time.Duration(envInt(cfg.LoginLockoutMinutes, 15)) * time.Minute,
+		window:     time.Duration(envInt(cfg.LoginWindowMinutes, 15)) * time.Minute,
+	}
+}
+
+func (g *loginGuard) Check(account, ip string) error {
+	now := time.Now()
+	if err := g.check(account, now, true); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	// por IP solo hay bloqueo, sin demora (puede haber muchos usuarios detrás de un NAT)
+	return g.check("ip:"+ip, now, false)
+}
+
+func (g *loginGuard) check(key string, now time.Time, progressive bool) error {
+	a, err := g.repo.Get(key)
+	if err != nil {
+		// sin store no se bloquea a nadie
+		log.Printf("[auth] ERROR: login attempts lookup %s: %v", key, err)
+		return nil
+	}
+	if a == nil {
+		return nil
+	}
+	if a.LockedUntil != nil && now.Before(*a.LockedUntil) {
+		return &ThrottledError{RetryAfter: a.LockedUntil.Sub(now)}
+	}
+	if !progressive || a.LastFailureAt.Before(now.Add(-g.window)) {
+		return nil
+	}
+	if next := a.LastFailureAt.Add(loginDelay(a.Failures)); now.Before(next) {
+		return &ThrottledError{RetryAfter: next.Sub(now)}
+	}
+	return nil
+}
+
+func (g *loginGuard) Failure(account, ip string) {
+	now := time.Now()
+	g.failure(account, g.maxAccount, now)
+	if ip != "" {
+		g.failure("ip:"+ip, g.maxIP, now)
+	}
+}
+
+func (g *loginGuard) failure(key string, max int, now time.Time) {
+	a, err := g.repo.RecordFailure(key, now, now.Add(-g.window))
+	if err != nil {
+		log.Printf("[auth] ERROR: record failed login %s: %v", key, err)
+		return
+	}
+	if a.Failures < max || (a.LockedUntil != nil && now.Before(*a.LockedUntil)) {
+		return
+	}
+	until := now.Add(g.lockout)
+	if err := g.repo.Lock(key, until); err != nil {
+		log.Printf("[auth] ERROR: lock %s: %v", key, err)
+		return
+	}
+	log.Printf("[auth] WARN: %s locked until %s after %d failed logins", key, until.Format(time.RFC3339), a.Failures)
+}
+
+func (g *loginGuard) Success(account string) {
+	if err := g.repo.Reset(account); err != nil {
+		log.Printf("[auth] ERROR: reset login attempts %s: %v", account, err)
+	}
+}
+
+func (g *loginGuard) Unlock(userID, by uint64) error {
+	if err := g.repo.Reset(fmt.Sprintf("user:%d", userID)); err != nil {
+		return err
+	}
+	log.Printf("[auth] account user:%d unlocked by admin %d", userID, by)
+	return nil
+}
+
+// loginDelay es la espera mínima desde el último fallo: 0 para los primeros
+// freeFailures y después 1s, 2s, 4s... hasta maxLoginWait
+func loginDelay(failures int) time.Duration {
+	if failures <= freeFailures {
+		return 0
+	}
+	d := time.Second << (failures - freeFailures - 1)
+	if d > maxLoginWait || d <= 0 {
+		return maxLoginWait
+	}
+	return d
+}
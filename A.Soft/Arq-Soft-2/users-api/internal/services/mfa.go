@@ -0,0 +1,269 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrMFANotEnabled     = errors.New("two-factor authentication not enabled")
+	ErrMFASetupMissing   = errors.New("two-factor setup not started")
+	ErrInvalidMFACode    = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken   = errors.New("invalid or expired mfa token")
+	// ErrMFARequired: la política (REQUIRE_ADMIN_2FA) no deja desactivarlo
+	ErrMFARequired = errors.New("two-factor authentication is required for this account")
+)
+
+// cantidad de códigos de recuperación que se entregan al activar 2FA
+const recoveryCodeCount = 10
+
+// MFAChallenge es la respuesta del primer paso del login cuando falta el
+// segundo factor (Purpose verify) o configurarlo (Purpose setup)
+type MFAChallenge struct {
+	Token     string
+	Purpose   string
+	ExpiresIn int64
+}
+
+type MFAService interface {
+	// Challenge devuelve nil si el usuario puede recibir tokens directamente
+	// después de la contraseña
+	Challenge(u *domain.User) (*MFAChallenge, error)
+	// Verify valida el token parcial y el código (TOTP o de recuperación);
+	// ip limita los intentos igual que en el login
+	Verify(mfaToken, code, ip string) (*domain.User, error)
+	// User devuelve el usuario (para emitir tokens al terminar el setup)
+	User(userID uint64) (*domain.User, error)
+	Status(userID uint64) (enabled bool, recoveryCodesLeft int64, err error)
+	// Setup genera un secreto nuevo (pendiente hasta Enable)
+	Setup(userID uint64) (secret, uri string, err error)
+	// Enable confirma el secreto con un código y devuelve los códigos de recuperación
+	Enable(userID uint64, code string) ([]string, error)
+	// Disable y RegenerateRecoveryCodes piden un código: los fallidos cuentan
+	// en el mismo guard que el login (*ThrottledError al bloquearse)
+	Disable(userID uint64, code, ip string) error
+	RegenerateRecoveryCodes(userID uint64, code, ip string) ([]string, error)
+}
+
+type mfaSvc struct {
+	mfa   repository.MFARepo
+	users repository.UsersRepo
+	guard LoginGuard
+	cfg   config.Config
+}
+
+func NewMFAService(repos repository.Repos, guard LoginGuard, cfg config.Config) MFAService {
+	return NewMFAServiceFromRepos(repos.MFA, repos.Users, guard, cfg)
+}
+
+// NewMFAServiceFromRepos permite inyectar los repos (tests, memoria)
+func NewMFAServiceFromRepos(mfa repository.MFARepo, users repository.UsersRepo, guard LoginGuard, cfg config.Config) MFAService {
+	return &mfaSvc{mfa: mfa, users: users, guard: guard, cfg: cfg}
+}
+
+func (s *mfaSvc) required(u *domain.User) bool {
+	return s.cfg.RequireAdmin2FA && u.Role == domain.RoleAdmin
+}
+
+func (s *mfaSvc) Challenge(u *domain.User) (*MFAChallenge, error) {
+	m, err := s.mfa.Get(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	purpose := ""
+	switch {
+	case m != nil && m.Enabled:
+		purpose = utils.MFAPurposeVerify
+	case s.required(u):
+		purpose = utils.MFAPurposeSetup
+	default:
+		return nil, nil
+	}
+	token, err := utils.GenerateMFAToken(s.cfg, u, purpose)
+	if err != nil {
+		return nil, err
+	}
+	return &MFAChallenge{Token: token, Purpose: purpose, ExpiresIn: int64(utils.MFATokenTTL(s.cfg).Seconds())}, nil
+}
+
+func (s *mfaSvc) Verify(mfaToken, code, ip string) (*domain.User, error) {
+	userID, purpose, err := utils.ParseMFAToken(s.cfg, mfaToken)
+	if err != nil || purpose != utils.MFAPurposeVerify {
+		return nil, ErrInvalidMFAToken
+	}
+	if err := s.guardedCheck(userID, code, true, ip); err != nil {
+		return nil, err
+	}
+	return s.users.FindByID(userID)
+}
+
+func (s *mfaSvc) User(userID uint64) (*domain.User, error) {
+	return s.users.FindByID(userID)
+}
+
+func (s *mfaSvc) Status(userID uint64) (bool, int64, error) {
+	m, err := s.mfa.Get(userID)
+	if err != nil || m == nil || !m.Enabled {
+		return false, 0, err
+	}
+	n, err := s.mfa.CountRecoveryCodes(userID)
+	return true, n, err
+}
+
+func (s *mfaSvc) Setup(userID uint64) (string, string, error) {
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return "", "", err
+	}
+	m, err := s.mfa.Get(userID)
+	if err != nil {
+		return "", "", err
+	}
+	if m != nil && m.Enabled {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.mfa.Save(&domain.MFA{UserID: userID, Secret: secret}); err != nil {
+		return "", "", err
+	}
+	return secret, utils.TOTPURI(s.cfg.MFAIssuer, u.Email, secret), nil
+}
+
+func (s *mfaSvc) Enable(userID uint64, code string) ([]string, error) {
+	m, err := s.mfa.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, ErrMFASetupMissing
+	}
+	if m.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	// para activar solo vale un código TOTP (todavía no hay de recuperación)
+	if err := s.checkCode(m, code, false); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	m, err = s.mfa.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	m.Enabled, m.EnabledAt = true, &now
+	if err := s.mfa.Save(m); err != nil {
+		return nil, err
+	}
+	log.Printf("[auth] two-factor authentication enabled for user %d", userID)
+	return s.newRecoveryCodes(userID)
+}
+
+func (s *mfaSvc) Disable(userID uint64, code, ip string) error {
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if s.required(u) {
+		return ErrMFARequired
+	}
+	if err := s.guardedCheck(userID, code, true, ip); err != nil {
+		return err
+	}
+	log.Printf("[auth] two-factor authentication disabled for user %d", userID)
+	return s.mfa.Delete(userID)
+}
+
+func (s *mfaSvc) RegenerateRecoveryCodes(userID uint64, code, ip string) ([]string, error) {
+	if err := s.guardedCheck(userID, code, false, ip); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(userID)
+}
+
+// guardedCheck valida el código del 2FA activo del usuario pasando por el
+// guard del login: con la cuenta o la IP bloqueadas no se prueba el código y
+// cada código inválido cuenta como un fallo
+func (s *mfaSvc) guardedCheck(userID uint64, code string, allowRecovery bool, ip string) error {
+	account := fmt.Sprintf("user:%d", userID)
+	if err := s.guard.Check(account, ip); err != nil {
+		return err
+	}
+	m, err := s.enabled(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkCode(m, code, allowRecovery); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.guard.Failure(account, ip)
+		}
+		return err
+	}
+	s.guard.Success(account)
+	return nil
+}
+
+func (s *mfaSvc) enabled(userID uint64) (*domain.MFA, error) {
+	m, err := s.mfa.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil || !m.Enabled {
+		return nil, ErrMFANotEnabled
+	}
+	return m, nil
+}
+
+// checkCode acepta un código TOTP (una sola vez por paso) o, si
+// allowRecovery, un código de recuperación no usado
+func (s *mfaSvc) checkCode(m *domain.MFA, code string, allowRecovery bool) error {
+	code = strings.TrimSpace(code)
+	now := time.Now()
+	if step, ok := utils.ValidateTOTP(m.Secret, code, now); ok {
+		fresh, err := s.mfa.UseStep(m.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+	if !allowRecovery {
+		return ErrInvalidMFACode
+	}
+	used, err := s.mfa.UseRecoveryCode(m.UserID, utils.HashToken(utils.NormalizeRecoveryCode(code)), now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	log.Printf("[auth] recovery code used by user %d", m.UserID)
+	return nil
+}
+
+func (s *mfaSvc) newRecoveryCodes(userID uint64) ([]string, error) {
+	codes, err := utils.RecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = utils.HashToken(c)
+	}
+	if err := s.mfa.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
@@ -0,0 +1,534 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sporthub/users-api/internal/clients"
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/events"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+var (
+	ErrUnknownProvider    = errors.New("unknown identity provider")
+	ErrInvalidOIDCState   = errors.New("invalid or expired login state")
+	ErrInvalidOIDCCode    = errors.New("invalid or expired login code")
+	ErrOIDCEmailRequired  = errors.New("the identity provider did not return a verified email")
+	ErrOIDCAccountPending = errors.New("an account with this email exists but its email is not verified")
+	ErrInvalidOIDCTicket  = errors.New("invalid or expired link ticket")
+	ErrOIDCIdentityInUse  = errors.New("this external account is linked to another user")
+	ErrOIDCReauthMismatch = errors.New("the external account is not linked to this user")
+)
+
+// el código que recibe el frontend se canjea enseguida (y el ticket para
+// ir al proveedor se usa apenas se pide)
+const oidcLoginCodeTTL = time.Minute
+
+// tiempo para usar la prueba de reautenticación
+const reauthTTL = 5 * time.Minute
+
+// propósito del ticket -> para qué es el viaje al proveedor
+var oidcTickets = map[string]string{
+	domain.TokenPurposeOIDCLink:   domain.OIDCPurposeLink,
+	domain.TokenPurposeOIDCReauth: domain.OIDCPurposeReauth,
+}
+
+// OIDCProviderInfo es lo que ve el frontend de cada proveedor (botones)
+type OIDCProviderInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// OIDCService es el login con proveedores externos (authorization code +
+// PKCE). Al volver del proveedor la cuenta externa se vincula con el usuario
+// que tiene ese email (verificado en los dos lados) o se crea uno nuevo; el
+// frontend recibe un código de un solo uso que canjea por los tokens de
+// siempre en Exchange. Un usuario logueado también puede vincular su cuenta
+// externa a mano (cuando el proveedor no da el email verificado): pide un
+// ticket y pasa por el proveedor con él; con el mismo mecanismo se
+// reautentica quien entra solo con el proveedor (la baja pide contraseña).
+type OIDCService interface {
+	Providers() []OIDCProviderInfo
+	// Ticket devuelve la URL de /login con un ticket de un solo uso para que
+	// el usuario logueado vaya al proveedor (purpose: domain.OIDCPurposeLink
+	// o domain.OIDCPurposeReauth)
+	Ticket(userID uint64, provider, purpose string) (string, error)
+	// Start guarda el login en curso y devuelve la URL del proveedor y el
+	// state que el controller ata al navegador con una cookie. ticket es ""
+	// para un login
+	Start(ctx context.Context, provider, ticket string) (*OIDCStart, error)
+	// Callback valida el state, canjea el code, valida el id_token y hace lo
+	// que pedía el login en curso (la cookie del state la revisa el controller)
+	Callback(ctx context.Context, provider, state, code string) (*OIDCResult, error)
+	// Exchange consume el código del callback y devuelve el usuario (los
+	// tokens los emite TokenService después del 2FA si hace falta)
+	Exchange(code string) (*domain.User, error)
+	// FrontendURL es la página del frontend con params agregados (?code= o ?error=)
+	FrontendURL(params url.Values) string
+}
+
+// OIDCStart es un login recién empezado. State viaja al proveedor y además
+// queda en una cookie HttpOnly del navegador que lo empezó: si el callback
+// llega sin esa cookie es un login forzado (login CSRF) y se rechaza.
+type OIDCStart struct {
+	AuthURL    string
+	State      string
+	CookiePath string // el path del callback, la cookie no viaja a otro lado
+	Secure     bool   // el callback es https
+	ExpiresAt  time.Time
+}
+
+// OIDCResult es cómo terminó la vuelta del proveedor: Code es el código que
+// el frontend canjea en Exchange (login) o la prueba de reautenticación que
+// manda en vez de la contraseña (reauth)
+type OIDCResult struct {
+	Purpose  string
+	Provider string
+	Code     string
+}
+
+type oidcSvc struct {
+	providers  map[string]*clients.OIDCProvider
+	order      []string
+	repos      repository.Repos
+	identities repository.IdentitiesRepo
+	users      repository.UsersRepo
+	userTokens repository.UserTokensRepo
+	cfg        config.Config
+}
+
+// NewOIDCService arma el servicio sobre repos (MySQL o memoria); los
+// proveedores salen de cfg.OIDCProviders
+func NewOIDCService(repos repository.Repos, cfg config.Config) OIDCService {
+	s := &oidcSvc{
+		providers:  map[string]*clients.OIDCProvider{},
+		repos:      repos,
+		identities: repos.Identities,
+		users:      repos.Users,
+		userTokens: repos.UserTokens,
+		cfg:        cfg,
+	}
+	for _, p := range cfg.OIDCProviders {
+		s.providers[p.ID] = clients.NewOIDCProvider(p)
+		s.order = append(s.order, p.ID)
+	}
+	return s
+}
+
+func (s *oidcSvc) Providers() []OIDCProviderInfo {
+	out := make([]OIDCProviderInfo, 0, len(s.order))
+	for _, id := range s.order {
+		out = append(out, OIDCProviderInfo{ID: id, Name: s.providers[id].Name()})
+	}
+	return out
+}
+
+func (s *oidcSvc) redirectURI(provider string) string {
+	return strings.TrimRight(s.cfg.OIDCRedirectBaseURL, "/") + "/auth/oidc/" + provider + "/callback"
+}
+
+func (s *oidcSvc) stateTTL() time.Duration {
+	return time.Duration(envInt(s.cfg.OIDCStateTTLMinutes, 10)) * time.Minute
+}
+
+func (s *oidcSvc) Ticket(userID uint64, provider, purpose string) (string, error) {
+	if _, ok := s.providers[provider]; !ok {
+		return "", ErrUnknownProvider
+	}
+	tokenPurpose := ""
+	for tp, p := range oidcTickets {
+		if p == purpose {
+			tokenPurpose = tp
+		}
+	}
+	if tokenPurpose == "" {
+		return "", fmt.Errorf("unknown oidc purpose %q", purpose)
+	}
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	err = s.userTokens.Create(&domain.UserToken{
+		UserID:    userID,
+		Purpose:   tokenPurpose,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: time.Now().Add(oidcLoginCodeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(s.cfg.OIDCRedirectBaseURL, "/") + "/auth/oidc/" + provider + "/login?" + url.Values{"ticket": {raw}}.Encode(), nil
+}
+
+// useTicket consume el ticket y devuelve el usuario y el propósito
+func (s *oidcSvc) useTicket(raw string) (uint64, string, error) {
+	for tokenPurpose, purpose := range oidcTickets {
+		t, err := s.userTokens.FindByHash(tokenPurpose, utils.HashToken(raw))
+		if err != nil {
+			return 0, "", err
+		}
+		if t == nil {
+			continue
+		}
+		if t.UsedAt != nil || time.Now().After(t.ExpiresAt) {
+			return 0, "", ErrInvalidOIDCTicket
+		}
+		ok, err := s.userTokens.MarkUsed(t.ID, time.Now())
+		if err != nil {
+			return 0, "", err
+		}
+		if !ok {
+			return 0, "", ErrInvalidOIDCTicket
+		}
+		return t.UserID, purpose, nil
+	}
+	return 0, "", ErrInvalidOIDCTicket
+}
+
+func (s *oidcSvc) Start(ctx context.Context, provider, ticket string) (*OIDCStart, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	login := &domain.OIDCLogin{Provider: provider, Purpose: domain.OIDCPurposeLogin}
+	if ticket != "" {
+		userID, purpose, err := s.useTicket(ticket)
+		if err != nil {
+			return nil, err
+		}
+		login.UserID, login.Purpose = &userID, purpose
+	}
+	state, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := utils.RandomToken(48) // 64 caracteres (RFC 7636: 43 a 128)
+	if err != nil {
+		return nil, err
+	}
+	redirectURI := s.redirectURI(provider)
+	authURL, err := p.AuthURL(ctx, redirectURI, state, nonce, codeChallenge(verifier))
+	if err != nil {
+		return nil, err
+	}
+	expires := time.Now().Add(s.stateTTL())
+	login.StateHash = utils.HashToken(state)
+	login.CodeVerifier = verifier
+	login.Nonce = nonce
+	login.ExpiresAt = expires
+	if err := s.identities.SaveLogin(login); err != nil {
+		return nil, err
+	}
+	start := &OIDCStart{AuthURL: authURL, State: state, CookiePath: "/", ExpiresAt: expires}
+	if u, err := url.Parse(redirectURI); err == nil {
+		start.CookiePath = u.Path
+		start.Secure = u.Scheme == "https"
+	}
+	return start, nil
+}
+
+// codeChallenge es el challenge S256 de PKCE: base64url(sha256(verifier))
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (s *oidcSvc) Callback(ctx context.Context, provider, state, code string) (*OIDCResult, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	if state == "" || code == "" {
+		return nil, ErrInvalidOIDCState
+	}
+	login, err := s.identities.ConsumeLogin(utils.HashToken(state))
+	if err != nil {
+		return nil, err
+	}
+	if login == nil || login.Provider != provider || time.Now().After(login.ExpiresAt) {
+		return nil, ErrInvalidOIDCState
+	}
+
+	rawIDToken, err := p.Exchange(ctx, code, login.CodeVerifier, s.redirectURI(provider))
+	if err != nil {
+		return nil, fmt.Errorf("%s code exchange: %w", provider, err)
+	}
+	claims, err := p.VerifyIDToken(ctx, rawIDToken, login.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%s id_token: %w", provider, err)
+	}
+	res := &OIDCResult{Purpose: login.Purpose, Provider: provider}
+	if login.Purpose == domain.OIDCPurposeLink {
+		if login.UserID == nil {
+			return nil, ErrInvalidOIDCState
+		}
+		return res, s.link(provider, claims, *login.UserID)
+	}
+	if login.Purpose == domain.OIDCPurposeReauth {
+		if login.UserID == nil {
+			return nil, ErrInvalidOIDCState
+		}
+		code, err := s.reauth(provider, claims, *login.UserID)
+		if err != nil {
+			return nil, err
+		}
+		res.Code = code
+		return res, nil
+	}
+	res.Purpose = domain.OIDCPurposeLogin
+	u, err := s.resolveUser(provider, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	err = s.userTokens.Create(&domain.UserToken{
+		UserID:    u.ID,
+		Purpose:   domain.TokenPurposeOIDCLogin,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: time.Now().Add(oidcLoginCodeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.Code = raw
+	return res, nil
+}
+
+// link vincula la cuenta externa al usuario logueado que pidió el ticket:
+// acaba de probar que controla las dos, así que acá el email no importa
+func (s *oidcSvc) link(provider string, claims *clients.IDClaims, userID uint64) error {
+	ident, err := s.identities.Find(provider, claims.Subject)
+	if err != nil {
+		return err
+	}
+	if ident != nil {
+		if ident.UserID != userID {
+			return ErrOIDCIdentityInUse
+		}
+		return nil
+	}
+	if _, err := s.users.FindByID(userID); err != nil {
+		return err
+	}
+	err = s.identities.Create(&domain.ExternalIdentity{
+		UserID: userID, Provider: provider, Subject: claims.Subject, Email: claims.Email,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[auth] user %d linked %s account by hand", userID, provider)
+	return nil
+}
+
+// resolveUser busca el usuario de la identidad externa: por provider+sub si
+// ya está vinculada, si no por email (tiene que venir verificado del
+// proveedor y estar verificado en la cuenta local, si no cualquiera que
+// registre el email ajeno se quedaría con la cuenta) o creando uno nuevo.
+// Sin email verificado no se vincula nada: el usuario entra con contraseña
+// y la vincula a mano (Ticket)
+func (s *oidcSvc) resolveUser(provider string, claims *clients.IDClaims) (*domain.User, error) {
+	now := time.Now()
+	ident, err := s.identities.Find(provider, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if ident != nil {
+		u, err := s.users.FindByID(ident.UserID)
+		if err == nil {
+			_ = s.identities.Touch(ident.ID, now)
+			return u, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		// el usuario se borró: la vinculación ya no vale
+		if err := s.identities.Delete(ident.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailRequired
+	}
+	u, err := s.users.FindByEmail(claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if u != nil && !u.EmailVerified {
+		return nil, ErrOIDCAccountPending
+	}
+	if u == nil {
+		if u, err = s.createUser(claims, now); err != nil {
+			return nil, err
+		}
+	}
+	err = s.identities.Create(&domain.ExternalIdentity{
+		UserID: u.ID, Provider: provider, Subject: claims.Subject, Email: claims.Email, LastLoginAt: &now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[auth] user %d linked to %s account", u.ID, provider)
+	return u, nil
+}
+
+// reauth comprueba que la cuenta externa sea una de las vinculadas al
+// usuario y devuelve la prueba de un solo uso
+func (s *oidcSvc) reauth(provider string, claims *clients.IDClaims, userID uint64) (string, error) {
+	ident, err := s.identities.Find(provider, claims.Subject)
+	if err != nil {
+		return "", err
+	}
+	if ident == nil || ident.UserID != userID {
+		return "", ErrOIDCReauthMismatch
+	}
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	err = s.userTokens.Create(&domain.UserToken{
+		UserID:    userID,
+		Purpose:   domain.TokenPurposeReauth,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: time.Now().Add(reauthTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+var usernameInvalid = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// createUser da de alta a quien entra por primera vez con un proveedor: rol
+// user, email ya verificado y una contraseña aleatoria (si quiere entrar con
+// contraseña usa el reset)
+func (s *oidcSvc) createUser(claims *clients.IDClaims, now time.Time) (*domain.User, error) {
+	password, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	username, err := s.freeUsername(claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	u := &domain.User{
+		Username:        username,
+		Email:           claims.Email,
+		PasswordHash:    hash,
+		Role:            domain.RoleUser,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+		DisplayName:     claims.Name,
+	}
+	err = s.repos.InTx(func(tx repository.Repos) error {
+		if err := tx.Users.Create(u); err != nil {
+			return err
+		}
+		return events.Enqueue(tx.Outbox, events.UserCreated, events.NewUserEvent("created", u))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// freeUsername arma el username con la parte local del email y le agrega
+// un número si ya está tomado
+func (s *oidcSvc) freeUsername(email string) (string, error) {
+	base, _, _ := strings.Cut(strings.ToLower(email), "@")
+	base = strings.Trim(usernameInvalid.ReplaceAllString(base, ""), ".-_")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	if len(base) < 3 {
+		base = "user" + base
+	}
+	name := base
+	for i := 0; i < 5; i++ {
+		u, err := s.users.FindByUsername(name)
+		if err != nil {
+			return "", err
+		}
+		if u == nil {
+			return name, nil
+		}
+		suffix, err := utils.RandomDigits(4)
+		if err != nil {
+			return "", err
+		}
+		name = base + suffix
+	}
+	return "", ErrUsernameTaken
+}
+
+func (s *oidcSvc) Exchange(code string) (*domain.User, error) {
+	t, err := s.userTokens.FindByHash(domain.TokenPurposeOIDCLogin, utils.HashToken(code))
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || t.UsedAt != nil || time.Now().After(t.ExpiresAt) {
+		return nil, ErrInvalidOIDCCode
+	}
+	ok, err := s.userTokens.MarkUsed(t.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidOIDCCode
+	}
+	return s.users.FindByID(t.UserID)
+}
+
+func (s *oidcSvc) FrontendURL(params url.Values) string {
+	sep := "?"
+	if strings.Contains(s.cfg.OIDCFrontendURL, "?") {
+		sep = "&"
+	}
+	return s.cfg.OIDCFrontendURL + sep + params.Encode()
+}
+
+// OIDCErrorCode es el ?error= que recibe el frontend para cada falla del callback
+func OIDCErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidOIDCState):
+		return "invalid_state"
+	case errors.Is(err, ErrOIDCEmailRequired):
+		return "email_not_verified"
+	case errors.Is(err, ErrOIDCAccountPending):
+		return "account_not_verified"
+	case errors.Is(err, ErrInvalidOIDCTicket):
+		return "invalid_ticket"
+	case errors.Is(err, ErrOIDCIdentityInUse):
+		return "identity_in_use"
+	case errors.Is(err, ErrOIDCReauthMismatch):
+		return "reauth_failed"
+	default:
+		return "login_failed"
+	}
+}
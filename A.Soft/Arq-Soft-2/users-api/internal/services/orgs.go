@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+)
+
+var (
+	ErrOrgNotFound    = errors.New("organization not found")
+	ErrOrgSlugTaken   = errors.New("organization slug already exists")
+	ErrInvalidOrgSlug = errors.New("slug must be 2-60 lowercase letters, digits or dashes")
+	ErrInvalidOrgRole = errors.New("invalid organization role")
+	ErrNotOrgMember   = errors.New("user is not a member of the organization")
+	ErrOrgUserMissing = errors.New("user not found")
+	// ErrLastOrgAdmin: no se puede sacar ni bajar de rol al último admin del club
+	ErrLastOrgAdmin = errors.New("an organization needs at least one admin")
+)
+
+var orgSlugRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,59}$`)
+
+// OrgMembership es una organización del usuario con su rol en ella
+type OrgMembership struct {
+	domain.Organization
+	Role domain.OrgRole `json:"role"`
+}
+
+// OrgsService maneja las organizaciones (clubes) y sus miembros. Quién puede
+// gestionar cada una lo resuelve el controller con el permiso org:manage.
+type OrgsService interface {
+	// Create crea la organización con createdBy como su primer admin
+	Create(name, slug string, createdBy uint64) (*domain.Organization, error)
+	Get(id uint64) (*domain.Organization, error)
+	// ListForUser son las organizaciones de las que el usuario es miembro
+	ListForUser(userID uint64) ([]OrgMembership, error)
+	Members(orgID uint64) ([]domain.Membership, error)
+	// Member es la membresía del usuario en la organización (ErrNotOrgMember
+	// si no es miembro)
+	Member(orgID, userID uint64) (*domain.Membership, error)
+	// SetMember agrega al usuario con role o le cambia el rol
+	SetMember(orgID, userID uint64, role domain.OrgRole) (*domain.Membership, error)
+	RemoveMember(orgID, userID uint64) error
+	// EnsureDefault crea la organización DEFAULT_ORG_ID si todavía no existe
+	EnsureDefault() error
+}
+
+type orgsSvc struct {
+	orgs  repository.OrgsRepo
+	users repository.UsersRepo
+	cfg   config.Config
+}
+
+func NewOrgsService(repos repository.Repos, cfg config.Config) OrgsService {
+	return NewOrgsServiceFromRepos(repos.Orgs, repos.Users, cfg)
+}
+
+// NewOrgsServiceFromRepos permite inyectar los repos (tests, memoria)
+func NewOrgsServiceFromRepos(orgs repository.OrgsRepo, users repository.UsersRepo, cfg config.Config) OrgsService {
+	return &orgsSvc{orgs: orgs, users: users, cfg: cfg}
+}
+
+func (s *orgsSvc) Create(name, slug string, createdBy uint64) (*domain.Organization, error) {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if !orgSlugRe.MatchString(slug) {
+		return nil, ErrInvalidOrgSlug
+	}
+	existing, err := s.orgs.FindBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrOrgSlugTaken
+	}
+	o := &domain.Organization{Name: strings.TrimSpace(name), Slug: slug, CreatedBy: createdBy}
+	if err := s.orgs.Create(o); err != nil {
+		return nil, err
+	}
+	if err := s.orgs.SaveMembership(&domain.Membership{OrgID: o.ID, UserID: createdBy, Role: domain.OrgRoleAdmin}); err != nil {
+		return nil, err
+	}
+	log.Printf("[orgs] organization %d (%s) created by user %d", o.ID, o.Slug, createdBy)
+	return o, nil
+}
+
+func (s *orgsSvc) Get(id uint64) (*domain.Organization, error) {
+	o, err := s.orgs.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		return nil, ErrOrgNotFound
+	}
+	return o, nil
+}
+
+func (s *orgsSvc) ListForUser(userID uint64) ([]OrgMembership, error) {
+	memberships, err := s.orgs.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	out := []OrgMembership{}
+	for _, m := range memberships {
+		o, err := s.orgs.FindByID(m.OrgID)
+		if err != nil {
+			return nil, err
+		}
+		if o != nil {
+			out = append(out, OrgMembership{Organization: *o, Role: m.Role})
+		}
+	}
+	return out, nil
+}
+
+func (s *orgsSvc) Members(orgID uint64) ([]domain.Membership, error) {
+	if _, err := s.Get(orgID); err != nil {
+		return nil, err
+	}
+	return s.orgs.ListMembers(orgID)
+}
+
+func (s *orgsSvc) Member(orgID, userID uint64) (*domain.Membership, error) {
+	m, err := s.orgs.GetMembership(orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, ErrNotOrgMember
+	}
+	return m, nil
+}
+
+func (s *orgsSvc) SetMember(orgID, userID uint64, role domain.OrgRole) (*domain.Membership, error) {
+	if !domain.ValidOrgRole(role) {
+		return nil, ErrInvalidOrgRole
+	}
+	if _, err := s.Get(orgID); err != nil {
+		return nil, err
+	}
+	if u, err := s.users.FindByID(userID); err != nil || u == nil {
+		return nil, ErrOrgUserMissing
+	}
+	current, err := s.orgs.GetMembership(orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && current.Role == domain.OrgRoleAdmin && role != domain.OrgRoleAdmin {
+		if err := s.keepAnAdmin(orgID); err != nil {
+			return nil, err
+		}
+	}
+	m := &domain.Membership{OrgID: orgID, UserID: userID, Role: role}
+	if err := s.orgs.SaveMembership(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *orgsSvc) RemoveMember(orgID, userID uint64) error {
+	current, err := s.orgs.GetMembership(orgID, userID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return ErrNotOrgMember
+	}
+	if current.Role == domain.OrgRoleAdmin {
+		if err := s.keepAnAdmin(orgID); err != nil {
+			return err
+		}
+	}
+	return s.orgs.DeleteMembership(orgID, userID)
+}
+
+// keepAnAdmin falla si el club se quedaría sin admins al perder uno
+func (s *orgsSvc) keepAnAdmin(orgID uint64) error {
+	n, err := s.orgs.CountAdmins(orgID)
+	if err != nil {
+		return err
+	}
+	if n <= 1 {
+		return ErrLastOrgAdmin
+	}
+	return nil
+}
+
+func (s *orgsSvc) EnsureDefault() error {
+	id, err := strconv.ParseUint(s.cfg.DefaultOrgID, 10, 64)
+	if err != nil {
+		return errors.New("DEFAULT_ORG_ID must be numeric")
+	}
+	o, err := s.orgs.FindByID(id)
+	if err != nil || o != nil {
+		return err
+	}
+	// los datos anteriores a los clubes quedan en esta organización; no tiene
+	// miembros: todos tienen ahí los permisos de su rol global
+	if err := s.orgs.Create(&domain.Organization{ID: id, Slug: "default", Name: "SportHub"}); err != nil {
+		return err
+	}
+	log.Printf("[orgs] default organization %d created", id)
+	return nil
+}
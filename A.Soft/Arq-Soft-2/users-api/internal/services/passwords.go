@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/mailer"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+	ErrWrongPassword     = errors.New("current password is incorrect")
+)
+
+type PasswordService interface {
+	// RequestReset cuenta el pedido de reset por email y por IP y lo deja en
+	// la cola de envíos; *ThrottledError si alguno pasó su límite por hora.
+	// Vuelve enseguida exista o no el email (el envío lo hace Forgot en un
+	// worker), así el tiempo de respuesta no revela qué emails existen.
+	RequestReset(email, ip string) error
+	// Forgot genera un token de reset y lo manda por email. Si el email no
+	// existe no hace nada y no devuelve error, para no revelar qué emails
+	// están registrados.
+	Forgot(ctx context.Context, email string) error
+	// Reset consume el token, cambia la contraseña y revoca las sesiones
+	// (refresh tokens) del usuario. El cambio y el consumo van en una misma
+	// transacción: si el cambio falla el link sigue sirviendo.
+	Reset(token, newPassword string) error
+	// Change cambia la contraseña de un usuario logueado verificando la actual.
+	// También revoca sus sesiones; el caller emite tokens nuevos para la actual.
+	// Los intentos con la contraseña equivocada cuentan en el LoginGuard como
+	// un login fallido (*ThrottledError si la cuenta o la IP están bloqueadas).
+	Change(userID uint64, currentPassword, newPassword, ip string) (*domain.User, error)
+}
+
+const (
+	// ventana de los límites de pedidos de reset
+	forgotWindow = time.Hour
+	// pedidos de reset esperando envío y workers que los mandan: si la cola
+	// se llena (SMTP caído, ráfaga) los pedidos nuevos se descartan
+	forgotQueueSize = 100
+	forgotWorkers   = 2
+)
+
+type passwordSvc struct {
+	repos      repository.Repos // para la transacción del reset
+	users      repository.UsersRepo
+	userTokens repository.UserTokensRepo
+	attempts   repository.LoginAttemptsRepo
+	tokens     TokenService
+	guard      LoginGuard
+	mail       mailer.Sender
+	cfg        config.Config
+
+	queue     chan string
+	startOnce sync.Once
+}
+
+func NewPasswordService(repos repository.Repos, tokens TokenService, guard LoginGuard, cfg config.Config) PasswordService {
+	return NewPasswordServiceFromRepos(repos, tokens, guard, mailer.FromConfig(cfg), cfg)
+}
+
+// NewPasswordServiceFromRepos permite inyectar repos y mailer (tests, memoria).
+// Usa Users, UserTokens y LoginAttempts, que cuenta los pedidos de reset (el
+// mismo store que los logins fallidos).
+func NewPasswordServiceFromRepos(repos repository.Repos, tokens TokenService, guard LoginGuard, mail mailer.Sender, cfg config.Config) PasswordService {
+	return &passwordSvc{repos: repos, users: repos.Users, userTokens: repos.UserTokens, attempts: repos.LoginAttempts, tokens: tokens, guard: guard,
+		mail: mail, cfg: cfg, queue: make(chan string, forgotQueueSize)}
+}
+
+func (s *passwordSvc) RequestReset(email, ip string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	now := time.Now()
+	if err := s.countForgot("forgot:email:"+email, envInt(s.cfg.PasswordForgotMaxPerEmail, 3), now); err != nil {
+		return err
+	}
+	if ip != "" {
+		if err := s.countForgot("forgot:ip:"+ip, envInt(s.cfg.PasswordForgotMaxPerIP, 20), now); err != nil {
+			return err
+		}
+	}
+	s.startOnce.Do(func() {
+		for i := 0; i < forgotWorkers; i++ {
+			go s.sendResets()
+		}
+	})
+	select {
+	case s.queue <- email:
+	default:
+		log.Printf("[auth] WARN: password reset queue full, dropping request")
+	}
+	return nil
+}
+
+// countForgot suma el pedido a la clave y falla si ya van más de max en la
+// ventana (se cuentan también los de emails que no existen)
+func (s *passwordSvc) countForgot(key string, max int, now time.Time) error {
+	a, err := s.attempts.RecordFailure(key, now, now.Add(-forgotWindow))
+	if err != nil {
+		// sin store no se limita a nadie (igual que el login)
+		log.Printf("[auth] ERROR: count reset requests %s: %v", key, err)
+		return nil
+	}
+	if a.Failures > max {
+		return &ThrottledError{RetryAfter: forgotWindow}
+	}
+	return nil
+}
+
+func (s *passwordSvc) sendResets() {
+	for email := range s.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := s.Forgot(ctx, email); err != nil {
+			log.Printf("[auth] ERROR: password reset email: %v", err)
+		}
+		cancel()
+	}
+}
+
+func (s *passwordSvc) Forgot(ctx context.Context, email string) error {
+	u, err := s.users.FindByEmail(strings.TrimSpace(email))
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		log.Printf("[auth] password reset requested for unknown email")
+		return nil
+	}
+
+	now := time.Now()
+	// solo vale el último link pedido
+	if err := s.userTokens.InvalidateUser(u.ID, domain.TokenPurposePasswordReset, now); err != nil {
+		return err
+	}
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	ttl := s.resetTTL()
+	t := &domain.UserToken{
+		UserID:    u.ID,
+		Purpose:   domain.TokenPurposePasswordReset,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := s.userTokens.Create(t); err != nil {
+		return err
+	}
+
+	link := s.cfg.PasswordResetURL + "?token=" + url.QueryEscape(raw)
+	return s.mail.Send(ctx, mailer.Message{
+		To:      u.Email,
+		Subject: "Restablecer tu contraseña de SportHub",
+		Body: fmt.Sprintf("Hola %s,\n\nPara elegir una contraseña nueva entra a:\n\n%s\n\n"+
+			"El link vence en %d minutos y sirve una sola vez. Si no lo pediste, ignora este email.\n",
+			u.Username, link, int(ttl.Minutes())),
+	})
+}
+
+func (s *passwordSvc) Reset(token, newPassword string) error {
+	t, err := s.userTokens.FindByHash(domain.TokenPurposePasswordReset, utils.HashToken(token))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if t == nil || t.UsedAt != nil || now.After(t.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	hash, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	// primero la contraseña (bloquea la fila del usuario) y después el token:
+	// si otro reset con el mismo link ganó, MarkUsed no toma nada y se
+	// deshace el cambio
+	err = s.repos.InTx(func(tx repository.Repos) error {
+		if err := tx.Users.UpdatePassword(t.UserID, hash); err != nil {
+			return err
+		}
+		ok, err := tx.UserTokens.MarkUsed(t.ID, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidResetToken
+		}
+		return tx.UserTokens.InvalidateUser(t.UserID, domain.TokenPurposePasswordReset, now)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[auth] password reset for user %d, revoking sessions", t.UserID)
+	return s.tokens.RevokeAllSessions(t.UserID)
+}
+
+func (s *passwordSvc) Change(userID uint64, currentPassword, newPassword, ip string) (*domain.User, error) {
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	// mismo guard que el login: con un access token robado no se puede
+	// adivinar la contraseña actual
+	account := AccountKey(u, "")
+	if err := s.guard.Check(account, ip); err != nil {
+		return nil, err
+	}
+	if !utils.CheckPasswordHash(currentPassword, u.PasswordHash) {
+		s.guard.Failure(account, ip)
+		return nil, ErrWrongPassword
+	}
+	s.guard.Success(account)
+	hash, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.UpdatePassword(userID, hash); err != nil {
+		return nil, err
+	}
+	u.PasswordHash = hash
+	log.Printf("[auth] password changed for user %d, revoking sessions", userID)
+	if err := s.tokens.RevokeAllSessions(userID); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *passwordSvc) resetTTL() time.Duration {
+	m, err := strconv.Atoi(s.cfg.PasswordResetTTLMinutes)
+	if err != nil || m <= 0 {
+		m = 60
+	}
+	return time.Duration(m) * time.Minute
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused se devuelve cuando llega un refresh token que ya
+	// se había rotado: se asume robado y se revoca toda su familia
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// Tokens es el par que se entrega al hacer login o refresh
+type Tokens struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"` // segundos de vida del access token
+}
+
+type TokenService interface {
+	// Issue emite access + refresh token para un login nuevo
+	Issue(u *domain.User) (*Tokens, error)
+	// Refresh rota el refresh token y emite un access token nuevo
+	Refresh(refreshToken string) (*domain.User, *Tokens, error)
+	// Logout revoca el access token (jti) y la familia del refresh token
+	Logout(userID uint64, refreshToken, jti string, accessExp time.Time) error
+	// RevokeAllSessions revoca todos los refresh tokens del usuario y los
+	// access tokens que siguen vigentes (p.ej. tras cambiar la contraseña)
+	RevokeAllSessions(userID uint64) error
+	IsRevoked(jti string) bool
+	RevokedSince(since time.Time) ([]domain.RevokedToken, error)
+}
+
+type tokenSvc struct {
+	users  repository.UsersRepo
+	tokens repository.TokensRepo
+	orgs   repository.OrgsRepo
+	cfg    config.Config
+}
+
+func NewTokenService(repos repository.Repos, cfg config.Config) TokenService {
+	return NewTokenServiceFromRepos(repos.Users, repos.Tokens, repos.Orgs, cfg)
+}
+
+// NewTokenServiceFromRepos permite inyectar los repos (tests, memoria)
+func NewTokenServiceFromRepos(users repository.UsersRepo, tokens repository.TokensRepo, orgs repository.OrgsRepo, cfg config.Config) TokenService {
+	return &tokenSvc{users: users, tokens: tokens, orgs: orgs, cfg: cfg}
+}
+
+func (s *tokenSvc) Issue(u *domain.User) (*Tokens, error) {
+	family, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(u, family)
+}
+
+func (s *tokenSvc) issue(u *domain.User, family string) (*Tokens, error) {
+	// las membresías se leen en cada emisión: un cambio de rol en un club se
+	// ve en el próximo /auth/refresh
+	memberships, err := s.orgs.ListByUser(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	access, err := utils.NewAccessToken(s.cfg, u, memberships)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	rt := &domain.RefreshToken{
+		UserID:          u.ID,
+		FamilyID:        family,
+		TokenHash:       utils.HashToken(raw),
+		ExpiresAt:       time.Now().Add(s.refreshTTL()),
+		AccessJTI:       access.JTI,
+		AccessExpiresAt: &access.ExpiresAt,
+	}
+	if err := s.tokens.CreateRefresh(rt); err != nil {
+		return nil, err
+	}
+	return &Tokens{
+		AccessToken:  access.Token,
+		RefreshToken: raw,
+		ExpiresIn:    int64(utils.AccessTokenTTL(s.cfg).Seconds()),
+	}, nil
+}
+
+func (s *tokenSvc) Refresh(refreshToken string) (*domain.User, *Tokens, error) {
+	rt, err := s.tokens.FindRefreshByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		return nil, nil, err
+	}
+	if rt == nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	now := time.Now()
+	if rt.RevokedAt != nil {
+		return nil, nil, s.reused(rt, now)
+	}
+	if now.After(rt.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	// la revocación es condicional: si dos requests rotan el mismo token a la
+	// vez, la segunda se trata como reuso
+	ok, err := s.tokens.RevokeRefresh(rt.ID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, s.reused(rt, now)
+	}
+
+	u, err := s.users.FindByID(rt.UserID)
+	if err != nil || u == nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	t, err := s.issue(u, rt.FamilyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return u, t, nil
+}
+
+func (s *tokenSvc) reused(rt *domain.RefreshToken, now time.Time) error {
+	log.Printf("[auth] WARN: refresh token reuse for user %d, revoking family", rt.UserID)
+	if err := s.tokens.RevokeFamily(rt.FamilyID, now); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func (s *tokenSvc) Logout(userID uint64, refreshToken, jti string, accessExp time.Time) error {
+	now := time.Now()
+	if jti != "" && accessExp.After(now) {
+		if err := s.tokens.RevokeAccess(&domain.RevokedToken{JTI: jti, ExpiresAt: accessExp, RevokedAt: now}); err != nil {
+			return err
+		}
+	}
+	if refreshToken == "" {
+		return nil
+	}
+	rt, err := s.tokens.FindRefreshByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	if rt == nil || rt.UserID != userID {
+		return nil
+	}
+	return s.tokens.RevokeFamily(rt.FamilyID, now)
+}
+
+func (s *tokenSvc) RevokeAllSessions(userID uint64) error {
+	return revokeUserTokens(s.tokens, userID, time.Now())
+}
+
+// revokeUserTokens revoca los refresh tokens del usuario y agrega a la lista
+// de revocados (que consultan los otros servicios) los access tokens que
+// todavía no vencieron, incluidos los de sesiones ya rotadas
+func revokeUserTokens(tokens repository.TokensRepo, userID uint64, now time.Time) error {
+	sessions, err := tokens.ListByUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := tokens.RevokeUser(userID, now); err != nil {
+		return err
+	}
+	for _, rt := range sessions {
+		if rt.AccessJTI == "" || rt.AccessExpiresAt == nil || !rt.AccessExpiresAt.After(now) {
+			continue
+		}
+		err := tokens.RevokeAccess(&domain.RevokedToken{JTI: rt.AccessJTI, ExpiresAt: *rt.AccessExpiresAt, RevokedAt: now})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *tokenSvc) IsRevoked(jti string) bool {
+	if jti == "" {
+		return false
+	}
+	revoked, err := s.tokens.IsAccessRevoked(jti)
+	if err != nil {
+		log.Printf("[auth] ERROR: revocation check: %v", err)
+		return false
+	}
+	return revoked
+}
+
+func (s *tokenSvc) RevokedSince(since time.Time) ([]domain.RevokedToken, error) {
+	return s.tokens.RevokedAccessSince(since, time.Now())
+}
+
+func (s *tokenSvc) refreshTTL() time.Duration {
+	h, err := strconv.Atoi(s.cfg.RefreshTokenTTLHours)
+	if err != nil || h <= 0 {
+		h = 720
+	}
+	return time.Duration(h) * time.Hour
+}
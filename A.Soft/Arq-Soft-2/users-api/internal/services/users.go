@@ -2,27 +2,79 @@ package services
 
 import (
 	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
 
 	"github.com/sporthub/users-api/internal/config"
 	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/events"
 	"github.com/sporthub/users-api/internal/repository"
 	"github.com/sporthub/users-api/internal/utils"
 )
 
 type UsersService interface {
 	Create(username, email, password string, role domain.Role) (*domain.User, error)
+	// Register es el alta pública: crea un user, o el rol de la invitación si
+	// viene un inviteToken válido. Un username o email tomado es
+	// ErrUsernameTaken/ErrEmailTaken.
+	Register(username, email, password, inviteToken string) (*domain.User, error)
 	GetByID(id uint64) (*domain.User, error)
-	Login(login, password string) (*domain.User, string, error)
+	// Authenticate valida credenciales (primer paso del login; los tokens los
+	// emite TokenService después del 2FA si hace falta). ip se usa para
+	// limitar intentos fallidos: devuelve *ThrottledError si la cuenta o la IP
+	// están bloqueadas.
+	Authenticate(login, password, ip string) (*domain.User, error)
 	Delete(id uint64) error
+	ChangeRole(id uint64, role domain.Role) (*domain.User, error)
+	// CreateInvitation devuelve el token (solo se muestra esta vez) y la invitación
+	CreateInvitation(role domain.Role, email string, ttl time.Duration, createdBy uint64) (string, *domain.Invitation, error)
+	// BootstrapAdminInvite crea una invitación de admin si no hay ningún admin;
+	// devuelve "" si ya existe alguno
+	BootstrapAdminInvite(ttl time.Duration) (string, error)
+	// UnlockLogin levanta el bloqueo por logins fallidos de un usuario
+	UnlockLogin(id, by uint64) error
+	// UpdateProfile aplica los campos no nil de p. Si cambia el email la cuenta
+	// vuelve a quedar sin verificar y previousEmail es la dirección anterior
+	// (para avisarle del cambio); si no cambió es "". Un username o email
+	// tomado, también por un alta concurrente, es ErrUsernameTaken/ErrEmailTaken.
+	UpdateProfile(id uint64, p ProfileUpdate) (u *domain.User, previousEmail string, err error)
+	// List es el directorio de usuarios para admins
+	List(f repository.UserFilter) ([]domain.User, int64, error)
+	// DeleteAccount es la baja hecha por el propio usuario: pide la
+	// contraseña (o, si reauthCode no es "", la prueba de que se acaba de
+	// reautenticar con su proveedor OIDC) y sigue el mismo camino que Delete
+	// (evento user.deleted). La contraseña pasa por el guard del login
+	// (*ThrottledError); el último admin no se puede dar de baja (ErrLastAdmin).
+	DeleteAccount(id uint64, password, reauthCode, ip string) error
+}
+
+// ProfileUpdate son los cambios de PATCH /users/me: nil = no se toca, "" = se
+// borra (salvo username y email, que no pueden quedar vacíos)
+type ProfileUpdate struct {
+	Username              *string
+	Email                 *string
+	DisplayName           *string
+	Phone                 *string
+	DateOfBirth           *string // YYYY-MM-DD
+	EmergencyContactName  *string
+	EmergencyContactPhone *string
 }
 
 type usersSvc struct {
-	repo repository.UsersRepo
-	cfg  config.Config
+	repos   repository.Repos // para las transacciones (cambio + evento en el outbox)
+	repo    repository.UsersRepo
+	invites repository.InvitationsRepo
+	guard   LoginGuard
+	cfg     config.Config
 }
 
-func NewUsersService(cfg config.Config) UsersService {
-	return &usersSvc{repo: repository.NewUsersMySQL(cfg), cfg: cfg}
+// NewUsersService arma el servicio sobre repos (MySQL o memoria). Los eventos
+// de usuario se guardan en repos.Outbox en la misma transacción que el cambio.
+func NewUsersService(repos repository.Repos, guard LoginGuard, cfg config.Config) UsersService {
+	return &usersSvc{repos: repos, repo: repos.Users, invites: repos.Invitations, guard: guard, cfg: cfg}
 }
 
 func (s *usersSvc) Create(username, email, password string, role domain.Role) (*domain.User, error) {
@@ -36,7 +88,47 @@ func (s *usersSvc) Create(username, email, password string, role domain.Role) (*
 		PasswordHash: hash,
 		Role:         role,
 	}
-	if err := s.repo.Create(u); err != nil {
+	err = s.repos.InTx(func(tx repository.Repos) error {
+		if err := tx.Users.Create(u); err != nil {
+			return err
+		}
+		return events.Enqueue(tx.Outbox, events.UserCreated, events.NewUserEvent("created", u))
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, s.takenError(u)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *usersSvc) Register(username, email, password, inviteToken string) (*domain.User, error) {
+	if inviteToken == "" {
+		return s.Create(username, email, password, domain.RoleUser)
+	}
+
+	inv, err := s.invites.FindByHash(utils.HashToken(inviteToken))
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil || inv.UsedAt != nil || time.Now().After(inv.ExpiresAt) {
+		return nil, ErrInvalidInvitation
+	}
+	if inv.Email != "" && !strings.EqualFold(inv.Email, email) {
+		return nil, ErrInvalidInvitation
+	}
+	ok, err := s.invites.MarkUsed(inv.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidInvitation
+	}
+	u, err := s.Create(username, email, password, inv.Role)
+	if err != nil {
+		// el alta falló (p.ej. username repetido): la invitación sigue valiendo
+		_ = s.invites.Release(inv.ID)
 		return nil, err
 	}
 	return u, nil
@@ -46,23 +138,21 @@ func (s *usersSvc) GetByID(id uint64) (*domain.User, error) {
 	return s.repo.FindByID(id)
 }
 
-func (s *usersSvc) Login(login, password string) (*domain.User, string, error) {
+func (s *usersSvc) Authenticate(login, password, ip string) (*domain.User, error) {
 	u, err := s.repo.FindByUsernameOrEmail(login)
 	if err != nil {
-		return nil, "", err
-	}
-	if u == nil {
-		return nil, "", errors.New("invalid credentials")
+		return nil, err
 	}
-
-	if !utils.CheckPasswordHash(password, u.PasswordHash) {
-		return nil, "", errors.New("invalid credentials")
+	account := AccountKey(u, login)
+	if err := s.guard.Check(account, ip); err != nil {
+		return nil, err
 	}
-	token, err := utils.GenerateJWT(s.cfg, u)
-	if err != nil {
-		return nil, "", err
+	if u == nil || !utils.CheckPasswordHash(password, u.PasswordHash) {
+		s.guard.Failure(account, ip)
+		return nil, errors.New("invalid credentials")
 	}
-	return u, token, nil
+	s.guard.Success(account)
+	return u, nil
 }
 
 func (s *usersSvc) Delete(id uint64) error {
@@ -76,7 +166,269 @@ func (s *usersSvc) Delete(id uint64) error {
 	if u.Role == domain.RoleAdmin {
 		return ErrForbiddenDeleteAdmin
 	}
-	return s.repo.DeleteByID(id)
+	return s.delete(u)
+}
+
+func (s *usersSvc) DeleteAccount(id uint64, password, reauthCode, ip string) error {
+	u, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if reauthCode != "" {
+		if err := s.useReauth(id, reauthCode); err != nil {
+			return err
+		}
+		return s.delete(u)
+	}
+	// mismo guard que el login: con un access token robado no se puede
+	// adivinar la contraseña
+	account := AccountKey(u, "")
+	if err := s.guard.Check(account, ip); err != nil {
+		return err
+	}
+	if !utils.CheckPasswordHash(password, u.PasswordHash) {
+		s.guard.Failure(account, ip)
+		return ErrWrongPassword
+	}
+	s.guard.Success(account)
+	return s.delete(u)
+}
+
+// useReauth consume la prueba de reautenticación del usuario (ver
+// OIDCService.Ticket con domain.OIDCPurposeReauth)
+func (s *usersSvc) useReauth(userID uint64, code string) error {
+	t, err := s.repos.UserTokens.FindByHash(domain.TokenPurposeReauth, utils.HashToken(code))
+	if err != nil {
+		return err
+	}
+	if t == nil || t.UserID != userID || t.UsedAt != nil || time.Now().After(t.ExpiresAt) {
+		return ErrInvalidReauth
+	}
+	ok, err := s.repos.UserTokens.MarkUsed(t.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidReauth
+	}
+	return nil
+}
+
+// delete borra la fila, revoca sus sesiones y tokens y avisa a los otros
+// servicios, que limpian lo suyo (inscripciones, actividades del usuario). El
+// user.deleted queda en el outbox en la misma transacción: no se pierde
+// aunque el proceso se caiga. Si es el último admin devuelve ErrLastAdmin.
+func (s *usersSvc) delete(u *domain.User) error {
+	err := s.repos.InTx(func(tx repository.Repos) error {
+		ok, err := tx.Users.DeleteUnlessLastAdmin(u.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrLastAdmin
+		}
+		if err := revokeUserTokens(tx.Tokens, u.ID, time.Now()); err != nil {
+			return err
+		}
+		// si no, alguien que entre con la cuenta externa caería en un id borrado
+		if err := tx.Identities.DeleteByUser(u.ID); err != nil {
+			return err
+		}
+		return events.Enqueue(tx.Outbox, events.UserDeleted, events.NewUserEvent("deleted", u))
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[auth] user %d deleted", u.ID)
+	return nil
+}
+
+func (s *usersSvc) ChangeRole(id uint64, role domain.Role) (*domain.User, error) {
+	if !domain.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	u, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.Role == role {
+		return u, nil
+	}
+	wasAdmin := u.Role == domain.RoleAdmin
+	u.Role = role
+	err = s.repos.InTx(func(tx repository.Repos) error {
+		if wasAdmin {
+			ok, err := tx.Users.DemoteAdmin(id, role)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return ErrLastAdmin
+			}
+		} else if err := tx.Users.UpdateRole(id, role); err != nil {
+			return err
+		}
+		// el access token lleva el rol y los permisos: con el rol nuevo hay
+		// que volver a entrar
+		if err := revokeUserTokens(tx.Tokens, id, time.Now()); err != nil {
+			return err
+		}
+		return events.Enqueue(tx.Outbox, events.UserUpdated, events.NewUserEvent("updated", u))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *usersSvc) CreateInvitation(role domain.Role, email string, ttl time.Duration, createdBy uint64) (string, *domain.Invitation, error) {
+	if !domain.ValidRole(role) {
+		return "", nil, ErrInvalidRole
+	}
+	if ttl <= 0 {
+		ttl = 72 * time.Hour
+	}
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	inv := &domain.Invitation{
+		TokenHash: utils.HashToken(token),
+		Role:      role,
+		Email:     email,
+		CreatedBy: createdBy,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.invites.Create(inv); err != nil {
+		return "", nil, err
+	}
+	return token, inv, nil
 }
 
-var ErrForbiddenDeleteAdmin = errors.New("cannot delete admin user")
+func (s *usersSvc) BootstrapAdminInvite(ttl time.Duration) (string, error) {
+	n, err := s.repo.CountByRole(domain.RoleAdmin)
+	if err != nil || n > 0 {
+		return "", err
+	}
+	token, _, err := s.CreateInvitation(domain.RoleAdmin, "", ttl, 0)
+	return token, err
+}
+
+func (s *usersSvc) UnlockLogin(id, by uint64) error {
+	if _, err := s.repo.FindByID(id); err != nil {
+		return err
+	}
+	return s.guard.Unlock(id, by)
+}
+
+func (s *usersSvc) List(f repository.UserFilter) ([]domain.User, int64, error) {
+	if f.Sort != "" && !repository.ValidUserSort(f.Sort) {
+		return nil, 0, ErrInvalidSort
+	}
+	if f.Role != "" && !domain.ValidRole(f.Role) {
+		return nil, 0, ErrInvalidRole
+	}
+	if f.Limit <= 0 || f.Limit > 100 {
+		f.Limit = 20
+	}
+	f.Search = strings.TrimSpace(f.Search)
+	return s.repo.List(f)
+}
+
+func (s *usersSvc) UpdateProfile(id uint64, p ProfileUpdate) (*domain.User, string, error) {
+	current, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, "", err
+	}
+	u := *current
+	previousEmail := ""
+
+	if p.Username != nil && *p.Username != u.Username {
+		other, err := s.repo.FindByUsername(*p.Username)
+		if err != nil {
+			return nil, "", err
+		}
+		if other != nil && other.ID != id {
+			return nil, "", ErrUsernameTaken
+		}
+		u.Username = *p.Username
+	}
+	if p.Email != nil && !strings.EqualFold(*p.Email, u.Email) {
+		other, err := s.repo.FindByEmail(*p.Email)
+		if err != nil {
+			return nil, "", err
+		}
+		if other != nil && other.ID != id {
+			return nil, "", ErrEmailTaken
+		}
+		u.Email = *p.Email
+		u.EmailVerified, u.EmailVerifiedAt = false, nil
+		previousEmail = current.Email
+	}
+	if p.DateOfBirth != nil {
+		if *p.DateOfBirth == "" {
+			u.DateOfBirth = nil
+		} else {
+			dob, err := time.Parse("2006-01-02", *p.DateOfBirth)
+			if err != nil || dob.After(time.Now()) || dob.Year() < 1900 {
+				return nil, "", ErrInvalidDateOfBirth
+			}
+			u.DateOfBirth = &dob
+		}
+	}
+	for dst, src := range map[*string]*string{
+		&u.DisplayName:           p.DisplayName,
+		&u.Phone:                 p.Phone,
+		&u.EmergencyContactName:  p.EmergencyContactName,
+		&u.EmergencyContactPhone: p.EmergencyContactPhone,
+	} {
+		if src != nil {
+			*dst = strings.TrimSpace(*src)
+		}
+	}
+
+	err = s.repos.InTx(func(tx repository.Repos) error {
+		if err := tx.Users.UpdateProfile(&u); err != nil {
+			return err
+		}
+		// los links de verificación pendientes fueron a la dirección anterior:
+		// no valen más, pase lo que pase con el reenvío al email nuevo
+		if previousEmail != "" {
+			if err := tx.UserTokens.InvalidateUser(id, domain.TokenPurposeEmailVerify, time.Now()); err != nil {
+				return err
+			}
+		}
+		return events.Enqueue(tx.Outbox, events.UserUpdated, events.NewUserEvent("updated", &u))
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// otro request tomó el username o el email entre el chequeo y el UPDATE
+		return nil, "", s.takenError(&u)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if previousEmail != "" {
+		log.Printf("[auth] user %d changed email, verification pending", id)
+	}
+	return &u, previousEmail, nil
+}
+
+// takenError dice cuál de los dos índices únicos chocó con u
+func (s *usersSvc) takenError(u *domain.User) error {
+	if other, err := s.repo.FindByUsername(u.Username); err == nil && other != nil && other.ID != u.ID {
+		return ErrUsernameTaken
+	}
+	return ErrEmailTaken
+}
+
+var (
+	ErrForbiddenDeleteAdmin = errors.New("cannot delete admin user")
+	ErrInvalidInvitation    = errors.New("invalid or expired invitation")
+	ErrInvalidRole          = errors.New("invalid role")
+	ErrLastAdmin            = errors.New("cannot demote the last admin")
+	ErrUsernameTaken        = errors.New("username already in use")
+	ErrEmailTaken           = errors.New("email already in use")
+	ErrInvalidDateOfBirth   = errors.New("invalid date of birth (YYYY-MM-DD)")
+	ErrInvalidSort          = errors.New("invalid sort field")
+	ErrInvalidReauth        = errors.New("invalid or expired reauthentication code")
+)
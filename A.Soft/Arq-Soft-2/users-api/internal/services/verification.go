@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/mailer"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+var (
+	ErrInvalidVerifyToken = errors.New("invalid or expired verification token")
+	ErrAlreadyVerified    = errors.New("email already verified")
+	// ErrVerifyRateLimited se devuelve si se pide otro email de verificación
+	// antes de EMAIL_VERIFY_RESEND_SECONDS o se superó el máximo por hora
+	ErrVerifyRateLimited = errors.New("too many verification emails, try again later")
+)
+
+type VerificationService interface {
+	// Send genera un token de verificación y lo manda al email del usuario,
+	// respetando el rate limit de reenvíos
+	Send(ctx context.Context, userID uint64) error
+	// Verify consume el token y marca el email como verificado
+	Verify(token string) (*domain.User, error)
+	// ResendInterval es el tiempo mínimo entre dos envíos (para Retry-After)
+	ResendInterval() time.Duration
+	// NotifyEmailChanged avisa a la dirección anterior que el email de la
+	// cuenta cambió (si no fue el dueño, se entera aunque ya no lo controle)
+	NotifyEmailChanged(ctx context.Context, previousEmail string, u *domain.User) error
+}
+
+type verificationSvc struct {
+	users      repository.UsersRepo
+	userTokens repository.UserTokensRepo
+	mail       mailer.Sender
+	cfg        config.Config
+}
+
+func NewVerificationService(repos repository.Repos, cfg config.Config) VerificationService {
+	return NewVerificationServiceFromRepos(repos.Users, repos.UserTokens, mailer.FromConfig(cfg), cfg)
+}
+
+// NewVerificationServiceFromRepos permite inyectar repos y mailer (tests, memoria)
+func NewVerificationServiceFromRepos(users repository.UsersRepo, userTokens repository.UserTokensRepo, mail mailer.Sender, cfg config.Config) VerificationService {
+	return &verificationSvc{users: users, userTokens: userTokens, mail: mail, cfg: cfg}
+}
+
+func (s *verificationSvc) Send(ctx context.Context, userID uint64) error {
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if u.EmailVerified {
+		return ErrAlreadyVerified
+	}
+
+	now := time.Now()
+	if n, err := s.userTokens.CountCreatedSince(u.ID, domain.TokenPurposeEmailVerify, now.Add(-s.ResendInterval())); err != nil {
+		return err
+	} else if n > 0 {
+		return ErrVerifyRateLimited
+	}
+	if n, err := s.userTokens.CountCreatedSince(u.ID, domain.TokenPurposeEmailVerify, now.Add(-time.Hour)); err != nil {
+		return err
+	} else if n >= int64(envInt(s.cfg.EmailVerifyMaxPerHour, 5)) {
+		return ErrVerifyRateLimited
+	}
+
+	// solo vale el último link enviado
+	if err := s.userTokens.InvalidateUser(u.ID, domain.TokenPurposeEmailVerify, now); err != nil {
+		return err
+	}
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	ttl := time.Duration(envInt(s.cfg.EmailVerifyTTLHours, 48)) * time.Hour
+	t := &domain.UserToken{
+		UserID:    u.ID,
+		Purpose:   domain.TokenPurposeEmailVerify,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+		Email:     u.Email,
+	}
+	if err := s.userTokens.Create(t); err != nil {
+		return err
+	}
+
+	link := s.cfg.EmailVerifyURL + "?token=" + url.QueryEscape(raw)
+	return s.mail.Send(ctx, mailer.Message{
+		To:      u.Email,
+		Subject: "Confirma tu email de SportHub",
+		Body: fmt.Sprintf("Hola %s,\n\nPara confirmar tu email entra a:\n\n%s\n\n"+
+			"El link vence en %d horas. Si no creaste una cuenta en SportHub, ignora este email.\n",
+			u.Username, link, int(ttl.Hours())),
+	})
+}
+
+func (s *verificationSvc) Verify(token string) (*domain.User, error) {
+	t, err := s.userTokens.FindByHash(domain.TokenPurposeEmailVerify, utils.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if t == nil || t.UsedAt != nil || now.After(t.ExpiresAt) {
+		return nil, ErrInvalidVerifyToken
+	}
+	// el link solo verifica la dirección a la que se mandó (los anteriores a
+	// que se guardara no tienen email y hay que pedir otro)
+	u, err := s.users.FindByID(t.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Email == "" || !strings.EqualFold(t.Email, u.Email) {
+		return nil, ErrInvalidVerifyToken
+	}
+	ok, err := s.userTokens.MarkUsed(t.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidVerifyToken
+	}
+	// con la condición sobre el email, un cambio entre la lectura y acá
+	// tampoco queda verificado
+	if ok, err := s.users.MarkEmailVerified(t.UserID, t.Email, now); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrInvalidVerifyToken
+	}
+	if err := s.userTokens.InvalidateUser(t.UserID, domain.TokenPurposeEmailVerify, now); err != nil {
+		return nil, err
+	}
+	return s.users.FindByID(t.UserID)
+}
+
+func (s *verificationSvc) NotifyEmailChanged(ctx context.Context, previousEmail string, u *domain.User) error {
+	return s.mail.Send(ctx, mailer.Message{
+		To:      previousEmail,
+		Subject: "Cambió el email de tu cuenta de SportHub",
+		Body: fmt.Sprintf("Hola %s,\n\nEl email de tu cuenta de SportHub se cambió a %s.\n\n"+
+			"Si no fuiste vos, restablece tu contraseña y contacta a soporte respondiendo este email.\n",
+			u.Username, u.Email),
+	})
+}
+
+func (s *verificationSvc) ResendInterval() time.Duration {
+	return time.Duration(envInt(s.cfg.EmailVerifyResendSecs, 60)) * time.Second
+}
+
+// envInt parsea un valor numérico de la config con default si falta o es inválido
+func envInt(v string, def int) int {
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return def
+	}
+	return n
+}
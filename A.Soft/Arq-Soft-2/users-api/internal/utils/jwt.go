@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	"strconv"
 	"time"
 
@@ -9,14 +10,190 @@ import (
 	"github.com/sporthub/users-api/internal/domain"
 )
 
-func GenerateJWT(cfg config.Config, u *domain.User) (string, error) {
+// AccessTokenTTL es la duración de los access tokens (JWT_EXP_MINUTES)
+func AccessTokenTTL(cfg config.Config) time.Duration {
 	min, _ := strconv.Atoi(cfg.JWTExpMinutes)
+	return time.Duration(min) * time.Minute
+}
+
+// GenerateJWT firma un access token RS256 con la clave actual del KeySet
+// (sin membresías: el claim orgs solo trae la organización por defecto)
+func GenerateJWT(cfg config.Config, u *domain.User) (string, error) {
+	return GenerateJWTWithMemberships(cfg, u, nil)
+}
+
+// GenerateJWTWithMemberships es GenerateJWT con los permisos de cada
+// organización de la que el usuario es miembro en el claim orgs
+func GenerateJWTWithMemberships(cfg config.Config, u *domain.User, memberships []domain.Membership) (string, error) {
+	at, err := NewAccessToken(cfg, u, memberships)
+	if err != nil {
+		return "", err
+	}
+	return at.Token, nil
+}
+
+// AccessToken es un access token firmado con su jti y vencimiento (para
+// poder revocarlo después)
+type AccessToken struct {
+	Token     string
+	JTI       string
+	ExpiresAt time.Time
+}
+
+// NewAccessToken firma el access token de u (ver GenerateJWTWithMemberships)
+func NewAccessToken(cfg config.Config, u *domain.User, memberships []domain.Membership) (*AccessToken, error) {
+	keys, err := DefaultKeySet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	jti, err := RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	exp := time.Now().Add(AccessTokenTTL(cfg))
 	claims := jwt.MapClaims{
+		"iss": cfg.JWTIssuer,
+		"aud": cfg.JWTAudience,
 		"sub": u.ID,
 		"rol": u.Role,
-		"exp": time.Now().Add(time.Duration(min) * time.Minute).Unix(),
+		"jti": jti,
+		"exp": exp.Unix(),
+		"iat": time.Now().Unix(),
+
+		// activities-api puede exigirlo para inscribirse (REQUIRE_VERIFIED_EMAIL)
+		"email_verified": u.EmailVerified,
+		// permisos del rol, los exige RequirePermission en cada servicio
+		"perms": domain.PermissionsFor(u.Role),
+		// orgId -> permisos en esa organización; activities-api usa los de
+		// la organización del request (X-Org-ID)
+		"orgs": domain.OrgPermissions(cfg.DefaultOrgID, u.Role, memberships),
+	}
+	token, err := keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &AccessToken{Token: token, JTI: jti, ExpiresAt: exp}, nil
+}
+
+// Propósitos del token parcial de 2FA
+const (
+	MFAPurposeVerify = "verify" // falta el código TOTP o de recuperación
+	MFAPurposeSetup  = "setup"  // la política exige 2FA y todavía no está activado
+)
+
+// MFAAudience es el aud de los tokens parciales: al no ser JWT_AUDIENCE
+// ningún servicio los acepta como access token
+func MFAAudience(cfg config.Config) string {
+	return cfg.JWTIssuer + "/mfa"
+}
+
+// MFATokenTTL es la vida del token parcial (MFA_TOKEN_TTL_MINUTES)
+func MFATokenTTL(cfg config.Config) time.Duration {
+	min, err := strconv.Atoi(cfg.MFATokenTTLMinutes)
+	if err != nil || min <= 0 {
+		min = 5
+	}
+	return time.Duration(min) * time.Minute
+}
+
+// GenerateMFAToken firma el token parcial que se entrega en el primer paso
+// del login cuando hace falta 2FA
+func GenerateMFAToken(cfg config.Config, u *domain.User, purpose string) (string, error) {
+	keys, err := DefaultKeySet(cfg)
+	if err != nil {
+		return "", err
+	}
+	claims := jwt.MapClaims{
+		"iss": cfg.JWTIssuer,
+		"aud": MFAAudience(cfg),
+		"sub": u.ID,
+		"mfa": purpose,
+		"exp": time.Now().Add(MFATokenTTL(cfg)).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	return keys.Sign(claims)
+}
+
+// ParseMFAToken valida un token parcial y devuelve el usuario y el propósito
+func ParseMFAToken(cfg config.Config, token string) (uint64, string, error) {
+	keys, err := DefaultKeySet(cfg)
+	if err != nil {
+		return 0, "", err
+	}
+	t, err := jwt.Parse(token, keys.Keyfunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(cfg.JWTIssuer),
+		jwt.WithAudience(MFAAudience(cfg)),
+		jwt.WithExpirationRequired())
+	if err != nil {
+		return 0, "", err
+	}
+	claims, _ := t.Claims.(jwt.MapClaims)
+	sub, ok := claims["sub"].(float64)
+	purpose, _ := claims["mfa"].(string)
+	if !ok || purpose == "" {
+		return 0, "", errors.New("invalid mfa token")
+	}
+	return uint64(sub), purpose, nil
+}
+
+// ServiceAudience es el aud de los tokens de servicio: solo users-api los
+// acepta, y nunca como token de un usuario
+func ServiceAudience(cfg config.Config) string {
+	return cfg.JWTIssuer + "/service"
+}
+
+// ServiceTokenTTL es la vida de los tokens de servicio (SERVICE_TOKEN_TTL_MINUTES)
+func ServiceTokenTTL(cfg config.Config) time.Duration {
+	min, err := strconv.Atoi(cfg.ServiceTokenTTLMinutes)
+	if err != nil || min <= 0 {
+		min = 10
+	}
+	return time.Duration(min) * time.Minute
+}
+
+// GenerateServiceToken firma el token que reciben los servicios por client
+// credentials. sub es el client_id.
+func GenerateServiceToken(cfg config.Config, clientID string) (string, error) {
+	return GenerateServiceTokenFor(cfg, clientID, ServiceAudience(cfg))
+}
+
+// GenerateServiceTokenFor firma un token de servicio para otro servicio
+// (audience), p. ej. cuando users-api llama a activities-api
+func GenerateServiceTokenFor(cfg config.Config, clientID, audience string) (string, error) {
+	keys, err := DefaultKeySet(cfg)
+	if err != nil {
+		return "", err
+	}
+	claims := jwt.MapClaims{
+		"iss": cfg.JWTIssuer,
+		"aud": audience,
+		"sub": clientID,
+		"rol": "service",
+		"exp": time.Now().Add(ServiceTokenTTL(cfg)).Unix(),
 		"iat": time.Now().Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.JWTSecret))
+	return keys.Sign(claims)
+}
+
+// ParseServiceToken valida un token de servicio y devuelve el client_id
+func ParseServiceToken(cfg config.Config, token string) (string, error) {
+	keys, err := DefaultKeySet(cfg)
+	if err != nil {
+		return "", err
+	}
+	t, err := jwt.Parse(token, keys.Keyfunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(cfg.JWTIssuer),
+		jwt.WithAudience(ServiceAudience(cfg)),
+		jwt.WithExpirationRequired())
+	if err != nil {
+		return "", err
+	}
+	claims, _ := t.Claims.(jwt.MapClaims)
+	clientID, _ := claims["sub"].(string)
+	if clientID == "" {
+		return "", errors.New("invalid service token")
+	}
+	return clientID, nil
 }
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sporthub/users-api/internal/config"
+)
+
+// KeySet tiene la clave RSA con la que se firman los tokens y las claves
+// anteriores que se siguen publicando en el JWKS mientras haya tokens
+// firmados con ellas (rotación). Cada clave se identifica por su kid.
+type KeySet struct {
+	signing *rsa.PrivateKey
+	kid     string
+	public  map[string]*rsa.PublicKey
+	order   []string
+}
+
+// JWK es una clave pública RSA en formato JWK (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var (
+	keySetsMu sync.Mutex
+	keySets   = map[string]*KeySet{}
+)
+
+// DefaultKeySet devuelve las claves de la config. Se cargan una sola vez por
+// combinación de JWT_SIGNING_KEY_FILE/JWT_VERIFY_KEY_FILES (así una config
+// sin clave usa siempre la misma clave efímera); un error no se cachea.
+func DefaultKeySet(cfg config.Config) (*KeySet, error) {
+	id := cfg.JWTSigningKeyFile + "|" + cfg.JWTVerifyKeyFiles
+	keySetsMu.Lock()
+	defer keySetsMu.Unlock()
+	if ks, ok := keySets[id]; ok {
+		return ks, nil
+	}
+	ks, err := LoadKeySet(cfg.JWTSigningKeyFile, cfg.JWTVerifyKeyFiles)
+	if err != nil {
+		return nil, err
+	}
+	keySets[id] = ks
+	return ks, nil
+}
+
+// LoadKeySet lee la clave privada de firma (PEM) y las claves anteriores
+// (PEM privadas o públicas, separadas por coma). Sin clave de firma se genera
+// una efímera: sirve para desarrollo pero los tokens no sobreviven un reinicio.
+func LoadKeySet(signingFile, verifyFiles string) (*KeySet, error) {
+	ks := &KeySet{public: map[string]*rsa.PublicKey{}}
+	if signingFile == "" {
+		log.Printf("[jwt] WARN: JWT_SIGNING_KEY_FILE not set, using an ephemeral RSA key")
+		k, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		ks.signing = k
+	} else {
+		k, err := readKey(signingFile)
+		if err != nil {
+			return nil, err
+		}
+		priv, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s: signing key must be an RSA private key", signingFile)
+		}
+		ks.signing = priv
+	}
+	ks.kid = ks.add(&ks.signing.PublicKey)
+
+	for _, f := range strings.Split(verifyFiles, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		k, err := readKey(f)
+		if err != nil {
+			return nil, err
+		}
+		switch key := k.(type) {
+		case *rsa.PrivateKey:
+			ks.add(&key.PublicKey)
+		case *rsa.PublicKey:
+			ks.add(key)
+		default:
+			return nil, fmt.Errorf("%s: unsupported key type %T", f, k)
+		}
+	}
+	log.Printf("[jwt] signing with kid=%s (%d keys published)", ks.kid, len(ks.order))
+	return ks, nil
+}
+
+func (ks *KeySet) add(pub *rsa.PublicKey) string {
+	kid := thumbprint(pub)
+	if _, ok := ks.public[kid]; !ok {
+		ks.public[kid] = pub
+		ks.order = append(ks.order, kid)
+	}
+	return kid
+}
+
+// Sign firma los claims con RS256 y la clave actual
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	t := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	t.Header["kid"] = ks.kid
+	return t.SignedString(ks.signing)
+}
+
+// Keyfunc resuelve la clave pública por kid para jwt.Parse
+func (ks *KeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	pub, ok := ks.public[kid]
+	if !ok {
+		return nil, errors.New("unknown kid")
+	}
+	return pub, nil
+}
+
+// JWKS devuelve las claves públicas para /.well-known/jwks.json
+func (ks *KeySet) JWKS() JWKS {
+	out := JWKS{Keys: make([]JWK, 0, len(ks.order))}
+	for _, kid := range ks.order {
+		pub := ks.public[kid]
+		out.Keys = append(out.Keys, JWK{
+			Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256",
+			N: b64(pub.N.Bytes()), E: b64(big.NewInt(int64(pub.E)).Bytes()),
+		})
+	}
+	return out
+}
+
+func readKey(path string) (crypto.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+}
+
+// thumbprint es el kid: thumbprint SHA-256 de la clave (RFC 7638)
+func thumbprint(pub *rsa.PublicKey) string {
+	j, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{b64(big.NewInt(int64(pub.E)).Bytes()), "RSA", b64(pub.N.Bytes())})
+	sum := sha256.Sum256(j)
+	return b64(sum[:])
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP según RFC 6238 con los parámetros que usan todas las apps
+// autenticadoras: HMAC-SHA1, 6 dígitos, pasos de 30s
+const (
+	totpDigits = 6
+	totpPeriod = 30
+	// se aceptan códigos de un paso antes y uno después (desfase de reloj)
+	totpSkew = 1
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret devuelve un secreto de 160 bits en base32 sin padding
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b32.EncodeToString(b), nil
+}
+
+// TOTPURI arma la URI otpauth:// que se muestra como QR en la app autenticadora
+func TOTPURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// TOTPCode calcula el código del paso que contiene t
+func TOTPCode(secret string, t time.Time) (string, error) {
+	return totpAt(secret, t.Unix()/totpPeriod)
+}
+
+// ValidateTOTP compara code con los pasos cercanos a t y devuelve el paso que
+// coincidió, para que el llamador rechace reusos del mismo código
+func ValidateTOTP(secret, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	now := t.Unix() / totpPeriod
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		want, err := totpAt(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func totpAt(secret string, step int64) (string, error) {
+	key, err := b32.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("totp secret: %w", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	// truncado dinámico (RFC 4226 §5.3)
+	off := sum[len(sum)-1] & 0x0f
+	n := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, n%1000000), nil
+}
+
+// RecoveryCodes genera n códigos de recuperación de un solo uso con formato
+// xxxxx-xxxxx (50 bits cada uno)
+func RecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		s := strings.ToLower(b32.EncodeToString(b))[:10]
+		codes[i] = s[:5] + "-" + s[5:]
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode permite tipear el código sin guion o en mayúsculas
+func NormalizeRecoveryCode(code string) string {
+	s := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(s) != 10 {
+		return s
+	}
+	return s[:5] + "-" + s[5:]
+}
@@ -16,7 +16,8 @@ import (
 func setupTestRouter(svc services.UsersService) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
-	authCtl := &AuthController{svc: svc}
+	// el login del mock emite tokens fijos (el servicio real solo autentica)
+	authCtl := &AuthController{svc: toConcreteMock(svc)}
 	r.POST("/auth/login", authCtl.Login)
 	r.POST("/register", func(c *gin.Context) {
 		var req struct {
@@ -46,7 +47,7 @@ type AuthController struct {
 	svc interface {
 		Create(username, email, password string, role domain.Role) (*domain.User, error)
 		GetByID(id uint64) (*domain.User, error)
-		Login(login, password string) (*domain.User, string, error)
+		Login(login, password, ip string) (*domain.User, *services.Tokens, error)
 	}
 }
 
@@ -63,12 +64,12 @@ func (a *AuthController) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
 		return
 	}
-	u, token, err := a.svc.Login(req.Login, req.Password)
+	u, tokens, err := a.svc.Login(req.Login, req.Password, c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"token": token, "userId": u.Username})
+	c.JSON(http.StatusOK, gin.H{"token": tokens.AccessToken, "refreshToken": tokens.RefreshToken, "userId": u.Username})
 }
 
 // mockUsersService is now defined in common.go
@@ -92,8 +93,9 @@ func TestLogin(t *testing.T) {
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody: map[string]interface{}{
-				"token":  "test-token",
-				"userId": "testuser",
+				"token":        "test-token",
+				"refreshToken": "test-refresh",
+				"userId":       "testuser",
 			},
 		},
 		{
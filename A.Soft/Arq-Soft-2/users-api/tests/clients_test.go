@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/controllers"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/middleware"
+	"github.com/sporthub/users-api/internal/services"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+func TestClientCredentials(t *testing.T) {
+	cfg := config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub", ServiceClients: "activities-api:s3cret, bad-entry"}
+	svc := services.NewClientsService(cfg)
+
+	if _, _, err := svc.Token("activities-api", "wrong"); !errors.Is(err, services.ErrInvalidClient) {
+		t.Errorf("expected ErrInvalidClient for a wrong secret, got %v", err)
+	}
+	if _, _, err := svc.Token("search-api", "s3cret"); !errors.Is(err, services.ErrInvalidClient) {
+		t.Errorf("expected ErrInvalidClient for an unknown client, got %v", err)
+	}
+	token, expiresIn, err := svc.Token("activities-api", "s3cret")
+	if err != nil || expiresIn != 600 {
+		t.Fatalf("Token() = %d, %v", expiresIn, err)
+	}
+	if id, err := utils.ParseServiceToken(cfg, token); err != nil || id != "activities-api" {
+		t.Errorf("ParseServiceToken() = %q, %v", id, err)
+	}
+}
+
+func TestGetUserRequiresSelfAdminOrService(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub", JWTExpMinutes: "15", ServiceClients: "activities-api:s3cret"}
+	keys, err := utils.DefaultKeySet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := NewMockUsersService()
+	ana, _ := users.Create("ana", "ana@example.com", "secret123", domain.RoleUser)
+	bob, _ := users.Create("bob", "bob@example.com", "secret123", domain.RoleUser)
+	root, _ := users.Create("root", "root@example.com", "secret123", domain.RoleAdmin)
+
+	auth := middleware.JWTAuth(keys, cfg, nil)
+	r := gin.New()
+	r.GET("/users/:id", middleware.AllowServiceToken(cfg, auth), controllers.NewUsersControllerWithService(users).GetByID)
+
+	get := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	anaToken, _ := utils.GenerateJWT(cfg, ana)
+	bobToken, _ := utils.GenerateJWT(cfg, bob)
+	rootToken, _ := utils.GenerateJWT(cfg, root)
+	serviceToken, _, _ := services.NewClientsService(cfg).Token("activities-api", "s3cret")
+
+	for name, tc := range map[string]struct {
+		token string
+		want  int
+	}{
+		"anonymous": {"", http.StatusUnauthorized},
+		"self":      {anaToken, http.StatusOK},
+		"other":     {bobToken, http.StatusForbidden},
+		"admin":     {rootToken, http.StatusOK},
+		"service":   {serviceToken, http.StatusOK},
+	} {
+		if got := get(tc.token); got != tc.want {
+			t.Errorf("%s: expected %d, got %d", name, tc.want, got)
+		}
+	}
+
+	// el token de servicio no sirve en rutas que solo aceptan usuarios
+	r.GET("/only-users", auth, func(c *gin.Context) { c.Status(http.StatusOK) })
+	req := httptest.NewRequest(http.MethodGet, "/only-users", nil)
+	req.Header.Set("Authorization", "Bearer "+serviceToken)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("service token must not pass JWTAuth, got %d", w.Code)
+	}
+}
+
+func TestRevokedListOnlyForServices(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub", JWTExpMinutes: "15", ServiceClients: "activities-api:s3cret"}
+	users := NewMockUsersService()
+	ana, _ := users.Create("ana", "ana@example.com", "secret123", domain.RoleAdmin)
+
+	r := gin.New()
+	r.GET("/auth/revoked", middleware.RequireServiceToken(cfg), func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/auth/revoked", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	userToken, _ := utils.GenerateJWT(cfg, ana)
+	serviceToken, _, _ := services.NewClientsService(cfg).Token("activities-api", "s3cret")
+
+	if got := get(""); got != http.StatusUnauthorized {
+		t.Errorf("anonymous: expected 401, got %d", got)
+	}
+	// ni siquiera un admin: la lista es solo para los otros servicios
+	if got := get(userToken); got != http.StatusUnauthorized {
+		t.Errorf("user token: expected 401, got %d", got)
+	}
+	if got := get(serviceToken); got != http.StatusOK {
+		t.Errorf("service token: expected 200, got %d", got)
+	}
+}
@@ -2,19 +2,36 @@ package tests
 
 import (
 	"errors"
+	"fmt"
+	"testing"
+	"time"
 
 	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
 	"github.com/sporthub/users-api/internal/services"
 )
 
+// mustFindUser lee el usuario tal como quedó guardado en el repo
+func mustFindUser(t *testing.T, users repository.UsersRepo, id uint64) *domain.User {
+	t.Helper()
+	u, err := users.FindByID(id)
+	if err != nil {
+		t.Fatalf("user %d: %v", id, err)
+	}
+	return u
+}
+
 // mockUsersService is a mock implementation of UsersService for testing
 type mockUsersService struct {
-	users map[string]*domain.User
+	users   map[string]*domain.User
+	invites map[string]domain.Role // token -> rol (se borra al usarla)
+	listed  repository.UserFilter  // último filtro recibido por List
 }
 
 func NewMockUsersService() services.UsersService {
 	return &mockUsersService{
-		users: make(map[string]*domain.User),
+		users:   make(map[string]*domain.User),
+		invites: make(map[string]domain.Role),
 	}
 }
 
@@ -32,6 +49,82 @@ func (m *mockUsersService) Create(username, email, password string, role domain.
 	return user, nil
 }
 
+func (m *mockUsersService) Register(username, email, password, inviteToken string) (*domain.User, error) {
+	role := domain.RoleUser
+	if inviteToken != "" {
+		r, ok := m.invites[inviteToken]
+		if !ok {
+			return nil, services.ErrInvalidInvitation
+		}
+		delete(m.invites, inviteToken)
+		role = r
+	}
+	return m.Create(username, email, password, role)
+}
+
+func (m *mockUsersService) ChangeRole(id uint64, role domain.Role) (*domain.User, error) {
+	if role != domain.RoleUser && role != domain.RoleAdmin {
+		return nil, services.ErrInvalidRole
+	}
+	u, err := m.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	u.Role = role
+	return u, nil
+}
+
+func (m *mockUsersService) CreateInvitation(role domain.Role, email string, ttl time.Duration, createdBy uint64) (string, *domain.Invitation, error) {
+	token := fmt.Sprintf("invite-%d", len(m.invites)+1)
+	m.invites[token] = role
+	return token, &domain.Invitation{Role: role, Email: email, CreatedBy: createdBy, ExpiresAt: time.Now().Add(ttl)}, nil
+}
+
+func (m *mockUsersService) BootstrapAdminInvite(ttl time.Duration) (string, error) {
+	for _, u := range m.users {
+		if u.Role == domain.RoleAdmin {
+			return "", nil
+		}
+	}
+	token, _, err := m.CreateInvitation(domain.RoleAdmin, "", ttl, 0)
+	return token, err
+}
+
+func (m *mockUsersService) Authenticate(login, password, ip string) (*domain.User, error) {
+	if user, exists := m.users[login]; exists && password == "validpass" {
+		return user, nil
+	}
+	return nil, errors.New("invalid credentials")
+}
+
+func (m *mockUsersService) UnlockLogin(id, by uint64) error {
+	_, err := m.GetByID(id)
+	return err
+}
+
+func (m *mockUsersService) UpdateProfile(id uint64, p services.ProfileUpdate) (*domain.User, string, error) {
+	u, err := m.GetByID(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if p.DisplayName != nil {
+		u.DisplayName = *p.DisplayName
+	}
+	return u, "", nil
+}
+
+func (m *mockUsersService) List(f repository.UserFilter) ([]domain.User, int64, error) {
+	if f.Sort != "" && !repository.ValidUserSort(f.Sort) {
+		return nil, 0, services.ErrInvalidSort
+	}
+	m.listed = f
+	var out []domain.User
+	for _, u := range m.users {
+		out = append(out, *u)
+	}
+	return out, int64(len(out)), nil
+}
+
 func (m *mockUsersService) GetByID(id uint64) (*domain.User, error) {
 	for _, user := range m.users {
 		if user.ID == id {
@@ -41,11 +134,18 @@ func (m *mockUsersService) GetByID(id uint64) (*domain.User, error) {
 	return nil, errors.New("not found")
 }
 
-func (m *mockUsersService) Login(login, password string) (*domain.User, string, error) {
+func (m *mockUsersService) Login(login, password, ip string) (*domain.User, *services.Tokens, error) {
 	if user, exists := m.users[login]; exists && password == "validpass" {
-		return user, "test-token", nil
+		return user, &services.Tokens{AccessToken: "test-token", RefreshToken: "test-refresh"}, nil
+	}
+	return nil, nil, errors.New("invalid credentials")
+}
+
+func (m *mockUsersService) DeleteAccount(id uint64, password, reauthCode, ip string) error {
+	if password != "validpass" && reauthCode != "valid-reauth" {
+		return services.ErrWrongPassword
 	}
-	return nil, "", errors.New("invalid credentials")
+	return m.Delete(id)
 }
 
 func (m *mockUsersService) Delete(id uint64) error {
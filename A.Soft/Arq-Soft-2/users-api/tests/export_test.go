@@ -0,0 +1,304 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/controllers"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/events"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+// fakeEnrollments es la fuente de inscripciones de activities-api; si
+// release no es nil espera a que se cierre antes de responder
+type fakeEnrollments struct {
+	release chan struct{}
+	err     error
+}
+
+func (f *fakeEnrollments) UserEnrollments(ctx context.Context, userID uint64) ([]json.RawMessage, error) {
+	if f.release != nil {
+		<-f.release
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []json.RawMessage{
+		json.RawMessage(`{"id":1,"userId":"1","estado":"confirmada"}`),
+		json.RawMessage(`{"id":2,"userId":"1","estado":"cancelada"}`),
+	}, nil
+}
+
+func newExportService(t *testing.T, enrollments services.EnrollmentsSource) (services.ExportService, repository.TokensRepo) {
+	t.Helper()
+	return newExportServiceOn(t, repository.NewExportsMemory(), enrollments)
+}
+
+func newExportServiceOn(t *testing.T, jobs repository.ExportsRepo, enrollments services.EnrollmentsSource) (services.ExportService, repository.TokensRepo) {
+	t.Helper()
+	users := repository.NewUsersMemory()
+	_ = users.Create(&domain.User{Username: "ana", Email: "ana@example.com", PasswordHash: "$2a$10$secret-hash", Phone: "555-1234", Role: domain.RoleUser})
+	_ = users.Create(&domain.User{Username: "bob", Email: "bob@example.com", Role: domain.RoleUser})
+	tokens := repository.NewTokensMemory()
+	_ = tokens.CreateRefresh(&domain.RefreshToken{UserID: 1, FamilyID: "f1", TokenHash: "refresh-hash", ExpiresAt: time.Now().Add(time.Hour)})
+	_ = tokens.CreateRefresh(&domain.RefreshToken{UserID: 2, FamilyID: "f2", TokenHash: "other-hash", ExpiresAt: time.Now().Add(time.Hour)})
+	identities := repository.NewIdentitiesMemory()
+	_ = identities.Create(&domain.ExternalIdentity{UserID: 1, Provider: "google", Subject: "google-123", Email: "ana@gmail.com"})
+	_ = identities.Create(&domain.ExternalIdentity{UserID: 2, Provider: "google", Subject: "google-456"})
+	orgs := repository.NewOrgsMemory()
+	_ = orgs.Create(&domain.Organization{Slug: "club-norte", Name: "Club Norte"})
+	_ = orgs.SaveMembership(&domain.Membership{OrgID: 1, UserID: 1, Role: domain.OrgRoleInstructor})
+	_ = orgs.SaveMembership(&domain.Membership{OrgID: 1, UserID: 2, Role: domain.OrgRoleMember})
+	outbox := repository.NewOutboxMemory()
+	for id := uint64(1); id <= 2; id++ {
+		u, _ := users.FindByID(id)
+		_ = events.Enqueue(outbox, events.UserCreated, events.NewUserEvent("created", u))
+	}
+	attempts := repository.NewLoginAttemptsMemory()
+	_, _ = attempts.RecordFailure("user:1", time.Now(), time.Now().Add(-time.Hour))
+	_, _ = attempts.RecordFailure("user:2", time.Now(), time.Now().Add(-time.Hour))
+	cfg := config.Config{ExportTTLHours: "24"}
+	svc := services.NewExportServiceFromRepos(jobs, users, repository.NewMFAMemory(), tokens,
+		repository.NewUserTokensMemory(), identities, orgs, outbox, attempts, enrollments, cfg)
+	return svc, tokens
+}
+
+// waitExport espera a que el job salga de pending/running
+func waitExport(t *testing.T, svc services.ExportService, userID uint64, id string) *domain.ExportJob {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := svc.Get(userID, id)
+		if err != nil {
+			t.Fatalf("Get() error: %v", err)
+		}
+		if job.Status == domain.ExportDone || job.Status == domain.ExportFailed {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("export did not finish")
+	return nil
+}
+
+func TestExportBundlesProfileSessionsAndEnrollments(t *testing.T) {
+	source := &fakeEnrollments{release: make(chan struct{})}
+	svc, _ := newExportService(t, source)
+
+	job, err := svc.Start(1)
+	if err != nil || job.Status != domain.ExportPending {
+		t.Fatalf("Start() = %+v, %v", job, err)
+	}
+	// mientras está en curso no se lanza otro
+	again, _ := svc.Start(1)
+	if again.ID != job.ID {
+		t.Error("a second Start while running must return the same job")
+	}
+	if _, err := svc.Download(1, job.ID); !errors.Is(err, services.ErrExportNotReady) {
+		t.Errorf("expected ErrExportNotReady, got %v", err)
+	}
+	close(source.release)
+
+	if done := waitExport(t, svc, 1, job.ID); done.Status != domain.ExportDone || done.ExpiresAt == nil {
+		t.Fatalf("unexpected job state: %+v", done)
+	}
+	if _, err := svc.Download(2, job.ID); !errors.Is(err, services.ErrExportNotFound) {
+		t.Errorf("another user must not see the export, got %v", err)
+	}
+	data, err := svc.Download(1, job.ID)
+	if err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil || len(zr.File) != 1 || zr.File[0].Name != services.ExportFileName {
+		t.Fatalf("unexpected zip: %v", err)
+	}
+	f, _ := zr.File[0].Open()
+	raw, _ := io.ReadAll(f)
+	if strings.Contains(string(raw), "hash") {
+		t.Errorf("export must not include hashes: %s", raw)
+	}
+	var bundle struct {
+		Profile struct {
+			Username string `json:"username"`
+			Phone    string `json:"phone"`
+		} `json:"profile"`
+		Sessions       []map[string]any `json:"sessions"`
+		LinkedAccounts []map[string]any `json:"linkedAccounts"`
+		Organizations  []map[string]any `json:"organizations"`
+		Audit          struct {
+			Events []struct {
+				RoutingKey string         `json:"routingKey"`
+				Payload    map[string]any `json:"payload"`
+			} `json:"events"`
+			LoginAttempts *struct {
+				Failures int `json:"failures"`
+			} `json:"loginAttempts"`
+		} `json:"audit"`
+		Enrollments []map[string]any `json:"enrollments"`
+	}
+	if err := json.Unmarshal(raw, &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Profile.Username != "ana" || bundle.Profile.Phone != "555-1234" {
+		t.Errorf("unexpected profile: %+v", bundle.Profile)
+	}
+	if len(bundle.Sessions) != 1 {
+		t.Errorf("expected only ana's session, got %d", len(bundle.Sessions))
+	}
+	if len(bundle.LinkedAccounts) != 1 || bundle.LinkedAccounts[0]["subject"] != "google-123" {
+		t.Errorf("expected ana's linked Google account, got %+v", bundle.LinkedAccounts)
+	}
+	if len(bundle.Organizations) != 1 || bundle.Organizations[0]["slug"] != "club-norte" || bundle.Organizations[0]["role"] != "instructor" {
+		t.Errorf("expected ana's membership in club-norte, got %+v", bundle.Organizations)
+	}
+	if ev := bundle.Audit.Events; len(ev) != 1 || ev[0].RoutingKey != events.UserCreated || ev[0].Payload["userId"] != "1" {
+		t.Errorf("expected only ana's user.created event, got %+v", ev)
+	}
+	if la := bundle.Audit.LoginAttempts; la == nil || la.Failures != 1 {
+		t.Errorf("expected ana's failed login, got %+v", la)
+	}
+	if len(bundle.Enrollments) != 2 || bundle.Enrollments[1]["estado"] != "cancelada" {
+		t.Errorf("cancelled enrollments must be exported: %+v", bundle.Enrollments)
+	}
+}
+
+func TestExportFailsWhenActivitiesUnavailable(t *testing.T) {
+	svc, _ := newExportService(t, &fakeEnrollments{err: errors.New("activities-api returned 503")})
+	job, _ := svc.Start(1)
+	if done := waitExport(t, svc, 1, job.ID); done.Status != domain.ExportFailed {
+		t.Fatalf("expected a failed job, got %+v", done)
+	}
+	if _, err := svc.Download(1, job.ID); !errors.Is(err, services.ErrExportNotReady) {
+		t.Errorf("expected ErrExportNotReady, got %v", err)
+	}
+	// se puede volver a intentar
+	if retry, _ := svc.Start(1); retry.ID == job.ID {
+		t.Error("a failed export must not block a new one")
+	}
+}
+
+func TestExportEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc, _ := newExportService(t, &fakeEnrollments{})
+	ctl := controllers.NewExportController(svc)
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("userId", uint64(1)) })
+	r.POST("/users/me/export", ctl.Start)
+	r.GET("/users/me/export", ctl.Latest)
+	r.GET("/users/me/export/:id", ctl.Status)
+	r.GET("/users/me/export/:id/download", ctl.Download)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/export", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 before any export, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/me/export", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var started struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &started)
+	if w.Header().Get("Location") != "/users/me/export/"+started.ID {
+		t.Errorf("unexpected Location %q", w.Header().Get("Location"))
+	}
+	waitExport(t, svc, 1, started.ID)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/export/"+started.ID, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"downloadUrl"`) {
+		t.Errorf("expected a finished job with downloadUrl, got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/export/"+started.ID+"/download", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" ||
+		!strings.Contains(w.Header().Get("Content-Disposition"), "attachment") {
+		t.Errorf("unexpected download response: %d %v", w.Code, w.Header())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/export/nope/download", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown job, got %d", w.Code)
+	}
+}
+
+func TestExportAllowsOneActiveJobPerUser(t *testing.T) {
+	source := &fakeEnrollments{release: make(chan struct{})}
+	svc, _ := newExportService(t, source)
+	defer close(source.release)
+
+	// pedidos simultáneos (doble click, varias réplicas) terminan en un solo job
+	ids := make(chan string, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job, err := svc.Start(1)
+			if err != nil {
+				t.Errorf("Start() error: %v", err)
+				return
+			}
+			ids <- job.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+	seen := map[string]bool{}
+	for id := range ids {
+		seen[id] = true
+	}
+	if len(seen) != 1 {
+		t.Errorf("expected a single active export, got %d", len(seen))
+	}
+}
+
+func TestExportRecoversJobsLeftRunningByADeadReplica(t *testing.T) {
+	jobs := repository.NewExportsMemory()
+	svc, _ := newExportServiceOn(t, jobs, &fakeEnrollments{})
+	userID := uint64(1)
+	startedAt := time.Now().Add(-time.Hour)
+	stuck := &domain.ExportJob{ID: "stuck", UserID: userID, ActiveUserID: &userID, Status: domain.ExportRunning, StartedAt: &startedAt}
+	if err := jobs.Create(stuck); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, err := svc.Get(userID, "stuck"); err != nil || job.Status != domain.ExportFailed || job.Error != "interrupted" {
+		t.Fatalf("a job nobody is running must show as failed, got %+v %v", job, err)
+	}
+	job, err := svc.Start(userID)
+	if err != nil || job.ID == "stuck" {
+		t.Fatalf("expected a new export after the stuck one, got %+v %v", job, err)
+	}
+	if done := waitExport(t, svc, userID, job.ID); done.Status != domain.ExportDone {
+		t.Fatalf("unexpected job state: %+v", done)
+	}
+	// el estado se lee sin el zip; el zip solo en la descarga
+	if j, _ := jobs.FindByID(job.ID); j.Data != nil {
+		t.Error("FindByID must not load the zip")
+	}
+	if data, err := svc.Download(userID, job.ID); err != nil || len(data) == 0 {
+		t.Errorf("Download() = %d bytes, %v", len(data), err)
+	}
+}
@@ -0,0 +1,232 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/controllers"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+func newUsersService(t *testing.T) (services.UsersService, repository.UsersRepo) {
+	t.Helper()
+	repos := repository.NewMemoryRepos()
+	cfg := config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub", JWTExpMinutes: "15", RefreshTokenTTLHours: "1"}
+	guard := services.NewLoginGuard(repos.LoginAttempts, cfg)
+	return services.NewUsersService(repos, guard, cfg), repos.Users
+}
+
+func TestSignupIgnoresRequestedRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ctl := controllers.NewUsersControllerWithService(NewMockUsersService())
+	r.POST("/users", ctl.CreateUser)
+
+	body, _ := json.Marshal(map[string]string{
+		"username": "mallory", "email": "mallory@example.com", "password": "secret123", "rol": "admin",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Role string `json:"role"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Role != string(domain.RoleUser) {
+		t.Errorf("public signup must create role user, got %q", resp.Role)
+	}
+
+	// una invitación inexistente es un 400, no un alta silenciosa
+	body, _ = json.Marshal(map[string]string{
+		"username": "eve", "email": "eve@example.com", "password": "secret123", "inviteToken": "bogus",
+	})
+	req = httptest.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid invitation, got %d", w.Code)
+	}
+}
+
+// brokenRegister simula una falla interna del alta (base caída)
+type brokenRegister struct{ services.UsersService }
+
+func (brokenRegister) Register(username, email, password, inviteToken string) (*domain.User, error) {
+	return nil, errors.New("dial tcp 10.0.0.5:3306: connection refused")
+}
+
+func TestSignupErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc, _ := newUsersService(t)
+	if _, err := svc.Register("ana", "ana@example.com", "secret123", ""); err != nil {
+		t.Fatal(err)
+	}
+	signup := func(svc services.UsersService, username, email string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.POST("/users", controllers.NewUsersControllerWithService(svc).CreateUser)
+		body, _ := json.Marshal(map[string]string{"username": username, "email": email, "password": "secret123"})
+		req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, tc := range []struct{ username, email, want string }{
+		{"ana", "otra@example.com", services.ErrUsernameTaken.Error()},
+		{"otra", "ana@example.com", services.ErrEmailTaken.Error()},
+	} {
+		w := signup(svc, tc.username, tc.email)
+		if w.Code != http.StatusConflict || !bytes.Contains(w.Body.Bytes(), []byte(tc.want)) {
+			t.Errorf("%s/%s: expected 409 %q, got %d %s", tc.username, tc.email, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	// una falla interna es un 500 genérico, sin el detalle del error
+	w := signup(brokenRegister{svc}, "bob", "bob@example.com")
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte("10.0.0.5")) {
+		t.Errorf("the internal error must not be echoed: %s", w.Body.String())
+	}
+}
+
+func TestInvitationGrantsRoleOnce(t *testing.T) {
+	svc, _ := newUsersService(t)
+	token, _, err := svc.CreateInvitation(domain.RoleAdmin, "", time.Hour, 1)
+	if err != nil {
+		t.Fatalf("CreateInvitation() error: %v", err)
+	}
+
+	u, err := svc.Register("root", "root@example.com", "secret123", token)
+	if err != nil {
+		t.Fatalf("Register() error: %v", err)
+	}
+	if u.Role != domain.RoleAdmin {
+		t.Errorf("expected admin role, got %q", u.Role)
+	}
+	if _, err := svc.Register("root2", "root2@example.com", "secret123", token); !errors.Is(err, services.ErrInvalidInvitation) {
+		t.Errorf("invitation must be single-use, got %v", err)
+	}
+}
+
+func TestInvitationRejectedWhenExpiredOrForOtherEmail(t *testing.T) {
+	svc, _ := newUsersService(t)
+
+	expired, _, _ := svc.CreateInvitation(domain.RoleAdmin, "", time.Nanosecond, 1)
+	time.Sleep(time.Millisecond)
+	if _, err := svc.Register("late", "late@example.com", "secret123", expired); !errors.Is(err, services.ErrInvalidInvitation) {
+		t.Errorf("expected expired invitation to be rejected, got %v", err)
+	}
+
+	bound, _, _ := svc.CreateInvitation(domain.RoleAdmin, "ana@example.com", time.Hour, 1)
+	if _, err := svc.Register("bob", "bob@example.com", "secret123", bound); !errors.Is(err, services.ErrInvalidInvitation) {
+		t.Errorf("expected invitation bound to another email to be rejected, got %v", err)
+	}
+	// el intento fallido no la consume
+	if _, err := svc.Register("ana", "ANA@example.com", "secret123", bound); err != nil {
+		t.Errorf("invitation should still be valid for its email: %v", err)
+	}
+}
+
+func TestChangeRoleKeepsLastAdmin(t *testing.T) {
+	svc, _ := newUsersService(t)
+	admin, _ := svc.Create("admin", "admin@example.com", "secret123", domain.RoleAdmin)
+	user, _ := svc.Create("ana", "ana@example.com", "secret123", domain.RoleUser)
+
+	if _, err := svc.ChangeRole(admin.ID, domain.RoleUser); !errors.Is(err, services.ErrLastAdmin) {
+		t.Fatalf("expected ErrLastAdmin, got %v", err)
+	}
+	if _, err := svc.ChangeRole(user.ID, "root"); !errors.Is(err, services.ErrInvalidRole) {
+		t.Errorf("expected ErrInvalidRole, got %v", err)
+	}
+
+	if u, err := svc.ChangeRole(user.ID, domain.RoleAdmin); err != nil || u.Role != domain.RoleAdmin {
+		t.Fatalf("promote failed: %+v, %v", u, err)
+	}
+	// con dos admins ya se puede degradar a uno
+	if _, err := svc.ChangeRole(admin.ID, domain.RoleUser); err != nil {
+		t.Errorf("demote with another admin left failed: %v", err)
+	}
+}
+
+func TestConcurrentDemotionsKeepOneAdmin(t *testing.T) {
+	svc, users := newUsersService(t)
+	a, _ := svc.Create("admin1", "admin1@example.com", "secret123", domain.RoleAdmin)
+	b, _ := svc.Create("admin2", "admin2@example.com", "secret123", domain.RoleAdmin)
+
+	// los dos admins se degradan a la vez: uno tiene que quedar
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, id := range []uint64{a.ID, b.ID} {
+		wg.Add(1)
+		go func(i int, id uint64) {
+			defer wg.Done()
+			_, errs[i] = svc.ChangeRole(id, domain.RoleUser)
+		}(i, id)
+	}
+	wg.Wait()
+
+	if n, _ := users.CountByRole(domain.RoleAdmin); n != 1 {
+		t.Fatalf("expected exactly one admin left, got %d (errs %v)", n, errs)
+	}
+	if !errors.Is(errs[0], services.ErrLastAdmin) && !errors.Is(errs[1], services.ErrLastAdmin) {
+		t.Errorf("one of the demotions must fail with ErrLastAdmin, got %v", errs)
+	}
+}
+
+func TestChangeRoleRevokesTheUsersTokens(t *testing.T) {
+	repos := repository.NewMemoryRepos()
+	cfg := config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub", JWTExpMinutes: "15", RefreshTokenTTLHours: "1"}
+	svc := services.NewUsersService(repos, services.NewLoginGuard(repos.LoginAttempts, cfg), cfg)
+	tokens := services.NewTokenService(repos, cfg)
+	_, _ = svc.Create("root", "root@example.com", "secret123", domain.RoleAdmin)
+	admin, _ := svc.Create("admin", "admin@example.com", "secret123", domain.RoleAdmin)
+
+	pair, err := tokens.Issue(admin)
+	if err != nil {
+		t.Fatalf("Issue() error: %v", err)
+	}
+	if _, err := svc.ChangeRole(admin.ID, domain.RoleUser); err != nil {
+		t.Fatalf("ChangeRole() error: %v", err)
+	}
+	// el token todavía dice admin: no puede seguir sirviendo
+	if !tokens.IsRevoked(jtiOf(t, pair.AccessToken)) {
+		t.Error("access token issued before the demotion must be revoked")
+	}
+	if _, _, err := tokens.Refresh(pair.RefreshToken); err == nil {
+		t.Error("refresh token issued before the demotion must be revoked")
+	}
+}
+
+func TestBootstrapAdminInviteOnlyWithoutAdmins(t *testing.T) {
+	svc, _ := newUsersService(t)
+	token, err := svc.BootstrapAdminInvite(time.Hour)
+	if err != nil || token == "" {
+		t.Fatalf("expected a bootstrap invite, got %q, %v", token, err)
+	}
+	if _, err := svc.Register("root", "root@example.com", "secret123", token); err != nil {
+		t.Fatalf("Register() with bootstrap invite: %v", err)
+	}
+	if token, _ := svc.BootstrapAdminInvite(time.Hour); token != "" {
+		t.Error("no bootstrap invite should be issued once an admin exists")
+	}
+}
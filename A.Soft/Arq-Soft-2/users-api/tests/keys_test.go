@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+func writeKey(t *testing.T, name string) string {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	b := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)})
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestKeyRotationKeepsOldTokensValid(t *testing.T) {
+	oldKey, newKey := writeKey(t, "old.pem"), writeKey(t, "new.pem")
+
+	before, err := utils.LoadKeySet(oldKey, "")
+	if err != nil {
+		t.Fatalf("LoadKeySet() error: %v", err)
+	}
+	oldToken, err := before.Sign(jwt.MapClaims{"sub": 1})
+	if err != nil {
+		t.Fatalf("Sign() error: %v", err)
+	}
+
+	// rotación: firma con la nueva, la vieja se sigue publicando
+	after, err := utils.LoadKeySet(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("LoadKeySet() error: %v", err)
+	}
+	if n := len(after.JWKS().Keys); n != 2 {
+		t.Fatalf("expected 2 published keys, got %d", n)
+	}
+	newToken, _ := after.Sign(jwt.MapClaims{"sub": 1})
+
+	for name, tok := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := jwt.Parse(tok, after.Keyfunc); err != nil {
+			t.Errorf("%s token should validate after rotation: %v", name, err)
+		}
+	}
+	if _, err := jwt.Parse(newToken, before.Keyfunc); err == nil {
+		t.Error("token signed with an unknown kid should not validate")
+	}
+}
+
+func TestDefaultKeySetFollowsTheConfig(t *testing.T) {
+	a := config.Config{JWTSigningKeyFile: writeKey(t, "a.pem"), JWTIssuer: "users-api", JWTAudience: "sporthub", JWTExpMinutes: "15"}
+	b := a
+	b.JWTSigningKeyFile = writeKey(t, "b.pem")
+
+	ka, err := utils.DefaultKeySet(a)
+	if err != nil {
+		t.Fatalf("DefaultKeySet(a) error: %v", err)
+	}
+	kb, err := utils.DefaultKeySet(b)
+	if err != nil {
+		t.Fatalf("DefaultKeySet(b) error: %v", err)
+	}
+	if again, _ := utils.DefaultKeySet(a); again != ka {
+		t.Fatal("the same config must reuse its key set")
+	}
+	if ka.JWKS().Keys[0].Kid == kb.JWKS().Keys[0].Kid {
+		t.Fatal("a config with another signing key must not get the first key set")
+	}
+
+	// GenerateJWT firma con la clave de su config
+	tok, err := utils.GenerateJWT(b, &domain.User{ID: 1, Role: domain.RoleUser})
+	if err != nil {
+		t.Fatalf("GenerateJWT() error: %v", err)
+	}
+	if _, err := jwt.Parse(tok, kb.Keyfunc); err != nil {
+		t.Fatalf("token should validate with b's keys: %v", err)
+	}
+	if _, err := jwt.Parse(tok, ka.Keyfunc); err == nil {
+		t.Fatal("token signed for b must not validate with a's keys")
+	}
+
+	if _, err := utils.DefaultKeySet(config.Config{JWTSigningKeyFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Fatal("expected an error for a missing signing key")
+	}
+}
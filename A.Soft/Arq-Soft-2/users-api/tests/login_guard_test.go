@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+func TestLoginGuardProgressiveDelay(t *testing.T) {
+	guard := services.NewLoginGuard(repository.NewLoginAttemptsMemory(), config.Config{LoginMaxFailures: "10"})
+
+	// los dos primeros fallos no tienen demora
+	guard.Failure("user:1", "10.0.0.1")
+	guard.Failure("user:1", "10.0.0.1")
+	if err := guard.Check("user:1", "10.0.0.1"); err != nil {
+		t.Fatalf("no delay expected after 2 failures, got %v", err)
+	}
+
+	guard.Failure("user:1", "10.0.0.1")
+	var throttled *services.ThrottledError
+	if err := guard.Check("user:1", "10.0.0.1"); !errors.As(err, &throttled) {
+		t.Fatalf("expected ThrottledError after 3 failures, got %v", err)
+	}
+	if throttled.RetryAfter <= 0 || throttled.RetryAfter > time.Second {
+		t.Errorf("expected a delay of at most 1s, got %v", throttled.RetryAfter)
+	}
+	// otra cuenta desde la misma IP no se ve afectada
+	if err := guard.Check("user:2", "10.0.0.1"); err != nil {
+		t.Errorf("other account should not be delayed: %v", err)
+	}
+}
+
+func TestLoginGuardLocksIP(t *testing.T) {
+	guard := services.NewLoginGuard(repository.NewLoginAttemptsMemory(), config.Config{LoginIPMaxFailures: "3"})
+	for _, account := range []string{"login:a", "login:b", "login:c"} {
+		guard.Failure(account, "10.0.0.9")
+	}
+	if err := guard.Check("login:d", "10.0.0.9"); !errors.Is(err, services.ErrLoginThrottled) {
+		t.Fatalf("expected the IP to be locked, got %v", err)
+	}
+	if err := guard.Check("login:d", "10.0.0.10"); err != nil {
+		t.Errorf("other IPs should not be locked: %v", err)
+	}
+}
+
+func TestLoginLockoutAndAdminUnlock(t *testing.T) {
+	users := repository.NewUsersMemory()
+	hash, _ := utils.HashPassword("secret123")
+	_ = users.Create(&domain.User{Username: "ana", Email: "ana@example.com", PasswordHash: hash, Role: domain.RoleUser})
+	cfg := config.Config{LoginMaxFailures: "2", LoginLockoutMinutes: "15"}
+	guard := services.NewLoginGuard(repository.NewLoginAttemptsMemory(), cfg)
+	repos := repository.NewMemoryRepos()
+	repos.Users = users
+	svc := services.NewUsersService(repos, guard, cfg)
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Authenticate("ana", "wrong", "10.0.0.1"); err == nil || errors.Is(err, services.ErrLoginThrottled) {
+			t.Fatalf("attempt %d: expected invalid credentials, got %v", i+1, err)
+		}
+	}
+	// bloqueada: ni con la contraseña correcta, ni por email
+	var throttled *services.ThrottledError
+	if _, err := svc.Authenticate("ana@example.com", "secret123", "10.0.0.2"); !errors.As(err, &throttled) {
+		t.Fatalf("expected account to be locked, got %v", err)
+	}
+	if throttled.RetryAfter < 14*time.Minute {
+		t.Errorf("expected ~15m lockout, got %v", throttled.RetryAfter)
+	}
+
+	if err := svc.UnlockLogin(1, 99); err != nil {
+		t.Fatalf("UnlockLogin() error: %v", err)
+	}
+	if _, err := svc.Authenticate("ana", "secret123", "10.0.0.1"); err != nil {
+		t.Errorf("login after unlock failed: %v", err)
+	}
+}
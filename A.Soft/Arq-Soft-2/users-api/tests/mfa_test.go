@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/middleware"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+func TestTOTPMatchesRFC6238(t *testing.T) {
+	// secreto ASCII "12345678901234567890" del apéndice B de la RFC
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	cases := map[int64]string{59: "287082", 1111111109: "081804", 2000000000: "279037"}
+	for unix, want := range cases {
+		got, err := utils.TOTPCode(secret, time.Unix(unix, 0))
+		if err != nil || got != want {
+			t.Errorf("TOTPCode(%d) = %q, %v; want %q", unix, got, err, want)
+		}
+	}
+	if _, ok := utils.ValidateTOTP(secret, "287082", time.Unix(59+30, 0)); !ok {
+		t.Error("code from the previous step should be accepted")
+	}
+	if _, ok := utils.ValidateTOTP(secret, "287082", time.Unix(59+90, 0)); ok {
+		t.Error("code from three steps ago should be rejected")
+	}
+}
+
+func newMFAService(t *testing.T, requireAdmin bool) (services.MFAService, repository.UsersRepo) {
+	t.Helper()
+	users := repository.NewUsersMemory()
+	_ = users.Create(&domain.User{Username: "root", Email: "root@example.com", Role: domain.RoleAdmin})
+	cfg := config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub", MFAIssuer: "SportHub", RequireAdmin2FA: requireAdmin}
+	guard := services.NewLoginGuard(repository.NewLoginAttemptsMemory(), cfg)
+	return services.NewMFAServiceFromRepos(repository.NewMFAMemory(), users, guard, cfg), users
+}
+
+func TestMFAEnableAndTwoStepLogin(t *testing.T) {
+	svc, users := newMFAService(t, false)
+	admin := mustFindUser(t, users, 1)
+
+	if ch, err := svc.Challenge(admin); err != nil || ch != nil {
+		t.Fatalf("no challenge expected without 2FA, got %+v, %v", ch, err)
+	}
+	secret, uri, err := svc.Setup(admin.ID)
+	if err != nil {
+		t.Fatalf("Setup() error: %v", err)
+	}
+	if !strings.HasPrefix(uri, "otpauth://totp/SportHub:root@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("unexpected otpauth uri %q", uri)
+	}
+	if _, err := svc.Enable(admin.ID, "000000"); !errors.Is(err, services.ErrInvalidMFACode) {
+		t.Fatalf("expected ErrInvalidMFACode, got %v", err)
+	}
+	code, _ := utils.TOTPCode(secret, time.Now())
+	recovery, err := svc.Enable(admin.ID, code)
+	if err != nil || len(recovery) != 10 {
+		t.Fatalf("Enable() = %d codes, %v", len(recovery), err)
+	}
+
+	ch, err := svc.Challenge(admin)
+	if err != nil || ch == nil || ch.Purpose != utils.MFAPurposeVerify {
+		t.Fatalf("expected a verify challenge, got %+v, %v", ch, err)
+	}
+	// el mismo código no se acepta dos veces
+	if _, err := svc.Verify(ch.Token, code, "10.0.0.1"); !errors.Is(err, services.ErrInvalidMFACode) {
+		t.Errorf("replayed code should be rejected, got %v", err)
+	}
+	next, _ := utils.TOTPCode(secret, time.Now().Add(30*time.Second))
+	if u, err := svc.Verify(ch.Token, next, "10.0.0.1"); err != nil || u.ID != admin.ID {
+		t.Fatalf("Verify() = %+v, %v", u, err)
+	}
+
+	// código de recuperación: sirve una vez, con o sin guion
+	plain := strings.ToUpper(strings.ReplaceAll(recovery[0], "-", ""))
+	if _, err := svc.Verify(ch.Token, plain, "10.0.0.1"); err != nil {
+		t.Fatalf("recovery code rejected: %v", err)
+	}
+	if _, err := svc.Verify(ch.Token, recovery[0], "10.0.0.1"); !errors.Is(err, services.ErrInvalidMFACode) {
+		t.Errorf("recovery code must be single-use, got %v", err)
+	}
+	if _, left, _ := svc.Status(admin.ID); left != 9 {
+		t.Errorf("expected 9 recovery codes left, got %d", left)
+	}
+	if _, err := svc.Verify("not-a-token", next, "10.0.0.1"); !errors.Is(err, services.ErrInvalidMFAToken) {
+		t.Errorf("expected ErrInvalidMFAToken, got %v", err)
+	}
+}
+
+func TestAdminPolicyRequiresMFA(t *testing.T) {
+	svc, users := newMFAService(t, true)
+	admin := mustFindUser(t, users, 1)
+
+	ch, err := svc.Challenge(admin)
+	if err != nil || ch == nil || ch.Purpose != utils.MFAPurposeSetup {
+		t.Fatalf("expected a setup challenge for admins, got %+v, %v", ch, err)
+	}
+	if _, purpose, err := utils.ParseMFAToken(config.Config{JWTIssuer: "users-api"}, ch.Token); err != nil || purpose != utils.MFAPurposeSetup {
+		t.Fatalf("ParseMFAToken() = %q, %v", purpose, err)
+	}
+
+	// el token parcial no sirve como access token: JWTAuth lo rechaza y solo
+	// las rutas de setup (AllowMFASetupToken) lo aceptan
+	gin.SetMode(gin.TestMode)
+	cfg := config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub", JWTExpMinutes: "15"}
+	keys, err := utils.DefaultKeySet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := middleware.JWTAuth(keys, cfg, nil)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r := gin.New()
+	r.GET("/users/me", auth, ok)
+	r.POST("/auth/2fa/setup", middleware.AllowMFASetupToken(cfg, auth), ok)
+	call := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if got := call(http.MethodGet, "/users/me", ch.Token); got != http.StatusUnauthorized {
+		t.Errorf("setup token on a normal route: expected 401, got %d", got)
+	}
+	if got := call(http.MethodPost, "/auth/2fa/setup", ch.Token); got != http.StatusOK {
+		t.Errorf("setup token on the setup route: expected 200, got %d", got)
+	}
+	full, _ := utils.GenerateJWT(cfg, admin)
+	if got := call(http.MethodGet, "/users/me", full); got != http.StatusOK {
+		t.Errorf("full token: expected 200, got %d", got)
+	}
+
+	secret, _, _ := svc.Setup(admin.ID)
+	code, _ := utils.TOTPCode(secret, time.Now())
+	if _, err := svc.Enable(admin.ID, code); err != nil {
+		t.Fatalf("Enable() error: %v", err)
+	}
+	next, _ := utils.TOTPCode(secret, time.Now().Add(30*time.Second))
+	if err := svc.Disable(admin.ID, next, "10.0.0.1"); !errors.Is(err, services.ErrMFARequired) {
+		t.Errorf("admins must not disable 2FA under the policy, got %v", err)
+	}
+
+	// con 2FA activado el login entrega un token de verificación: tampoco sirve
+	// como access token ni en las rutas de setup
+	verify, err := svc.Challenge(admin)
+	if err != nil || verify == nil || verify.Purpose != utils.MFAPurposeVerify {
+		t.Fatalf("expected a verify challenge, got %+v, %v", verify, err)
+	}
+	if got := call(http.MethodGet, "/users/me", verify.Token); got != http.StatusUnauthorized {
+		t.Errorf("verify token on a normal route: expected 401, got %d", got)
+	}
+	if got := call(http.MethodPost, "/auth/2fa/setup", verify.Token); got != http.StatusUnauthorized {
+		t.Errorf("verify token on the setup route: expected 401, got %d", got)
+	}
+}
+
+func TestMFAManagementCodesAreThrottled(t *testing.T) {
+	svc, users := newMFAService(t, false)
+	admin := mustFindUser(t, users, 1)
+	secret, _, _ := svc.Setup(admin.ID)
+	code, _ := utils.TOTPCode(secret, time.Now())
+	if _, err := svc.Enable(admin.ID, code); err != nil {
+		t.Fatalf("Enable() error: %v", err)
+	}
+
+	// con un access token robado no se puede adivinar el TOTP: los fallos de
+	// Disable y RegenerateRecoveryCodes cuentan como en el login (después de
+	// dos fallos libres cada intento tiene que esperar)
+	for i := 0; i < 3; i++ {
+		var err error
+		if i%2 == 0 {
+			err = svc.Disable(admin.ID, "000000", "10.0.0.1")
+		} else {
+			_, err = svc.RegenerateRecoveryCodes(admin.ID, "000000", "10.0.0.1")
+		}
+		if !errors.Is(err, services.ErrInvalidMFACode) {
+			t.Fatalf("attempt %d: expected ErrInvalidMFACode, got %v", i+1, err)
+		}
+	}
+	next, _ := utils.TOTPCode(secret, time.Now().Add(30*time.Second))
+	var throttled *services.ThrottledError
+	if err := svc.Disable(admin.ID, next, "10.0.0.1"); !errors.As(err, &throttled) {
+		t.Fatalf("expected *ThrottledError, got %v", err)
+	}
+	if _, err := svc.RegenerateRecoveryCodes(admin.ID, next, "10.0.0.1"); !errors.As(err, &throttled) {
+		t.Fatalf("expected *ThrottledError, got %v", err)
+	}
+	// el bloqueo también corta el segundo paso del login
+	ch, _ := svc.Challenge(admin)
+	if _, err := svc.Verify(ch.Token, next, "10.0.0.2"); !errors.As(err, &throttled) {
+		t.Fatalf("expected the login step to be throttled too, got %v", err)
+	}
+	if enabled, _, _ := svc.Status(admin.ID); !enabled {
+		t.Error("a throttled Disable must keep two-factor enabled")
+	}
+}
@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/sporthub/users-api/internal/db"
+	"github.com/sporthub/users-api/internal/migrations"
+)
+
+// mysqlForTest abre TEST_MYSQL_DSN (p.ej.
+// "root:root@tcp(localhost:3306)/users_test?parseTime=true")
+// o saltea el test. La base se vacía: tiene que ser una de prueba.
+func mysqlForTest(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	gdb, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("mysql: %v", err)
+	}
+	sqlDB, _ := gdb.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	dropAllTables(t, sqlDB)
+	return sqlDB
+}
+
+func dropAllTables(t *testing.T, sqlDB *sql.DB) {
+	t.Helper()
+	rows, err := sqlDB.Query("SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		_ = rows.Scan(&name)
+		tables = append(tables, name)
+	}
+	rows.Close()
+	for _, name := range tables {
+		if _, err := sqlDB.Exec("DROP TABLE `" + name + "`"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMySQLMigrationsUpAndDown(t *testing.T) {
+	sqlDB := mysqlForTest(t)
+	runner, err := db.NewMigrationRunner(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	migs, _ := migrations.Embedded()
+
+	applied, err := runner.Up(ctx)
+	if err != nil || len(applied) != len(migs) {
+		t.Fatalf("Up() = %d migrations, %v", len(applied), err)
+	}
+	if err := runner.Check(ctx); err != nil {
+		t.Fatalf("Check() after up: %v", err)
+	}
+	store := migrations.NewMySQLStore(sqlDB)
+	cols, err := store.Columns(ctx, "refresh_tokens")
+	if err != nil || len(cols) == 0 || cols[len(cols)-1] != "access_expires_at" {
+		t.Errorf("Columns(refresh_tokens) = %v, %v", cols, err)
+	}
+	if cols, _ := store.Columns(ctx, "no_such_table"); cols != nil {
+		t.Errorf("expected nil columns for a missing table, got %v", cols)
+	}
+
+	reverted, err := runner.Down(ctx, len(migs))
+	if err != nil || len(reverted) != len(migs) {
+		t.Fatalf("Down() = %d migrations, %v", len(reverted), err)
+	}
+	if cols, _ := store.Columns(ctx, "users"); cols != nil {
+		t.Errorf("users should be dropped, has %v", cols)
+	}
+}
+
+func TestMySQLBaselineRefusesOlderTables(t *testing.T) {
+	sqlDB := mysqlForTest(t)
+	// users como la dejaba un AutoMigrate anterior al perfil
+	_, err := sqlDB.Exec(`CREATE TABLE users (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  username VARCHAR(50) NOT NULL,
+  email VARCHAR(120) NOT NULL,
+  password_hash VARCHAR(255) NOT NULL,
+  role VARCHAR(16) NOT NULL,
+  created_at DATETIME(3) NULL,
+  updated_at DATETIME(3) NULL,
+  PRIMARY KEY (id)
+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner, err := db.NewMigrationRunner(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := runner.Up(ctx); !errors.Is(err, migrations.ErrSchemaMismatch) {
+		t.Fatalf("expected ErrSchemaMismatch, got %v", err)
+	}
+	if err := runner.Check(ctx); !errors.Is(err, migrations.ErrPending) {
+		t.Errorf("the baseline must not be recorded, got %v", err)
+	}
+}
+
+func TestMySQLBackfillsEmailVerified(t *testing.T) {
+	sqlDB := mysqlForTest(t)
+	runner, err := db.NewMigrationRunner(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// volvemos a antes del backfill con una cuenta vieja y un alta con link pendiente
+	if _, err := runner.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`INSERT INTO users (id, username, email, password_hash, role, created_at) VALUES
+			(1, 'old', 'old@example.com', 'x', 'user', NOW(3)),
+			(2, 'new', 'new@example.com', 'x', 'user', NOW(3))`,
+		`INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at, created_at)
+			VALUES (2, 'email_verify', 'hash', NOW(3) + INTERVAL 1 DAY, NOW(3))`,
+	} {
+		if _, err := sqlDB.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	verified := func(id int) bool {
+		var v bool
+		if err := sqlDB.QueryRow("SELECT email_verified FROM users WHERE id = ?", id).Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	if !verified(1) {
+		t.Error("existing account should be backfilled as verified")
+	}
+	if verified(2) {
+		t.Error("account with a pending verification link must stay unverified")
+	}
+
+	if _, err := runner.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if verified(1) {
+		t.Error("down should undo the backfill")
+	}
+}
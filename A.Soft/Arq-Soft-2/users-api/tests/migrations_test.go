@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sporthub/users-api/internal/migrations"
+)
+
+var testMigrations = fstest.MapFS{
+	"0001_create_things.up.sql":   {Data: []byte("-- tabla\nCREATE TABLE things (\n  id INT\n);\nCREATE INDEX idx_things ON things (id);\n")},
+	"0001_create_things.down.sql": {Data: []byte("DROP TABLE things;\n")},
+	"0002_seed_things.up.sql":     {Data: []byte("INSERT INTO things VALUES (1);\n")},
+	"0002_seed_things.down.sql":   {Data: []byte("DELETE FROM things;\n")},
+}
+
+func TestEmbeddedMigrationsAreNumberedInOrder(t *testing.T) {
+	migs, err := migrations.Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migs) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+	for i, m := range migs {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d has version %d: versions must be 1..n without gaps", i, m.Version)
+		}
+	}
+}
+
+func TestLoadRequiresUpAndDown(t *testing.T) {
+	fsys := fstest.MapFS{"0001_only_up.up.sql": {Data: []byte("SELECT 1;")}}
+	if _, err := migrations.Load(fsys); err == nil || !strings.Contains(err.Error(), "no down") {
+		t.Errorf("expected missing down error, got %v", err)
+	}
+	fsys = fstest.MapFS{"init.sql": {Data: []byte("SELECT 1;")}}
+	if _, err := migrations.Load(fsys); err == nil {
+		t.Error("expected error for a file without version")
+	}
+}
+
+func TestMigrateUpDownAndCheck(t *testing.T) {
+	migs, err := migrations.Load(testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := migrations.NewMemoryStore()
+	runner := migrations.NewRunner(store, migs)
+	ctx := context.Background()
+
+	if err := runner.Check(ctx); !errors.Is(err, migrations.ErrPending) {
+		t.Fatalf("empty schema: expected ErrPending, got %v", err)
+	}
+	applied, err := runner.Up(ctx)
+	if err != nil || len(applied) != 2 {
+		t.Fatalf("Up() = %d migrations, %v", len(applied), err)
+	}
+	if len(store.Executed) != 3 || store.Executed[1] != "CREATE INDEX idx_things ON things (id)" {
+		t.Errorf("unexpected statements: %q", store.Executed)
+	}
+	if err := runner.Check(ctx); err != nil {
+		t.Errorf("after up: %v", err)
+	}
+	if again, _ := runner.Up(ctx); len(again) != 0 {
+		t.Errorf("up is idempotent, applied %d again", len(again))
+	}
+
+	reverted, err := runner.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Fatalf("Down(1) = %+v, %v", reverted, err)
+	}
+	if err := runner.Check(ctx); !errors.Is(err, migrations.ErrPending) {
+		t.Errorf("after down: expected ErrPending, got %v", err)
+	}
+	statuses, _ := runner.Status(ctx)
+	if !statuses[0].Applied || statuses[1].Applied {
+		t.Errorf("unexpected status: %+v", statuses)
+	}
+}
+
+func TestMigrateRefusesDirtySchema(t *testing.T) {
+	migs, _ := migrations.Load(testMigrations)
+	store := migrations.NewMemoryStore()
+	store.FailOn = func(stmt string) error {
+		if strings.HasPrefix(stmt, "INSERT") {
+			return errors.New("boom")
+		}
+		return nil
+	}
+	runner := migrations.NewRunner(store, migs)
+	ctx := context.Background()
+
+	if _, err := runner.Up(ctx); err == nil {
+		t.Fatal("expected the failing migration to fail")
+	}
+	if err := runner.Check(ctx); !errors.Is(err, migrations.ErrDirty) {
+		t.Errorf("expected ErrDirty, got %v", err)
+	}
+	store.FailOn = nil
+	if _, err := runner.Up(ctx); !errors.Is(err, migrations.ErrDirty) {
+		t.Errorf("up on a dirty schema: expected ErrDirty, got %v", err)
+	}
+}
+
+func TestCheckRejectsUnknownVersion(t *testing.T) {
+	migs, _ := migrations.Load(testMigrations)
+	store := migrations.NewMemoryStore()
+	ctx := context.Background()
+	_ = store.Begin(ctx, 3, "from_a_newer_release")
+	_ = store.Finish(ctx, 3)
+
+	if err := migrations.NewRunner(store, migs).Check(ctx); !errors.Is(err, migrations.ErrUnknownVersion) {
+		t.Errorf("expected ErrUnknownVersion, got %v", err)
+	}
+}
+
+func TestStatementsSplitsEmbeddedDDL(t *testing.T) {
+	migs, err := migrations.Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migs {
+		for _, script := range []string{m.Up, m.Down} {
+			stmts := migrations.Statements(script)
+			if len(stmts) == 0 {
+				t.Errorf("%04d_%s: no statements", m.Version, m.Name)
+			}
+			for _, stmt := range stmts {
+				if strings.HasSuffix(stmt, ";") || strings.Contains(stmt, ";\n") || strings.Contains(stmt, "\n--") {
+					t.Errorf("%04d_%s: badly split statement %q", m.Version, m.Name, stmt)
+				}
+			}
+		}
+	}
+
+	tables := map[string][]string{}
+	for _, stmt := range migrations.Statements(migs[0].Up) {
+		if table, cols, ok := migrations.CreatedTable(stmt); ok {
+			tables[table] = cols
+		}
+	}
+	if len(tables) != 11 {
+		t.Errorf("expected the 11 baseline tables, got %d", len(tables))
+	}
+	users := strings.Join(tables["users"], ",")
+	if !strings.HasPrefix(users, "id,username,email,password_hash,role,") || !strings.HasSuffix(users, ",emergency_contact_phone") {
+		t.Errorf("unexpected users columns: %s", users)
+	}
+	if cols := tables["login_attempts"]; len(cols) != 4 || cols[0] != "key" {
+		t.Errorf("unexpected login_attempts columns: %v", cols)
+	}
+	// ENUM('a', 'b') no corta la columna
+	if cols := tables["memberships"]; len(cols) != 5 || cols[2] != "role" {
+		t.Errorf("unexpected memberships columns: %v", cols)
+	}
+}
+
+func TestUpRefusesTableWithOlderShape(t *testing.T) {
+	migs, err := migrations.Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := migrations.NewMemoryStore()
+	// users de antes del perfil y la verificación de email
+	store.Tables = map[string][]string{
+		"users": {"id", "username", "email", "password_hash", "role", "created_at", "updated_at"},
+	}
+	runner := migrations.NewRunner(store, migs)
+	ctx := context.Background()
+
+	_, err = runner.Up(ctx)
+	if !errors.Is(err, migrations.ErrSchemaMismatch) || !strings.Contains(err.Error(), "email_verified") {
+		t.Fatalf("expected ErrSchemaMismatch naming the missing columns, got %v", err)
+	}
+	if len(store.Executed) != 0 {
+		t.Errorf("nothing must run on a mismatched schema, ran %q", store.Executed)
+	}
+	// no queda registrada (ni dirty): se arregla la tabla y se vuelve a correr
+	if err := runner.Check(ctx); !errors.Is(err, migrations.ErrPending) {
+		t.Errorf("expected ErrPending, got %v", err)
+	}
+
+	store.Tables["users"] = append(store.Tables["users"], "email_verified", "email_verified_at", "display_name",
+		"phone", "date_of_birth", "emergency_contact_name", "emergency_contact_phone")
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatalf("Up() after fixing the table: %v", err)
+	}
+}
@@ -0,0 +1,533 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/controllers"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+const (
+	mockClientID     = "sporthub-web"
+	mockClientSecret = "mock-secret"
+	mockFrontendURL  = "http://frontend.test/auth/callback"
+)
+
+// mockOIDC es un proveedor OpenID Connect mínimo: discovery, authorize (que
+// "loguea" directo a la cuenta de account), token con PKCE S256 y JWKS
+type mockOIDC struct {
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu      sync.Mutex
+	account struct {
+		sub, email string
+		verified   bool
+	}
+	grants map[string]url.Values // code -> parámetros del authorize
+	// claims que se pisan en el id_token (nil borra el claim)
+	claims map[string]any
+}
+
+func newMockOIDC(t *testing.T) *mockOIDC {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockOIDC{key: key, grants: map[string]url.Values{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.srv.URL,
+			"authorization_endpoint": m.srv.URL + "/authorize",
+			"token_endpoint":         m.srv.URL + "/token",
+			"jwks_uri":               m.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "mock-1", "alg": "RS256", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	m.srv = httptest.NewServer(mux)
+	t.Cleanup(m.srv.Close)
+	m.login("google-123", "ana.garcia@example.com", true)
+	return m
+}
+
+// login cambia la cuenta con la que "entra" el próximo authorize
+func (m *mockOIDC) login(sub, email string, verified bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.account.sub, m.account.email, m.account.verified = sub, email, verified
+}
+
+// withClaims pisa claims del próximo id_token (p.ej. como los de Microsoft)
+func (m *mockOIDC) withClaims(claims map[string]any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.claims = claims
+}
+
+func (m *mockOIDC) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != mockClientID ||
+		q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" || q.Get("nonce") == "" {
+		http.Error(w, "bad authorize request", http.StatusBadRequest)
+		return
+	}
+	code, _ := utils.RandomToken(16)
+	m.mu.Lock()
+	q.Set("sub", m.account.sub)
+	q.Set("email", m.account.email)
+	q.Set("verified", map[bool]string{true: "true", false: "false"}[m.account.verified])
+	m.grants[code] = q
+	m.mu.Unlock()
+	http.Redirect(w, r, q.Get("redirect_uri")+"?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode(), http.StatusFound)
+}
+
+func (m *mockOIDC) token(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	m.mu.Lock()
+	grant, ok := m.grants[r.PostForm.Get("code")]
+	delete(m.grants, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("client_id") != mockClientID ||
+		r.PostForm.Get("client_secret") != mockClientSecret:
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	case !ok || r.PostForm.Get("redirect_uri") != grant.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != grant.Get("code_challenge"):
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+	claims := jwt.MapClaims{
+		"iss":            m.srv.URL,
+		"aud":            mockClientID,
+		"sub":            grant.Get("sub"),
+		"email":          grant.Get("email"),
+		"email_verified": grant.Get("verified") == "true",
+		"name":           "Ana García",
+		"nonce":          grant.Get("nonce"),
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+	}
+	m.mu.Lock()
+	for k, v := range m.claims {
+		if v == nil {
+			delete(claims, k)
+		} else {
+			claims[k] = v
+		}
+	}
+	m.mu.Unlock()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = "mock-1"
+	signed, _ := idToken.SignedString(m.key)
+	_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "opaque", "token_type": "Bearer", "id_token": signed})
+}
+
+func oidcConfig(m *mockOIDC, apiURL string) config.Config {
+	return config.Config{
+		JWTIssuer: "users-api", JWTAudience: "sporthub", JWTExpMinutes: "15", RefreshTokenTTLHours: "1",
+		OIDCProviders: []config.OIDCProvider{{
+			ID: "mock", Name: "Mock", Issuer: m.srv.URL, ClientID: mockClientID, ClientSecret: mockClientSecret,
+			Scopes: "openid email profile",
+		}},
+		OIDCRedirectBaseURL: apiURL,
+		OIDCFrontendURL:     mockFrontendURL,
+	}
+}
+
+// noRedirects no sigue redirects: cada paso se sigue a mano
+var noRedirects = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+// authorizeAt manda el navegador al proveedor y devuelve state y code del
+// redirect de vuelta
+func authorizeAt(t *testing.T, authURL string) (state, code string) {
+	t.Helper()
+	res, err := noRedirects.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	res.Body.Close()
+	back, err := url.Parse(res.Header.Get("Location"))
+	if err != nil || res.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: expected a redirect back, got %d %q", res.StatusCode, res.Header.Get("Location"))
+	}
+	return back.Query().Get("state"), back.Query().Get("code")
+}
+
+// stateCookie es la cookie que dejó /login en el navegador
+func stateCookie(t *testing.T, res *http.Response) *http.Cookie {
+	t.Helper()
+	for _, c := range res.Cookies() {
+		if c.Name == "oidc_state" {
+			return c
+		}
+	}
+	t.Fatal("login did not set the oidc_state cookie")
+	return nil
+}
+
+// oidcCallback vuelve del proveedor al callback, con la cookie si hay
+func oidcCallback(t *testing.T, callbackURL string, cookie *http.Cookie) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, callbackURL, nil)
+	if cookie != nil {
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	res, err := noRedirects.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res
+}
+
+func newOIDCService(t *testing.T, m *mockOIDC) (services.OIDCService, repository.UsersRepo) {
+	t.Helper()
+	repos := repository.NewMemoryRepos()
+	svc := services.NewOIDCService(repos, oidcConfig(m, "http://users-api.test"))
+	return svc, repos.Users
+}
+
+// oidcLogin hace el login completo a nivel servicio y devuelve el usuario
+func oidcLogin(t *testing.T, svc services.OIDCService) (*domain.User, error) {
+	t.Helper()
+	ctx := context.Background()
+	start, err := svc.Start(ctx, "mock", "")
+	if err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	state, code := authorizeAt(t, start.AuthURL)
+	res, err := svc.Callback(ctx, "mock", state, code)
+	if err != nil {
+		return nil, err
+	}
+	return svc.Exchange(res.Code)
+}
+
+func TestOIDCLoginCreatesUserAndIssuesTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newMockOIDC(t)
+	users := repository.NewUsersMemory()
+	r := gin.New()
+	api := httptest.NewServer(r)
+	defer api.Close()
+
+	cfg := oidcConfig(m, api.URL)
+	guard := services.NewLoginGuard(repository.NewLoginAttemptsMemory(), cfg)
+	tokens := services.NewTokenServiceFromRepos(users, repository.NewTokensMemory(), repository.NewOrgsMemory(), cfg)
+	mfa := services.NewMFAServiceFromRepos(repository.NewMFAMemory(), users, guard, cfg)
+	repos := repository.NewMemoryRepos()
+	repos.Users = users
+	oidc := services.NewOIDCService(repos, cfg)
+	ctl := controllers.NewOIDCController(oidc, mfa, tokens)
+	r.GET("/auth/oidc/providers", ctl.Providers)
+	r.GET("/auth/oidc/:provider/login", ctl.Login)
+	r.GET("/auth/oidc/:provider/callback", ctl.Callback)
+	r.POST("/auth/oidc/exchange", ctl.Exchange)
+
+	// login -> proveedor -> callback -> frontend con ?code=
+	res, err := noRedirects.Get(api.URL + "/auth/oidc/mock/login")
+	if err != nil || res.StatusCode != http.StatusFound {
+		t.Fatalf("login: expected redirect to the provider, got %v %v", res.StatusCode, err)
+	}
+	res.Body.Close()
+	if !strings.HasPrefix(res.Header.Get("Location"), m.srv.URL+"/authorize?") {
+		t.Fatalf("unexpected provider URL %q", res.Header.Get("Location"))
+	}
+	state, code := authorizeAt(t, res.Header.Get("Location"))
+	res = oidcCallback(t, api.URL+"/auth/oidc/mock/callback?"+url.Values{"state": {state}, "code": {code}}.Encode(), stateCookie(t, res))
+	front, _ := url.Parse(res.Header.Get("Location"))
+	if !strings.HasPrefix(front.String(), mockFrontendURL) || front.Query().Get("code") == "" {
+		t.Fatalf("expected a redirect to the frontend with a code, got %q", res.Header.Get("Location"))
+	}
+
+	exchange := func() *http.Response {
+		body, _ := json.Marshal(map[string]string{"code": front.Query().Get("code")})
+		res, err := http.Post(api.URL+"/auth/oidc/exchange", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	res = exchange()
+	var out struct {
+		Token         string `json:"token"`
+		UserID        uint64 `json:"userId"`
+		EmailVerified bool   `json:"emailVerified"`
+	}
+	_ = json.NewDecoder(res.Body).Decode(&out)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || out.Token == "" || !out.EmailVerified {
+		t.Fatalf("exchange: expected tokens for a verified user, got %d %+v", res.StatusCode, out)
+	}
+	u := mustFindUser(t, users, out.UserID)
+	if u.Email != "ana.garcia@example.com" || u.Username != "ana.garcia" || u.Role != domain.RoleUser {
+		t.Errorf("unexpected user created: %+v", u)
+	}
+	if res := exchange(); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("login code must be single-use, got %d", res.StatusCode)
+	}
+}
+
+func TestOIDCLinksExistingVerifiedAccountBySubject(t *testing.T) {
+	m := newMockOIDC(t)
+	svc, users := newOIDCService(t, m)
+	now := time.Now()
+	_ = users.Create(&domain.User{Username: "ana", Email: "ana.garcia@example.com", Role: domain.RoleInstructor, EmailVerified: true, EmailVerifiedAt: &now})
+
+	u, err := oidcLogin(t, svc)
+	if err != nil {
+		t.Fatalf("login error: %v", err)
+	}
+	if u.ID != 1 || u.Role != domain.RoleInstructor {
+		t.Fatalf("expected the existing account to be linked, got %+v", u)
+	}
+	// ya vinculada, la identidad se encuentra por sub aunque cambie el email
+	m.login("google-123", "ana@otro.com", true)
+	if u, err := oidcLogin(t, svc); err != nil || u.ID != 1 {
+		t.Errorf("expected the linked account again, got %+v %v", u, err)
+	}
+	if _, total, _ := users.List(repository.UserFilter{Limit: 10}); total != 1 {
+		t.Errorf("no new user should be created, got %d users", total)
+	}
+}
+
+func TestOIDCRequiresVerifiedEmails(t *testing.T) {
+	m := newMockOIDC(t)
+	svc, users := newOIDCService(t, m)
+
+	m.login("ms-1", "bob@example.com", false)
+	if _, err := oidcLogin(t, svc); !errors.Is(err, services.ErrOIDCEmailRequired) {
+		t.Errorf("expected ErrOIDCEmailRequired, got %v", err)
+	}
+	// una cuenta local sin verificar no se vincula (la podría haber
+	// registrado cualquiera con ese email)
+	_ = users.Create(&domain.User{Username: "carla", Email: "carla@example.com", Role: domain.RoleUser})
+	m.login("ms-2", "carla@example.com", true)
+	if _, err := oidcLogin(t, svc); !errors.Is(err, services.ErrOIDCAccountPending) {
+		t.Errorf("expected ErrOIDCAccountPending, got %v", err)
+	}
+}
+
+func TestOIDCStateIsSingleUseAndBoundToPKCE(t *testing.T) {
+	m := newMockOIDC(t)
+	svc, _ := newOIDCService(t, m)
+	ctx := context.Background()
+
+	first, _ := svc.Start(ctx, "mock", "")
+	second, _ := svc.Start(ctx, "mock", "")
+	_, codeA := authorizeAt(t, first.AuthURL)
+	stateB, _ := authorizeAt(t, second.AuthURL)
+	// el code del primer login con el state (y el verifier) del segundo
+	if _, err := svc.Callback(ctx, "mock", stateB, codeA); err == nil {
+		t.Fatal("a code must not be redeemable with another login's verifier")
+	}
+	if _, err := svc.Callback(ctx, "mock", stateB, codeA); !errors.Is(err, services.ErrInvalidOIDCState) {
+		t.Errorf("state must be single-use, got %v", err)
+	}
+	if _, err := svc.Start(ctx, "nope", ""); !errors.Is(err, services.ErrUnknownProvider) {
+		t.Errorf("expected ErrUnknownProvider, got %v", err)
+	}
+}
+
+func TestOIDCCallbackRequiresTheBrowsersStateCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newMockOIDC(t)
+	r := gin.New()
+	api := httptest.NewServer(r)
+	defer api.Close()
+
+	cfg := oidcConfig(m, api.URL)
+	repos := repository.NewMemoryRepos()
+	guard := services.NewLoginGuard(repository.NewLoginAttemptsMemory(), cfg)
+	tokens := services.NewTokenServiceFromRepos(repos.Users, repository.NewTokensMemory(), repository.NewOrgsMemory(), cfg)
+	mfa := services.NewMFAServiceFromRepos(repository.NewMFAMemory(), repos.Users, guard, cfg)
+	ctl := controllers.NewOIDCController(services.NewOIDCService(repos, cfg), mfa, tokens)
+	r.GET("/auth/oidc/:provider/login", ctl.Login)
+	r.GET("/auth/oidc/:provider/callback", ctl.Callback)
+
+	res, err := noRedirects.Get(api.URL + "/auth/oidc/mock/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	cookie := stateCookie(t, res)
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/auth/oidc/mock/callback" {
+		t.Errorf("state cookie must be HttpOnly, SameSite=Lax and scoped to the callback, got %+v", cookie)
+	}
+	state, code := authorizeAt(t, res.Header.Get("Location"))
+	callbackURL := api.URL + "/auth/oidc/mock/callback?" + url.Values{"state": {state}, "code": {code}}.Encode()
+
+	// el atacante le hace abrir a la víctima su propio callback: sin la
+	// cookie (o con la de otro login) no se completa
+	for _, c := range []*http.Cookie{nil, {Name: "oidc_state", Value: "otro-state"}} {
+		front, _ := url.Parse(oidcCallback(t, callbackURL, c).Header.Get("Location"))
+		if front.Query().Get("error") != "invalid_state" || front.Query().Get("code") != "" {
+			t.Fatalf("callback without the login's cookie must fail, got %q", front)
+		}
+	}
+	// el navegador que empezó el login sí lo completa (el state no se gastó)
+	front, _ := url.Parse(oidcCallback(t, callbackURL, cookie).Header.Get("Location"))
+	if front.Query().Get("code") == "" {
+		t.Fatalf("expected a login code for the browser that started the login, got %q", front)
+	}
+}
+
+func TestOIDCWithoutEmailVerifiedNeverLinksByEmail(t *testing.T) {
+	m := newMockOIDC(t)
+	svc, users := newOIDCService(t, m)
+	now := time.Now()
+	_ = users.Create(&domain.User{Username: "ana", Email: "ana.garcia@example.com", Role: domain.RoleAdmin, EmailVerified: true, EmailVerifiedAt: &now})
+
+	// como Microsoft: sin email_verified (y cualquiera puede poner ese email
+	// en una cuenta personal)
+	m.withClaims(map[string]any{"email_verified": nil})
+	if _, err := oidcLogin(t, svc); !errors.Is(err, services.ErrOIDCEmailRequired) {
+		t.Fatalf("expected ErrOIDCEmailRequired without email_verified, got %v", err)
+	}
+	m.withClaims(map[string]any{"email_verified": nil, "xms_edov": false})
+	if _, err := oidcLogin(t, svc); !errors.Is(err, services.ErrOIDCEmailRequired) {
+		t.Fatalf("expected ErrOIDCEmailRequired with xms_edov=false, got %v", err)
+	}
+	// xms_edov: el dominio del email es del tenant que lo emitió
+	m.withClaims(map[string]any{"email_verified": nil, "xms_edov": true})
+	if u, err := oidcLogin(t, svc); err != nil || u.ID != 1 {
+		t.Fatalf("expected the account linked through xms_edov, got %+v %v", u, err)
+	}
+}
+
+func TestOIDCManualLinkFromALoggedInSession(t *testing.T) {
+	m := newMockOIDC(t)
+	svc, users := newOIDCService(t, m)
+	ctx := context.Background()
+	_ = users.Create(&domain.User{Username: "bob", Email: "bob@example.com", Role: domain.RoleUser})
+	_ = users.Create(&domain.User{Username: "carla", Email: "carla@example.com", Role: domain.RoleUser})
+	m.login("ms-1", "bob@outlook.com", false)
+	m.withClaims(map[string]any{"email_verified": nil})
+
+	link := func(userID uint64) (*services.OIDCResult, error) {
+		t.Helper()
+		ticketURL, err := svc.Ticket(userID, "mock", domain.OIDCPurposeLink)
+		if err != nil {
+			t.Fatalf("Ticket() error: %v", err)
+		}
+		u, _ := url.Parse(ticketURL)
+		start, err := svc.Start(ctx, "mock", u.Query().Get("ticket"))
+		if err != nil {
+			t.Fatalf("Start() with ticket error: %v", err)
+		}
+		if _, err := svc.Start(ctx, "mock", u.Query().Get("ticket")); !errors.Is(err, services.ErrInvalidOIDCTicket) {
+			t.Errorf("tickets must be single-use, got %v", err)
+		}
+		state, code := authorizeAt(t, start.AuthURL)
+		return svc.Callback(ctx, "mock", state, code)
+	}
+
+	res, err := link(1)
+	if err != nil || res.Purpose != domain.OIDCPurposeLink || res.Code != "" {
+		t.Fatalf("expected the account to be linked, got %+v %v", res, err)
+	}
+	// ahora entra por sub aunque el proveedor no verifique el email
+	if u, err := oidcLogin(t, svc); err != nil || u.ID != 1 {
+		t.Fatalf("expected to log in as the linked user, got %+v %v", u, err)
+	}
+	// la misma cuenta externa no se puede vincular a otro usuario
+	if _, err := link(2); !errors.Is(err, services.ErrOIDCIdentityInUse) {
+		t.Errorf("expected ErrOIDCIdentityInUse, got %v", err)
+	}
+	if _, err := svc.Start(ctx, "mock", "not-a-ticket"); !errors.Is(err, services.ErrInvalidOIDCTicket) {
+		t.Errorf("expected ErrInvalidOIDCTicket, got %v", err)
+	}
+}
+
+func TestOIDCOnlyUserDeletesAccountByReauthenticating(t *testing.T) {
+	m := newMockOIDC(t)
+	repos := repository.NewMemoryRepos()
+	cfg := oidcConfig(m, "http://users-api.test")
+	svc := services.NewOIDCService(repos, cfg)
+	users := services.NewUsersService(repos, services.NewLoginGuard(repository.NewLoginAttemptsMemory(), cfg), cfg)
+	ctx := context.Background()
+
+	// entró siempre con Google: tiene una contraseña aleatoria que no conoce
+	u, err := oidcLogin(t, svc)
+	if err != nil {
+		t.Fatalf("login error: %v", err)
+	}
+	reauth := func(userID uint64) (*services.OIDCResult, error) {
+		t.Helper()
+		ticketURL, err := svc.Ticket(userID, "mock", domain.OIDCPurposeReauth)
+		if err != nil {
+			t.Fatalf("Ticket() error: %v", err)
+		}
+		ticket, _ := url.Parse(ticketURL)
+		start, err := svc.Start(ctx, "mock", ticket.Query().Get("ticket"))
+		if err != nil {
+			t.Fatalf("Start() error: %v", err)
+		}
+		state, code := authorizeAt(t, start.AuthURL)
+		return svc.Callback(ctx, "mock", state, code)
+	}
+
+	// con una cuenta externa que no es la suya no hay prueba
+	_ = repos.Users.Create(&domain.User{Username: "bob", Email: "bob@example.com", Role: domain.RoleUser})
+	if _, err := reauth(2); !errors.Is(err, services.ErrOIDCReauthMismatch) {
+		t.Fatalf("expected ErrOIDCReauthMismatch, got %v", err)
+	}
+	res, err := reauth(u.ID)
+	if err != nil || res.Purpose != domain.OIDCPurposeReauth || res.Code == "" {
+		t.Fatalf("expected a reauthentication code, got %+v %v", res, err)
+	}
+	if err := users.DeleteAccount(2, "", res.Code, "10.0.0.1"); !errors.Is(err, services.ErrInvalidReauth) {
+		t.Errorf("the code belongs to its user only, got %v", err)
+	}
+	if err := users.DeleteAccount(u.ID, "", res.Code, "10.0.0.1"); err != nil {
+		t.Fatalf("DeleteAccount() with reauth error: %v", err)
+	}
+	if err := users.DeleteAccount(u.ID, "", res.Code, "10.0.0.1"); err == nil {
+		t.Error("the reauthentication code must be single-use")
+	}
+	// la baja desvincula la cuenta externa: el mismo sub vuelve a ser nuevo
+	if ids, _ := repos.Identities.ListByUser(u.ID); len(ids) != 0 {
+		t.Errorf("identities must be deleted with the user, got %+v", ids)
+	}
+	again, err := oidcLogin(t, svc)
+	if err != nil || again.ID == u.ID {
+		t.Errorf("expected a brand new account after deletion, got %+v %v", again, err)
+	}
+}
@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/shared/authz"
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/controllers"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/middleware"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+var orgsCfg = config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub", JWTExpMinutes: "15", RefreshTokenTTLHours: "1", DefaultOrgID: "1"}
+
+func newOrgsService(t *testing.T) (services.OrgsService, repository.OrgsRepo, repository.UsersRepo) {
+	t.Helper()
+	users := repository.NewUsersMemory()
+	for _, name := range []string{"root", "ana", "bob"} {
+		_ = users.Create(&domain.User{Username: name, Email: name + "@example.com", Role: domain.RoleUser})
+	}
+	repo := repository.NewOrgsMemory()
+	svc := services.NewOrgsServiceFromRepos(repo, users, orgsCfg)
+	if err := svc.EnsureDefault(); err != nil {
+		t.Fatal(err)
+	}
+	return svc, repo, users
+}
+
+func TestCreateOrgMakesCreatorAdmin(t *testing.T) {
+	svc, _, _ := newOrgsService(t)
+
+	org, err := svc.Create("Club Norte", "club-norte", 2)
+	if err != nil || org.ID == 1 {
+		t.Fatalf("Create() = %+v, %v", org, err)
+	}
+	if _, err := svc.Create("Otro", "club-norte", 3); !errors.Is(err, services.ErrOrgSlugTaken) {
+		t.Errorf("expected ErrOrgSlugTaken, got %v", err)
+	}
+	if _, err := svc.Create("Malo", "Club Norte!", 3); !errors.Is(err, services.ErrInvalidOrgSlug) {
+		t.Errorf("expected ErrInvalidOrgSlug, got %v", err)
+	}
+	mine, _ := svc.ListForUser(2)
+	if len(mine) != 1 || mine[0].ID != org.ID || mine[0].Role != domain.OrgRoleAdmin {
+		t.Errorf("unexpected organizations for the creator: %+v", mine)
+	}
+}
+
+func TestOrgKeepsAtLeastOneAdmin(t *testing.T) {
+	svc, _, _ := newOrgsService(t)
+	org, _ := svc.Create("Club Norte", "club-norte", 2)
+
+	if _, err := svc.SetMember(org.ID, 2, domain.OrgRoleMember); !errors.Is(err, services.ErrLastOrgAdmin) {
+		t.Errorf("demoting the only admin: expected ErrLastOrgAdmin, got %v", err)
+	}
+	if err := svc.RemoveMember(org.ID, 2); !errors.Is(err, services.ErrLastOrgAdmin) {
+		t.Errorf("removing the only admin: expected ErrLastOrgAdmin, got %v", err)
+	}
+	if _, err := svc.SetMember(org.ID, 3, domain.OrgRoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.RemoveMember(org.ID, 2); err != nil {
+		t.Errorf("with another admin the first one can leave: %v", err)
+	}
+	if _, err := svc.SetMember(org.ID, 99, domain.OrgRoleMember); !errors.Is(err, services.ErrOrgUserMissing) {
+		t.Errorf("expected ErrOrgUserMissing, got %v", err)
+	}
+	if _, err := svc.SetMember(org.ID, 3, "owner"); !errors.Is(err, services.ErrInvalidOrgRole) {
+		t.Errorf("expected ErrInvalidOrgRole, got %v", err)
+	}
+}
+
+func TestOrgPermissionsAreScopedPerOrg(t *testing.T) {
+	orgs := domain.OrgPermissions("1", domain.RoleUser, []domain.Membership{{OrgID: 7, UserID: 2, Role: domain.OrgRoleAdmin}})
+
+	if !slices.Contains(orgs["7"], authz.PermActivityManageAny) || !slices.Contains(orgs["7"], authz.PermOrgManage) {
+		t.Errorf("an org admin manages everything in the org: %v", orgs["7"])
+	}
+	if slices.Contains(orgs["7"], authz.PermUserManage) || slices.Contains(orgs["7"], authz.PermSearchReindex) {
+		t.Errorf("an org admin has no global permissions: %v", orgs["7"])
+	}
+	if !slices.Equal(orgs["1"], domain.PermissionsFor(domain.RoleUser)) {
+		t.Errorf("the default org gets the global role permissions: %v", orgs["1"])
+	}
+}
+
+func TestRequireOrgPermissionOnlyForOwnOrg(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc, repo, users := newOrgsService(t)
+	org, _ := svc.Create("Club Norte", "club-norte", 2)
+	other, _ := svc.Create("Club Sur", "club-sur", 3)
+
+	tokens := services.NewTokenServiceFromRepos(users, repository.NewTokensMemory(), repo, orgsCfg)
+	keys, err := utils.DefaultKeySet(orgsCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/orgs/:id/members", middleware.JWTAuth(keys, orgsCfg, nil), middleware.RequireOrgPermission("id", authz.PermOrgManage),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	ana, _ := users.FindByID(2)
+	issued, err := tokens.Issue(ana)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(orgID uint64) int {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/orgs/%d/members", orgID), nil)
+		req.Header.Set("Authorization", "Bearer "+issued.AccessToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if got := get(org.ID); got != http.StatusOK {
+		t.Errorf("own org: expected 200, got %d", got)
+	}
+	if got := get(other.ID); got != http.StatusForbidden {
+		t.Errorf("another org: expected 403, got %d", got)
+	}
+	if got := get(1); got != http.StatusForbidden {
+		t.Errorf("default org without the global permission: expected 403, got %d", got)
+	}
+}
+
+func TestOrgMemberReadableByServicesAndTheMember(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc, repo, users := newOrgsService(t)
+	org, _ := svc.Create("Club Norte", "club-norte", 2)
+	if _, err := svc.SetMember(org.ID, 3, domain.OrgRoleInstructor); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := services.NewTokenServiceFromRepos(users, repository.NewTokensMemory(), repo, orgsCfg)
+	keys, err := utils.DefaultKeySet(orgsCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/orgs/:id/members/:userId", middleware.AllowServiceToken(orgsCfg, middleware.JWTAuth(keys, orgsCfg, nil)), controllers.NewOrgsController(svc).Member)
+
+	service, err := utils.GenerateServiceToken(orgsCfg, "activities-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenOf := func(id uint64) string {
+		u, _ := users.FindByID(id)
+		issued, err := tokens.Issue(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return issued.AccessToken
+	}
+	get := func(token string, userID uint64) int {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/orgs/%d/members/%d", org.ID, userID), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if got := get(service, 3); got != http.StatusOK {
+		t.Errorf("service token: expected 200, got %d", got)
+	}
+	if got := get(service, 1); got != http.StatusNotFound {
+		t.Errorf("non member: expected 404, got %d", got)
+	}
+	if got := get(tokenOf(3), 3); got != http.StatusOK {
+		t.Errorf("own membership: expected 200, got %d", got)
+	}
+	if got := get(tokenOf(2), 3); got != http.StatusOK {
+		t.Errorf("club admin: expected 200, got %d", got)
+	}
+	if got := get(tokenOf(1), 3); got != http.StatusForbidden {
+		t.Errorf("another user: expected 403, got %d", got)
+	}
+}
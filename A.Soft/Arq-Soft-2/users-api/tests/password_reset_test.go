@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/mailer"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+// fakeSender guarda los emails enviados
+type fakeSender struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (f *fakeSender) Send(_ context.Context, msg mailer.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+// waitSent espera a que se hayan enviado n emails (envíos en background)
+func (f *fakeSender) waitSent(t *testing.T, n int) []mailer.Message {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		f.mu.Lock()
+		sent := append([]mailer.Message(nil), f.sent...)
+		f.mu.Unlock()
+		if len(sent) >= n {
+			return sent
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d emails", n)
+	return nil
+}
+
+var emailTokenRe = regexp.MustCompile(`\?token=(\S+)`)
+
+func tokenFromEmail(t *testing.T, msg mailer.Message) string {
+	t.Helper()
+	m := emailTokenRe.FindStringSubmatch(msg.Body)
+	if m == nil {
+		t.Fatalf("no token link in email: %q", msg.Body)
+	}
+	token, _ := url.QueryUnescape(m[1])
+	return token
+}
+
+func newPasswordService(t *testing.T) (services.PasswordService, services.TokenService, repository.UsersRepo, *fakeSender) {
+	t.Helper()
+	return newPasswordServiceOn(t, repository.NewUsersMemory())
+}
+
+// newPasswordServiceOn arma el servicio sobre users, con la usuaria ana (id 1)
+func newPasswordServiceOn(t *testing.T, users repository.UsersRepo) (services.PasswordService, services.TokenService, repository.UsersRepo, *fakeSender) {
+	t.Helper()
+	hash, _ := utils.HashPassword("old-secret")
+	_ = users.Create(&domain.User{Username: "ana", Email: "ana@example.com", PasswordHash: hash, Role: domain.RoleUser})
+	cfg := config.Config{
+		JWTIssuer: "users-api", JWTAudience: "sporthub", JWTExpMinutes: "15", RefreshTokenTTLHours: "1",
+		PasswordResetURL: "http://localhost:3000/reset-password", PasswordResetTTLMinutes: "60",
+		PasswordForgotMaxPerEmail: "2", PasswordForgotMaxPerIP: "3", LoginMaxFailures: "3",
+	}
+	tokens := services.NewTokenServiceFromRepos(users, repository.NewTokensMemory(), repository.NewOrgsMemory(), cfg)
+	mail := &fakeSender{}
+	attempts := repository.NewLoginAttemptsMemory()
+	repos := repository.Repos{Users: users, UserTokens: repository.NewUserTokensMemory(), LoginAttempts: attempts}
+	svc := services.NewPasswordServiceFromRepos(repos, tokens, services.NewLoginGuard(attempts, cfg), mail, cfg)
+	return svc, tokens, users, mail
+}
+
+func TestForgotUnknownEmailSendsNothing(t *testing.T) {
+	svc, _, _, mail := newPasswordService(t)
+	if err := svc.Forgot(context.Background(), "nobody@example.com"); err != nil {
+		t.Fatalf("Forgot() must not fail for unknown emails: %v", err)
+	}
+	if len(mail.sent) != 0 {
+		t.Errorf("expected no email, got %d", len(mail.sent))
+	}
+}
+
+func TestResetChangesPasswordAndRevokesSessions(t *testing.T) {
+	svc, tokens, users, mail := newPasswordService(t)
+	u := mustFindUser(t, users, 1)
+	session, _ := tokens.Issue(u)
+
+	if err := svc.Forgot(context.Background(), "ana@example.com"); err != nil {
+		t.Fatalf("Forgot() error: %v", err)
+	}
+	if len(mail.sent) != 1 || mail.sent[0].To != "ana@example.com" {
+		t.Fatalf("expected one email to ana, got %+v", mail.sent)
+	}
+	token := tokenFromEmail(t, mail.sent[0])
+
+	if err := svc.Reset(token, "new-secret"); err != nil {
+		t.Fatalf("Reset() error: %v", err)
+	}
+	if !utils.CheckPasswordHash("new-secret", mustFindUser(t, users, u.ID).PasswordHash) {
+		t.Error("password was not updated")
+	}
+	if _, _, err := tokens.Refresh(session.RefreshToken); err == nil {
+		t.Error("existing sessions should be revoked after a reset")
+	}
+	// y el access token que ya tenía la sesión tampoco sirve más
+	if !tokens.IsRevoked(jtiOf(t, session.AccessToken)) {
+		t.Error("access tokens issued before the reset must be revoked")
+	}
+	if err := svc.Reset(token, "another-secret"); !errors.Is(err, services.ErrInvalidResetToken) {
+		t.Errorf("reset token must be single-use, got %v", err)
+	}
+}
+
+// failingPasswords es un UsersRepo cuyo UpdatePassword falla mientras fail
+// esté en true
+type failingPasswords struct {
+	repository.UsersRepo
+	fail bool
+}
+
+func (f *failingPasswords) UpdatePassword(id uint64, passwordHash string) error {
+	if f.fail {
+		return errors.New("mysql: connection lost")
+	}
+	return f.UsersRepo.UpdatePassword(id, passwordHash)
+}
+
+func TestResetTokenSurvivesAFailedPasswordUpdate(t *testing.T) {
+	users := &failingPasswords{UsersRepo: repository.NewUsersMemory(), fail: true}
+	svc, _, _, mail := newPasswordServiceOn(t, users)
+	_ = svc.Forgot(context.Background(), "ana@example.com")
+	token := tokenFromEmail(t, mail.sent[0])
+
+	if err := svc.Reset(token, "new-secret"); err == nil || errors.Is(err, services.ErrInvalidResetToken) {
+		t.Fatalf("expected the update error, got %v", err)
+	}
+	// el link no se gastó: se puede reintentar
+	users.fail = false
+	if err := svc.Reset(token, "new-secret"); err != nil {
+		t.Fatalf("the reset token must still work after a failed update: %v", err)
+	}
+	if !utils.CheckPasswordHash("new-secret", mustFindUser(t, users, 1).PasswordHash) {
+		t.Error("password was not updated")
+	}
+}
+
+func TestOnlyLatestResetTokenIsValid(t *testing.T) {
+	svc, _, _, mail := newPasswordService(t)
+	_ = svc.Forgot(context.Background(), "ana@example.com")
+	_ = svc.Forgot(context.Background(), "ana@example.com")
+	first, second := tokenFromEmail(t, mail.sent[0]), tokenFromEmail(t, mail.sent[1])
+
+	if err := svc.Reset(first, "new-secret"); !errors.Is(err, services.ErrInvalidResetToken) {
+		t.Errorf("older reset token should be invalidated, got %v", err)
+	}
+	if err := svc.Reset("bogus", "new-secret"); !errors.Is(err, services.ErrInvalidResetToken) {
+		t.Errorf("expected ErrInvalidResetToken, got %v", err)
+	}
+	if err := svc.Reset(second, "new-secret"); err != nil {
+		t.Errorf("latest reset token should work: %v", err)
+	}
+}
+
+func TestChangeRevokesOtherSessionsAccessTokens(t *testing.T) {
+	svc, tokens, users, _ := newPasswordService(t)
+	other, _ := tokens.Issue(mustFindUser(t, users, 1))
+	if _, err := svc.Change(1, "old-secret", "new-secret", "10.0.0.1"); err != nil {
+		t.Fatalf("Change() error: %v", err)
+	}
+	if !tokens.IsRevoked(jtiOf(t, other.AccessToken)) {
+		t.Error("access tokens issued before the change must be revoked")
+	}
+}
+
+func TestRequestResetIsRateLimitedPerEmailAndIP(t *testing.T) {
+	svc, _, _, mail := newPasswordService(t)
+
+	// 2 por email por hora: el tercero se rechaza, venga de la IP que venga
+	for i, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		if err := svc.RequestReset("ana@example.com", ip); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	var throttled *services.ThrottledError
+	if err := svc.RequestReset(" ANA@example.com", "10.0.0.3"); !errors.As(err, &throttled) {
+		t.Fatalf("expected the email to be throttled, got %v", err)
+	}
+	// los envíos salen en background
+	if sent := mail.waitSent(t, 2); sent[0].To != "ana@example.com" {
+		t.Errorf("unexpected email: %+v", sent[0])
+	}
+
+	// 3 por IP por hora, existan o no los emails
+	for i := 0; i < 2; i++ {
+		if err := svc.RequestReset("nadie"+string(rune('a'+i))+"@example.com", "10.0.0.1"); err != nil {
+			t.Fatalf("request from IP %d: %v", i+1, err)
+		}
+	}
+	if err := svc.RequestReset("otro@example.com", "10.0.0.1"); !errors.As(err, &throttled) {
+		t.Fatalf("expected the IP to be throttled, got %v", err)
+	}
+}
+
+func TestLogSenderRedactsLinkTokens(t *testing.T) {
+	body := "Entra a:\n\nhttp://localhost:3000/reset-password?token=abc123XYZ\n\ny http://x/verify?a=1&code=999 listo"
+	got := mailer.Redact(body)
+	if strings.Contains(got, "abc123XYZ") || strings.Contains(got, "999") {
+		t.Errorf("tokens must be redacted: %q", got)
+	}
+	if !strings.Contains(got, "reset-password?token=[redacted]") || !strings.Contains(got, "?a=1&code=[redacted] listo") {
+		t.Errorf("the rest of the email must stay: %q", got)
+	}
+}
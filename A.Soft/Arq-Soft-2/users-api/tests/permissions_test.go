@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/sporthub/shared/authz"
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/middleware"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+func TestRolePermissions(t *testing.T) {
+	user := domain.PermissionsFor(domain.RoleUser)
+	instructor := domain.PermissionsFor(domain.RoleInstructor)
+	admin := domain.PermissionsFor(domain.RoleAdmin)
+
+	for _, p := range user {
+		if !slices.Contains(instructor, p) || !slices.Contains(admin, p) {
+			t.Errorf("instructors and admins must also have %s", p)
+		}
+	}
+	if !slices.Contains(instructor, authz.PermActivityCreate) || slices.Contains(instructor, authz.PermActivityManageAny) {
+		t.Errorf("instructors manage only their own activities: %v", instructor)
+	}
+	if slices.Contains(instructor, authz.PermUserRead) || !slices.Contains(admin, authz.PermUserManage) {
+		t.Errorf("unexpected user permissions: instructor %v, admin %v", instructor, admin)
+	}
+	if domain.PermissionsFor("normal") != nil {
+		t.Error("unknown roles have no permissions")
+	}
+}
+
+func TestRequirePermissionUsesTokenClaim(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub", JWTExpMinutes: "15"}
+	keys, err := utils.DefaultKeySet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/users", middleware.JWTAuth(keys, cfg, nil), authz.RequirePermission(authz.PermUserRead),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for role, want := range map[domain.Role]int{
+		domain.RoleUser:       http.StatusForbidden,
+		domain.RoleInstructor: http.StatusForbidden,
+		domain.RoleAdmin:      http.StatusOK,
+	} {
+		token, _ := utils.GenerateJWT(cfg, &domain.User{ID: 7, Role: role})
+		if got := get(token); got != want {
+			t.Errorf("%s: expected %d, got %d", role, want, got)
+		}
+	}
+
+	// un token sin perms (emitido antes de los permisos) usa los del rol
+	legacy, _ := keys.Sign(jwt.MapClaims{
+		"iss": cfg.JWTIssuer, "aud": cfg.JWTAudience, "sub": 7, "rol": "admin",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	if got := get(legacy); got != http.StatusOK {
+		t.Errorf("legacy admin token: expected 200, got %d", got)
+	}
+}
+
+func TestChangeRoleToInstructor(t *testing.T) {
+	svc, _ := newUsersService(t)
+	_, _ = svc.Create("root", "root@example.com", "secret123", domain.RoleAdmin)
+	ana, _ := svc.Create("ana", "ana@example.com", "secret123", domain.RoleUser)
+
+	u, err := svc.ChangeRole(ana.ID, domain.RoleInstructor)
+	if err != nil || u.Role != domain.RoleInstructor {
+		t.Fatalf("ChangeRole(instructor) = %+v, %v", u, err)
+	}
+}
@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/controllers"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestUpdateProfileChecksUniquenessAndReverifiesEmail(t *testing.T) {
+	svc, users := newUsersService(t)
+	ana, _ := svc.Create("ana", "ana@example.com", "secret123", domain.RoleUser)
+	_, _ = svc.Create("bob", "bob@example.com", "secret123", domain.RoleUser)
+	_, _ = users.MarkEmailVerified(ana.ID, ana.Email, time.Now())
+
+	if _, _, err := svc.UpdateProfile(ana.ID, services.ProfileUpdate{Username: strPtr("bob")}); !errors.Is(err, services.ErrUsernameTaken) {
+		t.Errorf("expected ErrUsernameTaken, got %v", err)
+	}
+	if _, _, err := svc.UpdateProfile(ana.ID, services.ProfileUpdate{Email: strPtr("bob@example.com")}); !errors.Is(err, services.ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken, got %v", err)
+	}
+	if _, _, err := svc.UpdateProfile(ana.ID, services.ProfileUpdate{DateOfBirth: strPtr("2999-01-01")}); !errors.Is(err, services.ErrInvalidDateOfBirth) {
+		t.Errorf("expected ErrInvalidDateOfBirth, got %v", err)
+	}
+	// un intento rechazado no deja cambios a medias
+	if !mustFindUser(t, users, ana.ID).EmailVerified || mustFindUser(t, users, ana.ID).Username != "ana" {
+		t.Fatal("rejected update must not modify the user")
+	}
+
+	u, previousEmail, err := svc.UpdateProfile(ana.ID, services.ProfileUpdate{
+		Email: strPtr("ana@new.example.com"), DisplayName: strPtr(" Ana G. "), DateOfBirth: strPtr("1990-05-17"),
+	})
+	if err != nil {
+		t.Fatalf("UpdateProfile() error: %v", err)
+	}
+	if previousEmail != "ana@example.com" || u.EmailVerified || mustFindUser(t, users, ana.ID).EmailVerified {
+		t.Error("changing the email must reset verification")
+	}
+	if u.DisplayName != "Ana G." || u.DateOfBirth == nil || u.DateOfBirth.Format("2006-01-02") != "1990-05-17" {
+		t.Errorf("profile fields not applied: %+v", u)
+	}
+
+	// nil no toca el campo, "" lo borra
+	u, previousEmail, _ = svc.UpdateProfile(ana.ID, services.ProfileUpdate{DateOfBirth: strPtr("")})
+	if previousEmail != "" || u.DisplayName != "Ana G." || u.DateOfBirth != nil {
+		t.Errorf("unexpected partial update result: %+v", u)
+	}
+}
+
+// staleUsers simula el alta concurrente: la búsqueda previa por email no ve al
+// otro usuario, pero el índice único sí
+type staleUsers struct {
+	repository.UsersRepo
+}
+
+func (staleUsers) FindByEmail(string) (*domain.User, error) { return nil, nil }
+
+func TestUpdateProfileRaceIsAConflict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepos()
+	repos.Users = staleUsers{repos.Users}
+	cfg := config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub"}
+	svc := services.NewUsersService(repos, services.NewLoginGuard(repos.LoginAttempts, cfg), cfg)
+	ana, _ := svc.Create("ana", "ana@example.com", "secret123", domain.RoleUser)
+	_, _ = svc.Create("bob", "bob@example.com", "secret123", domain.RoleUser)
+
+	if _, _, err := svc.UpdateProfile(ana.ID, services.ProfileUpdate{Email: strPtr("bob@example.com")}); !errors.Is(err, services.ErrEmailTaken) {
+		t.Fatalf("expected ErrEmailTaken from the unique index, got %v", err)
+	}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("userId", ana.ID) })
+	r.PATCH("/users/me", controllers.NewUsersControllerWithService(svc).UpdateMe)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/users/me", strings.NewReader(`{"email":"bob@example.com"}`)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestEmailChangeNotifiesThePreviousAddress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepos()
+	cfg := config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub", EmailVerifyURL: "http://localhost:8081/auth/verify"}
+	svc := services.NewUsersService(repos, services.NewLoginGuard(repos.LoginAttempts, cfg), cfg)
+	mail := &fakeSender{}
+	verify := services.NewVerificationServiceFromRepos(repos.Users, repos.UserTokens, mail, cfg)
+	ana, _ := svc.Create("ana", "ana@example.com", "secret123", domain.RoleUser)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("userId", ana.ID) })
+	r.PATCH("/users/me", controllers.NewUsersController(svc, verify).UpdateMe)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/users/me", strings.NewReader(`{"email":"ana@new.example.com"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	to := map[string]string{}
+	for _, msg := range mail.waitSent(t, 2) {
+		to[msg.To] = msg.Body
+	}
+	if !strings.Contains(to["ana@new.example.com"], "?token=") {
+		t.Errorf("expected a verification link to the new address, got %+v", to)
+	}
+	if body, ok := to["ana@example.com"]; !ok || !strings.Contains(body, "ana@new.example.com") || strings.Contains(body, "token=") {
+		t.Errorf("expected a change notice without links to the previous address, got %+v", to)
+	}
+}
+
+func TestChangePasswordRequiresCurrentAndRevokesSessions(t *testing.T) {
+	svc, tokens, users, _ := newPasswordService(t)
+	u := mustFindUser(t, users, 1)
+	session, _ := tokens.Issue(u)
+
+	if _, err := svc.Change(u.ID, "wrong", "new-secret", "10.0.0.1"); !errors.Is(err, services.ErrWrongPassword) {
+		t.Fatalf("expected ErrWrongPassword, got %v", err)
+	}
+	if _, err := svc.Change(u.ID, "old-secret", "new-secret", "10.0.0.1"); err != nil {
+		t.Fatalf("Change() error: %v", err)
+	}
+	if !utils.CheckPasswordHash("new-secret", mustFindUser(t, users, 1).PasswordHash) {
+		t.Error("password was not updated")
+	}
+	if _, _, err := tokens.Refresh(session.RefreshToken); err == nil {
+		t.Error("existing sessions must be revoked after a password change")
+	}
+}
+
+func TestChangePasswordIsThrottled(t *testing.T) {
+	svc, _, users, _ := newPasswordService(t)
+	u := mustFindUser(t, users, 1)
+
+	// LoginMaxFailures = 3: al tercer fallo la cuenta queda bloqueada
+	for i := 0; i < 3; i++ {
+		if _, err := svc.Change(u.ID, "wrong", "new-secret", "10.0.0.1"); !errors.Is(err, services.ErrWrongPassword) {
+			t.Fatalf("attempt %d: expected ErrWrongPassword, got %v", i+1, err)
+		}
+	}
+	var throttled *services.ThrottledError
+	if _, err := svc.Change(u.ID, "old-secret", "new-secret", "10.0.0.1"); !errors.As(err, &throttled) {
+		t.Fatalf("expected *ThrottledError after repeated failures, got %v", err)
+	}
+	if !utils.CheckPasswordHash("old-secret", mustFindUser(t, users, 1).PasswordHash) {
+		t.Error("a throttled change must not update the password")
+	}
+}
+
+func TestUsersMeDoesNotClashWithID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := NewMockUsersService()
+	_, _ = svc.Create("ana", "ana@example.com", "secret123", domain.RoleUser)
+	ctl := controllers.NewUsersControllerWithService(svc)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("userId", uint64(1)) })
+	r.GET("/users/:id", ctl.GetByID)
+	r.GET("/users/me", ctl.Me)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"phone"`) {
+		t.Fatalf("expected the full profile, got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"phone"`) {
+		t.Errorf("public view must not include contact data, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+func newTokenService(t *testing.T) (services.TokenService, *domain.User) {
+	t.Helper()
+	users := repository.NewUsersMemory()
+	u := &domain.User{Username: "ana", Email: "ana@example.com", Role: domain.RoleUser}
+	_ = users.Create(u)
+	cfg := config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub", JWTExpMinutes: "15", RefreshTokenTTLHours: "1"}
+	return services.NewTokenServiceFromRepos(users, repository.NewTokensMemory(), repository.NewOrgsMemory(), cfg), u
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	svc, u := newTokenService(t)
+	first, err := svc.Issue(u)
+	if err != nil {
+		t.Fatalf("Issue() error: %v", err)
+	}
+	if first.AccessToken == "" || first.RefreshToken == "" || first.ExpiresIn != 900 {
+		t.Fatalf("unexpected tokens: %+v", first)
+	}
+
+	got, second, err := svc.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error: %v", err)
+	}
+	if got.ID != u.ID || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("expected a rotated refresh token for user %d", u.ID)
+	}
+	if _, _, err := svc.Refresh("not-a-token"); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Errorf("expected ErrInvalidRefreshToken, got %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	svc, u := newTokenService(t)
+	first, _ := svc.Issue(u)
+	_, second, err := svc.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error: %v", err)
+	}
+
+	// reusar el token ya rotado revoca también el que se emitió al rotarlo
+	if _, _, err := svc.Refresh(first.RefreshToken); !errors.Is(err, services.ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+	if _, _, err := svc.Refresh(second.RefreshToken); !errors.Is(err, services.ErrRefreshTokenReused) {
+		t.Fatalf("expected the whole family to be revoked, got %v", err)
+	}
+
+	// otro login (otra familia) no se ve afectado
+	other, _ := svc.Issue(u)
+	if _, _, err := svc.Refresh(other.RefreshToken); err != nil {
+		t.Errorf("other family should still refresh: %v", err)
+	}
+}
+
+func TestLogoutRevokesAccessAndRefresh(t *testing.T) {
+	svc, u := newTokenService(t)
+	tokens, _ := svc.Issue(u)
+	before := time.Now().Add(-time.Second)
+
+	if err := svc.Logout(u.ID, tokens.RefreshToken, "jti-1", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("Logout() error: %v", err)
+	}
+	if !svc.IsRevoked("jti-1") || svc.IsRevoked("jti-2") {
+		t.Error("expected only jti-1 to be revoked")
+	}
+	if _, _, err := svc.Refresh(tokens.RefreshToken); err == nil {
+		t.Error("refresh token should be revoked after logout")
+	}
+
+	list, err := svc.RevokedSince(before)
+	if err != nil || len(list) != 1 || list[0].JTI != "jti-1" {
+		t.Fatalf("RevokedSince() = %+v, %v", list, err)
+	}
+	if list, _ := svc.RevokedSince(time.Now().Add(time.Second)); len(list) != 0 {
+		t.Errorf("expected no revocations after now, got %d", len(list))
+	}
+}
+
+// jtiOf lee el jti de un access token (sin validar la firma)
+func jtiOf(t *testing.T, token string) string {
+	t.Helper()
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	jti, _ := claims["jti"].(string)
+	return jti
+}
+
+func TestRevokeAllSessionsRevokesAccessTokens(t *testing.T) {
+	svc, u := newTokenService(t)
+	first, _ := svc.Issue(u)
+	_, rotated, _ := svc.Refresh(first.RefreshToken)
+	other, _ := svc.Issue(u)
+
+	if err := svc.RevokeAllSessions(u.ID); err != nil {
+		t.Fatalf("RevokeAllSessions() error: %v", err)
+	}
+	// también el access token de la sesión ya rotada, que sigue vigente
+	for _, tk := range []*services.Tokens{first, rotated, other} {
+		if !svc.IsRevoked(jtiOf(t, tk.AccessToken)) {
+			t.Errorf("access token %s should be revoked", jtiOf(t, tk.AccessToken))
+		}
+	}
+	if _, _, err := svc.Refresh(other.RefreshToken); err == nil {
+		t.Error("refresh tokens should be revoked")
+	}
+}
+
+func TestDeleteRevokesTokens(t *testing.T) {
+	repos := repository.NewMemoryRepos()
+	cfg := config.Config{JWTIssuer: "users-api", JWTAudience: "sporthub", JWTExpMinutes: "15", RefreshTokenTTLHours: "1"}
+	users := services.NewUsersService(repos, services.NewLoginGuard(repos.LoginAttempts, cfg), cfg)
+	tokens := services.NewTokenService(repos, cfg)
+	ana, _ := users.Create("ana", "ana@example.com", "secret123", domain.RoleUser)
+	issued, _ := tokens.Issue(ana)
+
+	if err := users.DeleteAccount(ana.ID, "secret123", "", "10.0.0.1"); err != nil {
+		t.Fatalf("DeleteAccount() error: %v", err)
+	}
+	if !tokens.IsRevoked(jtiOf(t, issued.AccessToken)) {
+		t.Error("the access token of a deleted user must be revoked")
+	}
+	if _, _, err := tokens.Refresh(issued.RefreshToken); !errors.Is(err, services.ErrRefreshTokenReused) {
+		t.Errorf("expected the refresh token to be revoked, got %v", err)
+	}
+}
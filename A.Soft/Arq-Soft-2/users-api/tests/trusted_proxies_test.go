@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/controllers"
+	"github.com/sporthub/users-api/internal/middleware"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+// loginRouter es POST /auth/login con el bloqueo por IP en 3 fallos
+func loginRouter(t *testing.T, trustedProxies string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := config.Config{LoginMaxFailures: "50", LoginIPMaxFailures: "3", LoginLockoutMinutes: "15", TrustedProxies: trustedProxies}
+	repos := repository.NewMemoryRepos()
+	guard := services.NewLoginGuard(repository.NewLoginAttemptsMemory(), cfg)
+	users := services.NewUsersService(repos, guard, cfg)
+	tokens := services.NewTokenServiceFromRepos(repos.Users, repository.NewTokensMemory(), repository.NewOrgsMemory(), cfg)
+	mfa := services.NewMFAServiceFromRepos(repository.NewMFAMemory(), repos.Users, guard, cfg)
+	r := gin.New()
+	if err := middleware.TrustProxies(r, cfg); err != nil {
+		t.Fatal(err)
+	}
+	r.POST("/auth/login", controllers.NewAuthController(users, tokens, mfa).Login)
+	return r
+}
+
+// failedLogin manda un login fallido desde remoteAddr con un X-Forwarded-For
+func failedLogin(r *gin.Engine, remoteAddr, forwardedFor string, i int) int {
+	body, _ := json.Marshal(map[string]string{"login": fmt.Sprintf("nadie%d", i), "password": "wrong"})
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestSpoofedForwardedForDoesNotDodgeIPLockout(t *testing.T) {
+	r := loginRouter(t, "")
+	// el atacante cambia el header en cada intento, la conexión es la misma
+	for i := 0; i < 3; i++ {
+		if code := failedLogin(r, "203.0.113.7:4000", fmt.Sprintf("10.0.0.%d", i), i); code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, code)
+		}
+	}
+	if code := failedLogin(r, "203.0.113.7:4001", "10.0.0.99", 99); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the connection's IP to be locked despite X-Forwarded-For, got %d", code)
+	}
+}
+
+func TestForwardedForFromTrustedProxyIsTheClientIP(t *testing.T) {
+	r := loginRouter(t, "192.0.2.0/24")
+	// detrás del proxy cada cliente tiene su IP aunque la conexión sea la misma
+	for i := 0; i < 4; i++ {
+		if code := failedLogin(r, "192.0.2.10:4000", fmt.Sprintf("198.51.100.%d", i), i); code != http.StatusUnauthorized {
+			t.Fatalf("client %d: expected 401, got %d", i+1, code)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		failedLogin(r, "192.0.2.10:4000", "198.51.100.200", 10+i)
+	}
+	if code := failedLogin(r, "192.0.2.10:4000", "198.51.100.200", 20); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the forwarded client IP to be locked, got %d", code)
+	}
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/events"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+// fakePublisher guarda los eventos publicados; con fail != nil falla
+type fakePublisher struct {
+	mu     sync.Mutex
+	events []events.UserEvent
+	keys   []string
+	fail   error
+}
+
+func (f *fakePublisher) Publish(routing string, payload any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail != nil {
+		return f.fail
+	}
+	// el relay publica el JSON tal como quedó en el outbox
+	b, _ := json.Marshal(payload)
+	var ev events.UserEvent
+	_ = json.Unmarshal(b, &ev)
+	f.keys = append(f.keys, routing)
+	f.events = append(f.events, ev)
+	return nil
+}
+
+func (f *fakePublisher) Close() {}
+
+func TestUserLifecyclePublishesEvents(t *testing.T) {
+	repos := repository.NewMemoryRepos()
+	pub := &fakePublisher{}
+	cfg := config.Config{}
+	guard := services.NewLoginGuard(repos.LoginAttempts, cfg)
+	svc := services.NewUsersService(repos, guard, cfg)
+	relay := events.NewRelay(repos.Outbox, pub, time.Second)
+
+	ana, _ := svc.Register("ana", "ana@example.com", "secret123", "")
+	_, _, _ = svc.UpdateProfile(ana.ID, services.ProfileUpdate{DisplayName: strPtr("Ana")})
+	if err := svc.DeleteAccount(ana.ID, "wrong", "", "10.0.0.1"); !errors.Is(err, services.ErrWrongPassword) {
+		t.Fatalf("expected ErrWrongPassword, got %v", err)
+	}
+	if err := svc.DeleteAccount(ana.ID, "secret123", "", "10.0.0.1"); err != nil {
+		t.Fatalf("DeleteAccount() error: %v", err)
+	}
+	if u, _ := repos.Users.FindByID(ana.ID); u != nil {
+		t.Error("user row should be deleted")
+	}
+	// nada se publica hasta que corre el relay
+	if len(pub.keys) != 0 {
+		t.Fatalf("events must go through the outbox, got %v", pub.keys)
+	}
+	if n, err := relay.Flush(context.Background()); err != nil || n != 3 {
+		t.Fatalf("Flush() = %d, %v", n, err)
+	}
+
+	want := []string{events.UserCreated, events.UserUpdated, events.UserDeleted}
+	if len(pub.keys) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, pub.keys)
+	}
+	for i, k := range want {
+		if pub.keys[i] != k || pub.events[i].UserID != "1" {
+			t.Errorf("event %d: got %s %+v", i, pub.keys[i], pub.events[i])
+		}
+	}
+	// el evento de baja no lleva datos personales
+	if del := pub.events[2]; del.Email != "" || del.Username != "" {
+		t.Errorf("user.deleted must only carry the id, got %+v", del)
+	}
+	// ya publicados: no se vuelven a mandar
+	if n, _ := relay.Flush(context.Background()); n != 0 {
+		t.Errorf("published events must not be sent again, got %d", n)
+	}
+}
+
+func TestRelayKeepsEventsUntilPublished(t *testing.T) {
+	outbox := repository.NewOutboxMemory()
+	pub := &fakePublisher{fail: errors.New("broker down")}
+	relay := events.NewRelay(outbox, pub, time.Second)
+	for _, id := range []uint64{1, 2} {
+		u := &domain.User{ID: id}
+		if err := events.Enqueue(outbox, events.UserDeleted, events.NewUserEvent("deleted", u)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := relay.Flush(context.Background()); err == nil {
+		t.Fatal("expected the publish error")
+	}
+	// el lote se soltó: se puede volver a tomar enseguida, en el mismo orden
+	pub.fail = nil
+	if n, err := relay.Flush(context.Background()); err != nil || n != 2 {
+		t.Fatalf("Flush() after recovery = %d, %v", n, err)
+	}
+	if pub.events[0].UserID != "1" || pub.events[1].UserID != "2" {
+		t.Errorf("events published out of order: %+v", pub.events)
+	}
+
+	// un lote tomado por otra réplica no se publica dos veces
+	_ = events.Enqueue(outbox, events.UserDeleted, events.NewUserEvent("deleted", &domain.User{ID: 3}))
+	now := time.Now()
+	if evs, _ := outbox.Claim("other", now, now.Add(time.Minute), 10); len(evs) != 1 {
+		t.Fatalf("expected the other replica to claim the event, got %d", len(evs))
+	}
+	if n, _ := relay.Flush(context.Background()); n != 0 {
+		t.Errorf("events leased by another replica must be skipped, got %d", n)
+	}
+}
+
+func TestLastAdminCannotDeleteOwnAccount(t *testing.T) {
+	svc, _ := newUsersService(t)
+	root, _ := svc.Create("root", "root@example.com", "secret123", domain.RoleAdmin)
+	if err := svc.DeleteAccount(root.ID, "secret123", "", "10.0.0.1"); !errors.Is(err, services.ErrLastAdmin) {
+		t.Errorf("expected ErrLastAdmin, got %v", err)
+	}
+}
+
+func TestConcurrentAdminDeletionsKeepOneAdmin(t *testing.T) {
+	svc, users := newUsersService(t)
+	a, _ := svc.Create("admin1", "admin1@example.com", "secret123", domain.RoleAdmin)
+	b, _ := svc.Create("admin2", "admin2@example.com", "secret123", domain.RoleAdmin)
+
+	// los dos admins se dan de baja a la vez: uno tiene que quedar
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, id := range []uint64{a.ID, b.ID} {
+		wg.Add(1)
+		go func(i int, id uint64) {
+			defer wg.Done()
+			errs[i] = svc.DeleteAccount(id, "secret123", "", "10.0.0.1")
+		}(i, id)
+	}
+	wg.Wait()
+
+	if n, _ := users.CountByRole(domain.RoleAdmin); n != 1 {
+		t.Fatalf("expected exactly one admin left, got %d (errs %v)", n, errs)
+	}
+	if !errors.Is(errs[0], services.ErrLastAdmin) && !errors.Is(errs[1], services.ErrLastAdmin) {
+		t.Errorf("one of the deletions must fail with ErrLastAdmin, got %v", errs)
+	}
+}
+
+func TestDeleteAccountIsThrottled(t *testing.T) {
+	repos := repository.NewMemoryRepos()
+	cfg := config.Config{LoginMaxFailures: "3", LoginLockoutMinutes: "15"}
+	svc := services.NewUsersService(repos, services.NewLoginGuard(repos.LoginAttempts, cfg), cfg)
+	ana, _ := svc.Register("ana", "ana@example.com", "secret123", "")
+
+	// al tercer fallo la cuenta queda bloqueada, como en el login
+	for i := 0; i < 3; i++ {
+		if err := svc.DeleteAccount(ana.ID, "wrong", "", "10.0.0.1"); !errors.Is(err, services.ErrWrongPassword) {
+			t.Fatalf("attempt %d: expected ErrWrongPassword, got %v", i+1, err)
+		}
+	}
+	var throttled *services.ThrottledError
+	if err := svc.DeleteAccount(ana.ID, "secret123", "", "10.0.0.1"); !errors.As(err, &throttled) {
+		t.Fatalf("expected *ThrottledError after repeated failures, got %v", err)
+	}
+	if u, _ := repos.Users.FindByID(ana.ID); u == nil {
+		t.Error("a throttled deletion must not delete the account")
+	}
+}
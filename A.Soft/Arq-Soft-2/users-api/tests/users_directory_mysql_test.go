@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/sporthub/users-api/internal/db"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+)
+
+// usersMySQLForTest migra la base de TEST_MYSQL_DSN y carga usuarios con
+// fechas de alta conocidas (uno por día desde el 1/1/2024, en este orden)
+func usersMySQLForTest(t *testing.T) repository.UsersRepo {
+	t.Helper()
+	sqlDB := mysqlForTest(t)
+	runner, err := db.NewMigrationRunner(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	gdb, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB}), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := repository.NewUsersMySQL(gdb)
+	day := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, u := range []struct {
+		username, email string
+		role            domain.Role
+	}{
+		{"carla", "carla@example.com", domain.RoleUser},
+		{"ana", "zeta@example.com", domain.RoleAdmin},
+		{"a_b", "ab@example.com", domain.RoleUser},
+		{"axb", "axb@example.com", domain.RoleInstructor},
+		{"bruno", "bruno@club.org", domain.RoleUser},
+	} {
+		err := repo.Create(&domain.User{Username: u.username, Email: u.email, PasswordHash: "x", Role: u.role,
+			CreatedAt: day.AddDate(0, 0, i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func usernames(users []domain.User) []string {
+	out := make([]string, 0, len(users))
+	for _, u := range users {
+		out = append(out, u.Username)
+	}
+	return out
+}
+
+func TestMySQLListUsersSearchAndFilters(t *testing.T) {
+	repo := usersMySQLForTest(t)
+	from := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		f     repository.UserFilter
+		want  []string
+		total int64
+	}{
+		{"no filter", repository.UserFilter{Limit: 10}, []string{"carla", "ana", "a_b", "axb", "bruno"}, 5},
+		{"search by username or email", repository.UserFilter{Search: "ar", Limit: 10}, []string{"carla"}, 1},
+		{"search by email domain", repository.UserFilter{Search: "club.org", Limit: 10}, []string{"bruno"}, 1},
+		// _ y % se buscan literales, no como comodines de LIKE
+		{"underscore is literal", repository.UserFilter{Search: "a_b", Limit: 10}, []string{"a_b"}, 1},
+		{"percent is literal", repository.UserFilter{Search: "%", Limit: 10}, []string{}, 0},
+		{"role", repository.UserFilter{Role: domain.RoleAdmin, Limit: 10}, []string{"ana"}, 1},
+		{"created range", repository.UserFilter{CreatedFrom: &from, CreatedTo: &to, Limit: 10}, []string{"ana", "a_b"}, 2},
+	}
+	for _, tt := range tests {
+		users, total, err := repo.List(tt.f)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := usernames(users); total != tt.total || !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v (total %d), want %v (total %d)", tt.name, got, total, tt.want, tt.total)
+		}
+	}
+}
+
+func TestMySQLListUsersSortAndPaging(t *testing.T) {
+	repo := usersMySQLForTest(t)
+
+	tests := []struct {
+		name string
+		f    repository.UserFilter
+		want []string
+	}{
+		{"username", repository.UserFilter{Sort: "username", Limit: 10}, []string{"a_b", "ana", "axb", "bruno", "carla"}},
+		{"email desc", repository.UserFilter{Sort: "email", Desc: true, Limit: 10}, []string{"ana", "carla", "bruno", "axb", "a_b"}},
+		{"createdAt desc", repository.UserFilter{Sort: "createdAt", Desc: true, Limit: 10}, []string{"bruno", "axb", "a_b", "ana", "carla"}},
+		// fuera de la lista blanca ordena por fecha de alta: nunca llega al SQL
+		{"unknown column", repository.UserFilter{Sort: "password_hash", Limit: 10}, []string{"carla", "ana", "a_b", "axb", "bruno"}},
+		{"injection", repository.UserFilter{Sort: "username; DROP TABLE users", Limit: 10}, []string{"carla", "ana", "a_b", "axb", "bruno"}},
+		{"second page", repository.UserFilter{Sort: "username", Offset: 2, Limit: 2}, []string{"axb", "bruno"}},
+		{"past the end", repository.UserFilter{Sort: "username", Offset: 10, Limit: 2}, []string{}},
+	}
+	for _, tt := range tests {
+		users, total, err := repo.List(tt.f)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		// el total no depende de la página
+		if got := usernames(users); total != 5 || !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v (total %d), want %v (total 5)", tt.name, got, total, tt.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sporthub/users-api/internal/controllers"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+)
+
+func TestListUsersParsesFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := NewMockUsersService()
+	_, _ = svc.Create("ana", "ana@example.com", "secret123", domain.RoleAdmin)
+	r := gin.New()
+	r.GET("/users", controllers.NewUsersControllerWithService(svc).List)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users?q=ana&rol=admin&sort=-username&page=3&size=5&createdFrom=2024-01-01&createdTo=2024-01-31", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Users []map[string]any `json:"users"`
+		Total int64            `json:"total"`
+		Page  int              `json:"page"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Total != 1 || len(resp.Users) != 1 || resp.Page != 3 {
+		t.Errorf("unexpected response: %s", w.Body.String())
+	}
+
+	f := toConcreteMock(svc).listed
+	if f.Search != "ana" || f.Role != domain.RoleAdmin || f.Sort != "username" || !f.Desc || f.Offset != 10 || f.Limit != 5 {
+		t.Errorf("unexpected filter: %+v", f)
+	}
+	// createdTo incluye el día entero
+	if f.CreatedFrom == nil || f.CreatedTo == nil || f.CreatedTo.Sub(*f.CreatedFrom).Hours() != 31*24 {
+		t.Errorf("unexpected date range: %v - %v", f.CreatedFrom, f.CreatedTo)
+	}
+
+	for _, query := range []string{"sort=password", "rol=normal", "size=500", "createdFrom=yesterday"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestListUsersFiltersByRole(t *testing.T) {
+	svc, _ := newUsersService(t)
+	_, _ = svc.Create("root", "root@example.com", "secret123", domain.RoleAdmin)
+	_, _ = svc.Create("ana", "ana@example.com", "secret123", domain.RoleUser)
+
+	users, total, err := svc.List(repository.UserFilter{Role: domain.RoleAdmin})
+	if err != nil || total != 1 || users[0].Username != "root" {
+		t.Fatalf("List(admin) = %+v, %d, %v", users, total, err)
+	}
+	if _, _, err := svc.List(repository.UserFilter{Role: "normal"}); !errors.Is(err, services.ErrInvalidRole) {
+		t.Errorf("the normal role no longer exists, got %v", err)
+	}
+}
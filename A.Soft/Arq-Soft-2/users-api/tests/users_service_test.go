@@ -2,10 +2,60 @@ package tests
 
 import (
 	"testing"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+	"github.com/sporthub/users-api/internal/utils"
 )
 
 func TestCreate(t *testing.T) {
-	// This test is skipped because it requires database connection
-	// In production, you'd want dependency injection or a test constructor
-	t.Skip("Skipping due to database dependency")
+	repos := repository.NewMemoryRepos()
+	cfg := config.Config{}
+	guard := services.NewLoginGuard(repos.LoginAttempts, cfg)
+	svc := services.NewUsersService(repos, guard, cfg)
+
+	u, err := svc.Create("ana", "ana@example.com", "secret123", domain.RoleUser)
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if u.ID == 0 || u.PasswordHash == "secret123" {
+		t.Fatalf("expected a stored user with a hashed password, got %+v", u)
+	}
+	stored := mustFindUser(t, repos.Users, u.ID)
+	if !utils.CheckPasswordHash("secret123", stored.PasswordHash) {
+		t.Error("stored hash does not match the password")
+	}
+	if _, err := svc.Create("ana", "otra@example.com", "secret123", domain.RoleUser); err == nil {
+		t.Error("duplicate username must be rejected")
+	}
+	if _, err := svc.Create("otra", "ana@example.com", "secret123", domain.RoleUser); err == nil {
+		t.Error("duplicate email must be rejected")
+	}
+}
+
+func TestUsersMemoryList(t *testing.T) {
+	users := repository.NewUsersMemory()
+	for _, u := range []*domain.User{
+		{Username: "carla", Email: "carla@example.com", Role: domain.RoleUser},
+		{Username: "ana", Email: "ana@club.com", Role: domain.RoleAdmin},
+		{Username: "bruno", Email: "bruno@example.com", Role: domain.RoleUser},
+	} {
+		if err := users.Create(u); err != nil {
+			t.Fatalf("Create() error: %v", err)
+		}
+	}
+
+	list, total, err := users.List(repository.UserFilter{Search: "example", Sort: "username", Limit: 10})
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if total != 2 || list[0].Username != "bruno" || list[1].Username != "carla" {
+		t.Errorf("unexpected search result: total=%d %+v", total, list)
+	}
+	list, total, _ = users.List(repository.UserFilter{Role: domain.RoleUser, Sort: "username", Desc: true, Offset: 1, Limit: 1})
+	if total != 2 || len(list) != 1 || list[0].Username != "bruno" {
+		t.Errorf("unexpected page: total=%d %+v", total, list)
+	}
 }
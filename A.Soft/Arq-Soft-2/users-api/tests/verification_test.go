@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sporthub/users-api/internal/config"
+	"github.com/sporthub/users-api/internal/domain"
+	"github.com/sporthub/users-api/internal/repository"
+	"github.com/sporthub/users-api/internal/services"
+	"github.com/sporthub/users-api/internal/utils"
+)
+
+func newVerificationService(t *testing.T, resendSecs, maxPerHour string) (services.VerificationService, repository.UsersRepo, repository.UserTokensRepo, *fakeSender) {
+	t.Helper()
+	users := repository.NewUsersMemory()
+	_ = users.Create(&domain.User{Username: "ana", Email: "ana@example.com", Role: domain.RoleUser})
+	cfg := config.Config{
+		EmailVerifyURL: "http://localhost:8081/auth/verify", EmailVerifyTTLHours: "48",
+		EmailVerifyResendSecs: resendSecs, EmailVerifyMaxPerHour: maxPerHour,
+	}
+	tokens := repository.NewUserTokensMemory()
+	mail := &fakeSender{}
+	return services.NewVerificationServiceFromRepos(users, tokens, mail, cfg), users, tokens, mail
+}
+
+func TestVerifyMarksEmailVerified(t *testing.T) {
+	svc, users, _, mail := newVerificationService(t, "60", "5")
+	if err := svc.Send(context.Background(), 1); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if len(mail.sent) != 1 || mail.sent[0].To != "ana@example.com" {
+		t.Fatalf("expected one email to ana, got %+v", mail.sent)
+	}
+	token := tokenFromEmail(t, mail.sent[0])
+
+	u, err := svc.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if !u.EmailVerified || !mustFindUser(t, users, 1).EmailVerified || u.EmailVerifiedAt == nil {
+		t.Error("user should be marked as verified")
+	}
+	if _, err := svc.Verify(token); !errors.Is(err, services.ErrInvalidVerifyToken) {
+		t.Errorf("verification token must be single-use, got %v", err)
+	}
+	if err := svc.Send(context.Background(), 1); !errors.Is(err, services.ErrAlreadyVerified) {
+		t.Errorf("expected ErrAlreadyVerified, got %v", err)
+	}
+}
+
+func TestResendIsRateLimited(t *testing.T) {
+	svc, _, _, mail := newVerificationService(t, "60", "5")
+	if err := svc.Send(context.Background(), 1); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if err := svc.Send(context.Background(), 1); !errors.Is(err, services.ErrVerifyRateLimited) {
+		t.Fatalf("expected ErrVerifyRateLimited within the resend interval, got %v", err)
+	}
+	if len(mail.sent) != 1 {
+		t.Errorf("rate limited resend must not send an email, got %d", len(mail.sent))
+	}
+	if _, err := svc.Verify("bogus"); !errors.Is(err, services.ErrInvalidVerifyToken) {
+		t.Errorf("expected ErrInvalidVerifyToken, got %v", err)
+	}
+}
+
+func TestResendIsCappedPerHour(t *testing.T) {
+	svc, _, tokens, mail := newVerificationService(t, "60", "2")
+	// dos envíos en la última hora, los dos fuera del intervalo entre envíos
+	seed := func(hash string, ago time.Duration) {
+		now := time.Now()
+		err := tokens.Create(&domain.UserToken{UserID: 1, Purpose: domain.TokenPurposeEmailVerify, TokenHash: hash,
+			ExpiresAt: now.Add(48 * time.Hour), CreatedAt: now.Add(-ago)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	seed("h1", 50*time.Minute)
+	seed("h2", 20*time.Minute)
+	if err := svc.Send(context.Background(), 1); !errors.Is(err, services.ErrVerifyRateLimited) {
+		t.Fatalf("expected ErrVerifyRateLimited after the hourly cap, got %v", err)
+	}
+	if len(mail.sent) != 0 {
+		t.Errorf("capped resend must not send an email, got %d", len(mail.sent))
+	}
+
+	// los envíos de hace más de una hora no cuentan
+	svc, _, tokens, _ = newVerificationService(t, "60", "2")
+	seed("h3", 90*time.Minute)
+	seed("h4", 20*time.Minute)
+	if err := svc.Send(context.Background(), 1); err != nil {
+		t.Errorf("only one send in the last hour, expected no error, got %v", err)
+	}
+}
+
+func TestEmailChangeVoidsPendingVerificationLinks(t *testing.T) {
+	repos := repository.NewMemoryRepos()
+	cfg := config.Config{
+		JWTIssuer: "users-api", JWTAudience: "sporthub",
+		EmailVerifyURL: "http://localhost:8081/auth/verify", EmailVerifyResendSecs: "60",
+	}
+	users := services.NewUsersService(repos, services.NewLoginGuard(repos.LoginAttempts, cfg), cfg)
+	mail := &fakeSender{}
+	verify := services.NewVerificationServiceFromRepos(repos.Users, repos.UserTokens, mail, cfg)
+	ana, _ := users.Create("ana", "ana@example.com", "secret123", domain.RoleUser)
+
+	if err := verify.Send(context.Background(), ana.ID); err != nil {
+		t.Fatal(err)
+	}
+	oldLink := tokenFromEmail(t, mail.sent[0])
+	if _, _, err := users.UpdateProfile(ana.ID, services.ProfileUpdate{Email: strPtr("victim@example.com")}); err != nil {
+		t.Fatal(err)
+	}
+	// el reenvío al email nuevo cae en el rate limit: igual el link viejo no vale
+	if err := verify.Send(context.Background(), ana.ID); !errors.Is(err, services.ErrVerifyRateLimited) {
+		t.Fatalf("expected ErrVerifyRateLimited, got %v", err)
+	}
+	if _, err := verify.Verify(oldLink); !errors.Is(err, services.ErrInvalidVerifyToken) {
+		t.Errorf("a link sent to the previous address must not verify the new one, got %v", err)
+	}
+
+	// aunque el token siguiera pendiente, solo verifica la dirección a la que se mandó
+	_ = repos.UserTokens.Create(&domain.UserToken{UserID: ana.ID, Purpose: domain.TokenPurposeEmailVerify,
+		TokenHash: utils.HashToken("stale"), ExpiresAt: time.Now().Add(time.Hour), Email: "ana@example.com"})
+	if _, err := verify.Verify("stale"); !errors.Is(err, services.ErrInvalidVerifyToken) {
+		t.Errorf("expected ErrInvalidVerifyToken for a token of another address, got %v", err)
+	}
+	if mustFindUser(t, repos.Users, ana.ID).EmailVerified {
+		t.Error("the new address must stay unverified")
+	}
+}